@@ -0,0 +1,202 @@
+package encoding
+
+import (
+	"encoding"
+	"encoding/gob"
+	"fmt"
+	"io"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/op"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// UnmarshalOp makes an op back from the attributes its MarshalBinary method returned
+type UnmarshalOp func(attrs []byte) (op.Op, error)
+
+var ops = make(map[string]UnmarshalOp)
+
+// RegisterOp registers the ops of the type of o, so that the graphs that hold them can be saved and loaded.
+// The ops have to implement encoding.BinaryMarshaler, and unmarshal makes them back from their attributes.
+func RegisterOp(o op.Op, unmarshal UnmarshalOp) { ops[opName(o)] = unmarshal }
+
+func opName(o op.Op) string { return fmt.Sprintf("%T", o) }
+
+// dtypes are the dtypes of the values a graph can be saved with, by name
+var dtypes = make(map[string]tensor.Dtype)
+
+func init() {
+	for _, dt := range []tensor.Dtype{
+		tensor.Bool, tensor.Int, tensor.Int8, tensor.Int16, tensor.Int32, tensor.Int64,
+		tensor.Uint, tensor.Uint8, tensor.Uint16, tensor.Uint32, tensor.Uint64,
+		tensor.Float32, tensor.Float64, tensor.Complex64, tensor.Complex128,
+	} {
+		dtypes[dt.Name()] = dt
+	}
+}
+
+// ParseDtype returns the dtype of the given name, for the ops that save a dtype among their attributes
+func ParseDtype(name string) (tensor.Dtype, error) {
+	dt, ok := dtypes[name]
+	if !ok {
+		return tensor.Dtype{}, errors.Errorf("Cannot save or load values of %v", name)
+	}
+	return dt, nil
+}
+
+// savedGraph is what Save writes
+type savedGraph struct {
+	Nodes   []savedNode // the inputs of a node come before it
+	Outputs []int
+}
+
+type savedNode struct {
+	Name, Group string
+	Dtype       string
+	Tensor      bool // whether the node has a tensor type, rather than a dtype
+	Shape       []int
+
+	Op     string // empty for the leaves
+	Attrs  []byte
+	Inputs []int
+
+	Value *tensor.Dense // the value bound to a leaf, if any
+}
+
+// Save writes the nodes of g the outputs are computed from to w, along with the values bound to the leaves, such as the weights.
+// The ops have to be registered with RegisterOp.
+func Save(w io.Writer, g *exprgraph.ExprGraph, outputs ...*exprgraph.Node) error {
+	var sg savedGraph
+	index := make(map[int64]int)
+	var save func(n *exprgraph.Node) (int, error)
+	save = func(n *exprgraph.Node) (int, error) {
+		if i, ok := index[n.ID()]; ok {
+			return i, nil
+		}
+		sn := savedNode{Name: n.Name, Group: n.Group, Shape: []int(n.Shape.Clone())}
+		dt, tt, err := dtypeOf(n.T)
+		if err != nil {
+			return -1, errors.Wrapf(err, "Cannot save %v", n.Name)
+		}
+		if _, err = ParseDtype(dt.Name()); err != nil {
+			return -1, errors.Wrapf(err, "Cannot save %v", n.Name)
+		}
+		sn.Dtype, sn.Tensor = dt.Name(), tt
+
+		for _, in := range g.InputsOf(n) {
+			i, err := save(in)
+			if err != nil {
+				return -1, err
+			}
+			sn.Inputs = append(sn.Inputs, i)
+		}
+		if n.Op != nil {
+			if sn.Attrs, err = marshalOp(n.Op); err != nil {
+				return -1, errors.Wrapf(err, "Cannot save %v", n.Name)
+			}
+			sn.Op = opName(n.Op)
+		} else if n.BoundTo != nil {
+			var ok bool
+			if sn.Value, ok = n.Value().(*tensor.Dense); !ok {
+				return -1, errors.Errorf("Cannot save the value of %v: expected a *tensor.Dense. Got %T instead", n.Name, n.Value())
+			}
+		}
+
+		index[n.ID()] = len(sg.Nodes)
+		sg.Nodes = append(sg.Nodes, sn)
+		return len(sg.Nodes) - 1, nil
+	}
+
+	for _, out := range outputs {
+		i, err := save(out)
+		if err != nil {
+			return err
+		}
+		sg.Outputs = append(sg.Outputs, i)
+	}
+	return errors.Wrap(gob.NewEncoder(w).Encode(sg), "Cannot save the graph")
+}
+
+// Load reads a graph written by Save, and returns it with its outputs, in the order they were saved.
+func Load(r io.Reader) (g *exprgraph.ExprGraph, outputs []*exprgraph.Node, err error) {
+	var sg savedGraph
+	if err = gob.NewDecoder(r).Decode(&sg); err != nil {
+		return nil, nil, errors.Wrap(err, "Cannot load the graph")
+	}
+
+	g = exprgraph.NewGraph()
+	nodes := make([]*exprgraph.Node, len(sg.Nodes))
+	for i, sn := range sg.Nodes {
+		n := g.NewVertex()
+		n.Name, n.Group = sn.Name, sn.Group
+		var dt tensor.Dtype
+		if dt, err = ParseDtype(sn.Dtype); err != nil {
+			return nil, nil, errors.Wrapf(err, "Cannot load %v", sn.Name)
+		}
+		if sn.Value != nil {
+			if err = n.ApplyData(sn.Value); err != nil {
+				return nil, nil, errors.Wrapf(err, "Cannot load %v", sn.Name)
+			}
+		}
+		// ApplyData sets the bare dtype, so the type and shape are set from what was saved afterwards
+		n.Shape = tensor.Shape(sn.Shape)
+		n.T = dt
+		if sn.Tensor {
+			n.T = factory.NewTensorType(len(sn.Shape), dt)
+		}
+		if sn.Op != "" {
+			unmarshal, ok := ops[sn.Op]
+			if !ok {
+				return nil, nil, errors.Errorf("Cannot load %v: %v is not registered", sn.Name, sn.Op)
+			}
+			if n.Op, err = unmarshal(sn.Attrs); err != nil {
+				return nil, nil, errors.Wrapf(err, "Cannot load %v", sn.Name)
+			}
+		}
+		g.AddNode(n)
+		for j, in := range sn.Inputs {
+			if in < 0 || in >= i {
+				return nil, nil, errors.Errorf("Cannot load %v: input %d is not one of the nodes before it", sn.Name, j)
+			}
+			g.SetWeightedEdge(g.NewWeightedEdge(n, nodes[in], float64(j)))
+		}
+		nodes[i] = n
+	}
+	for _, i := range sg.Outputs {
+		if i < 0 || i >= len(nodes) {
+			return nil, nil, errors.Errorf("Output %d is not one of the %d nodes of the graph", i, len(nodes))
+		}
+		outputs = append(outputs, nodes[i])
+	}
+	return g, outputs, nil
+}
+
+// marshalOp returns the attributes of a registered op
+func marshalOp(o op.Op) ([]byte, error) {
+	if _, ok := ops[opName(o)]; !ok {
+		return nil, errors.Errorf("%v is not registered", opName(o))
+	}
+	m, ok := o.(encoding.BinaryMarshaler)
+	if !ok {
+		return nil, errors.Errorf("%v does not implement encoding.BinaryMarshaler", opName(o))
+	}
+	return m.MarshalBinary()
+}
+
+// dtypeOf returns the dtype of a scalar type or of the elements of a tensor type, and whether the type is a tensor type
+func dtypeOf(t hm.Type) (dt tensor.Dtype, isTensor bool, err error) {
+	switch p := t.(type) {
+	case tensor.Dtype:
+		return p, false, nil
+	case factory.TensorType:
+		dt, _, err = dtypeOf(p.Of)
+		return dt, true, err
+	case *factory.TensorType:
+		dt, _, err = dtypeOf(p.Of)
+		return dt, true, err
+	}
+	return tensor.Dtype{}, false, errors.Errorf("Expected a dtype or a tensor type. Got %v", t)
+}
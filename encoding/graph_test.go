@@ -0,0 +1,152 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"testing"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/op"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// scaleOp multiplies its input by k. It stands for the ops of the other packages.
+type scaleOp struct{ k float64 }
+
+func (o *scaleOp) Arity() int { return 1 }
+func (o *scaleOp) Type() hm.Type {
+	return hm.NewFnType(hm.TypeVariable('a'), hm.TypeVariable('a'))
+}
+func (o *scaleOp) InferShape(ds ...op.DimSizer) (tensor.Shape, error) {
+	return ds[0].(tensor.Shape).Clone(), nil
+}
+func (o *scaleOp) Do(vs ...value.Value) (value.Value, error) {
+	return tensor.Mul(vs[0].(tensor.Tensor), o.k)
+}
+func (o *scaleOp) ReturnsPtr() bool      { return false }
+func (o *scaleOp) CallsExtern() bool     { return false }
+func (o *scaleOp) OverwritesInput() int  { return -1 }
+func (o *scaleOp) WriteHash(h hash.Hash) { fmt.Fprint(h, o.String()) }
+func (o *scaleOp) Hashcode() uint32 {
+	h := fnv.New32a()
+	o.WriteHash(h)
+	return h.Sum32()
+}
+func (o *scaleOp) String() string { return fmt.Sprintf("×%v", o.k) }
+
+func (o *scaleOp) MarshalBinary() ([]byte, error) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(o.k))
+	return buf[:], nil
+}
+
+// unregisteredOp is a scaleOp that was not registered
+type unregisteredOp struct{ scaleOp }
+
+func init() {
+	RegisterOp(&scaleOp{}, func(data []byte) (op.Op, error) {
+		if len(data) != 8 {
+			return nil, errors.Errorf("Expected 8 bytes. Got %d", len(data))
+		}
+		return &scaleOp{math.Float64frombits(binary.LittleEndian.Uint64(data))}, nil
+	})
+}
+
+func TestSaveLoad(t *testing.T) {
+	g := exprgraph.NewGraph()
+	w := g.NewVertex()
+	w.Name, w.Group = "w", "weights"
+	if err := w.ApplyData(tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]float64{1, 2, 3, 4, 5, 6}))); err != nil {
+		t.Fatal(err)
+	}
+	w.T = factory.NewTensorType(2, tensor.Float64)
+	g.AddNode(w)
+	x := g.NewVertex()
+	x.Name, x.T, x.Shape = "x", factory.NewTensorType(2, tensor.Float64), tensor.Shape{2, 3}
+	g.AddNode(x)
+	unused := g.NewVertex()
+	unused.Name = "unused"
+	g.AddNode(unused)
+
+	apply := func(name string, o op.Op, inputs ...*exprgraph.Node) *exprgraph.Node {
+		n := g.NewVertex()
+		n.Name, n.Op, n.T, n.Shape = name, o, inputs[0].T, inputs[0].Shape.Clone()
+		g.AddNode(n)
+		for i, in := range inputs {
+			g.SetWeightedEdge(g.NewWeightedEdge(n, in, float64(i)))
+		}
+		return n
+	}
+	y := apply("y", &scaleOp{2}, w)
+	z := apply("z", &scaleOp{-0.5}, y, x)
+	u := apply("u", &scaleOp{3}, y)
+
+	var buf bytes.Buffer
+	if err := Save(&buf, g, z, u); err != nil {
+		t.Fatal(err)
+	}
+	g2, outputs, err := Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g2.Nodes().Len() != 5 {
+		t.Errorf("Expected the 5 nodes z and u are computed from to be saved. Got %d", g2.Nodes().Len())
+	}
+	if len(outputs) != 2 || outputs[0].Name != "z" || outputs[1].Name != "u" {
+		t.Fatalf("Expected z and u to be loaded, in order. Got %v", outputs)
+	}
+
+	z2 := outputs[0]
+	if z2.Op.String() != z.Op.String() || !z2.T.Eq(z.T) || !z2.Shape.Eq(z.Shape) {
+		t.Errorf("Expected %v of %v %v. Got %v of %v %v", z.Op, z.T, z.Shape, z2.Op, z2.T, z2.Shape)
+	}
+	ins := g2.InputsOf(z2)
+	if len(ins) != 2 || ins[0].Name != "y" || ins[1].Name != "x" {
+		t.Fatalf("Expected z to take y and x, in order. Got %v", ins)
+	}
+	if y2 := g2.InputsOf(outputs[1]); len(y2) != 1 || y2[0] != ins[0] {
+		t.Errorf("Expected z and u to share y")
+	}
+	if x2 := ins[1]; x2.Value() != nil || !x2.T.Eq(x.T) {
+		t.Errorf("Expected x to be loaded without a value, of %v. Got %v of %v", x.T, x2.Value(), x2.T)
+	}
+	w2 := g2.InputsOf(ins[0])[0]
+	if w2.Name != "w" || w2.Group != "weights" || !w2.T.Eq(w.T) {
+		t.Errorf("Expected w in weights of %v. Got %v in %v of %v", w.T, w2.Name, w2.Group, w2.T)
+	}
+	if w2.Value() == nil || !w2.Value().(*tensor.Dense).Eq(w.Value()) {
+		t.Errorf("Expected the value of w to be loaded. Got %v", w2.Value())
+	}
+
+	// errors
+	if err = Save(new(bytes.Buffer), g, apply("v", &unregisteredOp{scaleOp{1}}, w)); err == nil {
+		t.Errorf("Expected an op that is not registered not to be saved")
+	}
+	if _, _, err = Load(bytes.NewReader([]byte("not a graph"))); err == nil {
+		t.Errorf("Expected garbage not to be loaded")
+	}
+}
+
+func TestParseDtype(t *testing.T) {
+	for _, dt := range []tensor.Dtype{tensor.Float64, tensor.Float32, tensor.Int8, tensor.Int32, tensor.Bool} {
+		got, err := ParseDtype(dt.Name())
+		if err != nil {
+			t.Errorf("%v: %v", dt, err)
+			continue
+		}
+		if got != dt {
+			t.Errorf("Expected %v. Got %v", dt, got)
+		}
+	}
+	if _, err := ParseDtype("float128"); err == nil {
+		t.Errorf("Expected an unknown dtype to fail")
+	}
+}
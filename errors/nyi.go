@@ -29,3 +29,12 @@ func (e *ErrDoFail) Error() string {
 	}
 	return fmt.Sprintf("cannot perform do: %+v", e.NestedError)
 }
+
+// ErrDivisionByZero is fired when an integer is divided by zero. Unlike floats, integers have no Inf or NaN to hold the result.
+type ErrDivisionByZero struct {
+	Action string
+}
+
+func (e *ErrDivisionByZero) Error() string {
+	return fmt.Sprintf("%s: integer division by zero", e.Action)
+}
@@ -0,0 +1,117 @@
+package hooks
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value"
+)
+
+// ActivationCapture is a Hook that keeps a copy of the outputs of the nodes, keyed by Node.Name, e.g. to inspect the activations
+// of a model. Nodes without a name are not captured. A node that is executed again replaces what was captured of it.
+type ActivationCapture struct {
+	mu   sync.Mutex
+	only map[string]bool
+	acts map[string]value.Value
+}
+
+// NewActivationCapture creates an ActivationCapture of the nodes with the given names, or of all the named nodes if there are none.
+func NewActivationCapture(names ...string) *ActivationCapture {
+	c := &ActivationCapture{acts: make(map[string]value.Value)}
+	if len(names) > 0 {
+		c.only = make(map[string]bool, len(names))
+		for _, name := range names {
+			c.only[name] = true
+		}
+	}
+	return c
+}
+
+// BeforeOp ...
+func (c *ActivationCapture) BeforeOp(n *exprgraph.Node, inputs []value.Value) error { return nil }
+
+// AfterOp captures a copy of the output.
+func (c *ActivationCapture) AfterOp(n *exprgraph.Node, output value.Value, err error) error {
+	if err != nil || output == nil || n.Name == "" || (c.only != nil && !c.only[n.Name]) {
+		return err
+	}
+	v, err := value.CloneValue(output)
+	if err != nil {
+		return errors.Wrapf(err, "Cannot capture the output of %v", n.Name)
+	}
+	c.mu.Lock()
+	c.acts[n.Name] = v
+	c.mu.Unlock()
+	return nil
+}
+
+// Get returns what was captured of the node with the given name.
+func (c *ActivationCapture) Get(name string) (v value.Value, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok = c.acts[name]
+	return
+}
+
+// Activations returns everything captured so far, keyed by node name.
+func (c *ActivationCapture) Activations() map[string]value.Value {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	retVal := make(map[string]value.Value, len(c.acts))
+	for name, v := range c.acts {
+		retVal[name] = v
+	}
+	return retVal
+}
+
+// Reset drops everything captured so far.
+func (c *ActivationCapture) Reset() {
+	c.mu.Lock()
+	c.acts = make(map[string]value.Value)
+	c.mu.Unlock()
+}
+
+// NumericCheck is a Hook that stops the execution at the first node that returns a NaN or an Inf, with the *exprgraph.ErrNumeric
+// of G.CheckNumerics.
+type NumericCheck struct {
+	G *exprgraph.ExprGraph
+}
+
+// BeforeOp ...
+func (c NumericCheck) BeforeOp(n *exprgraph.Node, inputs []value.Value) error { return nil }
+
+// AfterOp checks the output.
+func (c NumericCheck) AfterOp(n *exprgraph.Node, output value.Value, err error) error {
+	if err != nil || output == nil {
+		return err
+	}
+	return c.G.CheckNumerics(n, output)
+}
+
+// Logger is a Hook that logs every call with Printf, which may be log.Printf or testing.T's Logf.
+type Logger struct {
+	Printf func(format string, args ...interface{})
+}
+
+// BeforeOp ...
+func (l Logger) BeforeOp(n *exprgraph.Node, inputs []value.Value) error {
+	shapes := make([]string, len(inputs))
+	for i, v := range inputs {
+		shapes[i] = fmt.Sprintf("%v%v", v.Dtype(), v.Shape())
+	}
+	l.Printf("→ %v = %v %v", exprgraph.NameOf(n), n.Op, shapes)
+	return nil
+}
+
+// AfterOp ...
+func (l Logger) AfterOp(n *exprgraph.Node, output value.Value, err error) error {
+	switch {
+	case err != nil:
+		l.Printf("← %v failed: %v", exprgraph.NameOf(n), err)
+	case output != nil:
+		l.Printf("← %v: %v%v", exprgraph.NameOf(n), output.Dtype(), output.Shape())
+	}
+	return err
+}
@@ -0,0 +1,85 @@
+// Package hooks provides the instrumentation points an executor calls around the execution of the nodes of a graph,
+// and a few hooks built on them: activation capture, numeric checks and logging.
+package hooks
+
+import (
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value"
+)
+
+var (
+	_ Hook = Hooks{}
+	_ Hook = Funcs{}
+	_ Hook = &ActivationCapture{}
+	_ Hook = NumericCheck{}
+	_ Hook = Logger{}
+)
+
+// Hook is called by an executor around the execution of every node. A hook that returns an error from BeforeOp or AfterOp
+// stops the execution with it, which is how custom assertions are made.
+//
+// The values given to a hook belong to the executor, which may reuse their memory once the hook returns: a hook that keeps
+// a value has to clone it.
+type Hook interface {
+	// BeforeOp is called before the op of n is executed on the inputs.
+	BeforeOp(n *exprgraph.Node, inputs []value.Value) error
+	// AfterOp is called after the op of n is executed, with the output, or the error the op failed with.
+	// It returns the error the execution carries on with: err itself, unless the hook fails the execution or recovers from err.
+	AfterOp(n *exprgraph.Node, output value.Value, err error) error
+}
+
+// Hooks calls several hooks in order. BeforeOp stops at the first hook that returns an error,
+// while AfterOp gives every hook the error returned by the previous one.
+type Hooks []Hook
+
+// BeforeOp ...
+func (hs Hooks) BeforeOp(n *exprgraph.Node, inputs []value.Value) error {
+	for _, h := range hs {
+		if err := h.BeforeOp(n, inputs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AfterOp ...
+func (hs Hooks) AfterOp(n *exprgraph.Node, output value.Value, err error) error {
+	for _, h := range hs {
+		err = h.AfterOp(n, output, err)
+	}
+	return err
+}
+
+// Exec executes n with do between the calls to h.BeforeOp and h.AfterOp, as an executor does. If BeforeOp fails, n is not executed.
+func Exec(h Hook, n *exprgraph.Node, inputs []value.Value, do func() (value.Value, error)) (value.Value, error) {
+	if err := h.BeforeOp(n, inputs); err != nil {
+		return nil, err
+	}
+	retVal, err := do()
+	if err = h.AfterOp(n, retVal, err); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+// Funcs is a Hook made of functions, for hooks that only need some of the calls. The nil functions do nothing.
+type Funcs struct {
+	Before func(n *exprgraph.Node, inputs []value.Value) error
+	After  func(n *exprgraph.Node, output value.Value, err error) error
+}
+
+// BeforeOp ...
+func (f Funcs) BeforeOp(n *exprgraph.Node, inputs []value.Value) error {
+	if f.Before == nil {
+		return nil
+	}
+	return f.Before(n, inputs)
+}
+
+// AfterOp ...
+func (f Funcs) AfterOp(n *exprgraph.Node, output value.Value, err error) error {
+	if f.After == nil {
+		return err
+	}
+	return f.After(n, output, err)
+}
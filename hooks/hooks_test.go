@@ -0,0 +1,98 @@
+package hooks
+
+import (
+	"math"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+func TestHooks(t *testing.T) {
+	g := exprgraph.NewGraph()
+	node := func(name string, inputs ...*exprgraph.Node) *exprgraph.Node {
+		n := g.NewVertex()
+		n.Name = name
+		g.AddNode(n)
+		for i, in := range inputs {
+			g.SetWeightedEdge(g.NewWeightedEdge(n, in, float64(i)))
+		}
+		return n
+	}
+	x := node("x")
+	if err := x.ApplyData(tensor.New(tensor.WithBacking([]float64{1, 2}))); err != nil {
+		t.Fatal(err)
+	}
+	y := node("y", x)
+	z, bad := node("z", y), node("bad", x)
+	returns := func(data ...float64) func() (value.Value, error) {
+		return func() (value.Value, error) { return tensor.New(tensor.WithBacking(data)), nil }
+	}
+
+	capture := NewActivationCapture()
+	var before int
+	hs := Hooks{
+		capture,
+		NumericCheck{G: g},
+		Funcs{Before: func(n *exprgraph.Node, inputs []value.Value) error {
+			before++
+			if n.Name == "bad" {
+				return errors.New("bad is not to be executed")
+			}
+			return nil
+		}},
+		Logger{Printf: t.Logf},
+	}
+
+	out, err := Exec(hs, y, []value.Value{x.Value()}, returns(2, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = y.ApplyData(out); err != nil {
+		t.Fatal(err)
+	}
+	// the capture is a copy
+	out.Data().([]float64)[0] = 100
+	if v, ok := capture.Get("y"); !ok || v.Data().([]float64)[0] != 2 {
+		t.Errorf("Expected a copy of the output of y to be captured. Got %v", v)
+	}
+
+	if _, err = Exec(hs, z, []value.Value{y.Value()}, returns(math.NaN())); err == nil {
+		t.Error("Expected a NaN to stop the execution")
+	} else if _, ok := err.(*exprgraph.ErrNumeric); !ok {
+		t.Errorf("Expected an *exprgraph.ErrNumeric. Got %v", err)
+	}
+
+	executed := false
+	if _, err = Exec(hs, bad, []value.Value{x.Value()}, func() (value.Value, error) { executed = true; return nil, nil }); err == nil || executed {
+		t.Errorf("Expected bad not to be executed. Got %v", err)
+	}
+	if before != 3 {
+		t.Errorf("Expected BeforeOp to be called for every node. Got %d calls", before)
+	}
+
+	// the error of the op goes through the hooks
+	failed := errors.New("failed")
+	if _, err = Exec(hs, y, nil, func() (value.Value, error) { return nil, failed }); err != failed {
+		t.Errorf("Expected the error of the op. Got %v", err)
+	}
+	if acts := capture.Activations(); len(acts) != 2 {
+		t.Errorf("Expected y and z to be captured. Got %v", acts)
+	}
+
+	only := NewActivationCapture("z")
+	for _, n := range []*exprgraph.Node{y, z} {
+		if err = only.AfterOp(n, tensor.New(tensor.WithBacking([]float64{1})), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok := only.Get("y"); ok {
+		t.Error("Expected only z to be captured")
+	}
+	only.Reset()
+	if len(only.Activations()) != 0 {
+		t.Error("Expected nothing to be left after a reset")
+	}
+}
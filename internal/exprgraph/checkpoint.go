@@ -0,0 +1,139 @@
+package exprgraph
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/graph/topo"
+	"gorgonia.org/gorgonia/internal/op"
+)
+
+// RecomputeGroup is the Group of the nodes added by Rematerialize. They belong to the backward pass: an executor should compute
+// them as late as it can, so that the activations they recompute are not alive any longer than the nodes that need them.
+const RecomputeGroup = "recompute"
+
+// forwardOf returns the IDs of the nodes of the forward pass: loss and the nodes it is computed from
+func (g *ExprGraph) forwardOf(loss *Node) map[int64]bool {
+	fwd := map[int64]bool{loss.ID(): true}
+	stack := []*Node{loss}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, in := range g.InputsOf(n) {
+			if !fwd[in.ID()] {
+				fwd[in.ID()] = true
+				stack = append(stack, in)
+			}
+		}
+	}
+	return fwd
+}
+
+// isLeaf returns true if n is computed from no other node. Leaves are never recomputed, as they may not be computable
+// (e.g. the inputs), or may not compute to the same value (e.g. random numbers).
+func (g *ExprGraph) isLeaf(n *Node) bool { return g.From(n.ID()).Len() == 0 }
+
+// isStateful returns true if the op of n is an op.StatefulOp, such as a dropout. Recomputing it would not compute the same
+// value (e.g. another mask), so it is never recomputed.
+func isStateful(n *Node) bool {
+	s, ok := n.Op.(op.StatefulOp)
+	return ok && s.IsStateful()
+}
+
+// SqrtCheckpoints marks evenly spaced nodes of the forward pass of loss as checkpoints, one every ⌈√n⌉ nodes in the order they are
+// computed, where n is the number of nodes computed from other nodes. Once rematerialized, a chain of n nodes then keeps about √n
+// activations for the backward pass, and recomputes each of the others once.
+//
+// It returns the nodes marked.
+func (g *ExprGraph) SqrtCheckpoints(loss *Node) ([]*Node, error) {
+	sorted, err := topo.Sort(g)
+	if err != nil {
+		return nil, errors.Wrap(err, "Cannot checkpoint")
+	}
+
+	// the edges go from a node to its inputs, so the order of computation is the reverse
+	fwd := g.forwardOf(loss)
+	var order []*Node
+	for i := len(sorted) - 1; i >= 0; i-- {
+		n, ok := sorted[i].(*Node)
+		if ok && fwd[n.ID()] && n != loss && !g.isLeaf(n) {
+			order = append(order, n)
+		}
+	}
+	if len(order) == 0 {
+		return nil, nil
+	}
+
+	k := int(math.Ceil(math.Sqrt(float64(len(order)))))
+	var marked []*Node
+	for i := k - 1; i < len(order); i += k {
+		order[i].Checkpoint = true
+		marked = append(marked, order[i])
+	}
+	return marked, nil
+}
+
+// Rematerialize rewrites g so that the backward pass recomputes the activations of the forward pass from the nearest checkpoints,
+// instead of keeping them from the forward pass. The forward pass is made of loss and the nodes it is computed from; the backward
+// pass is made of all the other nodes that are computed from some of them (e.g. the gradients).
+//
+// Every node of the backward pass that takes an activation that is neither a checkpoint, a leaf, a stateful op nor loss is given
+// a copy of it instead, computed from copies of its own inputs, down to the checkpoints, the leaves and the stateful ops. An activation is copied once, whatever
+// the number of nodes of the backward pass that need it. The copies are in the RecomputeGroup, and are returned.
+//
+// Nodes are marked as checkpoints by setting Checkpoint, or with SqrtCheckpoints. Without any, the backward pass recomputes
+// the whole forward pass.
+func (g *ExprGraph) Rematerialize(loss *Node) (recomputed []*Node) {
+	fwd := g.forwardOf(loss)
+	kept := func(n *Node) bool { return n == loss || n.Checkpoint || g.isLeaf(n) || isStateful(n) }
+
+	// the graph is only changed once the edges to rewire are known
+	type edge struct {
+		from, to *Node
+		i        float64
+	}
+	var edges []edge
+	nodes := g.Nodes()
+	for nodes.Next() {
+		n, ok := nodes.Node().(*Node)
+		if !ok || fwd[n.ID()] || g.isLeaf(n) {
+			continue
+		}
+		for _, in := range g.InputsOf(n) {
+			if fwd[in.ID()] && !kept(in) {
+				w, _ := g.Weight(n.ID(), in.ID())
+				edges = append(edges, edge{from: n, to: in, i: w})
+			}
+		}
+	}
+
+	copies := make(map[int64]*Node)
+	var recompute func(n *Node) *Node
+	recompute = func(n *Node) *Node {
+		if kept(n) {
+			return n
+		}
+		if c, ok := copies[n.ID()]; ok {
+			return c
+		}
+		c := g.NewVertex()
+		c.T, c.Shape, c.Op = n.T, n.Shape.Clone(), n.Op
+		c.Name, c.Group = n.Name, RecomputeGroup
+		c.DataOn = n.DataOn
+		g.AddNode(c)
+		copies[n.ID()] = c
+		recomputed = append(recomputed, c)
+		for _, in := range g.InputsOf(n) {
+			w, _ := g.Weight(n.ID(), in.ID())
+			g.SetWeightedEdge(g.NewWeightedEdge(c, recompute(in), w))
+		}
+		return c
+	}
+
+	for _, e := range edges {
+		c := recompute(e.to)
+		g.RemoveEdge(e.from.ID(), e.to.ID())
+		g.SetWeightedEdge(g.NewWeightedEdge(e.from, c, e.i))
+	}
+	return recomputed
+}
@@ -0,0 +1,132 @@
+package exprgraph
+
+import "testing"
+
+func TestGraph_Rematerialize(t *testing.T) {
+	g := NewGraph()
+	node := func(name string, inputs ...*Node) *Node {
+		n := g.NewVertex()
+		n.Name = name
+		g.AddNode(n)
+		for i, in := range inputs {
+			g.SetWeightedEdge(g.NewWeightedEdge(n, in, float64(i)))
+		}
+		return n
+	}
+	input := func(n *Node, i int) *Node {
+		ins := g.InputsOf(n)
+		if i >= len(ins) {
+			t.Fatalf("Expected %v to have an input %d. Got %d inputs", n.Name, i, len(ins))
+		}
+		return ins[i]
+	}
+
+	// a chain of 8 activations, and the backward pass that takes each of them
+	x := node("x")
+	hs := []*Node{x}
+	for i := 1; i <= 8; i++ {
+		hs = append(hs, node("h", hs[i-1]))
+	}
+	loss := node("loss", hs[8])
+	grads := make([]*Node, 10)
+	grads[9] = node("dloss", loss)
+	for i := 8; i >= 1; i-- {
+		grads[i] = node("dh", grads[i+1], hs[i])
+	}
+
+	marked, err := g.SqrtCheckpoints(loss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(marked) != 2 || marked[0] != hs[3] || marked[1] != hs[6] {
+		t.Fatalf("Expected h3 and h6 to be checkpoints, one every ⌈√8⌉ = 3 activations. Got %d checkpoints", len(marked))
+	}
+
+	recomputed := g.Rematerialize(loss)
+	if len(recomputed) != 6 {
+		t.Fatalf("Expected the 6 activations that aren't checkpoints to be recomputed. Got %d", len(recomputed))
+	}
+	for i := 1; i <= 8; i++ {
+		h := input(grads[i], 1)
+		switch i {
+		case 3, 6:
+			if h != hs[i] {
+				t.Errorf("Expected the backward pass to keep h%d", i)
+			}
+			continue
+		}
+		if h == hs[i] || h.Group != RecomputeGroup {
+			t.Errorf("Expected the backward pass to recompute h%d", i)
+			continue
+		}
+		// back to the nearest checkpoint, or to x
+		from := hs[(i-1)/3*3]
+		for j := i - 1; j > (i-1)/3*3; j-- {
+			if h = input(h, 0); h == hs[j] || h.Group != RecomputeGroup {
+				t.Errorf("Expected h%d to be recomputed from a copy of h%d", j+1, j)
+			}
+		}
+		if input(h, 0) != from {
+			t.Errorf("Expected h%d to be recomputed from the nearest checkpoint", i)
+		}
+	}
+	// the forward pass is left as it is
+	if input(loss, 0) != hs[8] || input(hs[8], 0) != hs[7] {
+		t.Error("Expected the forward pass to be left as it is")
+	}
+	if again := g.Rematerialize(loss); len(again) != 0 {
+		t.Errorf("Expected nothing more to be recomputed. Got %d nodes", len(again))
+	}
+}
+
+// statefulOp is an elemOp that draws random numbers, such as a dropout
+type statefulOp struct{ elemOp }
+
+func (o statefulOp) IsStateful() bool { return true }
+
+func TestGraph_Rematerialize_Stateful(t *testing.T) {
+	g := NewGraph()
+	node := func(name string, inputs ...*Node) *Node {
+		n := g.NewVertex()
+		n.Name = name
+		g.AddNode(n)
+		for i, in := range inputs {
+			g.SetWeightedEdge(g.NewWeightedEdge(n, in, float64(i)))
+		}
+		return n
+	}
+
+	// a dropout between the checkpoints h1 and h4
+	x := node("x")
+	h1 := node("h1", x)
+	h1.Checkpoint = true
+	h2 := node("h2", h1)
+	drop := node("dropout", h2)
+	drop.Op = statefulOp{elemOp{1}}
+	h3 := node("h3", drop)
+	h4 := node("h4", h3)
+	h4.Checkpoint = true
+	loss := node("loss", h4)
+	dh3 := node("dh3", loss, h3)
+	ddrop := node("ddropout", dh3, drop)
+	dh2 := node("dh2", ddrop, h2)
+
+	recomputed := g.Rematerialize(loss)
+	if len(recomputed) != 2 {
+		t.Fatalf("Expected h2 and h3 to be recomputed. Got %d nodes", len(recomputed))
+	}
+	if g.InputsOf(ddrop)[1] != drop {
+		t.Error("Expected the backward pass to keep the output of the dropout rather than to draw another mask")
+	}
+	c3 := g.InputsOf(dh3)[1]
+	if c3 == h3 || c3.Group != RecomputeGroup || g.InputsOf(c3)[0] != drop {
+		t.Error("Expected h3 to be recomputed from the output of the dropout")
+	}
+	c2 := g.InputsOf(dh2)[1]
+	if c2 == h2 || c2.Group != RecomputeGroup || g.InputsOf(c2)[0] != h1 {
+		t.Error("Expected h2 to be recomputed from h1")
+	}
+	if g.InputsOf(drop)[0] != h2 {
+		t.Error("Expected the forward pass to be left as it is")
+	}
+}
@@ -2,7 +2,10 @@ package exprgraph
 
 import (
 	"math"
+	"sort"
 
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
 	"gonum.org/v1/gonum/graph/simple"
 	"gorgonia.org/gorgonia/internal/op"
 )
@@ -21,8 +24,55 @@ func NewGraph() *ExprGraph {
 	}
 }
 
-// ApplyOp op to node n
-func (g *ExprGraph) ApplyOp(op op.Op, n *Node) error {
-	// TODO infer type and shape to set the node's T and Shape value
+// ApplyOp applies o to node n. The inputs of n are the nodes it has edges to, in the order of the weights of the edges.
+// The type and the shape of n are inferred from the op and the inputs.
+func (g *ExprGraph) ApplyOp(o op.Op, n *Node) error {
+	ins := g.InputsOf(n)
+	if err := op.CheckArity(o, len(ins)); err != nil {
+		return err
+	}
+
+	t := o.Type()
+	if fnt, ok := t.(*hm.FunctionType); ok {
+		// the type of the output is what the return type of the op becomes once its arguments are unified with the inputs
+		ret := fnt.Ret(true)
+		ts := make([]hm.Type, 0, len(ins)+1)
+		for _, in := range ins {
+			ts = append(ts, in.T)
+		}
+		subs, err := hm.Unify(fnt, hm.NewFnType(append(ts, ret)...))
+		if err != nil {
+			return errors.Wrapf(err, "Cannot infer the type of %v applied to %v", o, ts)
+		}
+		t = ret.Apply(subs).(hm.Type)
+	}
+
+	shapes := make([]op.DimSizer, len(ins))
+	for i, in := range ins {
+		shapes[i] = in.Shape
+	}
+	s, err := o.InferShape(shapes...)
+	if err != nil {
+		return errors.Wrapf(err, "Cannot infer the shape of %v", o)
+	}
+
+	n.Op, n.T, n.Shape = o, t, s
 	return nil
 }
+
+// InputsOf returns the inputs of n in order. The weight of an edge is the index of the input.
+func (g *ExprGraph) InputsOf(n *Node) []*Node {
+	var ins []*Node
+	it := g.From(n.ID())
+	for it.Next() {
+		if in, ok := it.Node().(*Node); ok {
+			ins = append(ins, in)
+		}
+	}
+	sort.Slice(ins, func(i, j int) bool {
+		wi, _ := g.Weight(n.ID(), ins[i].ID())
+		wj, _ := g.Weight(n.ID(), ins[j].ID())
+		return wi < wj
+	})
+	return ins
+}
@@ -1,13 +1,49 @@
 package exprgraph
 
 import (
+	"fmt"
+	"hash"
 	"math"
 	"testing"
 
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
 	"gonum.org/v1/gonum/graph"
 	"gonum.org/v1/gonum/graph/testgraph"
+	"gorgonia.org/gorgonia/internal/op"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
 )
 
+// elemOp is an elementwise op of the given arity, of type a → a → ... → a
+type elemOp struct{ arity int }
+
+func (o elemOp) Arity() int { return o.arity }
+func (o elemOp) Type() hm.Type {
+	ts := make([]hm.Type, o.arity+1)
+	for i := range ts {
+		ts[i] = hm.TypeVariable('a')
+	}
+	return hm.NewFnType(ts...)
+}
+func (o elemOp) InferShape(ds ...op.DimSizer) (tensor.Shape, error) {
+	s := ds[0].(tensor.Shape)
+	for _, d := range ds[1:] {
+		if !d.(tensor.Shape).Eq(s) {
+			return nil, errors.Errorf("Shape mismatch: %v and %v", s, d)
+		}
+	}
+	return s.Clone(), nil
+}
+func (o elemOp) Do(vs ...value.Value) (value.Value, error) { return vs[0], nil }
+func (o elemOp) ReturnsPtr() bool                          { return false }
+func (o elemOp) CallsExtern() bool                         { return false }
+func (o elemOp) OverwritesInput() int                      { return -1 }
+func (o elemOp) WriteHash(h hash.Hash)                     { fmt.Fprint(h, o.String()) }
+func (o elemOp) Hashcode() uint32                          { return uint32(o.arity) }
+func (o elemOp) String() string                            { return fmt.Sprintf("elem%d", o.arity) }
+
 func TestGraph_AddNode(t *testing.T) {
 	g := NewGraph()
 	// Simple test
@@ -18,10 +54,49 @@ func TestGraph_WeightedEdges(t *testing.T) {
 	for _, w := range []float64{-1, 0, math.MaxFloat64} {
 		g := NewGraph()
 		testgraph.AddWeightedEdges(t, 500, g, w, func(id int64) graph.Node {
-			return &node{
+			return &Node{
 				id: id,
 			}
 		}, true, true)
 	}
 
 }
+
+func TestGraph_ApplyOp(t *testing.T) {
+	g := NewGraph()
+	node := func(name string, typ hm.Type, s tensor.Shape, inputs ...*Node) *Node {
+		n := g.NewVertex()
+		n.Name, n.T, n.Shape = name, typ, s
+		g.AddNode(n)
+		for i, in := range inputs {
+			g.SetWeightedEdge(g.NewWeightedEdge(n, in, float64(i)))
+		}
+		return n
+	}
+	mat := factory.NewTensorType(2, tensor.Float64)
+	x, y := node("x", mat, tensor.Shape{2, 3}), node("y", mat, tensor.Shape{2, 3})
+	if x.Graph() != g {
+		t.Errorf("Expected a node to know the graph that created it")
+	}
+
+	z := node("z", nil, nil, x, y)
+	if err := g.ApplyOp(elemOp{2}, z); err != nil {
+		t.Fatal(err)
+	}
+	if z.Op == nil || !z.T.Eq(mat) || !z.Shape.Eq(tensor.Shape{2, 3}) {
+		t.Errorf("Expected z to be an elem2 of %v %v. Got %v of %v %v", mat, tensor.Shape{2, 3}, z.Op, z.T, z.Shape)
+	}
+
+	// errors
+	if err := g.ApplyOp(elemOp{1}, node("arity", nil, nil, x, y)); err == nil {
+		t.Errorf("Expected an op applied to the wrong number of inputs to fail")
+	}
+	v := node("v", factory.NewTensorType(1, tensor.Float32), tensor.Shape{6})
+	if err := g.ApplyOp(elemOp{2}, node("type", nil, nil, x, v)); err == nil {
+		t.Errorf("Expected inputs of different types to fail")
+	}
+	w := node("w", mat, tensor.Shape{3, 2})
+	if err := g.ApplyOp(elemOp{2}, node("shape", nil, nil, x, w)); err == nil {
+		t.Errorf("Expected inputs of different shapes to fail")
+	}
+}
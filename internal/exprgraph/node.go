@@ -1,6 +1,8 @@
 package exprgraph
 
 import (
+	"fmt"
+
 	"github.com/chewxy/hm"
 	"gorgonia.org/gorgonia/internal/execution"
 	"gorgonia.org/gorgonia/internal/op"
@@ -20,6 +22,9 @@ type Node struct {
 	Name  string
 	Group string
 
+	// Checkpoint marks a node of the forward pass whose value is kept for the backward pass (see Rematerialize)
+	Checkpoint bool
+
 	// value bondage
 	// inputs are bound to values directly
 	BoundTo value.Value
@@ -31,6 +36,8 @@ type Node struct {
 
 	// for hashing nodes
 	id int64 // id is the ID at which the node is added to the graph
+
+	g *ExprGraph // the graph that created the node
 }
 
 // Nodes ...
@@ -54,6 +61,19 @@ func (n *Node) GetName() string {
 	return n.Name
 }
 
+// NameOf returns the name of n, or its ID if it has no name. This is how nodes are named in errors, logs and profiles.
+func NameOf(n *Node) string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return fmt.Sprintf("node %d", n.ID())
+}
+
+// Graph returns the graph that created the node. It is nil for the nodes that were not created by a graph.
+func (n *Node) Graph() *ExprGraph {
+	return n.g
+}
+
 // ID fulfills the graph.Node interface
 func (n *Node) ID() int64 {
 	return n.id
@@ -0,0 +1,105 @@
+package exprgraph
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/value"
+)
+
+// ErrNumeric is returned in numeric check mode by the first node whose output holds a NaN or an Inf.
+type ErrNumeric struct {
+	Node   *Node
+	Output value.Stats
+	Inputs []value.Stats // the stats of the values of the inputs of Node, in order
+
+	// Path is a path from an input of the graph to Node. At each step it goes back through the input of largest magnitude,
+	// which is where exploding values usually come from.
+	Path []*Node
+}
+
+func (e *ErrNumeric) Error() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "%v = %v returned non-finite values: %v", NameOf(e.Node), e.Node.Op, e.Output)
+	for i, s := range e.Inputs {
+		fmt.Fprintf(&buf, "\n\tinput %d: %v", i, s)
+	}
+	names := make([]string, len(e.Path))
+	for i, n := range e.Path {
+		names[i] = NameOf(n)
+	}
+	fmt.Fprintf(&buf, "\n\tpath: %v", strings.Join(names, " → "))
+	return buf.String()
+}
+
+// CheckNumerics scans out, the output of n, for NaNs and Infs. It is what an executor in numeric check mode does after the Do of
+// every node: it returns an *ErrNumeric on the first offending node, so that execution stops there. There is no executor in
+// this package yet, so for now whatever runs the ops of the graph calls it.
+//
+// The stats of the inputs and the path are computed from the values bound to the nodes, so the inputs of n have to be bound
+// to the values n was computed from.
+func (g *ExprGraph) CheckNumerics(n *Node, out value.Value) error {
+	s, err := value.StatsOf(out)
+	if err != nil {
+		return errors.Wrapf(err, "Cannot check the output of %v", NameOf(n))
+	}
+	if s.Finite() {
+		return nil
+	}
+
+	e := &ErrNumeric{Node: n, Output: s}
+	ins := g.InputsOf(n)
+	e.Inputs = make([]value.Stats, len(ins))
+	for i, in := range ins {
+		if e.Inputs[i], err = statsOf(in); err != nil {
+			return errors.Wrapf(err, "Cannot check input %d of %v", i, NameOf(n))
+		}
+	}
+
+	// back from n through the input of largest magnitude, to an input of the graph
+	e.Path = []*Node{n}
+	for cur := n; ; {
+		var next *Node
+		largest := -1.0
+		for _, in := range g.InputsOf(cur) {
+			is, err := statsOf(in)
+			if err != nil {
+				return errors.Wrapf(err, "Cannot check %v", NameOf(in))
+			}
+			if m := magnitude(is); m > largest {
+				next, largest = in, m
+			}
+		}
+		if next == nil {
+			break
+		}
+		e.Path = append(e.Path, next)
+		cur = next
+	}
+	for i, j := 0, len(e.Path)-1; i < j; i, j = i+1, j-1 {
+		e.Path[i], e.Path[j] = e.Path[j], e.Path[i]
+	}
+	return e
+}
+
+// statsOf returns the stats of the value bound to n. A node without a value has no elements.
+func statsOf(n *Node) (value.Stats, error) {
+	v := n.Value()
+	if v == nil {
+		return value.Stats{Min: math.NaN(), Max: math.NaN(), Mean: math.NaN(), FirstNonFinite: -1}, nil
+	}
+	return value.StatsOf(v)
+}
+
+// magnitude orders the values for the path of an ErrNumeric: non-finite values come first, then the largest ones.
+func magnitude(s value.Stats) float64 {
+	switch {
+	case !s.Finite():
+		return math.Inf(1)
+	case s.Size == 0 || math.IsNaN(s.Min):
+		return 0
+	}
+	return math.Max(math.Abs(s.Min), math.Abs(s.Max))
+}
@@ -0,0 +1,54 @@
+package exprgraph
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+func TestGraph_CheckNumerics(t *testing.T) {
+	g := NewGraph()
+	vec := func(data ...float64) value.Value { return tensor.New(tensor.WithBacking(data)) }
+	// node adds a node bound to v, computed from the inputs
+	node := func(name string, v value.Value, inputs ...*Node) *Node {
+		n := g.NewVertex()
+		n.Name = name
+		if err := n.ApplyData(v); err != nil {
+			t.Fatal(err)
+		}
+		g.AddNode(n)
+		for i, in := range inputs {
+			g.SetWeightedEdge(g.NewWeightedEdge(n, in, float64(i)))
+		}
+		return n
+	}
+	x, w, b := node("x", vec(1, 2)), node("w", vec(1e300, -1e300)), node("b", vec(0.5, 0.5))
+	y := node("y", vec(1e300, -2e300), x, w)
+	z := node("z", vec(0, 0), b, y)
+
+	if err := g.CheckNumerics(y, y.Value()); err != nil {
+		t.Errorf("Expected finite values to pass. Got %v", err)
+	}
+
+	err := g.CheckNumerics(z, vec(math.Inf(1), math.Inf(-1)))
+	e, ok := err.(*ErrNumeric)
+	if !ok {
+		t.Fatalf("Expected an *ErrNumeric. Got %v", err)
+	}
+	if e.Node != z || e.Output.PosInfs != 1 || e.Output.NegInfs != 1 {
+		t.Errorf("Expected z to return a +Inf and a -Inf. Got %v", e.Output)
+	}
+	if len(e.Inputs) != 2 || e.Inputs[0].Max != 0.5 || e.Inputs[1].Min != -2e300 {
+		t.Errorf("Expected the stats of b and y, in order. Got %v", e.Inputs)
+	}
+	// the path goes back through the largest values
+	if len(e.Path) != 3 || e.Path[0] != w || e.Path[1] != y || e.Path[2] != z {
+		t.Errorf("Expected the path w → y → z. Got %v", e)
+	}
+	if msg := e.Error(); !strings.Contains(msg, "w → y → z") {
+		t.Errorf("Expected the error to name the path. Got %q", msg)
+	}
+}
@@ -31,6 +31,7 @@ func (g *ExprGraph) NewNode() graph.Node {
 	n := new(Node)
 	n.DataOn = execution.CPU
 	n.id = g.w.NewNode().ID()
+	n.g = g
 	//n.fix()
 	return n
 }
@@ -0,0 +1,447 @@
+package nnops
+
+import (
+	"github.com/pkg/errors"
+	G "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+// AvgPool2D average pools x, which is expected to be in NCHW format.
+// If countPad is true, the padded zeroes count towards the size of each window.
+func AvgPool2D(x *G.Node, kernel tensor.Shape, pad, stride []int, countPad bool) (*G.Node, error) {
+	op, err := newAvgPoolOp(x.Shape(), kernel, pad, stride, countPad)
+	if err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, x)
+}
+
+// GlobalAvgPool2D averages each channel of x. A (N, C, H, W) input returns a (N, C) output.
+func GlobalAvgPool2D(x *G.Node) (*G.Node, error) {
+	return G.ApplyOp(newGlobalPoolOp(false), x)
+}
+
+// GlobalMaxPool2D takes the maximum of each channel of x. A (N, C, H, W) input returns a (N, C) output.
+func GlobalMaxPool2D(x *G.Node) (*G.Node, error) {
+	return G.ApplyOp(newGlobalPoolOp(true), x)
+}
+
+// AdaptiveAvgPool2D average pools x to (N, C, outH, outW), whatever the height and width of x are.
+func AdaptiveAvgPool2D(x *G.Node, outH, outW int) (*G.Node, error) {
+	op, err := newAdaptiveAvgPoolOp(outH, outW)
+	if err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, x)
+}
+
+// SeededDropout randomly zeroes elements of x with probability prob, scaling the rest by 1/(1-prob).
+// The elements dropped are determined by seed. Use the returned op to switch between training and testing.
+func SeededDropout(x *G.Node, prob float64, seed int64) (retVal *G.Node, op *DropoutOp, err error) {
+	return applyDropout(x, prob, standardDropout, seed)
+}
+
+// AlphaDropout is the dropout to use with SELU activations. It keeps the mean and variance of its input.
+func AlphaDropout(x *G.Node, prob float64, seed int64) (retVal *G.Node, op *DropoutOp, err error) {
+	return applyDropout(x, prob, alphaDropout, seed)
+}
+
+// SpatialDropout randomly zeroes entire channels of x, which is expected to be in (N, C, ...) format.
+func SpatialDropout(x *G.Node, prob float64, seed int64) (retVal *G.Node, op *DropoutOp, err error) {
+	return applyDropout(x, prob, spatialDropout, seed)
+}
+
+func applyDropout(x *G.Node, prob float64, mode dropoutMode, seed int64) (retVal *G.Node, op *DropoutOp, err error) {
+	if op, err = newDropoutOp(prob, mode, seed); err != nil {
+		return nil, nil, err
+	}
+	if retVal, err = G.ApplyOp(op, x); err != nil {
+		return nil, nil, err
+	}
+	return retVal, op, nil
+}
+
+// Softmax computes the softmax of x along the given axis. Negative axes count from the last axis.
+func Softmax(x *G.Node, axis int) (*G.Node, error) {
+	return G.ApplyOp(newSoftmaxOp(axis, false), x)
+}
+
+// LogSoftmax computes the log of the softmax of x along the given axis. Negative axes count from the last axis.
+// It is more numerically stable than taking the log of Softmax.
+func LogSoftmax(x *G.Node, axis int) (*G.Node, error) {
+	return G.ApplyOp(newSoftmaxOp(axis, true), x)
+}
+
+// SoftmaxCrossEntropy computes the cross entropy between softmax(logits) and the class labels.
+// logits is expected to have the shape (N, C), and labels is expected to be a vector of N integers in [0, C).
+func SoftmaxCrossEntropy(logits, labels *G.Node, reduction Reduction) (*G.Node, error) {
+	return applyLoss(softmaxCrossEntropyLoss, logits, labels, reduction, 0)
+}
+
+// BCEWithLogits computes the binary cross entropy between sigmoid(logits) and the targets, which are expected to be in [0, 1].
+func BCEWithLogits(logits, targets *G.Node, reduction Reduction) (*G.Node, error) {
+	return applyLoss(bceWithLogitsLoss, logits, targets, reduction, 0)
+}
+
+// MSE computes the squared error between the predictions and the targets.
+func MSE(pred, targets *G.Node, reduction Reduction) (*G.Node, error) {
+	return applyLoss(mseLoss, pred, targets, reduction, 0)
+}
+
+// Huber computes the Huber loss between the predictions and the targets. The loss is quadratic for errors smaller than delta, and linear otherwise.
+func Huber(pred, targets *G.Node, delta float64, reduction Reduction) (*G.Node, error) {
+	return applyLoss(huberLoss, pred, targets, reduction, delta)
+}
+
+// KLDiv computes the Kullback-Leibler divergence of the target distribution from the predicted one.
+// The predictions are expected to be log probabilities (e.g. the result of LogSoftmax), while the targets are expected to be probabilities.
+func KLDiv(logProbs, targets *G.Node, reduction Reduction) (*G.Node, error) {
+	return applyLoss(klDivLoss, logProbs, targets, reduction, 0)
+}
+
+func applyLoss(kind lossKind, pred, target *G.Node, reduction Reduction, delta float64) (*G.Node, error) {
+	op, err := newLossOp(kind, reduction, pred.Shape().Dims(), delta)
+	if err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, pred, target)
+}
+
+// LayerNorm normalizes x over its last axes axes, then scales and shifts the result by scale and bias.
+// scale and bias are expected to have the shape of the last axes axes of x.
+func LayerNorm(x, scale, bias *G.Node, axes int, eps float64) (*G.Node, error) {
+	op, err := newNormOp(layerNorm, x.Shape(), axes, 1, eps)
+	if err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, x, scale, bias)
+}
+
+// RMSNorm divides x by the root mean square of its last axes axes, then scales the result by scale.
+// scale is expected to have the shape of the last axes axes of x.
+func RMSNorm(x, scale *G.Node, axes int, eps float64) (*G.Node, error) {
+	op, err := newNormOp(rmsNorm, x.Shape(), axes, 1, eps)
+	if err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, x, scale)
+}
+
+// GroupNorm splits the channels of x, which is expected to be in (N, C, ...) format, into groups and normalizes each group of each example.
+// scale and bias are expected to be vectors of C elements.
+func GroupNorm(x, scale, bias *G.Node, groups int, eps float64) (*G.Node, error) {
+	op, err := newNormOp(groupNorm, x.Shape(), 0, groups, eps)
+	if err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, x, scale, bias)
+}
+
+// InstanceNorm normalizes each channel of each example of x, which is expected to be in (N, C, ...) format.
+// scale and bias are expected to be vectors of C elements.
+func InstanceNorm(x, scale, bias *G.Node, eps float64) (*G.Node, error) {
+	op, err := newNormOp(instanceNorm, x.Shape(), 0, 0, eps)
+	if err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, x, scale, bias)
+}
+
+// Embedding looks up the rows of weight, a (V, D) matrix, given a tensor of integer indices. The result has the shape (indices..., D).
+// The gradient with regards to weight only holds the rows that were looked up.
+func Embedding(weight, indices *G.Node) (*G.Node, error) {
+	op, err := newEmbeddingOp(indices.Shape().Dims())
+	if err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, weight, indices)
+}
+
+// LSTMCell runs one step of an LSTM. x is a (N, I) input, and state stacks h and c into a (2, N, H) tensor.
+// w (I, 4H), u (H, 4H) and b (4H) hold the weights of the gates, in the order i, f, g, o. It returns the new state.
+func LSTMCell(x, state, w, u, b *G.Node) (*G.Node, error) {
+	return applyRNN(lstmCell, false, x, state, w, u, b, nil, false)
+}
+
+// GRUCell runs one step of a GRU. x is a (N, I) input, and h is the (N, H) hidden state.
+// w (I, 3H), u (H, 3H) and b (3H) hold the weights of the gates, in the order r, z, n. It returns the new hidden state.
+func GRUCell(x, h, w, u, b *G.Node) (*G.Node, error) {
+	return applyRNN(gruCell, false, x, h, w, u, b, nil, false)
+}
+
+// LSTM runs an LSTMCell over x, a (T, N, I) sequence, starting from state, and returns the (T, N, H) hidden states of each step.
+// If reverse is true, the sequence is run from its last step to its first.
+// mask is either nil or a (T, N) matrix. Where it is 0, the state is carried through unchanged and the output is 0.
+func LSTM(x, state, w, u, b, mask *G.Node, reverse bool) (*G.Node, error) {
+	return applyRNN(lstmCell, true, x, state, w, u, b, mask, reverse)
+}
+
+// GRU runs a GRUCell over x, a (T, N, I) sequence, starting from h, and returns the (T, N, H) hidden states of each step.
+// If reverse is true, the sequence is run from its last step to its first.
+// mask is either nil or a (T, N) matrix. Where it is 0, the state is carried through unchanged and the output is 0.
+func GRU(x, h, w, u, b, mask *G.Node, reverse bool) (*G.Node, error) {
+	return applyRNN(gruCell, true, x, h, w, u, b, mask, reverse)
+}
+
+func applyRNN(kind rnnKind, seq bool, x, state, w, u, b, mask *G.Node, reverse bool) (*G.Node, error) {
+	op, err := newRNNOp(kind, seq, reverse, mask != nil)
+	if err != nil {
+		return nil, err
+	}
+	if mask != nil {
+		return G.ApplyOp(op, x, state, w, u, b, mask)
+	}
+	return G.ApplyOp(op, x, state, w, u, b)
+}
+
+// ScaledDotProductAttention computes softmax(scale·q·kᵀ + mask)·v. q is expected to have the shape (..., Lq, D),
+// k the shape (..., Lk, D) and v the shape (..., Lk, Dv). The result has the shape (..., Lq, Dv).
+//
+// mask is either nil or an additive mask that broadcasts to the (..., Lq, Lk) scores, with -Inf for the positions that are masked out.
+// If causal is true, query i only attends to the keys up to i+Lk-Lq. A scale of 0 stands for 1/√D.
+//
+// The full (Lq, Lk) attention matrix is never held in memory.
+func ScaledDotProductAttention(q, k, v, mask *G.Node, causal bool, scale float64) (*G.Node, error) {
+	var maskDims int
+	if mask != nil {
+		maskDims = mask.Shape().Dims()
+	}
+	op, err := newAttentionOp(q.Shape().Dims(), maskDims, causal, scale)
+	if err != nil {
+		return nil, err
+	}
+	if mask != nil {
+		return G.ApplyOp(op, q, k, v, mask)
+	}
+	return G.ApplyOp(op, q, k, v)
+}
+
+// MultiHeadAttentionParams holds the weights of a multi-head attention.
+// WQ, WK and WV project the queries, keys and values to E features, and WO projects the E features of the heads back.
+// The biases are vectors of E elements, and are optional.
+type MultiHeadAttentionParams struct {
+	Heads          int
+	WQ, WK, WV, WO *G.Node
+	BQ, BK, BV, BO *G.Node
+}
+
+// MultiHeadAttention projects q, k and v, splits them into heads, runs ScaledDotProductAttention on each head,
+// and projects the concatenated heads. q is expected to have the shape (N, Lq, Eq), and k and v the shapes (N, Lk, Ek) and (N, Lk, Ev).
+// The result has the shape (N, Lq, E).
+//
+// mask is either nil or an additive mask that broadcasts to the (N, Heads, Lq, Lk) scores, e.g. a (Lq, Lk) matrix,
+// or a (N, 1, 1, Lk) tensor to mask out padding.
+func MultiHeadAttention(q, k, v *G.Node, params MultiHeadAttentionParams, mask *G.Node, causal bool) (retVal *G.Node, err error) {
+	if q.Shape().Dims() != 3 || k.Shape().Dims() != 3 || v.Shape().Dims() != 3 {
+		return nil, errors.Errorf("MultiHeadAttention expects (N, L, E) inputs. Got %v, %v and %v", q.Shape(), k.Shape(), v.Shape())
+	}
+	e := params.WO.Shape()[0]
+	if params.Heads <= 0 || e%params.Heads != 0 {
+		return nil, errors.Errorf("Cannot split %d features into %d heads", e, params.Heads)
+	}
+
+	var qh, kh, vh *G.Node
+	if qh, err = params.heads(q, params.WQ, params.BQ); err != nil {
+		return nil, errors.Wrap(err, "Cannot project the queries")
+	}
+	if kh, err = params.heads(k, params.WK, params.BK); err != nil {
+		return nil, errors.Wrap(err, "Cannot project the keys")
+	}
+	if vh, err = params.heads(v, params.WV, params.BV); err != nil {
+		return nil, errors.Wrap(err, "Cannot project the values")
+	}
+	if retVal, err = ScaledDotProductAttention(qh, kh, vh, mask, causal, 0); err != nil {
+		return nil, err
+	}
+
+	// (N, H, Lq, E/H) → (N·Lq, E)
+	n, l := q.Shape()[0], q.Shape()[1]
+	if retVal, err = G.Transpose(retVal, 0, 2, 1, 3); err != nil {
+		return nil, err
+	}
+	if retVal, err = G.Reshape(retVal, tensor.Shape{n * l, e}); err != nil {
+		return nil, err
+	}
+	if retVal, err = params.project(retVal, params.WO, params.BO); err != nil {
+		return nil, errors.Wrap(err, "Cannot project the heads")
+	}
+	return G.Reshape(retVal, tensor.Shape{n, l, retVal.Shape()[1]})
+}
+
+// heads projects a (N, L, E) input with w and b, and splits it into a (N, Heads, L, E/Heads) tensor.
+func (p *MultiHeadAttentionParams) heads(x, w, b *G.Node) (retVal *G.Node, err error) {
+	s := x.Shape()
+	if retVal, err = G.Reshape(x, tensor.Shape{s[0] * s[1], s[2]}); err != nil {
+		return nil, err
+	}
+	if retVal, err = p.project(retVal, w, b); err != nil {
+		return nil, err
+	}
+	e := retVal.Shape()[1]
+	if e%p.Heads != 0 {
+		return nil, errors.Errorf("Cannot split %d features into %d heads", e, p.Heads)
+	}
+	if retVal, err = G.Reshape(retVal, tensor.Shape{s[0], s[1], p.Heads, e / p.Heads}); err != nil {
+		return nil, err
+	}
+	return G.Transpose(retVal, 0, 2, 1, 3)
+}
+
+// project computes x·w + b, where x is a matrix. b may be nil.
+func (p *MultiHeadAttentionParams) project(x, w, b *G.Node) (retVal *G.Node, err error) {
+	if retVal, err = G.Mul(x, w); err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return retVal, nil
+	}
+	return G.BroadcastAdd(retVal, b, nil, []byte{0})
+}
+
+// FFT computes the discrete Fourier transform of x, a tensor of complex numbers, along the given axes, or along its last axis if none are given.
+// Negative axes count from the last axis.
+func FFT(x *G.Node, axes ...int) (*G.Node, error) {
+	axes, err := fftAxes(x, axes)
+	if err != nil {
+		return nil, err
+	}
+	return applyFFT(forwardFFT, x, axes)
+}
+
+// IFFT computes the inverse discrete Fourier transform of x, a tensor of complex numbers, along the given axes, or along its last axis if none are given.
+// The result is scaled by 1/n along each axis, so that IFFT(FFT(x)) = x. Negative axes count from the last axis.
+func IFFT(x *G.Node, axes ...int) (*G.Node, error) {
+	axes, err := fftAxes(x, axes)
+	if err != nil {
+		return nil, err
+	}
+	return applyFFT(inverseFFT, x, axes)
+}
+
+// RFFT computes the discrete Fourier transform of x, a tensor of floats, along the given axes, or along its last axis if none are given.
+// Only the n/2+1 non-negative frequencies of the last of the axes are returned, as the others are their complex conjugates.
+// Negative axes count from the last axis.
+func RFFT(x *G.Node, axes ...int) (retVal *G.Node, err error) {
+	if axes, err = fftAxes(x, axes); err != nil {
+		return nil, err
+	}
+	last := axes[len(axes)-1]
+	var op *fftOp
+	if op, err = newFFTOp(realFFT, x.Shape().Dims(), last, x.Shape()[last]); err != nil {
+		return nil, err
+	}
+	if retVal, err = G.ApplyOp(op, x); err != nil {
+		return nil, err
+	}
+	return applyFFT(forwardFFT, retVal, axes[:len(axes)-1])
+}
+
+// IRFFT is the inverse of RFFT: it takes the non-negative frequencies of a real signal back to the signal, which has a length of n along the last of the axes.
+// x is expected to hold n/2+1 frequencies along the last of the axes. Negative axes count from the last axis.
+func IRFFT(x *G.Node, n int, axes ...int) (retVal *G.Node, err error) {
+	if axes, err = fftAxes(x, axes); err != nil {
+		return nil, err
+	}
+	if retVal, err = applyFFT(inverseFFT, x, axes[:len(axes)-1]); err != nil {
+		return nil, err
+	}
+	var op *fftOp
+	if op, err = newFFTOp(inverseRealFFT, x.Shape().Dims(), axes[len(axes)-1], n); err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, retVal)
+}
+
+// applyFFT applies a complex transform to x along each of the axes in turn
+func applyFFT(kind fftKind, x *G.Node, axes []int) (retVal *G.Node, err error) {
+	retVal = x
+	for _, axis := range axes {
+		var op *fftOp
+		if op, err = newFFTOp(kind, retVal.Shape().Dims(), axis, retVal.Shape()[axis]); err != nil {
+			return nil, err
+		}
+		if retVal, err = G.ApplyOp(op, retVal); err != nil {
+			return nil, err
+		}
+	}
+	return retVal, nil
+}
+
+// fftAxes resolves the negative axes, and defaults to the last axis of x
+func fftAxes(x *G.Node, axes []int) ([]int, error) {
+	dims := x.Shape().Dims()
+	if len(axes) == 0 {
+		return []int{dims - 1}, nil
+	}
+	retVal := make([]int, len(axes))
+	for i, axis := range axes {
+		if axis < 0 {
+			axis += dims
+		}
+		if axis < 0 || axis >= dims {
+			return nil, errors.Errorf("Axis %d is out of bounds for a tensor with %d dimensions", axes[i], dims)
+		}
+		retVal[i] = axis
+	}
+	return retVal, nil
+}
+
+// Quantize maps x, a float tensor, to int8 with the params, which are usually given by an Observer after calibration.
+func Quantize(x *G.Node, p QuantParams) (*G.Node, error) {
+	op, err := newQuantizeOp(p, x.Shape().Dims())
+	if err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, x)
+}
+
+// Dequantize maps x, a tensor of int8, back to floats of the given dtype with the params.
+func Dequantize(x *G.Node, p QuantParams, dt tensor.Dtype) (*G.Node, error) {
+	op, err := newDequantizeOp(p, dt, x.Shape().Dims())
+	if err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, x)
+}
+
+// QuantizedMatMul multiplies x, a (M, K) int8 matrix quantized with xp, by w, (K, N) int8 weights quantized with wp,
+// and requantizes the result with yp. xp and yp are per tensor, and wp is either per tensor or per column.
+// bias is either nil or a vector of N int32, as given by QuantizeBias.
+func QuantizedMatMul(x, w, bias *G.Node, xp, wp, yp QuantParams) (*G.Node, error) {
+	op, err := newQuantizedMatMulOp(xp, wp, yp, bias != nil)
+	if err != nil {
+		return nil, err
+	}
+	if bias != nil {
+		return G.ApplyOp(op, x, w, bias)
+	}
+	return G.ApplyOp(op, x, w)
+}
+
+// QuantizedConv2d is the int8 counterpart of GroupConv2d. im is a NCHW int8 image quantized with xp, filter are KCHW int8 filters quantized with wp,
+// and the result is requantized with yp. xp and yp are per tensor, and wp is either per tensor or per filter.
+// bias is either nil or a vector of K int32, as given by QuantizeBias.
+func QuantizedConv2d(im, filter, bias *G.Node, pad, stride, dilation []int, groups int, xp, wp, yp QuantParams) (*G.Node, error) {
+	op, err := newQuantizedConvOp(im.Shape(), filter.Shape(), pad, stride, dilation, groups, xp, wp, yp, bias != nil)
+	if err != nil {
+		return nil, err
+	}
+	if bias != nil {
+		return G.ApplyOp(op, im, filter, bias)
+	}
+	return G.ApplyOp(op, im, filter)
+}
+
+// FakeQuantize simulates the int8 quantization of x with the params, for quantization-aware training.
+// If scale is not nil, it holds learnable scales (one per channel of the params) that are used instead of those of the params.
+// The gradient of x goes straight through, except where x is clamped.
+func FakeQuantize(x, scale *G.Node, p QuantParams) (*G.Node, error) {
+	op, err := newFakeQuantOp(p, x.Shape().Dims(), scale != nil)
+	if err != nil {
+		return nil, err
+	}
+	if scale != nil {
+		return G.ApplyOp(op, x, scale)
+	}
+	return G.ApplyOp(op, x)
+}
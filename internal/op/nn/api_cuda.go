@@ -3,20 +3,32 @@
 package nnops
 
 import (
+	"github.com/pkg/errors"
 	G "gorgonia.org/gorgonia"
 	"gorgonia.org/tensor"
 )
 
 func Conv2d(im, filter *G.Node, kernelShape tensor.Shape, pad, stride, dilation []int) (retVal *G.Node, err error) {
+	return GroupConv2d(im, filter, kernelShape, pad, stride, dilation, 1)
+}
+
+func Conv1d(in, filter *G.Node, kernel, pad, stride, dilation int) (*G.Node, error) {
+	return Conv2d(in, filter, tensor.Shape{1, kernel}, []int{0, pad}, []int{1, stride}, []int{1, dilation})
+}
+
+// GroupConv2d is a Conv2d where the input channels and the filters are split into groups. Each group of filters only sees its own group of input channels.
+// The filter is expected to have the shape (K, C/groups, kh, kw).
+func GroupConv2d(im, filter *G.Node, kernelShape tensor.Shape, pad, stride, dilation []int, groups int) (retVal *G.Node, err error) {
 	var op *convolution
-	if op, err = makeConvolutionOp(im, filter, kernelShape, pad, stride, dilation); err != nil {
+	if op, err = makeConvolutionOp(im, filter, kernelShape, pad, stride, dilation, groups); err != nil {
 		return nil, err
 	}
 	return G.ApplyOp(op, im, filter)
 }
 
-func Conv1d(in, filter *G.Node, kernel, pad, stride, dilation int) (*G.Node, error) {
-	return Conv2d(in, filter, tensor.Shape{1, kernel}, []int{0, pad}, []int{1, stride}, []int{1, dilation})
+// GroupConv1d is the 1D version of GroupConv2d.
+func GroupConv1d(in, filter *G.Node, kernel, pad, stride, dilation, groups int) (*G.Node, error) {
+	return GroupConv2d(in, filter, tensor.Shape{1, kernel}, []int{0, pad}, []int{1, stride}, []int{1, dilation}, groups)
 }
 
 func MaxPool2D(x *G.Node, kernel tensor.Shape, pad, stride []int) (retVal *G.Node, err error) {
@@ -27,9 +39,14 @@ func MaxPool2D(x *G.Node, kernel tensor.Shape, pad, stride []int) (retVal *G.Nod
 	return G.ApplyOp(op, x)
 }
 
-func Dropout(x *G.Node, prob float64) (retVal *G.Node, err error) {
+// Dropout randomly zeroes elements of x with probability prob, scaling the rest by 1/(1-prob).
+// The mask is seeded from s, so that graphs built from streams with the same seed drop the same elements.
+func Dropout(x *G.Node, prob float64, s *RandomStream) (retVal *G.Node, err error) {
+	if s == nil {
+		return nil, errors.New("Dropout expects a RandomStream")
+	}
 	var op *dropout
-	if op, err = newDropout(x, prob); err != nil {
+	if op, err = newDropout(x, prob, uint64(s.int63())); err != nil {
 		return nil, err
 	}
 
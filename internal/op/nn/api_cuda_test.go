@@ -15,7 +15,7 @@ import (
 func TestDropout(t *testing.T) {
 	g := G.NewGraph()
 	x := G.NewMatrix(g, G.Float64, G.WithShape(2, 3), G.WithName("x"))
-	do, _ := Dropout(x, 0.5)
+	do, _ := Dropout(x, 0.5, NewRandomStream(1337))
 	log.Printf("%v", do)
 	ioutil.WriteFile("foo.dot", []byte(g.ToDot()), 0644)
 
@@ -3,30 +3,116 @@
 package nnops
 
 import (
+	"github.com/pkg/errors"
 	G "gorgonia.org/gorgonia"
 	"gorgonia.org/tensor"
 )
 
 func Conv2d(im, filter *G.Node, kernelShape tensor.Shape, pad, stride, dilation []int) (retVal *G.Node, err error) {
-	return G.Conv2d(im, filter, kernelShape, pad, stride, dilation)
+	return GroupConv2d(im, filter, kernelShape, pad, stride, dilation, 1)
 }
 
 func Conv1d(in, filter *G.Node, kernel, pad, stride, dilation int) (*G.Node, error) {
 	return Conv2d(in, filter, tensor.Shape{1, kernel}, []int{0, pad}, []int{1, stride}, []int{1, dilation})
 }
 
+// GroupConv2d is a Conv2d where the input channels and the filters are split into groups. Each group of filters only sees its own group of input channels.
+// The filter is expected to have the shape (K, C/groups, kh, kw).
+func GroupConv2d(im, filter *G.Node, kernelShape tensor.Shape, pad, stride, dilation []int, groups int) (retVal *G.Node, err error) {
+	var op *convolution
+	if op, err = makeConvolutionOp(im, filter, kernelShape, pad, stride, dilation, groups); err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(op, im, filter)
+}
+
+// GroupConv1d is the 1D version of GroupConv2d.
+func GroupConv1d(in, filter *G.Node, kernel, pad, stride, dilation, groups int) (*G.Node, error) {
+	return GroupConv2d(in, filter, tensor.Shape{1, kernel}, []int{0, pad}, []int{1, stride}, []int{1, dilation}, groups)
+}
+
 func MaxPool2D(x *G.Node, kernel tensor.Shape, pad, stride []int) (*G.Node, error) {
-	return G.MaxPool2D(x, kernel, pad, stride)
+	if err := checkPoolParams(x.Shape(), kernel, pad, stride); err != nil {
+		return nil, err
+	}
+	return G.ApplyOp(newMaxPoolOp(x.Shape(), kernel, pad, stride), x)
+}
+
+// MaxUnpool2D writes each value of pooled back to the position it was found in by MaxPool2D. All other positions are zero.
+// pooled has to be the result of MaxPool2D, as the unpooling uses the argmax mask of that op.
+func MaxUnpool2D(pooled *G.Node) (*G.Node, error) {
+	pool, ok := pooled.Op().(*maxPoolOp)
+	if !ok {
+		return nil, errors.Errorf("MaxUnpool2D expects the result of MaxPool2D. Got %v instead", pooled.Op())
+	}
+	return G.ApplyOp(newMaxUnpoolOp(pool), pooled)
 }
 
-func Dropout(x *G.Node, prob float64) (retVal *G.Node, err error) {
-	return G.Dropout(x, prob)
+// Dropout randomly zeroes elements of x with probability prob, scaling the rest by 1/(1-prob).
+// The mask is seeded from s, so that graphs built from streams with the same seed drop the same elements.
+func Dropout(x *G.Node, prob float64, s *RandomStream) (retVal *G.Node, err error) {
+	if s == nil {
+		return nil, errors.New("Dropout expects a RandomStream")
+	}
+	retVal, _, err = SeededDropout(x, prob, s.int63())
+	return
 }
 
 func Rectify(x *G.Node) (retVal *G.Node, err error) {
 	return G.Rectify(x)
 }
 
-func BatchNorm(x, scale, bias *G.Node, momentum, epsilon float64) (retVal, γ, β *G.Node, op *G.BatchNormOp, err error) {
-	return G.BatchNorm(x, scale, bias, momentum, epsilon)
+// BatchNorm normalizes x, which is expected to be in (N, C, ...) format, over the batch. If scale or bias are nil, they are created.
+// Use BatchNormWith to access the running statistics.
+func BatchNorm(x, scale, bias *G.Node, momentum, epsilon float64) (retVal, γ, β *G.Node, op *BatchNormOp, err error) {
+	params := &BatchNormParams{Scale: scale, Bias: bias}
+	if retVal, op, err = BatchNormWith(x, params, momentum, epsilon); err != nil {
+		return nil, nil, nil, nil, err
+	}
+	return retVal, params.Scale, params.Bias, op, nil
+}
+
+// BatchNormParams holds the learnable parameters and the running statistics of a batch normalization.
+// All of them are vectors with one element per channel.
+//
+// The running statistics are ordinary nodes: they can be read and set like any other node, so they can be checkpointed,
+// or loaded from a model trained elsewhere.
+type BatchNormParams struct {
+	Scale, Bias                  *G.Node
+	RunningMean, RunningVariance *G.Node
+}
+
+// BatchNormWith normalizes x, which is expected to be in (N, C, ...) format, over the batch, using the given parameters.
+// Any nil field of params is created and set: γ and the running variance are initialized to ones, β and the running mean to zeroes.
+func BatchNormWith(x *G.Node, params *BatchNormParams, momentum, epsilon float64) (retVal *G.Node, op *BatchNormOp, err error) {
+	if op, err = newBatchNormOp(x.Shape().Dims(), momentum, epsilon); err != nil {
+		return nil, nil, err
+	}
+	dt, err := dtypeOf(x.Type())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	g := x.Graph()
+	channels := x.Shape()[1]
+	vector := func(name string, init G.InitWFn) *G.Node {
+		return G.NewVector(g, dt, G.WithShape(channels), G.WithName(x.Name()+name), G.WithInit(init))
+	}
+	if params.Scale == nil {
+		params.Scale = vector("_γ", G.Ones())
+	}
+	if params.Bias == nil {
+		params.Bias = vector("_β", G.Zeroes())
+	}
+	if params.RunningMean == nil {
+		params.RunningMean = vector("_mean", G.Zeroes())
+	}
+	if params.RunningVariance == nil {
+		params.RunningVariance = vector("_variance", G.Ones())
+	}
+
+	if retVal, err = G.ApplyOp(op, x, params.Scale, params.Bias, params.RunningMean, params.RunningVariance); err != nil {
+		return nil, nil, err
+	}
+	return retVal, op, nil
 }
@@ -0,0 +1,580 @@
+package nnops
+
+import (
+	"fmt"
+	"hash"
+	"math"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/blas"
+	"gorgonia.org/gorgonia/internal/constructor"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &attentionOp{}
+	_ ops.Op = &attentionDiffOp{}
+)
+
+// attentionTile is the number of queries and keys processed at once.
+// Only a tile of attention scores is ever held in memory, rather than the full (Lq, Lk) matrix.
+const attentionTile = 64
+
+// attentionOp computes softmax(scale·Q·Kᵀ + mask)·V.
+//
+// Its inputs are Q (..., Lq, D), K (..., Lk, D), V (..., Lk, Dv) and an optional additive mask, and it returns a (..., Lq, Dv) tensor.
+// The mask broadcasts to the (..., Lq, Lk) scores: it either is a (Lq, Lk) matrix, or has the dims of the scores with any axis being 1.
+// Masked out positions are expected to be -Inf. A query that cannot attend to any key gets an output of 0.
+//
+// If causal is set, query i only attends to the keys up to i+Lk-Lq, i.e. the queries are aligned with the last keys.
+//
+// The scores are computed tile by tile, with the softmax computed online as in Dao et al. (2022) - https://arxiv.org/abs/2205.14135,
+// so the memory used does not grow with the square of the sequence length. The backward pass computes the scores again
+// from the log-sum-exp of each query. All the computations are done in float64 precision, even for float32 inputs.
+type attentionOp struct {
+	dims     int // dims of Q, K and V
+	maskDims int // dims of the mask. 0 if there is no mask
+	causal   bool
+	scale    float64
+}
+
+func newAttentionOp(dims, maskDims int, causal bool, scale float64) (*attentionOp, error) {
+	if dims < 2 {
+		return nil, errors.Errorf("Attention expects Q, K and V to have at least 2 dimensions. Got %d", dims)
+	}
+	if maskDims != 0 && maskDims != 2 && maskDims != dims {
+		return nil, errors.Errorf("Attention expects a mask with 2 or %d dimensions. Got %d", dims, maskDims)
+	}
+	if scale < 0 {
+		return nil, errors.Errorf("Attention expects a positive scale. Got %v", scale)
+	}
+	return &attentionOp{dims: dims, maskDims: maskDims, causal: causal, scale: scale}, nil
+}
+
+// Arity ...
+func (op *attentionOp) Arity() int {
+	if op.maskDims > 0 {
+		return 4
+	}
+	return 3
+}
+
+// attentionOp has this type:
+//		op :: Tensor-d a → Tensor-d a → Tensor-d a → (Tensor-m a) → Tensor-d a
+// Only an op with a mask takes the mask.
+func (op *attentionOp) Type() hm.Type {
+	return hm.NewFnType(append(op.inputTypes(), op.inputTypes()[0])...)
+}
+
+// InferShape ...
+func (op *attentionOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "attention")
+	}
+	shapes := make([]tensor.Shape, len(ns))
+	for i, n := range ns {
+		s, ok := n.(tensor.Shape)
+		if !ok {
+			return nil, errors.Errorf("Expected a shape")
+		}
+		shapes[i] = s
+	}
+	if _, err := op.layout(shapes...); err != nil {
+		return nil, err
+	}
+	return op.retShape(shapes[0], shapes[2]), nil
+}
+
+// Do ...
+func (op *attentionOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "attention Do")
+	}
+	out := tensor.New(tensor.Of(values[0].Dtype()), tensor.WithShape(op.retShape(values[0].Shape(), values[2].Shape())...))
+	return op.UsePreallocDo(out, values...)
+}
+
+// ReturnsPtr ...
+func (op *attentionOp) ReturnsPtr() bool { return true }
+
+// CallsExtern ...
+func (op *attentionOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *attentionOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *attentionOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *attentionOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *attentionOp) String() string {
+	return fmt.Sprintf("Attention{%d, mask: %d, causal: %t, scale: %v}", op.dims, op.maskDims, op.causal, op.scale)
+}
+
+// DiffWRT ...
+func (op *attentionOp) DiffWRT(inputs int) []bool {
+	retVal := make([]bool, inputs)
+	for i := range retVal {
+		retVal[i] = true
+	}
+	return retVal
+}
+
+// SymDiff ...
+func (op *attentionOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	args := append(append(Nodes(nil), inputs...), grad)
+	retVal = make(Nodes, len(inputs))
+	for i := range inputs {
+		diff := &attentionDiffOp{attentionOp: op, wrt: i}
+		if retVal[i], err = ApplyOp(diff, args...); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// DoDiff runs the backward pass once, and adds the gradients to the derivatives of the inputs.
+func (op *attentionOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	values := make([]value.Value, len(inputs))
+	for i, n := range inputs {
+		values[i] = n.Value()
+	}
+	_, ydv := getDV(inputs[0], output)
+
+	var grads [][]float64
+	if grads, err = op.grads(append(values, ydv.D)...); err != nil {
+		return errors.Wrapf(err, doFail, op)
+	}
+	for i, g := range grads {
+		_, dv := getDV(inputs[0], inputs[i])
+		d, ok := dv.D.(*tensor.Dense)
+		if !ok {
+			return errors.Errorf("Expected the derivative of input %d to be a *tensor.Dense. Got %T instead", i, dv.D)
+		}
+		if err = addFloat64s(d, g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UsePreallocDo ...
+func (op *attentionOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "attention UsePreallocDo")
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	var in *attentionInputs
+	if in, err = op.checkInput(inputs...); err != nil {
+		return nil, err
+	}
+	ret, _ := op.forward(in)
+	if out.Shape().TotalSize() != len(ret) {
+		return nil, errors.Errorf("Expected prealloc to have %d elements. Got %v", len(ret), out.Shape())
+	}
+	if err = setFloat64s(out, ret); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (op *attentionOp) inputTypes() []hm.Type {
+	a := hm.TypeVariable('a')
+	t := constructor.NewTensorType(op.dims, a)
+	retVal := []hm.Type{t, t, t}
+	if op.maskDims > 0 {
+		retVal = append(retVal, constructor.NewTensorType(op.maskDims, a))
+	}
+	return retVal
+}
+
+func (op *attentionOp) retShape(q, v tensor.Shape) tensor.Shape {
+	retVal := q.Clone()
+	retVal[len(retVal)-1] = v[len(v)-1]
+	return retVal
+}
+
+// attentionLayout holds the sizes of the inputs of an attentionOp, and where the mask of each score is.
+type attentionLayout struct {
+	batch, lq, lk, d, dv int
+
+	maskOffsets              []int // offset of the mask of each batch
+	maskStrideI, maskStrideJ int   // strides of the mask along the queries and the keys. 0 if the mask is broadcast along them
+}
+
+// maskAt returns the index of the mask of the score of query i and key j of batch b
+func (l *attentionLayout) maskAt(b, i, j int) int {
+	return l.maskOffsets[b] + i*l.maskStrideI + j*l.maskStrideJ
+}
+
+// keys returns the number of keys the queries up to end (exclusive) attend to
+func (op *attentionOp) keys(l *attentionLayout, end int) int {
+	if !op.causal {
+		return l.lk
+	}
+	n := end + l.lk - l.lq
+	switch {
+	case n < 0:
+		return 0
+	case n > l.lk:
+		return l.lk
+	}
+	return n
+}
+
+func (op *attentionOp) layout(shapes ...tensor.Shape) (l *attentionLayout, err error) {
+	q, k, v := shapes[0], shapes[1], shapes[2]
+	for _, s := range shapes[:3] {
+		if s.Dims() != op.dims {
+			return nil, errors.Errorf("%v expects Q, K and V to have %d dimensions. Got %v, %v and %v", op, op.dims, q, k, v)
+		}
+	}
+	n := op.dims
+	lead := q[:n-2]
+	if !k[:n-2].Eq(lead) || !v[:n-2].Eq(lead) {
+		return nil, errors.Errorf("%v expects Q, K and V to have the same leading dimensions. Got %v, %v and %v", op, q, k, v)
+	}
+	l = &attentionLayout{batch: 1, lq: q[n-2], lk: k[n-2], d: q[n-1], dv: v[n-1]}
+	for _, s := range lead {
+		l.batch *= s
+	}
+	if k[n-1] != l.d {
+		return nil, errors.Errorf("%v expects Q and K to have the same last dimension. Got %v and %v", op, q, k)
+	}
+	if v[n-2] != l.lk {
+		return nil, errors.Errorf("%v expects K and V to have as many keys. Got %v and %v", op, k, v)
+	}
+
+	l.maskOffsets = make([]int, l.batch)
+	if op.maskDims == 0 {
+		return l, nil
+	}
+	m := shapes[3]
+	if m.Dims() != op.maskDims {
+		return nil, errors.Errorf("%v expects a mask with %d dimensions. Got %v", op, op.maskDims, m)
+	}
+	// the scores have the shape (lead..., Lq, Lk). Axes of size 1 in the mask are broadcast
+	scores := append(lead.Clone(), l.lq, l.lk)
+	scores = scores[len(scores)-m.Dims():]
+	strides := make([]int, m.Dims())
+	stride := 1
+	for i := m.Dims() - 1; i >= 0; i-- {
+		switch m[i] {
+		case scores[i]:
+			strides[i] = stride
+		case 1:
+		default:
+			return nil, errors.Errorf("%v cannot broadcast a mask of shape %v to scores of shape %v", op, m, scores)
+		}
+		stride *= m[i]
+	}
+	l.maskStrideI, l.maskStrideJ = strides[m.Dims()-2], strides[m.Dims()-1]
+	if m.Dims() > 2 {
+		for b := range l.maskOffsets {
+			rem := b
+			for i := len(lead) - 1; i >= 0; i-- {
+				l.maskOffsets[b] += (rem % lead[i]) * strides[i]
+				rem /= lead[i]
+			}
+		}
+	}
+	return l, nil
+}
+
+// attentionInputs are the inputs of an attentionOp, as float64s
+type attentionInputs struct {
+	*attentionLayout
+	q, k, v, mask []float64
+	scale         float64
+}
+
+func (op *attentionOp) checkInput(inputs ...value.Value) (in *attentionInputs, err error) {
+	shapes := make([]tensor.Shape, op.Arity())
+	data := make([][]float64, op.Arity())
+	for i := range shapes {
+		t, ok := inputs[i].(tensor.Tensor)
+		if !ok {
+			return nil, errors.Errorf("Expected input %d to be a tensor. Got %T instead", i, inputs[i])
+		}
+		if i > 0 && t.Dtype() != inputs[0].Dtype() {
+			return nil, errors.Errorf("Expected input %d to be of %v. Got %v instead", i, inputs[0].Dtype(), t.Dtype())
+		}
+		shapes[i] = t.Shape()
+		if data[i], err = float64sOf(t); err != nil {
+			return nil, err
+		}
+	}
+	in = &attentionInputs{q: data[0], k: data[1], v: data[2], scale: op.scale}
+	if in.attentionLayout, err = op.layout(shapes...); err != nil {
+		return nil, err
+	}
+	if op.maskDims > 0 {
+		in.mask = data[3]
+	}
+	if in.scale == 0 {
+		in.scale = 1 / math.Sqrt(float64(in.d))
+	}
+	return in, nil
+}
+
+// scores writes the scores of the bq queries from q0 and the bk keys from k0 of batch b into s
+func (op *attentionOp) scores(in *attentionInputs, b, q0, bq, k0, bk int, s []float64) {
+	q := in.q[(b*in.lq+q0)*in.d:]
+	k := in.k[(b*in.lk+k0)*in.d:]
+	whichblas.Dgemm(blas.NoTrans, blas.Trans, bq, bk, in.d, in.scale, q, in.d, k, in.d, 0, s, bk)
+
+	shift := in.lk - in.lq
+	for r := 0; r < bq; r++ {
+		i := q0 + r
+		row := s[r*bk : (r+1)*bk]
+		for c := range row {
+			j := k0 + c
+			if in.mask != nil {
+				row[c] += in.mask[in.maskAt(b, i, j)]
+			}
+			if op.causal && j > i+shift {
+				row[c] = math.Inf(-1)
+			}
+		}
+	}
+}
+
+// forward returns the output, and the log-sum-exp of the scores of each query.
+func (op *attentionOp) forward(in *attentionInputs) (out, lse []float64) {
+	out = make([]float64, in.batch*in.lq*in.dv)
+	lse = make([]float64, in.batch*in.lq)
+
+	s := make([]float64, attentionTile*attentionTile)
+	runMax := make([]float64, attentionTile) // running max of the scores of each query
+	runSum := make([]float64, attentionTile) // running sum of exp(score - max) of each query
+	for b := 0; b < in.batch; b++ {
+		v := in.v[b*in.lk*in.dv : (b+1)*in.lk*in.dv]
+		for q0 := 0; q0 < in.lq; q0 += attentionTile {
+			bq := minInt(attentionTile, in.lq-q0)
+			o := out[(b*in.lq+q0)*in.dv : (b*in.lq+q0+bq)*in.dv]
+			for r := 0; r < bq; r++ {
+				runMax[r], runSum[r] = math.Inf(-1), 0
+			}
+
+			for k0, keys := 0, op.keys(in.attentionLayout, q0+bq); k0 < keys; k0 += attentionTile {
+				bk := minInt(attentionTile, in.lk-k0)
+				p := s[:bq*bk]
+				op.scores(in, b, q0, bq, k0, bk, p)
+				for r := 0; r < bq; r++ {
+					row := p[r*bk : (r+1)*bk]
+					m := runMax[r]
+					for _, x := range row {
+						m = math.Max(m, x)
+					}
+					if math.IsInf(m, -1) {
+						for c := range row {
+							row[c] = 0
+						}
+						continue
+					}
+					// rescale what was accumulated so far to the new max
+					alpha := math.Exp(runMax[r] - m)
+					var rowSum float64
+					for c, x := range row {
+						row[c] = math.Exp(x - m)
+						rowSum += row[c]
+					}
+					runSum[r] = runSum[r]*alpha + rowSum
+					runMax[r] = m
+					if alpha != 1 {
+						for d := range o[r*in.dv : (r+1)*in.dv] {
+							o[r*in.dv+d] *= alpha
+						}
+					}
+				}
+				whichblas.Dgemm(blas.NoTrans, blas.NoTrans, bq, in.dv, bk, 1, p, bk, v[k0*in.dv:], in.dv, 1, o, in.dv)
+			}
+
+			for r := 0; r < bq; r++ {
+				i := b*in.lq + q0 + r
+				if runSum[r] == 0 {
+					lse[i] = math.Inf(-1)
+					continue
+				}
+				for d := range o[r*in.dv : (r+1)*in.dv] {
+					o[r*in.dv+d] /= runSum[r]
+				}
+				lse[i] = runMax[r] + math.Log(runSum[r])
+			}
+		}
+	}
+	return out, lse
+}
+
+// backward returns the gradients of Q, K, V and the mask (if any), given the output, the log-sum-exp of the scores and the gradient of the output.
+func (op *attentionOp) backward(in *attentionInputs, out, lse, grad []float64) [][]float64 {
+	dq := make([]float64, len(in.q))
+	dk := make([]float64, len(in.k))
+	dv := make([]float64, len(in.v))
+	var dmask []float64
+	if in.mask != nil {
+		dmask = make([]float64, len(in.mask))
+	}
+
+	// Δ = rowsum(dO⊙O), so that the gradient of the scores is P⊙(dO·Vᵀ - Δ)
+	delta := make([]float64, in.batch*in.lq)
+	for i := range delta {
+		g, o := grad[i*in.dv:(i+1)*in.dv], out[i*in.dv:(i+1)*in.dv]
+		for d := range g {
+			delta[i] += g[d] * o[d]
+		}
+	}
+
+	p := make([]float64, attentionTile*attentionTile)
+	ds := make([]float64, attentionTile*attentionTile)
+	for b := 0; b < in.batch; b++ {
+		for q0 := 0; q0 < in.lq; q0 += attentionTile {
+			bq := minInt(attentionTile, in.lq-q0)
+			qOff := b*in.lq + q0
+			for k0, keys := 0, op.keys(in.attentionLayout, q0+bq); k0 < keys; k0 += attentionTile {
+				bk := minInt(attentionTile, in.lk-k0)
+				kOff := b*in.lk + k0
+				pt, dst := p[:bq*bk], ds[:bq*bk]
+				op.scores(in, b, q0, bq, k0, bk, pt)
+				for r := 0; r < bq; r++ {
+					for c := 0; c < bk; c++ {
+						if l := lse[qOff+r]; math.IsInf(l, -1) {
+							pt[r*bk+c] = 0
+						} else {
+							pt[r*bk+c] = math.Exp(pt[r*bk+c] - l)
+						}
+					}
+				}
+
+				g := grad[qOff*in.dv:]
+				// dV += Pᵀ·dO and dP = dO·Vᵀ
+				whichblas.Dgemm(blas.Trans, blas.NoTrans, bk, in.dv, bq, 1, pt, bk, g, in.dv, 1, dv[kOff*in.dv:], in.dv)
+				whichblas.Dgemm(blas.NoTrans, blas.Trans, bq, bk, in.dv, 1, g, in.dv, in.v[kOff*in.dv:], in.dv, 0, dst, bk)
+				for r := 0; r < bq; r++ {
+					for c := 0; c < bk; c++ {
+						x := pt[r*bk+c] * (dst[r*bk+c] - delta[qOff+r])
+						dst[r*bk+c] = x
+						if dmask != nil {
+							dmask[in.maskAt(b, q0+r, k0+c)] += x
+						}
+					}
+				}
+				// dQ += scale·dS·K and dK += scale·dSᵀ·Q
+				whichblas.Dgemm(blas.NoTrans, blas.NoTrans, bq, in.d, bk, in.scale, dst, bk, in.k[kOff*in.d:], in.d, 1, dq[qOff*in.d:], in.d)
+				whichblas.Dgemm(blas.Trans, blas.NoTrans, bk, in.d, bq, in.scale, dst, bk, in.q[qOff*in.d:], in.d, 1, dk[kOff*in.d:], in.d)
+			}
+		}
+	}
+
+	grads := [][]float64{dq, dk, dv}
+	if dmask != nil {
+		grads = append(grads, dmask)
+	}
+	return grads
+}
+
+// grads computes the gradients of all the inputs. Its inputs are the inputs of the op followed by the gradient of the output.
+func (op *attentionOp) grads(inputs ...value.Value) (grads [][]float64, err error) {
+	var in *attentionInputs
+	if in, err = op.checkInput(inputs[:op.Arity()]...); err != nil {
+		return nil, err
+	}
+	gt, ok := inputs[op.Arity()].(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected grad to be a tensor. Got %T instead", inputs[op.Arity()])
+	}
+	var g []float64
+	if g, err = float64sOf(gt); err != nil {
+		return nil, err
+	}
+	out, lse := op.forward(in)
+	if len(g) != len(out) {
+		return nil, errors.Errorf("Expected the gradient to have %d elements. Got %v", len(out), gt.Shape())
+	}
+	return op.backward(in, out, lse, g), nil
+}
+
+// attentionDiffOp computes the gradient of an attentionOp with regards to one of its inputs.
+// Its inputs are the inputs of the attentionOp followed by the gradient of the output.
+type attentionDiffOp struct {
+	*attentionOp
+	wrt int // 0 for Q, 1 for K, 2 for V and 3 for the mask
+}
+
+// Arity ...
+func (op *attentionDiffOp) Arity() int { return op.attentionOp.Arity() + 1 }
+
+// Type ...
+func (op *attentionDiffOp) Type() hm.Type {
+	ts := op.inputTypes()
+	return hm.NewFnType(append(ts, ts[0], ts[op.wrt])...)
+}
+
+// InferShape ...
+func (op *attentionDiffOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "attentionDiff")
+	}
+	s, ok := ns[op.wrt].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	return s.Clone(), nil
+}
+
+// Do ...
+func (op *attentionDiffOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "attentionDiff Do")
+	}
+	var out value.Value
+	if out, err = value.CloneValue(values[op.wrt]); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values...)
+}
+
+// WriteHash ...
+func (op *attentionDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *attentionDiffOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *attentionDiffOp) String() string { return fmt.Sprintf("%vDiff%d", op.attentionOp, op.wrt) }
+
+// UsePreallocDo ...
+func (op *attentionDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "attentionDiff UsePreallocDo")
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	if op.wrt < 0 || op.wrt >= op.attentionOp.Arity() {
+		return nil, errors.Errorf("%v has no input %d", op.attentionOp, op.wrt)
+	}
+	var grads [][]float64
+	if grads, err = op.grads(inputs...); err != nil {
+		return nil, err
+	}
+	if err = setFloat64s(out, grads[op.wrt]); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
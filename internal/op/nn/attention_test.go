@@ -0,0 +1,133 @@
+package nnops
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+// naiveAttention computes the attention of a (B, Lq, D) q with the full attention matrix, with a (Lq, Lk) mask
+func naiveAttention(q, k, v, mask []float64, b, lq, lk, d, dv int, causal bool) []float64 {
+	scale := 1 / math.Sqrt(float64(d))
+	out := make([]float64, b*lq*dv)
+	for n := 0; n < b; n++ {
+		for i := 0; i < lq; i++ {
+			scores := make([]float64, lk)
+			max := math.Inf(-1)
+			for j := range scores {
+				for x := 0; x < d; x++ {
+					scores[j] += q[(n*lq+i)*d+x] * k[(n*lk+j)*d+x]
+				}
+				scores[j] = scores[j]*scale + mask[i*lk+j]
+				if causal && j > i+lk-lq {
+					scores[j] = math.Inf(-1)
+				}
+				max = math.Max(max, scores[j])
+			}
+			var sum float64
+			for j, s := range scores {
+				scores[j] = math.Exp(s - max)
+				sum += scores[j]
+			}
+			for j, p := range scores {
+				for x := 0; x < dv; x++ {
+					out[(n*lq+i)*dv+x] += p / sum * v[(n*lk+j)*dv+x]
+				}
+			}
+		}
+	}
+	return out
+}
+
+func TestAttentionOp_Tiles(t *testing.T) {
+	// the sequences are longer than a tile, so the online softmax has to combine several tiles
+	const b, lq, lk, d, dv = 2, attentionTile + 6, attentionTile + 10, 4, 3
+	for _, causal := range []bool{false, true} {
+		op, err := newAttentionOp(3, 2, causal, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := newSines(1, 1.3)
+		q, k, v := src.dense(b, lq, d), src.dense(b, lk, d), src.dense(b, lk, dv)
+		mask := src.dense(lq, lk)
+		y, err := op.Do(q, k, v, mask)
+		if err != nil {
+			t.Fatal(err)
+		}
+		correct := naiveAttention(q.Float64s(), k.Float64s(), v.Float64s(), mask.Float64s(), b, lq, lk, d, dv, causal)
+		for i, v := range y.Data().([]float64) {
+			if math.Abs(v-correct[i]) > 1e-12 {
+				t.Errorf("%v: output %d - expected %v. Got %v", op, i, correct[i], v)
+				break
+			}
+		}
+	}
+}
+
+func TestAttentionOp_Grads(t *testing.T) {
+	attentionTests := []struct {
+		causal    bool
+		maskShape tensor.Shape
+	}{
+		{false, nil},
+		{true, nil},
+		{false, tensor.Shape{3, 5}},
+		{true, tensor.Shape{2, 1, 1, 5}}, // a padding mask, broadcast along the heads and the queries
+	}
+
+	const b, h, lq, lk, d, dv = 2, 2, 3, 5, 3, 2
+	for _, at := range attentionTests {
+		op, err := newAttentionOp(4, at.maskShape.Dims(), at.causal, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := newSines(1, 1.3)
+		inputs := []value.Value{
+			src.dense(b, h, lq, d),
+			src.dense(b, h, lk, d),
+			src.dense(b, h, lk, dv),
+		}
+		if at.maskShape != nil {
+			inputs = append(inputs, src.dense(at.maskShape...))
+		}
+		grad := src.dense(b, h, lq, dv)
+		gData := grad.Float64s()
+
+		// the gradients are checked against the numerical gradients of Σ y⊙grad
+		loss := func() float64 {
+			y, err := op.Do(inputs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sum float64
+			for i, v := range y.Data().([]float64) {
+				sum += v * gData[i]
+			}
+			return sum
+		}
+
+		for wrt, in := range inputs {
+			diff := &attentionDiffOp{attentionOp: op, wrt: wrt}
+			dx, err := diff.Do(append(inputs, grad)...)
+			if err != nil {
+				t.Errorf("%v: %v", diff, err)
+				continue
+			}
+			const eps = 1e-6
+			data := in.(*tensor.Dense).Float64s()
+			for i, v := range dx.Data().([]float64) {
+				orig := data[i]
+				data[i] = orig + eps
+				lp := loss()
+				data[i] = orig - eps
+				lm := loss()
+				data[i] = orig
+				if ng := (lp - lm) / (2 * eps); math.Abs(ng-v) > 1e-6 {
+					t.Errorf("%v: gradient %d - expected %v. Got %v", diff, i, ng, v)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,453 @@
+// +build !cuda
+
+package nnops
+
+import (
+	"fmt"
+	"hash"
+	"math"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/constructor"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &BatchNormOp{}
+	_ ops.Op = &batchNormDiffOp{}
+)
+
+// BatchNormOp is a CPU implementation of batch normalization, as described by Ioffe and Szegedy (2015) - https://arxiv.org/abs/1502.03167.
+//
+// The input is expected to be in (N, C, ...) format, with any number of trailing axes: (N, F), (N, C, L) and (N, C, H, W) inputs all work.
+// Each channel is normalized over the batch and the trailing axes.
+//
+// The inputs are x, γ, β, the running mean and the running variance. γ, β and the running statistics are vectors of C elements.
+// When training, the batch statistics are used, and the running statistics are updated in place:
+//	running = momentum × running + (1 - momentum) × batch
+// The running variance is updated with the unbiased variance of the batch.
+//
+// When testing, the running statistics are used and left untouched. Gradients still flow to x, γ and β,
+// so SetTesting is also how the statistics are frozen when fine tuning.
+type BatchNormOp struct {
+	momentum, epsilon float64
+	dims              int // dims of the input
+
+	training bool
+}
+
+func newBatchNormOp(dims int, momentum, epsilon float64) (*BatchNormOp, error) {
+	if dims < 2 {
+		return nil, errors.Errorf("BatchNorm expects a (N, C, ...) input. Got an input with %d dimensions", dims)
+	}
+	if momentum < 0 || momentum > 1 {
+		return nil, errors.Errorf("BatchNorm expects a momentum in [0, 1]. Got %v", momentum)
+	}
+	if epsilon <= 0 {
+		return nil, errors.Errorf("BatchNorm expects a positive epsilon. Got %v", epsilon)
+	}
+	return &BatchNormOp{
+		momentum: momentum,
+		epsilon:  epsilon,
+		dims:     dims,
+		training: true,
+	}, nil
+}
+
+// Arity ...
+func (op *BatchNormOp) Arity() int { return 5 }
+
+// BatchNormOp has this type:
+//		op :: Tensor-d a → Vector a → Vector a → Vector a → Vector a → Tensor-d a
+func (op *BatchNormOp) Type() hm.Type {
+	x, v := op.types()
+	return hm.NewFnType(x, // x
+		v, // scale
+		v, // bias
+		v, // running mean
+		v, // running variance
+		x) // retVal
+}
+
+// InferShape ...
+func (op *BatchNormOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "batchnorm")
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	if s.Dims() != op.dims {
+		return nil, errors.Errorf("%v expects an input with %d dimensions. Got %v", op, op.dims, s)
+	}
+	return s.Clone(), nil
+}
+
+// Do ...
+func (op *BatchNormOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "batchnorm Do")
+	}
+	var out value.Value
+	if out, err = value.CloneValue(values[0]); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values...)
+}
+
+// ReturnsPtr ...
+func (op *BatchNormOp) ReturnsPtr() bool { return true }
+
+// CallsExtern ...
+func (op *BatchNormOp) CallsExtern() bool { return false }
+
+// OverwritesInput returns -1. The running statistics are updated in place, but the output is never written into an input.
+func (op *BatchNormOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *BatchNormOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *BatchNormOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *BatchNormOp) String() string {
+	return fmt.Sprintf("BatchNorm{%d, %v, %v}", op.dims, op.momentum, op.epsilon)
+}
+
+// DiffWRT ...
+func (op *BatchNormOp) DiffWRT(inputs int) []bool { return []bool{true, true, true, false, false} }
+
+// SymDiff ...
+func (op *BatchNormOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	retVal = make(Nodes, len(inputs))
+	for i := 0; i < 3; i++ {
+		diff := &batchNormDiffOp{BatchNormOp: op, wrt: i}
+		if retVal[i], err = ApplyOp(diff, inputs[0], inputs[1], inputs[3], inputs[4], grad); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// DoDiff ...
+func (op *BatchNormOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	xdv, scaledv := getDV(inputs[0], inputs[1])
+	_, ydv := getDV(inputs[0], output)
+
+	for i := 0; i < 3; i++ {
+		_, dv := getDV(inputs[0], inputs[i])
+		diff := &batchNormDiffOp{BatchNormOp: op, wrt: i}
+		if _, err = diff.UsePreallocDo(dv.D, xdv.Value, scaledv.Value, inputs[3].Value(), inputs[4].Value(), ydv.D); err != nil {
+			return errors.Wrapf(err, doFail, diff)
+		}
+	}
+	return nil
+}
+
+// UsePreallocDo ...
+func (op *BatchNormOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "batchnorm UsePreallocDo")
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	var x, scale, bias []float64
+	var channels, inner int
+	if x, channels, inner, err = op.checkInput(inputs[0]); err != nil {
+		return nil, err
+	}
+	if scale, err = op.vectorOf(inputs[1], "γ", channels); err != nil {
+		return nil, err
+	}
+	if bias, err = op.vectorOf(inputs[2], "β", channels); err != nil {
+		return nil, err
+	}
+	runningMean, runningVar, err := op.runningStats(inputs[3], inputs[4], channels)
+	if err != nil {
+		return nil, err
+	}
+
+	xhat, mean, variance := op.normalize(x, channels, inner, runningMean, runningVar)
+	for i, v := range xhat {
+		c := (i / inner) % channels
+		xhat[i] = scale[c]*v + bias[c]
+	}
+	if err = setFloat64s(out, xhat); err != nil {
+		return nil, err
+	}
+
+	if !op.training {
+		return out, nil
+	}
+	n := float64(len(x) / channels)
+	for c := range runningMean {
+		runningMean[c] = op.momentum*runningMean[c] + (1-op.momentum)*mean[c]
+		unbiased := variance[c]
+		if n > 1 {
+			unbiased *= n / (n - 1)
+		}
+		runningVar[c] = op.momentum*runningVar[c] + (1-op.momentum)*unbiased
+	}
+	if err = setFloat64s(inputs[3].(*tensor.Dense), runningMean); err != nil {
+		return nil, err
+	}
+	if err = setFloat64s(inputs[4].(*tensor.Dense), runningVar); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetTraining makes the op use and update the batch statistics.
+func (op *BatchNormOp) SetTraining() { op.training = true }
+
+// SetTesting makes the op use the running statistics, without updating them.
+func (op *BatchNormOp) SetTesting() { op.training = false }
+
+// Reset is a no-op. The running statistics are held by the inputs of the op, so they are reset by resetting those.
+func (op *BatchNormOp) Reset() error { return nil }
+
+// Training returns whether the op is in training mode.
+func (op *BatchNormOp) Training() bool { return op.training }
+
+// types returns the types of the input and of the per channel vectors
+func (op *BatchNormOp) types() (x, v hm.Type) {
+	a := hm.TypeVariable('a')
+	return constructor.NewTensorType(op.dims, a), constructor.NewTensorType(1, a)
+}
+
+// checkInput returns the data of x, its number of channels and the number of elements of each channel of each example.
+func (op *BatchNormOp) checkInput(xv value.Value) (x []float64, channels, inner int, err error) {
+	xt, ok := xv.(tensor.Tensor)
+	if !ok {
+		err = errors.Errorf("Expected input to be a tensor. Got %T instead", xv)
+		return
+	}
+	s := xt.Shape()
+	if s.Dims() != op.dims {
+		err = errors.Errorf("%v expects an input with %d dimensions. Got %v", op, op.dims, s)
+		return
+	}
+	channels, inner = s[1], 1
+	if op.dims > 2 {
+		inner = tensor.Shape(s[2:]).TotalSize()
+	}
+	x, err = float64sOf(xt)
+	return
+}
+
+func (op *BatchNormOp) vectorOf(v value.Value, name string, channels int) ([]float64, error) {
+	t, ok := v.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected %s to be a tensor. Got %T instead", name, v)
+	}
+	if t.Shape().TotalSize() != channels {
+		return nil, errors.Errorf("%v expects %s to have %d elements. Got %v", op, name, channels, t.Shape())
+	}
+	return float64sOf(t)
+}
+
+// runningStats returns copies of the running statistics, which are written back by UsePreallocDo when training.
+func (op *BatchNormOp) runningStats(meanV, varV value.Value, channels int) (mean, variance []float64, err error) {
+	if _, ok := meanV.(*tensor.Dense); !ok {
+		return nil, nil, errors.Errorf("Expected the running mean to be a *tensor.Dense. Got %T instead", meanV)
+	}
+	if _, ok := varV.(*tensor.Dense); !ok {
+		return nil, nil, errors.Errorf("Expected the running variance to be a *tensor.Dense. Got %T instead", varV)
+	}
+	if mean, err = op.vectorOf(meanV, "the running mean", channels); err != nil {
+		return
+	}
+	if variance, err = op.vectorOf(varV, "the running variance", channels); err != nil {
+		return
+	}
+	return append([]float64(nil), mean...), append([]float64(nil), variance...), nil
+}
+
+// normalize returns the normalized input x̂, and the mean and the (biased) variance of each channel it was normalized with.
+// When training, those are the statistics of the batch. Otherwise they are the running statistics.
+func (op *BatchNormOp) normalize(x []float64, channels, inner int, runningMean, runningVar []float64) (xhat, mean, variance []float64) {
+	if op.training {
+		mean = make([]float64, channels)
+		variance = make([]float64, channels)
+		n := float64(len(x) / channels)
+		for i, v := range x {
+			mean[(i/inner)%channels] += v
+		}
+		for c := range mean {
+			mean[c] /= n
+		}
+		for i, v := range x {
+			c := (i / inner) % channels
+			variance[c] += (v - mean[c]) * (v - mean[c])
+		}
+		for c := range variance {
+			variance[c] /= n
+		}
+	} else {
+		mean, variance = runningMean, runningVar
+	}
+
+	xhat = make([]float64, len(x))
+	for i, v := range x {
+		c := (i / inner) % channels
+		xhat[i] = (v - mean[c]) / math.Sqrt(variance[c]+op.epsilon)
+	}
+	return
+}
+
+// batchNormDiffOp computes the gradient of a BatchNormOp with regards to x, γ or β.
+// Its inputs are x, γ, the running mean, the running variance and the gradient of the output.
+//
+// When training, with ĝ = g⊙γ, the gradient with regards to the input of each channel is
+//	dx = (ĝ - mean(ĝ) - x̂ mean(ĝ⊙x̂)) / σ
+// When testing, the statistics are constants, and dx = ĝ / σ.
+// The gradients of γ and β are Σ g⊙x̂ and Σ g over each channel.
+type batchNormDiffOp struct {
+	*BatchNormOp
+	wrt int // 0 for x, 1 for γ and 2 for β
+}
+
+// Arity ...
+func (op *batchNormDiffOp) Arity() int { return 5 }
+
+// Type ...
+func (op *batchNormDiffOp) Type() hm.Type {
+	x, v := op.types()
+	if op.wrt == 0 {
+		return hm.NewFnType(x, v, v, v, x, x)
+	}
+	return hm.NewFnType(x, v, v, v, x, v)
+}
+
+// InferShape ...
+func (op *batchNormDiffOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "batchnormDiff")
+	}
+	if op.wrt == 0 {
+		return ns[0].(tensor.Shape).Clone(), nil
+	}
+	return ns[1].(tensor.Shape).Clone(), nil
+}
+
+// Do ...
+func (op *batchNormDiffOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "batchnormDiff Do")
+	}
+	// the gradient of β has the shape of γ
+	in := values[0]
+	if op.wrt > 0 {
+		in = values[1]
+	}
+	var out value.Value
+	if out, err = value.CloneValue(in); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values...)
+}
+
+// WriteHash ...
+func (op *batchNormDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *batchNormDiffOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *batchNormDiffOp) String() string { return fmt.Sprintf("%vDiff%d", op.BatchNormOp, op.wrt) }
+
+// UsePreallocDo ...
+func (op *batchNormDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "batchnormDiff UsePreallocDo")
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	var x, scale, runningMean, runningVar, g []float64
+	var channels, inner int
+	if x, channels, inner, err = op.checkInput(inputs[0]); err != nil {
+		return nil, err
+	}
+	if scale, err = op.vectorOf(inputs[1], "γ", channels); err != nil {
+		return nil, err
+	}
+	if runningMean, runningVar, err = op.runningStats(inputs[2], inputs[3], channels); err != nil {
+		return nil, err
+	}
+	gt, ok := inputs[4].(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected grad to be a tensor. Got %T instead", inputs[4])
+	}
+	if g, err = float64sOf(gt); err != nil {
+		return nil, err
+	}
+	if len(g) != len(x) {
+		return nil, errors.Errorf("Expected the gradient to have %d elements. Got %v", len(x), gt.Shape())
+	}
+
+	xhat, _, variance := op.normalize(x, channels, inner, runningMean, runningVar)
+	var grads []float64
+	switch op.wrt {
+	case 0:
+		grads = op.dx(xhat, variance, scale, g, channels, inner)
+	case 1, 2:
+		grads = make([]float64, channels)
+		for i, v := range g {
+			if op.wrt == 1 {
+				v *= xhat[i]
+			}
+			grads[(i/inner)%channels] += v
+		}
+	default:
+		return nil, errors.Errorf("%v has no gradient for input %d", op.BatchNormOp, op.wrt)
+	}
+	if err = setFloat64s(out, grads); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (op *batchNormDiffOp) dx(xhat, variance, scale, g []float64, channels, inner int) []float64 {
+	dx := make([]float64, len(xhat))
+	rstd := make([]float64, channels)
+	for c := range rstd {
+		rstd[c] = 1 / math.Sqrt(variance[c]+op.epsilon)
+	}
+	for i := range dx {
+		dx[i] = g[i] * scale[(i/inner)%channels]
+	}
+	if !op.training {
+		for i := range dx {
+			dx[i] *= rstd[(i/inner)%channels]
+		}
+		return dx
+	}
+
+	meanG := make([]float64, channels)
+	meanGX := make([]float64, channels)
+	for i, v := range dx {
+		c := (i / inner) % channels
+		meanG[c] += v
+		meanGX[c] += v * xhat[i]
+	}
+	n := float64(len(dx) / channels)
+	for i := range dx {
+		c := (i / inner) % channels
+		dx[i] = (dx[i] - meanG[c]/n - xhat[i]*meanGX[c]/n) * rstd[c]
+	}
+	return dx
+}
@@ -0,0 +1,106 @@
+// +build !cuda
+
+package nnops
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/tensor"
+)
+
+func TestBatchNormOp_RunningStats(t *testing.T) {
+	op, err := newBatchNormOp(2, 0.9, 1e-5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// (N, F) input: the features have means 2 and 10, and unbiased variances 1 and 4
+	x := tensor.New(tensor.WithShape(3, 2), tensor.WithBacking([]float64{1, 8, 2, 10, 3, 12}))
+	scale := tensor.New(tensor.WithShape(2), tensor.WithBacking([]float64{1, 1}))
+	bias := tensor.New(tensor.WithShape(2), tensor.WithBacking([]float64{0, 0}))
+	mean := tensor.New(tensor.WithShape(2), tensor.WithBacking([]float64{0, 0}))
+	variance := tensor.New(tensor.WithShape(2), tensor.WithBacking([]float64{1, 1}))
+
+	y, err := op.Do(x, scale, bias, mean, variance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	correctMean := []float64{0.2, 1}
+	correctVar := []float64{1, 1.3}
+	for i := range correctMean {
+		if math.Abs(mean.Float64s()[i]-correctMean[i]) > 1e-12 || math.Abs(variance.Float64s()[i]-correctVar[i]) > 1e-12 {
+			t.Errorf("Expected running statistics %v and %v. Got %v and %v", correctMean, correctVar, mean.Float64s(), variance.Float64s())
+		}
+	}
+	if v := y.Data().([]float64)[0]; math.Abs(v+math.Sqrt(1.5)) > 1e-4 {
+		t.Errorf("Expected the first element to be normalized to %v. Got %v", -math.Sqrt(1.5), v)
+	}
+
+	// when testing, the running statistics are used and left alone
+	op.SetTesting()
+	if y, err = op.Do(x, scale, bias, mean, variance); err != nil {
+		t.Fatal(err)
+	}
+	if mean.Float64s()[0] != 0.2 {
+		t.Errorf("Expected the running mean to be frozen. Got %v", mean.Float64s())
+	}
+	if v, want := y.Data().([]float64)[0], 0.8/math.Sqrt(1+1e-5); math.Abs(v-want) > 1e-12 {
+		t.Errorf("Expected %v. Got %v", want, v)
+	}
+}
+
+func TestBatchNormOp_Gradients(t *testing.T) {
+	shape := tensor.Shape{2, 3, 4}
+	xData := newSines(3, 1.7).next(shape.TotalSize())
+	gData := newSines(1, 0.9).next(len(xData))
+	x := tensor.New(tensor.WithShape(shape...), tensor.WithBacking(xData))
+	grad := tensor.New(tensor.WithShape(shape...), tensor.WithBacking(gData))
+	scale := tensor.New(tensor.WithShape(3), tensor.WithBacking([]float64{0.5, 0.8, 1.1}))
+	bias := tensor.New(tensor.WithShape(3), tensor.WithBacking([]float64{0, 0.1, 0.2}))
+
+	for _, training := range []bool{true, false} {
+		op, _ := newBatchNormOp(3, 0.9, 1e-5)
+		if !training {
+			op.SetTesting()
+		}
+		mean := tensor.New(tensor.WithShape(3), tensor.WithBacking([]float64{0.1, -0.2, 0.3}))
+		variance := tensor.New(tensor.WithShape(3), tensor.WithBacking([]float64{1.5, 2, 0.7}))
+
+		// the gradients are checked against the numerical gradients of Σ y⊙grad.
+		// The running statistics are restored after each run so that every run normalizes with the same statistics.
+		loss := func() float64 {
+			m, v := mean.Clone().(*tensor.Dense), variance.Clone().(*tensor.Dense)
+			y, err := op.Do(x, scale, bias, m, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sum float64
+			for i, v := range y.Data().([]float64) {
+				sum += v * gData[i]
+			}
+			return sum
+		}
+
+		for wrt, in := range []*tensor.Dense{x, scale, bias} {
+			diff := &batchNormDiffOp{BatchNormOp: op, wrt: wrt}
+			d, err := diff.Do(x, scale, mean, variance, grad)
+			if err != nil {
+				t.Errorf("%v: %v", diff, err)
+				continue
+			}
+			const eps = 1e-6
+			data := in.Float64s()
+			for i, v := range d.Data().([]float64) {
+				orig := data[i]
+				data[i] = orig + eps
+				lp := loss()
+				data[i] = orig - eps
+				lm := loss()
+				data[i] = orig
+				if ng := (lp - lm) / (2 * eps); math.Abs(ng-v) > 1e-6 {
+					t.Errorf("%v training %t: gradient %d - expected %v. Got %v", diff, training, i, ng, v)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,488 @@
+package nnops
+
+import (
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+// QuantParams describe how real numbers are mapped to int8:
+//		q = clamp(round(x/Scale) + ZeroPoint, -128, 127)
+//		x ≈ (q - ZeroPoint)·Scale
+// Per-tensor params have a single scale and zero point, and an Axis of -1.
+// Per-channel params have a scale and a zero point for each index along Axis.
+type QuantParams struct {
+	Scale     []float64
+	ZeroPoint []int32
+	Axis      int
+}
+
+// PerTensor returns per-tensor params
+func PerTensor(scale float64, zeroPoint int32) QuantParams {
+	return QuantParams{Scale: []float64{scale}, ZeroPoint: []int32{zeroPoint}, Axis: -1}
+}
+
+// Channels returns the number of channels the params have
+func (p QuantParams) Channels() int { return len(p.Scale) }
+
+// IsPerTensor returns true if the params have a single scale and zero point
+func (p QuantParams) IsPerTensor() bool { return p.Axis < 0 }
+
+// IsSymmetric returns true if all the zero points are 0
+func (p QuantParams) IsSymmetric() bool {
+	for _, z := range p.ZeroPoint {
+		if z != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (p QuantParams) String() string {
+	if p.IsPerTensor() {
+		return fmt.Sprintf("{scale: %v, zero: %d}", p.Scale[0], p.ZeroPoint[0])
+	}
+	return fmt.Sprintf("{axis: %d, scales: %v, zeros: %v}", p.Axis, p.Scale, p.ZeroPoint)
+}
+
+// check checks that the params are well formed, and fit a value of the given shape
+func (p QuantParams) check(s tensor.Shape) error {
+	if len(p.Scale) == 0 || len(p.Scale) != len(p.ZeroPoint) {
+		return errors.Errorf("Expected as many scales as zero points. Got %d and %d", len(p.Scale), len(p.ZeroPoint))
+	}
+	for _, sc := range p.Scale {
+		if !(sc > 0) || math.IsInf(sc, 0) {
+			return errors.Errorf("Expected the scales to be positive. Got %v", p.Scale)
+		}
+	}
+	for _, z := range p.ZeroPoint {
+		if z < math.MinInt8 || z > math.MaxInt8 {
+			return errors.Errorf("Expected the zero points to be int8s. Got %v", p.ZeroPoint)
+		}
+	}
+	if p.IsPerTensor() {
+		if len(p.Scale) != 1 {
+			return errors.Errorf("Expected per-tensor params to have a single scale. Got %d", len(p.Scale))
+		}
+		return nil
+	}
+	if p.Axis >= s.Dims() {
+		return errors.Errorf("Axis %d of the params is out of bounds for a value of shape %v", p.Axis, s)
+	}
+	if s[p.Axis] != len(p.Scale) {
+		return errors.Errorf("Expected %d channels along axis %d. Got a value of shape %v", len(p.Scale), p.Axis, s)
+	}
+	return nil
+}
+
+// at returns the scale and the zero point of the channel ch
+func (p QuantParams) at(ch int) (float64, int32) {
+	if p.IsPerTensor() {
+		return p.Scale[0], p.ZeroPoint[0]
+	}
+	return p.Scale[ch], p.ZeroPoint[ch]
+}
+
+// channels returns a function that maps the index of an element of a value of shape s to its channel
+func (p QuantParams) channels(s tensor.Shape) func(i int) int { return channelsAlong(s, p.Axis) }
+
+// channelsAlong returns a function that maps the index of an element of a value of shape s to its index along the axis.
+// Every element is in channel 0 if the axis is negative.
+func channelsAlong(s tensor.Shape, axis int) func(i int) int {
+	if axis < 0 {
+		return func(int) int { return 0 }
+	}
+	inner := 1
+	for _, d := range s[axis+1:] {
+		inner *= d
+	}
+	n := s[axis]
+	return func(i int) int { return (i / inner) % n }
+}
+
+// quantize maps x to an int8 with the scale and the zero point
+func quantize(x, scale float64, zero int32) int8 {
+	q := math.RoundToEven(x/scale) + float64(zero)
+	return int8(math.Max(math.MinInt8, math.Min(math.MaxInt8, q)))
+}
+
+// paramsOf returns the params that map [lo, hi] to int8. The range is widened to hold 0, so that 0 is exactly representable.
+// Symmetric params map [-a, a] to [-127, 127], where a = max(|lo|, |hi|), and have a zero point of 0.
+func paramsOf(lo, hi float64, symmetric bool) (scale float64, zero int32) {
+	lo, hi = math.Min(lo, 0), math.Max(hi, 0)
+	if symmetric {
+		if scale = math.Max(-lo, hi) / math.MaxInt8; scale == 0 {
+			return 1, 0
+		}
+		return scale, 0
+	}
+	if scale = (hi - lo) / (math.MaxInt8 - math.MinInt8); scale == 0 {
+		return 1, 0
+	}
+	z := math.Round(math.MinInt8 - lo/scale)
+	return scale, int32(math.Max(math.MinInt8, math.Min(math.MaxInt8, z)))
+}
+
+// CalibrationMethod is how an Observer picks the range of the values it saw
+type CalibrationMethod byte
+
+const (
+	// MinMax uses the smallest and the largest values
+	MinMax CalibrationMethod = iota
+	// Percentile clips the outliers, using the values at the given lower and upper percentiles
+	Percentile
+)
+
+func (m CalibrationMethod) String() string {
+	switch m {
+	case MinMax:
+		return "MinMax"
+	case Percentile:
+		return "Percentile"
+	}
+	return fmt.Sprintf("CalibrationMethod(%d)", byte(m))
+}
+
+// histBins is the number of bins of the histograms kept by the percentile observers
+const histBins = 2048
+
+// Observer records statistics of the values that flow through a node during calibration,
+// and turns them into the QuantParams of the node.
+//
+// The statistics are kept either for the whole tensor, or for each channel along an axis, as is usual for the weights of a MatMul or a Conv.
+// Percentile observers keep a histogram of each channel, so calibration data of any size can be observed in constant memory.
+type Observer struct {
+	method     CalibrationMethod
+	percentile float64
+	axis       int // -1 for per-tensor statistics
+	symmetric  bool
+
+	min, max []float64
+	hists    []histogram
+}
+
+// NewMinMaxObserver creates an Observer that records the smallest and the largest values.
+// An axis of -1 records them for the whole tensor, otherwise they are recorded for each channel along the axis.
+func NewMinMaxObserver(axis int, symmetric bool) *Observer {
+	return &Observer{method: MinMax, axis: axis, symmetric: symmetric}
+}
+
+// NewPercentileObserver creates an Observer that clips the values below the (100-p)th percentile and above the pth percentile, e.g. 99.99.
+// An axis of -1 records the percentiles for the whole tensor, otherwise they are recorded for each channel along the axis.
+func NewPercentileObserver(p float64, axis int, symmetric bool) (*Observer, error) {
+	if !(p > 50 && p <= 100) {
+		return nil, errors.Errorf("Expected a percentile in (50, 100]. Got %v", p)
+	}
+	return &Observer{method: Percentile, percentile: p, axis: axis, symmetric: symmetric}, nil
+}
+
+// Observe records the statistics of a float value
+func (o *Observer) Observe(v value.Value) error {
+	t, ok := v.(tensor.Tensor)
+	if !ok {
+		return errors.Errorf("Expected a tensor. Got %T instead", v)
+	}
+	data, err := float64sOf(tensor.Materialize(t))
+	if err != nil {
+		return err
+	}
+
+	s, chans := t.Shape(), 1
+	if o.axis >= 0 {
+		if o.axis >= s.Dims() {
+			return errors.Errorf("%v: axis %d is out of bounds for a value of shape %v", o, o.axis, s)
+		}
+		chans = s[o.axis]
+	}
+	if o.min == nil {
+		o.min, o.max = make([]float64, chans), make([]float64, chans)
+		for i := range o.min {
+			o.min[i], o.max[i] = math.Inf(1), math.Inf(-1)
+		}
+	}
+	if len(o.min) != chans {
+		return errors.Errorf("%v expects %d channels. Got a value of shape %v", o, len(o.min), s)
+	}
+
+	// the range of this batch
+	chanOf := channelsAlong(s, o.axis)
+	lo, hi := make([]float64, chans), make([]float64, chans)
+	for i := range lo {
+		lo[i], hi[i] = math.Inf(1), math.Inf(-1)
+	}
+	for i, x := range data {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return errors.Errorf("%v cannot observe %v", o, x)
+		}
+		ch := chanOf(i)
+		lo[ch], hi[ch] = math.Min(lo[ch], x), math.Max(hi[ch], x)
+	}
+	for ch := range lo {
+		o.min[ch], o.max[ch] = math.Min(o.min[ch], lo[ch]), math.Max(o.max[ch], hi[ch])
+	}
+	if o.method != Percentile {
+		return nil
+	}
+
+	if o.hists == nil {
+		o.hists = make([]histogram, chans)
+	}
+	for ch := range o.hists {
+		o.hists[ch].widen(o.min[ch], o.max[ch])
+	}
+	for i, x := range data {
+		o.hists[chanOf(i)].add(x)
+	}
+	return nil
+}
+
+// Range returns the range of channel ch that is mapped to int8, before it is widened to hold 0
+func (o *Observer) Range(ch int) (lo, hi float64) {
+	if o.method == Percentile {
+		h := &o.hists[ch]
+		return h.quantile(1 - o.percentile/100), h.quantile(o.percentile / 100)
+	}
+	return o.min[ch], o.max[ch]
+}
+
+// Params returns the QuantParams of the values observed so far
+func (o *Observer) Params() (QuantParams, error) {
+	if o.min == nil {
+		return QuantParams{}, errors.Errorf("%v has not observed any value", o)
+	}
+	p := QuantParams{Scale: make([]float64, len(o.min)), ZeroPoint: make([]int32, len(o.min)), Axis: o.axis}
+	for ch := range o.min {
+		lo, hi := o.Range(ch)
+		p.Scale[ch], p.ZeroPoint[ch] = paramsOf(lo, hi, o.symmetric)
+	}
+	return p, nil
+}
+
+// Reset forgets the values observed so far
+func (o *Observer) Reset() {
+	o.min, o.max, o.hists = nil, nil, nil
+}
+
+func (o *Observer) String() string {
+	if o.method == Percentile {
+		return fmt.Sprintf("%v{%v, axis: %d, symmetric: %t}", o.method, o.percentile, o.axis, o.symmetric)
+	}
+	return fmt.Sprintf("%v{axis: %d, symmetric: %t}", o.method, o.axis, o.symmetric)
+}
+
+// histogram counts values in histBins bins of equal width over [lo, hi]
+type histogram struct {
+	lo, hi float64
+	counts []float64
+	total  float64
+}
+
+func (h *histogram) width() float64 { return (h.hi - h.lo) / histBins }
+
+func (h *histogram) bin(x float64) int {
+	w := h.width()
+	if w == 0 {
+		return 0
+	}
+	return minInt(int((x-h.lo)/w), histBins-1)
+}
+
+// widen widens the histogram to [lo, hi]. The counts already in the histogram are moved to the bins their centers fall in.
+func (h *histogram) widen(lo, hi float64) {
+	if h.counts == nil {
+		h.lo, h.hi, h.counts = lo, hi, make([]float64, histBins)
+		return
+	}
+	if lo >= h.lo && hi <= h.hi {
+		return
+	}
+	old := *h
+	h.lo, h.hi, h.counts = math.Min(lo, old.lo), math.Max(hi, old.hi), make([]float64, histBins)
+	w := old.width()
+	for i, c := range old.counts {
+		if c != 0 {
+			h.counts[h.bin(old.lo+(float64(i)+0.5)*w)] += c
+		}
+	}
+}
+
+func (h *histogram) add(x float64) {
+	h.counts[h.bin(x)]++
+	h.total++
+}
+
+// quantile returns the value below which the fraction q of the values fall, interpolating linearly within the bins
+func (h *histogram) quantile(q float64) float64 {
+	target := q * h.total
+	var seen float64
+	w := h.width()
+	for i, c := range h.counts {
+		if c > 0 && seen+c >= target {
+			return h.lo + (float64(i)+(target-seen)/c)*w
+		}
+		seen += c
+	}
+	return h.hi
+}
+
+// DriftReport accumulates how far the dequantized outputs of a quantized model are from the outputs of the float model
+type DriftReport struct {
+	MaxAbsErr float64 // the largest absolute error
+	Size      int     // the number of elements compared
+
+	sumSqErr, sumSq float64
+}
+
+// Add compares the output of the float model to the dequantized output of the quantized model for the same input
+func (r *DriftReport) Add(float, dequantized value.Value) error {
+	if !float.Shape().Eq(dequantized.Shape()) {
+		return errors.Errorf("Cannot compare outputs of different shapes: %v and %v", float.Shape(), dequantized.Shape())
+	}
+	var fs, ds []float64
+	var err error
+	ft, ok := float.(tensor.Tensor)
+	if !ok {
+		return errors.Errorf("Expected a tensor. Got %T instead", float)
+	}
+	dt, ok := dequantized.(tensor.Tensor)
+	if !ok {
+		return errors.Errorf("Expected a tensor. Got %T instead", dequantized)
+	}
+	if fs, err = float64sOf(tensor.Materialize(ft)); err != nil {
+		return err
+	}
+	if ds, err = float64sOf(tensor.Materialize(dt)); err != nil {
+		return err
+	}
+	for i, f := range fs {
+		e := math.Abs(f - ds[i])
+		r.MaxAbsErr = math.Max(r.MaxAbsErr, e)
+		r.sumSqErr += e * e
+		r.sumSq += f * f
+	}
+	r.Size += len(fs)
+	return nil
+}
+
+// RMSE returns the root mean square error
+func (r *DriftReport) RMSE() float64 {
+	if r.Size == 0 {
+		return 0
+	}
+	return math.Sqrt(r.sumSqErr / float64(r.Size))
+}
+
+// RelErr returns the root mean square error relative to the root mean square of the float outputs
+func (r *DriftReport) RelErr() float64 {
+	if r.sumSq == 0 {
+		return 0
+	}
+	return math.Sqrt(r.sumSqErr / r.sumSq)
+}
+
+// SQNR returns the signal to quantization noise ratio in decibels. The higher the better; int8 typically gives 30 to 40 dB.
+func (r *DriftReport) SQNR() float64 {
+	return 10 * math.Log10(r.sumSq/r.sumSqErr)
+}
+
+func (r *DriftReport) String() string {
+	return fmt.Sprintf("%d elements. Max abs error: %v, RMSE: %v, relative error: %v, SQNR: %.1f dB", r.Size, r.MaxAbsErr, r.RMSE(), r.RelErr(), r.SQNR())
+}
+
+// QuantizeRule tells Calibrate whether to rewrite the node n, a MatMul or a Conv of a model, into an int8 op, and how to calibrate it.
+// n takes the activations as input 0 and the weights as input 1; a bias is added by another node, and stays in floats.
+// x, w and y observe the activations, the weights and the output of n. x and y have to observe per tensor, and w either per tensor
+// or per output channel: along axis 1 for a MatMul and axis 0 for a Conv.
+type QuantizeRule func(n *exprgraph.Node) (x, w, y *Observer, ok bool)
+
+// Calibration holds the observers of the nodes of a graph that are to be quantized, once calibration data has been run through the graph.
+// It is turned into int8 ops by QuantizeGraph.
+type Calibration struct {
+	g         *exprgraph.ExprGraph
+	nodes     []*exprgraph.Node
+	observers map[int64][3]*Observer // x, w and y of each node
+}
+
+// Calibrate runs calibration data through g, and observes the activations, the weights and the outputs of the nodes the rule asks for.
+//
+// run is called until it returns io.EOF. Each call binds a batch of calibration data to the inputs of g and executes g,
+// so that every node holds its value.
+func Calibrate(g *exprgraph.ExprGraph, rule QuantizeRule, run func() error) (*Calibration, error) {
+	c := &Calibration{g: g, observers: make(map[int64][3]*Observer)}
+	nodes := g.Nodes()
+	for nodes.Next() {
+		n, ok := nodes.Node().(*exprgraph.Node)
+		if !ok || n.Op == nil {
+			continue
+		}
+		x, w, y, want := rule(n)
+		if !want {
+			continue
+		}
+		if ins := g.InputsOf(n); len(ins) != 2 {
+			return nil, errors.Errorf("Expected %v to take the activations and the weights. Got %d inputs", n.Name, len(ins))
+		}
+		c.nodes = append(c.nodes, n)
+		c.observers[n.ID()] = [3]*Observer{x, w, y}
+	}
+
+	for {
+		err := run()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Calibration failed")
+		}
+		for _, n := range c.nodes {
+			ins := g.InputsOf(n)
+			for i, v := range []*exprgraph.Node{ins[0], ins[1], n} {
+				if v.Value() == nil {
+					return nil, errors.Errorf("Expected %v to hold a value after a calibration batch", v.Name)
+				}
+				if err = c.observers[n.ID()][i].Observe(v.Value()); err != nil {
+					return nil, errors.Wrapf(err, "Cannot observe %v", v.Name)
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+// MeasureDrift runs data through a graph rewritten by QuantizeGraph, and reports how far each dequantized output is from the float output it replaced.
+//
+// run is called until it returns io.EOF. Each call binds a batch of data to the inputs of the graph and executes both the float
+// and the quantized nodes, so that they hold their values.
+func MeasureDrift(float, dequantized []*exprgraph.Node, run func() error) ([]*DriftReport, error) {
+	if len(float) != len(dequantized) {
+		return nil, errors.Errorf("Expected as many float outputs as dequantized outputs. Got %d and %d", len(float), len(dequantized))
+	}
+	reports := make([]*DriftReport, len(float))
+	for i := range reports {
+		reports[i] = new(DriftReport)
+	}
+	for {
+		err := run()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "Measuring the drift failed")
+		}
+		for i, f := range float {
+			d := dequantized[i]
+			if f.Value() == nil || d.Value() == nil {
+				return nil, errors.Errorf("Expected %v and %v to hold values after a batch", f.Name, d.Name)
+			}
+			if err = reports[i].Add(f.Value(), d.Value()); err != nil {
+				return nil, errors.Wrapf(err, "Cannot compare %v and %v", f.Name, d.Name)
+			}
+		}
+	}
+	return reports, nil
+}
@@ -0,0 +1,118 @@
+package nnops
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gorgonia.org/tensor"
+)
+
+func TestParamsOf(t *testing.T) {
+	for _, r := range [][2]float64{{-1, 3}, {0.5, 2}, {-4, -1}, {0, 0}} {
+		for _, symmetric := range []bool{false, true} {
+			scale, zero := paramsOf(r[0], r[1], symmetric)
+			// 0 is exactly representable
+			if q := quantize(0, scale, zero); float64(int32(q)-zero)*scale != 0 {
+				t.Errorf("%v, symmetric %t: 0 is quantized to %d, which is not 0", r, symmetric, q)
+			}
+			// the range fits
+			for _, x := range r {
+				if got := float64(int32(quantize(x, scale, zero))-zero) * scale; math.Abs(got-x) > scale/2+1e-12 {
+					t.Errorf("%v, symmetric %t: %v is dequantized to %v", r, symmetric, x, got)
+				}
+			}
+			if symmetric && zero != 0 {
+				t.Errorf("%v: expected symmetric params to have a zero point of 0. Got %d", r, zero)
+			}
+		}
+	}
+}
+
+func TestObserver(t *testing.T) {
+	// per channel along axis 1
+	o := NewMinMaxObserver(1, true)
+	x := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]float64{1, -2, 0.5, -1, 4, 0.25}))
+	if err := o.Observe(x); err != nil {
+		t.Fatal(err)
+	}
+	x = tensor.New(tensor.WithShape(1, 3), tensor.WithBacking([]float64{3, 1, -0.75}))
+	if err := o.Observe(x); err != nil {
+		t.Fatal(err)
+	}
+	p, err := o.Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	correct := []float64{3.0 / 127, 4.0 / 127, 0.75 / 127}
+	if p.Axis != 1 || p.Channels() != 3 || !p.IsSymmetric() {
+		t.Fatalf("Expected symmetric params for 3 channels along axis 1. Got %v", p)
+	}
+	for i, s := range p.Scale {
+		if math.Abs(s-correct[i]) > 1e-12 {
+			t.Errorf("Expected scales %v. Got %v", correct, p.Scale)
+			break
+		}
+	}
+	if err = o.Observe(tensor.New(tensor.WithShape(2, 2), tensor.WithBacking([]float64{1, 2, 3, 4}))); err == nil {
+		t.Error("Expected a value with a different number of channels to fail")
+	}
+
+	// the percentiles clip the outliers that min/max keeps
+	r := rand.New(rand.NewSource(1))
+	po, err := NewPercentileObserver(99.9, -1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mo := NewMinMaxObserver(-1, false)
+	for b := 0; b < 3; b++ {
+		data := make([]float32, 10000)
+		for i := range data {
+			data[i] = float32(r.NormFloat64() * float64(b+1))
+		}
+		data[b] = 1000 // an outlier
+		x := tensor.New(tensor.WithShape(100, 100), tensor.WithBacking(data))
+		if err = po.Observe(x); err != nil {
+			t.Fatal(err)
+		}
+		if err = mo.Observe(x); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, hi := mo.Range(0); hi != 1000 {
+		t.Errorf("Expected min/max to keep the outlier. Got %v", hi)
+	}
+	// the 99.9th percentile of the mixture of N(0, 1), N(0, 2) and N(0, 3) is about 8
+	if lo, hi := po.Range(0); hi < 6 || hi > 10 || lo > -6 || lo < -10 {
+		t.Errorf("Expected the percentiles to be about ±8. Got %v and %v", lo, hi)
+	}
+
+	po.Reset()
+	if _, err = po.Params(); err == nil {
+		t.Error("Expected an observer that was reset to have no params")
+	}
+	if _, err = NewPercentileObserver(30, -1, false); err == nil {
+		t.Error("Expected a percentile below 50 to fail")
+	}
+}
+
+func TestDriftReport(t *testing.T) {
+	var r DriftReport
+	float := tensor.New(tensor.WithShape(4), tensor.WithBacking([]float64{1, -2, 3, 4}))
+	deq := tensor.New(tensor.WithShape(4), tensor.WithBacking([]float64{1, -2.5, 3, 4.5}))
+	if err := r.Add(float, deq); err != nil {
+		t.Fatal(err)
+	}
+	if r.Size != 4 || r.MaxAbsErr != 0.5 {
+		t.Errorf("Expected 4 elements with a max abs error of 0.5. Got %v", &r)
+	}
+	if rmse := r.RMSE(); math.Abs(rmse-math.Sqrt(0.125)) > 1e-12 {
+		t.Errorf("Expected a RMSE of %v. Got %v", math.Sqrt(0.125), rmse)
+	}
+	if rel := r.RelErr(); math.Abs(rel-math.Sqrt(0.5/30)) > 1e-12 {
+		t.Errorf("Expected a relative error of %v. Got %v", math.Sqrt(0.5/30), rel)
+	}
+	if err := r.Add(float, tensor.New(tensor.WithShape(2, 2), tensor.WithBacking([]float64{1, 2, 3, 4}))); err == nil {
+		t.Error("Expected outputs of different shapes to fail")
+	}
+}
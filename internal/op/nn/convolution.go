@@ -0,0 +1,518 @@
+// +build !cuda
+
+package nnops
+
+import (
+	"fmt"
+	"hash"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/blas"
+	G "gorgonia.org/gorgonia"
+	"gorgonia.org/gorgonia/internal/constructor"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &convolution{}
+	_ ops.Op = &convDiffIm{}
+	_ ops.Op = &convDiffFilter{}
+)
+
+// convolution is the CPU counterpart of the cuDNN backed convolution op (see convolution_cuda.go).
+//
+// The convolution is computed as an im2col followed by a GEMM per group:
+//
+//	out[b, g] = filter[g] · im2col(im[b, g])ᵀ
+//
+// where filter[g] is the (K/groups × C/groups·kh·kw) slice of the filter that belongs to group g.
+// The image is expected to be in NCHW format and the filter in KCHW format, exactly like the CUDA version.
+type convolution struct {
+	im2colOp // kernel, padding, stride and dilation
+
+	groups int
+
+	// created with these attributes
+	padding, stride, dilation []int
+	inShape, filterShape      tensor.Shape
+}
+
+func makeConvolutionOp(im, filter *G.Node, kernelShape tensor.Shape, pad, stride, dilation []int, groups int) (retVal *convolution, err error) {
+	if err = CheckConvolutionParams(pad, stride, dilation); err != nil {
+		return nil, err
+	}
+	if len(kernelShape) != 2 || len(pad) != 2 || len(stride) != 2 || len(dilation) != 2 {
+		return nil, errors.Errorf("Conv2d expects 2 dimensional kernel, pad, stride and dilation. Got %v, %v, %v, %v", kernelShape, pad, stride, dilation)
+	}
+	if groups <= 0 {
+		return nil, errors.Errorf("Cannot use groups of less than or equal 0: %d", groups)
+	}
+
+	inShape := im.Shape()
+	filterShape := filter.Shape()
+	if inShape.Dims() != 4 {
+		return nil, errors.Errorf("Expected im to have 4 dimensions (NCHW). Got %v instead", inShape)
+	}
+	if filterShape.Dims() != 4 {
+		return nil, errors.Errorf("Expected filter to have 4 dimensions (KCHW). Got %v instead", filterShape)
+	}
+
+	channels, kernels := inShape[1], filterShape[0]
+	if channels%groups != 0 || kernels%groups != 0 {
+		return nil, errors.Errorf("Input channels (%d) and output channels (%d) must both be divisible by groups (%d)", channels, kernels, groups)
+	}
+	if filterShape[1] != channels/groups {
+		return nil, errors.Errorf("Expected filter to have %d input channels. Got %v instead", channels/groups, filterShape)
+	}
+	if filterShape[2] != kernelShape[0] || filterShape[3] != kernelShape[1] {
+		return nil, errors.Errorf("Filter shape %v does not match kernel shape %v", filterShape, kernelShape)
+	}
+
+	return &convolution{
+		im2colOp: makeIm2ColOp(kernelShape[0], kernelShape[1], pad[0], pad[1], stride[0], stride[1], dilation[0], dilation[1]),
+		groups:   groups,
+
+		padding:  pad,
+		stride:   stride,
+		dilation: dilation,
+
+		inShape:     inShape.Clone(),
+		filterShape: filterShape.Clone(),
+	}, nil
+}
+
+func (c *convolution) Arity() int { return 2 }
+
+// convolution :: (Floats a) ⇒ Tensor a → Tensor a → Tensor a
+func (c *convolution) Type() hm.Type {
+	t := constructor.MakeTensorType(4, hm.TypeVariable('a'))
+	return hm.NewFnType(t, t, t)
+}
+
+func (c *convolution) InferShape(inputs ...ops.DimSizer) (retVal tensor.Shape, err error) {
+	if err = ops.CheckArity(c, len(inputs)); err != nil {
+		return
+	}
+	return c.outShape(), nil
+}
+
+func (c *convolution) Do(inputs ...value.Value) (retVal value.Value, err error) {
+	var im, filter *tensor.Dense
+	if im, filter, err = c.checkInput(inputs...); err != nil {
+		return nil, err
+	}
+	prealloc := tensor.New(tensor.Of(im.Dtype()), tensor.WithShape(c.outShape()...))
+	return c.do(prealloc, im, filter)
+}
+
+func (c *convolution) ReturnsPtr() bool     { return false }
+func (c *convolution) CallsExtern() bool    { return false }
+func (c *convolution) OverwritesInput() int { return -1 }
+
+func (c *convolution) WriteHash(h hash.Hash) {
+	fmt.Fprintf(h, "Convolution:%v-%v-%v-%d", c.padding, c.stride, c.dilation, c.groups)
+}
+
+func (c *convolution) Hashcode() uint32 { return simpleHash(c) }
+
+func (c *convolution) String() string {
+	return fmt.Sprintf("Convolution:%v-%v-%v-%d", c.padding, c.stride, c.dilation, c.groups)
+}
+
+func (c *convolution) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (value.Value, error) {
+	im, filter, err := c.checkInput(inputs...)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(prealloc, im, filter)
+}
+
+func (c *convolution) DiffWRT(inputs int) []bool { return []bool{true, true} }
+
+func (c *convolution) SymDiff(inputs Nodes, output, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(c, len(inputs)); err != nil {
+		return
+	}
+	diffIm := &convDiffIm{c}
+	diffFilter := &convDiffFilter{c}
+
+	retVal = make(Nodes, 2)
+	if retVal[0], err = ApplyOp(diffIm, inputs[1], grad); err != nil {
+		return nil, err
+	}
+	if retVal[1], err = ApplyOp(diffFilter, inputs[0], grad); err != nil {
+		return nil, err
+	}
+	return
+}
+
+func (c *convolution) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(c, len(inputs)); err != nil {
+		return
+	}
+	imdv, filterdv := getDV(inputs[0], inputs[1])
+	_, outdv := getDV(inputs[0], output)
+
+	diffIm := &convDiffIm{c}
+	if _, err = diffIm.IncrDo(imdv.D, filterdv.Value, outdv.D); err != nil {
+		return errors.Wrapf(err, doFail, diffIm)
+	}
+
+	diffFilter := &convDiffFilter{c}
+	if _, err = diffFilter.IncrDo(filterdv.D, imdv.Value, outdv.D); err != nil {
+		return errors.Wrapf(err, doFail, diffFilter)
+	}
+	return
+}
+
+func (c *convolution) checkInput(inputs ...value.Value) (im, filter *tensor.Dense, err error) {
+	if err = ops.CheckArity(c, len(inputs)); err != nil {
+		return
+	}
+	var ok bool
+	if im, ok = inputs[0].(*tensor.Dense); !ok {
+		return nil, nil, errors.Errorf("Expected im to be a *tensor.Dense. Got %T instead", inputs[0])
+	}
+	if filter, ok = inputs[1].(*tensor.Dense); !ok {
+		return nil, nil, errors.Errorf("Expected filter to be a *tensor.Dense. Got %T instead", inputs[1])
+	}
+	if !im.Shape().Eq(c.inShape) {
+		return nil, nil, errors.Errorf("Expected im to have shape %v. Got %v instead", c.inShape, im.Shape())
+	}
+	if !filter.Shape().Eq(c.filterShape) {
+		return nil, nil, errors.Errorf("Expected filter to have shape %v. Got %v instead", c.filterShape, filter.Shape())
+	}
+	if im.Dtype() != filter.Dtype() {
+		return nil, nil, errors.Errorf("Dtype mismatch between im (%v) and filter (%v)", im.Dtype(), filter.Dtype())
+	}
+	return
+}
+
+func (c *convolution) outShape() tensor.Shape {
+	h, w := c.retHW(c.inShape[2], c.inShape[3])
+	return tensor.Shape{c.inShape[0], c.filterShape[0], h, w}
+}
+
+// dims returns the sizes required by the GEMMs:
+//
+//	channels - number of input channels
+//	outH, outW - spatial size of the output. There are outH × outW patches per image
+//	colWidth - the width of a row of the im2col matrix (channels × kh × kw)
+//	groupK, groupCols - the number of kernels and the number of im2col columns in each group
+func (c *convolution) dims() (channels, outH, outW, colWidth, groupK, groupCols int) {
+	channels = c.inShape[1]
+	outH, outW = c.retHW(c.inShape[2], c.inShape[3])
+	colWidth = channels * c.h * c.w
+	groupK = c.filterShape[0] / c.groups
+	groupCols = colWidth / c.groups
+	return
+}
+
+// col allocates the scratch space for the im2col matrix of a single image
+func (c *convolution) col(dt tensor.Dtype) *tensor.Dense {
+	_, outH, outW, colWidth, _, _ := c.dims()
+	return tensor.New(tensor.Of(dt), tensor.WithShape(outH*outW, colWidth))
+}
+
+func (c *convolution) do(prealloc value.Value, im, filter *tensor.Dense) (retVal value.Value, err error) {
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+
+	batches := c.inShape[0]
+	channels, outH, outW, colWidth, groupK, groupCols := c.dims()
+	patches := outH * outW
+	imH, imW := c.inShape[2], c.inShape[3]
+	imStride := channels * imH * imW
+	outStride := c.filterShape[0] * patches
+	col := c.col(im.Dtype())
+
+	switch im.Dtype() {
+	case tensor.Float64:
+		imData, filterData, outData, colData := im.Float64s(), filter.Float64s(), out.Float64s(), col.Float64s()
+		for b := 0; b < batches; b++ {
+			c.im2colOp.f64s(channels, imH, imW, imH*imW, imW, outH, outW, imData[b*imStride:(b+1)*imStride], colData)
+			o := outData[b*outStride : (b+1)*outStride]
+			for g := 0; g < c.groups; g++ {
+				whichblas.Dgemm(blas.NoTrans, blas.Trans, groupK, patches, groupCols,
+					1, filterData[g*groupK*groupCols:], groupCols,
+					colData[g*groupCols:], colWidth,
+					0, o[g*groupK*patches:], patches)
+			}
+		}
+	case tensor.Float32:
+		imData, filterData, outData, colData := im.Float32s(), filter.Float32s(), out.Float32s(), col.Float32s()
+		for b := 0; b < batches; b++ {
+			c.im2colOp.f32s(channels, imH, imW, imH*imW, imW, outH, outW, imData[b*imStride:(b+1)*imStride], colData)
+			o := outData[b*outStride : (b+1)*outStride]
+			for g := 0; g < c.groups; g++ {
+				whichblas.Sgemm(blas.NoTrans, blas.Trans, groupK, patches, groupCols,
+					1, filterData[g*groupK*groupCols:], groupCols,
+					colData[g*groupCols:], colWidth,
+					0, o[g*groupK*patches:], patches)
+			}
+		}
+	default:
+		return nil, errors.Errorf(nyiFail, "convolution", im.Dtype())
+	}
+	return out, nil
+}
+
+// convDiffIm is the d(z)/d(im) operation. See also convDiffFilter
+//
+// For each image and group it computes
+//
+//	dcol = gradᵀ · filter
+//
+// and scatters dcol back into the shape of the image with col2im.
+type convDiffIm struct {
+	*convolution
+}
+
+func (c *convDiffIm) Arity() int { return 2 }
+
+func (c *convDiffIm) Type() hm.Type {
+	t := constructor.MakeTensorType(4, hm.TypeVariable('a'))
+	return hm.NewFnType(t, t, t)
+}
+
+func (c *convDiffIm) InferShape(shps ...ops.DimSizer) (tensor.Shape, error) {
+	return c.inShape.Clone(), nil
+}
+
+func (c *convDiffIm) Do(inputs ...value.Value) (value.Value, error) {
+	if err := ops.CheckArity(c, len(inputs)); err != nil {
+		return nil, err
+	}
+	prealloc := tensor.New(tensor.Of(inputs[0].Dtype()), tensor.WithShape(c.inShape.Clone()...))
+	return c.do(prealloc, false, inputs[0], inputs[1])
+}
+
+func (c *convDiffIm) ReturnsPtr() bool     { return false }
+func (c *convDiffIm) CallsExtern() bool    { return false }
+func (c *convDiffIm) OverwritesInput() int { return -1 }
+
+func (c *convDiffIm) WriteHash(h hash.Hash) {
+	fmt.Fprintf(h, "ConvolutionImDiff:%v-%v-%v-%d", c.padding, c.stride, c.dilation, c.groups)
+}
+
+func (c *convDiffIm) Hashcode() uint32 { return simpleHash(c) }
+
+func (c *convDiffIm) String() string {
+	return fmt.Sprintf("ConvolutionImDiff:%v-%v-%v-%d", c.padding, c.stride, c.dilation, c.groups)
+}
+
+func (c *convDiffIm) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (value.Value, error) {
+	if err := ops.CheckArity(c, len(inputs)); err != nil {
+		return nil, err
+	}
+	return c.do(prealloc, false, inputs[0], inputs[1])
+}
+
+// IncrDo adds the gradient of the image to incr.
+func (c *convDiffIm) IncrDo(incr value.Value, inputs ...value.Value) (value.Value, error) {
+	if err := ops.CheckArity(c, len(inputs)); err != nil {
+		return nil, err
+	}
+	return c.do(incr, true, inputs[0], inputs[1])
+}
+
+func (c *convDiffIm) do(prealloc value.Value, incr bool, filterV, gradV value.Value) (retVal value.Value, err error) {
+	var imGrad, filter, grad *tensor.Dense
+	var ok bool
+	if imGrad, ok = prealloc.(*tensor.Dense); !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	if filter, ok = filterV.(*tensor.Dense); !ok {
+		return nil, errors.Errorf("Expected filter to be a *tensor.Dense. Got %T instead", filterV)
+	}
+	if grad, ok = gradV.(*tensor.Dense); !ok {
+		return nil, errors.Errorf("Expected grad to be a *tensor.Dense. Got %T instead", gradV)
+	}
+
+	batches := c.inShape[0]
+	channels, outH, outW, colWidth, groupK, groupCols := c.dims()
+	patches := outH * outW
+	imH, imW := c.inShape[2], c.inShape[3]
+	imStride := channels * imH * imW
+	gradStride := c.filterShape[0] * patches
+	col := c.col(filter.Dtype())
+	c2i := col2imOp{
+		unpaddedB: 1,
+		unpaddedC: channels,
+		unpaddedH: imH,
+		unpaddedW: imW,
+		im2colOp:  c.im2colOp,
+	}
+
+	switch filter.Dtype() {
+	case tensor.Float64:
+		filterData, gradData, imGradData, colData := filter.Float64s(), grad.Float64s(), imGrad.Float64s(), col.Float64s()
+		var tmp []float64
+		if incr {
+			tmp = make([]float64, imStride)
+		}
+		for b := 0; b < batches; b++ {
+			gr := gradData[b*gradStride : (b+1)*gradStride]
+			for g := 0; g < c.groups; g++ {
+				whichblas.Dgemm(blas.Trans, blas.NoTrans, patches, groupCols, groupK,
+					1, gr[g*groupK*patches:], patches,
+					filterData[g*groupK*groupCols:], groupCols,
+					0, colData[g*groupCols:], colWidth)
+			}
+			dst := imGradData[b*imStride : (b+1)*imStride]
+			if !incr {
+				c2i.f64s(channels, imH, imW, imH*imW, imW, outH, outW, colData, dst)
+				continue
+			}
+			c2i.f64s(channels, imH, imW, imH*imW, imW, outH, outW, colData, tmp)
+			for i, v := range tmp {
+				dst[i] += v
+			}
+		}
+	case tensor.Float32:
+		filterData, gradData, imGradData, colData := filter.Float32s(), grad.Float32s(), imGrad.Float32s(), col.Float32s()
+		var tmp []float32
+		if incr {
+			tmp = make([]float32, imStride)
+		}
+		for b := 0; b < batches; b++ {
+			gr := gradData[b*gradStride : (b+1)*gradStride]
+			for g := 0; g < c.groups; g++ {
+				whichblas.Sgemm(blas.Trans, blas.NoTrans, patches, groupCols, groupK,
+					1, gr[g*groupK*patches:], patches,
+					filterData[g*groupK*groupCols:], groupCols,
+					0, colData[g*groupCols:], colWidth)
+			}
+			dst := imGradData[b*imStride : (b+1)*imStride]
+			if !incr {
+				c2i.f32s(channels, imH, imW, imH*imW, imW, outH, outW, colData, dst)
+				continue
+			}
+			c2i.f32s(channels, imH, imW, imH*imW, imW, outH, outW, colData, tmp)
+			for i, v := range tmp {
+				dst[i] += v
+			}
+		}
+	default:
+		return nil, errors.Errorf(nyiFail, "convDiffIm", filter.Dtype())
+	}
+	return imGrad, nil
+}
+
+// convDiffFilter is the d(z)/d(filter) operation. See also convDiffIm
+//
+// The filter gradient of group g is accumulated over the batch:
+//
+//	dfilter[g] = Σ grad[b, g] · im2col(im[b, g])
+type convDiffFilter struct {
+	*convolution // shared struct as convDiffIm
+}
+
+func (c *convDiffFilter) Arity() int { return 2 }
+
+func (c *convDiffFilter) Type() hm.Type {
+	t := constructor.MakeTensorType(4, hm.TypeVariable('a'))
+	return hm.NewFnType(t, t, t)
+}
+
+func (c *convDiffFilter) InferShape(...ops.DimSizer) (tensor.Shape, error) {
+	return c.filterShape.Clone(), nil
+}
+
+func (c *convDiffFilter) Do(inputs ...value.Value) (value.Value, error) {
+	if err := ops.CheckArity(c, len(inputs)); err != nil {
+		return nil, err
+	}
+	prealloc := tensor.New(tensor.Of(inputs[0].Dtype()), tensor.WithShape(c.filterShape.Clone()...))
+	return c.do(prealloc, false, inputs[0], inputs[1])
+}
+
+func (c *convDiffFilter) ReturnsPtr() bool     { return false }
+func (c *convDiffFilter) CallsExtern() bool    { return false }
+func (c *convDiffFilter) OverwritesInput() int { return -1 }
+
+func (c *convDiffFilter) WriteHash(h hash.Hash) {
+	fmt.Fprintf(h, "ConvolutionFilterDiff:%v-%v-%v-%d", c.padding, c.stride, c.dilation, c.groups)
+}
+
+func (c *convDiffFilter) Hashcode() uint32 { return simpleHash(c) }
+
+func (c *convDiffFilter) String() string {
+	return fmt.Sprintf("ConvolutionFilterDiff:%v-%v-%v-%d", c.padding, c.stride, c.dilation, c.groups)
+}
+
+func (c *convDiffFilter) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (value.Value, error) {
+	if err := ops.CheckArity(c, len(inputs)); err != nil {
+		return nil, err
+	}
+	return c.do(prealloc, false, inputs[0], inputs[1])
+}
+
+// IncrDo adds the gradient of the filter to incr.
+func (c *convDiffFilter) IncrDo(incr value.Value, inputs ...value.Value) (value.Value, error) {
+	if err := ops.CheckArity(c, len(inputs)); err != nil {
+		return nil, err
+	}
+	return c.do(incr, true, inputs[0], inputs[1])
+}
+
+func (c *convDiffFilter) do(prealloc value.Value, incr bool, imV, gradV value.Value) (retVal value.Value, err error) {
+	var filterGrad, im, grad *tensor.Dense
+	var ok bool
+	if filterGrad, ok = prealloc.(*tensor.Dense); !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	if im, ok = imV.(*tensor.Dense); !ok {
+		return nil, errors.Errorf("Expected im to be a *tensor.Dense. Got %T instead", imV)
+	}
+	if grad, ok = gradV.(*tensor.Dense); !ok {
+		return nil, errors.Errorf("Expected grad to be a *tensor.Dense. Got %T instead", gradV)
+	}
+	if !incr {
+		filterGrad.Zero()
+	}
+
+	batches := c.inShape[0]
+	channels, outH, outW, colWidth, groupK, groupCols := c.dims()
+	patches := outH * outW
+	imH, imW := c.inShape[2], c.inShape[3]
+	imStride := channels * imH * imW
+	gradStride := c.filterShape[0] * patches
+	col := c.col(im.Dtype())
+
+	switch im.Dtype() {
+	case tensor.Float64:
+		imData, gradData, filterGradData, colData := im.Float64s(), grad.Float64s(), filterGrad.Float64s(), col.Float64s()
+		for b := 0; b < batches; b++ {
+			c.im2colOp.f64s(channels, imH, imW, imH*imW, imW, outH, outW, imData[b*imStride:(b+1)*imStride], colData)
+			gr := gradData[b*gradStride : (b+1)*gradStride]
+			for g := 0; g < c.groups; g++ {
+				whichblas.Dgemm(blas.NoTrans, blas.NoTrans, groupK, groupCols, patches,
+					1, gr[g*groupK*patches:], patches,
+					colData[g*groupCols:], colWidth,
+					1, filterGradData[g*groupK*groupCols:], groupCols)
+			}
+		}
+	case tensor.Float32:
+		imData, gradData, filterGradData, colData := im.Float32s(), grad.Float32s(), filterGrad.Float32s(), col.Float32s()
+		for b := 0; b < batches; b++ {
+			c.im2colOp.f32s(channels, imH, imW, imH*imW, imW, outH, outW, imData[b*imStride:(b+1)*imStride], colData)
+			gr := gradData[b*gradStride : (b+1)*gradStride]
+			for g := 0; g < c.groups; g++ {
+				whichblas.Sgemm(blas.NoTrans, blas.NoTrans, groupK, groupCols, patches,
+					1, gr[g*groupK*patches:], patches,
+					colData[g*groupCols:], colWidth,
+					1, filterGradData[g*groupK*groupCols:], groupCols)
+			}
+		}
+	default:
+		return nil, errors.Errorf(nyiFail, "convDiffFilter", im.Dtype())
+	}
+	return filterGrad, nil
+}
@@ -30,7 +30,10 @@ type convolution struct {
 	wDesc        *cudnn.Filter
 }
 
-func makeConvolutionOp(im, filter *G.Node, kernelShape tensor.Shape, pad, stride, dilation []int) (retVal *convolution, err error) {
+func makeConvolutionOp(im, filter *G.Node, kernelShape tensor.Shape, pad, stride, dilation []int, groups int) (retVal *convolution, err error) {
+	if err = CheckConvolutionParams(pad, stride, dilation); err != nil {
+		return nil, err
+	}
 	var xDesc *cudnn.TensorDescriptor
 	var wDesc *cudnn.Filter
 	if xDesc, err = t2cudnn.Describe(im); err != nil {
@@ -40,7 +43,7 @@ func makeConvolutionOp(im, filter *G.Node, kernelShape tensor.Shape, pad, stride
 		return nil, err
 	}
 	datatype := t2cudnn.Dtype2DataType(im.Dtype())
-	conv, err := cudnn.NewConvolution(cudnn.DefaultMath, 1, pad, stride, dilation, cudnn.StandardConvolution, datatype)
+	conv, err := cudnn.NewConvolution(cudnn.DefaultMath, groups, pad, stride, dilation, cudnn.StandardConvolution, datatype)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,152 @@
+// +build !cuda
+
+package nnops
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gorgonia.org/tensor"
+)
+
+var convTests = []struct {
+	name                  string
+	b, c, h, w, k         int
+	kh, kw                int
+	pad, stride, dilation []int
+	groups                int
+}{
+	{"3x3 same", 2, 3, 5, 6, 4, 3, 3, []int{1, 1}, []int{1, 1}, []int{1, 1}, 1},
+	{"strided dilated", 2, 4, 7, 6, 6, 3, 2, []int{2, 0}, []int{2, 1}, []int{2, 1}, 2},
+	{"conv1d depthwise", 1, 4, 1, 9, 8, 1, 3, []int{0, 1}, []int{1, 2}, []int{1, 2}, 4},
+}
+
+func makeTestConv(b, c, h, w, k, kh, kw int, pad, stride, dilation []int, groups int) *convolution {
+	return &convolution{
+		im2colOp: makeIm2ColOp(kh, kw, pad[0], pad[1], stride[0], stride[1], dilation[0], dilation[1]),
+		groups:   groups,
+
+		padding:  pad,
+		stride:   stride,
+		dilation: dilation,
+
+		inShape:     tensor.Shape{b, c, h, w},
+		filterShape: tensor.Shape{k, c / groups, kh, kw},
+	}
+}
+
+// naiveConv computes the convolution directly from its definition.
+func naiveConv(op *convolution, im, filter []float64) []float64 {
+	b, c, h, w := op.inShape[0], op.inShape[1], op.inShape[2], op.inShape[3]
+	k := op.filterShape[0]
+	outH, outW := op.retHW(h, w)
+	cg, kg := c/op.groups, k/op.groups
+
+	out := make([]float64, b*k*outH*outW)
+	for n := 0; n < b; n++ {
+		for kk := 0; kk < k; kk++ {
+			g := kk / kg
+			for r := 0; r < outH; r++ {
+				for q := 0; q < outW; q++ {
+					var sum float64
+					for ci := 0; ci < cg; ci++ {
+						ch := g*cg + ci
+						for kr := 0; kr < op.h; kr++ {
+							for kc := 0; kc < op.w; kc++ {
+								ir := r*op.strideH - op.padH + kr*op.dilationH
+								ic := q*op.strideW - op.padW + kc*op.dilationW
+								if ir < 0 || ir >= h || ic < 0 || ic >= w {
+									continue
+								}
+								sum += im[((n*c+ch)*h+ir)*w+ic] * filter[((kk*cg+ci)*op.h+kr)*op.w+kc]
+							}
+						}
+					}
+					out[((n*k+kk)*outH+r)*outW+q] = sum
+				}
+			}
+		}
+	}
+	return out
+}
+
+func randFloat64s(r *rand.Rand, n int) []float64 {
+	retVal := make([]float64, n)
+	for i := range retVal {
+		retVal[i] = r.NormFloat64()
+	}
+	return retVal
+}
+
+func dotF64s(a, b []float64) (retVal float64) {
+	for i := range a {
+		retVal += a[i] * b[i]
+	}
+	return
+}
+
+func TestConvolution_F64(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	for _, ct := range convTests {
+		op := makeTestConv(ct.b, ct.c, ct.h, ct.w, ct.k, ct.kh, ct.kw, ct.pad, ct.stride, ct.dilation, ct.groups)
+		imData := randFloat64s(r, op.inShape.TotalSize())
+		filterData := randFloat64s(r, op.filterShape.TotalSize())
+		im := tensor.New(tensor.WithShape(op.inShape.Clone()...), tensor.WithBacking(imData))
+		filter := tensor.New(tensor.WithShape(op.filterShape.Clone()...), tensor.WithBacking(filterData))
+
+		out, err := op.Do(im, filter)
+		if err != nil {
+			t.Errorf("%v: %v", ct.name, err)
+			continue
+		}
+		if !out.Shape().Eq(op.outShape()) {
+			t.Errorf("%v: expected output shape %v. Got %v", ct.name, op.outShape(), out.Shape())
+			continue
+		}
+		correct := naiveConv(op, imData, filterData)
+		for i, v := range out.Data().([]float64) {
+			if math.Abs(v-correct[i]) > 1e-10 {
+				t.Errorf("%v: output %d - expected %v. Got %v", ct.name, i, correct[i], v)
+				break
+			}
+		}
+
+		// gradients are checked against central differences of L = Σ out ⊙ grad
+		gradData := randFloat64s(r, len(correct))
+		grad := tensor.New(tensor.WithShape(out.Shape().Clone()...), tensor.WithBacking(gradData))
+		dIm, err := (&convDiffIm{op}).Do(filter, grad)
+		if err != nil {
+			t.Errorf("%v: %v", ct.name, err)
+			continue
+		}
+		dFilter, err := (&convDiffFilter{op}).Do(im, grad)
+		if err != nil {
+			t.Errorf("%v: %v", ct.name, err)
+			continue
+		}
+
+		const eps = 1e-6
+		numGrad := func(xs []float64, i int) float64 {
+			orig := xs[i]
+			xs[i] = orig + eps
+			lp := dotF64s(naiveConv(op, imData, filterData), gradData)
+			xs[i] = orig - eps
+			lm := dotF64s(naiveConv(op, imData, filterData), gradData)
+			xs[i] = orig
+			return (lp - lm) / (2 * eps)
+		}
+		for i, v := range dIm.Data().([]float64) {
+			if ng := numGrad(imData, i); math.Abs(ng-v) > 1e-6 {
+				t.Errorf("%v: dIm[%d] - expected %v. Got %v", ct.name, i, ng, v)
+				break
+			}
+		}
+		for i, v := range dFilter.Data().([]float64) {
+			if ng := numGrad(filterData, i); math.Abs(ng-v) > 1e-6 {
+				t.Errorf("%v: dFilter[%d] - expected %v. Got %v", ct.name, i, ng, v)
+				break
+			}
+		}
+	}
+}
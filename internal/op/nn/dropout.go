@@ -0,0 +1,397 @@
+package nnops
+
+import (
+	"fmt"
+	"hash"
+	"math"
+	"math/rand"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op         = &DropoutOp{}
+	_ ops.Op         = &dropoutDiffOp{}
+	_ ops.StatefulOp = &DropoutOp{}
+)
+
+// dropoutMode is the kind of dropout a DropoutOp performs
+type dropoutMode byte
+
+const (
+	// standardDropout zeroes each element with the given probability and scales the kept elements by 1/(1-p).
+	standardDropout dropoutMode = iota
+	// alphaDropout is the dropout for SELU networks, as described by Klambauer et al. (2017) - https://arxiv.org/abs/1706.02515.
+	// Dropped elements are set to the negative saturation value of SELU, and the result is scaled and shifted
+	// so that the mean and variance of the input are kept.
+	alphaDropout
+	// spatialDropout drops entire channels of a (N, C, ...) input, as described by Tompson et al. (2015) - https://arxiv.org/abs/1411.4280
+	spatialDropout
+)
+
+func (m dropoutMode) String() string {
+	switch m {
+	case standardDropout:
+		return "Dropout"
+	case alphaDropout:
+		return "AlphaDropout"
+	case spatialDropout:
+		return "SpatialDropout"
+	}
+	return fmt.Sprintf("dropoutMode(%d)", byte(m))
+}
+
+// alphaPrime is -λα of SELU, the value that SELU saturates to.
+const alphaPrime = -1.0507009873554804934193349852946 * 1.6732632423543772848170429916717
+
+// DropoutOp is a CPU implementation of dropout using inverted scaling: in training, kept elements are scaled by 1/(1-p),
+// so that when testing, the op is an identity.
+//
+// The mask is generated from a seedable random source, so that two DropoutOps with the same seed
+// drop the same elements given the same sequence of inputs.
+type DropoutOp struct {
+	prob float64
+	mode dropoutMode
+	seed int64
+
+	// execution state
+	rand *rand.Rand
+	// mask holds the factor each element of the input was multiplied by in the last training pass.
+	// It is 0 for the dropped elements.
+	mask *tensor.Dense
+
+	// training? if not training, the op is an identity
+	training bool
+}
+
+func newDropoutOp(prob float64, mode dropoutMode, seed int64) (*DropoutOp, error) {
+	if prob < 0 || prob >= 1 {
+		return nil, errors.Errorf("Dropout probability has to be in [0, 1). Got %v", prob)
+	}
+	if mode > spatialDropout {
+		return nil, errors.Errorf("Unknown dropout mode %v", mode)
+	}
+	return &DropoutOp{
+		prob:     prob,
+		mode:     mode,
+		seed:     seed,
+		rand:     rand.New(rand.NewSource(seed)),
+		training: true,
+	}, nil
+}
+
+// Arity ...
+func (op *DropoutOp) Arity() int { return 1 }
+
+// Type ...
+func (op *DropoutOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	return hm.NewFnType(a, a)
+}
+
+// InferShape ...
+func (op *DropoutOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "dropout")
+	}
+	s := ns[0].(tensor.Shape)
+	if op.mode == spatialDropout && s.Dims() < 3 {
+		return nil, errors.Errorf("SpatialDropout expects an input with at least 3 dimensions. Got %v", s)
+	}
+	return s.Clone(), nil
+}
+
+// Do ...
+func (op *DropoutOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "dropout Do")
+	}
+	var out value.Value
+	if out, err = value.CloneValue(values[0]); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values[0])
+}
+
+// ReturnsPtr ...
+func (op *DropoutOp) ReturnsPtr() bool { return true }
+
+// CallsExtern ...
+func (op *DropoutOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *DropoutOp) OverwritesInput() int { return -1 }
+
+// IsStateful returns true, as every training pass draws another mask. A dropout is never recomputed.
+func (op *DropoutOp) IsStateful() bool { return true }
+
+// WriteHash ...
+func (op *DropoutOp) WriteHash(h hash.Hash) { fmt.Fprintf(h, "%v-%v-%d", op.mode, op.prob, op.seed) }
+
+// Hashcode ...
+func (op *DropoutOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *DropoutOp) String() string { return fmt.Sprintf("%v(%v)", op.mode, op.prob) }
+
+// DoDiff ...
+func (op *DropoutOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) error {
+	diff := &dropoutDiffOp{op}
+	xdv, ydv := getDV(inputs[0], output)
+	_, err := diff.UsePreallocDo(xdv.D, ydv.D)
+	return err
+}
+
+// DiffWRT ...
+func (op *DropoutOp) DiffWRT(inputs int) []bool { return []bool{true} }
+
+// SymDiff ...
+func (op *DropoutOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	diff := &dropoutDiffOp{op}
+
+	var ret *Node
+	if ret, err = ApplyOp(diff, grad); err != nil {
+		return nil, err
+	}
+	return Nodes{ret}, nil
+}
+
+// UsePreallocDo ...
+func (op *DropoutOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "dropout UsePreallocDo")
+	}
+	x, ok := inputs[0].(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected input to be a *tensor.Dense. Got %T instead", inputs[0])
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+
+	if !op.training || op.prob == 0 {
+		if err = tensor.Copy(out, x); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+
+	if err = op.fillMask(x.Shape(), x.Dtype()); err != nil {
+		return nil, err
+	}
+	a, b := op.alphaParams()
+	switch x.Dtype() {
+	case Float64:
+		dropoutF64s(out.Float64s(), x.Float64s(), op.mask.Float64s(), op.mode == alphaDropout, a*alphaPrime+b, b)
+	case Float32:
+		dropoutF32s(out.Float32s(), x.Float32s(), op.mask.Float32s(), op.mode == alphaDropout, float32(a*alphaPrime+b), float32(b))
+	default:
+		return nil, nyi("Dropout Do", x.Dtype())
+	}
+	return out, nil
+}
+
+// SetTraining sets the op to training mode, where elements are dropped.
+func (op *DropoutOp) SetTraining() { op.training = true }
+
+// SetTesting sets the op to testing mode. In testing mode the op is an identity.
+func (op *DropoutOp) SetTesting() { op.training = false }
+
+// Reset reseeds the random source with the seed the op was created with, and clears the mask.
+func (op *DropoutOp) Reset() error {
+	op.Reseed(op.seed)
+	return nil
+}
+
+// Reseed restarts the random source of the op with the given seed.
+func (op *DropoutOp) Reseed(seed int64) {
+	op.seed = seed
+	op.rand = rand.New(rand.NewSource(seed))
+	op.mask = nil
+}
+
+// Mask returns the factors each element of the input was multiplied by in the last training pass. Dropped elements have a factor of 0.
+// It returns nil if the op has not been executed in training mode.
+func (op *DropoutOp) Mask() tensor.Tensor {
+	if op.mask == nil {
+		return nil
+	}
+	return op.mask
+}
+
+// Prob returns the probability of an element being dropped.
+func (op *DropoutOp) Prob() float64 { return op.prob }
+
+// alphaParams returns the affine transform applied after alpha dropout. For the other modes, it's the identity.
+func (op *DropoutOp) alphaParams() (a, b float64) {
+	if op.mode != alphaDropout {
+		return 1, 0
+	}
+	p := op.prob
+	a = 1 / math.Sqrt((1-p)*(1+p*alphaPrime*alphaPrime))
+	b = -a * alphaPrime * p
+	return a, b
+}
+
+// fillMask draws a new mask for an input of the given shape and dtype.
+func (op *DropoutOp) fillMask(s tensor.Shape, dt tensor.Dtype) error {
+	if op.mask == nil || !op.mask.Shape().Eq(s) || op.mask.Dtype() != dt {
+		op.mask = tensor.New(tensor.Of(dt), tensor.WithShape(s.Clone()...))
+	}
+
+	// each decision covers a block of the input. For spatial dropout a block is a channel.
+	block := 1
+	if op.mode == spatialDropout {
+		if s.Dims() < 3 {
+			return errors.Errorf("SpatialDropout expects an input with at least 3 dimensions. Got %v", s)
+		}
+		block = s[2:].TotalSize()
+	}
+
+	scale, _ := op.alphaParams()
+	if op.mode != alphaDropout {
+		scale = 1 / (1 - op.prob)
+	}
+
+	switch dt {
+	case Float64:
+		data := op.mask.Float64s()
+		for i := 0; i < len(data); i += block {
+			var m float64
+			if op.rand.Float64() >= op.prob {
+				m = scale
+			}
+			for j := i; j < i+block; j++ {
+				data[j] = m
+			}
+		}
+	case Float32:
+		data := op.mask.Float32s()
+		for i := 0; i < len(data); i += block {
+			var m float32
+			if op.rand.Float64() >= op.prob {
+				m = float32(scale)
+			}
+			for j := i; j < i+block; j++ {
+				data[j] = m
+			}
+		}
+	default:
+		return nyi("Dropout mask", dt)
+	}
+	return nil
+}
+
+// dropoutF64s computes out = mask ⊙ x. For alpha dropout, dropped elements are set to dropped, and kept elements are shifted by shift.
+func dropoutF64s(out, x, mask []float64, alpha bool, dropped, shift float64) {
+	for i, m := range mask {
+		switch {
+		case !alpha:
+			out[i] = m * x[i]
+		case m == 0:
+			out[i] = dropped
+		default:
+			out[i] = m*x[i] + shift
+		}
+	}
+}
+
+// dropoutF32s computes out = mask ⊙ x. For alpha dropout, dropped elements are set to dropped, and kept elements are shifted by shift.
+func dropoutF32s(out, x, mask []float32, alpha bool, dropped, shift float32) {
+	for i, m := range mask {
+		switch {
+		case !alpha:
+			out[i] = m * x[i]
+		case m == 0:
+			out[i] = dropped
+		default:
+			out[i] = m*x[i] + shift
+		}
+	}
+}
+
+// dropoutDiffOp is the gradient of a DropoutOp: the output gradient multiplied by the mask of the last training pass.
+type dropoutDiffOp struct{ *DropoutOp }
+
+// Arity ...
+func (op *dropoutDiffOp) Arity() int { return 1 }
+
+// Type ...
+func (op *dropoutDiffOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	return hm.NewFnType(a, a)
+}
+
+// InferShape ...
+func (op *dropoutDiffOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "dropoutDiff")
+	}
+	return ns[0].(tensor.Shape).Clone(), nil
+}
+
+// Do ...
+func (op *dropoutDiffOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "dropoutDiff Do")
+	}
+	var out value.Value
+	if out, err = value.CloneValue(values[0]); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values[0])
+}
+
+// WriteHash ...
+func (op *dropoutDiffOp) WriteHash(h hash.Hash) {
+	fmt.Fprintf(h, "%v-%v-%d-diff", op.mode, op.prob, op.seed)
+}
+
+// Hashcode ...
+func (op *dropoutDiffOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *dropoutDiffOp) String() string { return fmt.Sprintf("%vDiff(%v)", op.mode, op.prob) }
+
+// UsePreallocDo ...
+func (op *dropoutDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "dropoutDiff UsePreallocDo")
+	}
+	grad, ok := inputs[0].(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected grad to be a *tensor.Dense. Got %T instead", inputs[0])
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+
+	if !op.training || op.prob == 0 {
+		if err = tensor.Copy(out, grad); err != nil {
+			return nil, err
+		}
+		return out, nil
+	}
+	if op.mask == nil {
+		return nil, errors.Errorf("%v has not been executed. There is no mask to differentiate with", op.DropoutOp)
+	}
+
+	// the gradient is just the mask (the alpha dropout shift is a constant)
+	switch grad.Dtype() {
+	case Float64:
+		dropoutF64s(out.Float64s(), grad.Float64s(), op.mask.Float64s(), false, 0, 0)
+	case Float32:
+		dropoutF32s(out.Float32s(), grad.Float32s(), op.mask.Float32s(), false, 0, 0)
+	default:
+		return nil, nyi("DropoutDiff Do", grad.Dtype())
+	}
+	return out, nil
+}
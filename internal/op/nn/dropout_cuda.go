@@ -5,7 +5,6 @@ package nnops
 import (
 	"fmt"
 	"hash"
-	"time"
 	"unsafe"
 
 	"github.com/chewxy/hm"
@@ -23,7 +22,7 @@ type dropout struct {
 	xDesc *cudnn.TensorDescriptor
 }
 
-func newDropout(x *gorgonia.Node, prob float64) (*dropout, error) {
+func newDropout(x *gorgonia.Node, prob float64, seed uint64) (*dropout, error) {
 	xDesc, err := t2cudnn.Describe(x)
 	if err != nil {
 		return nil, err
@@ -36,7 +35,7 @@ func newDropout(x *gorgonia.Node, prob float64) (*dropout, error) {
 	return &dropout{
 		Dropout: internal,
 		xDesc:   xDesc,
-		seed:    uint64(time.Now().UnixNano()),
+		seed:    seed,
 	}, nil
 }
 
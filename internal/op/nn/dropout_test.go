@@ -0,0 +1,171 @@
+package nnops
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/tensor"
+)
+
+func TestDropoutOp(t *testing.T) {
+	x := tensor.New(tensor.WithShape(2, 3, 4, 5), tensor.WithBacking(tensor.Random(tensor.Float64, 120)))
+
+	op, err := newDropoutOp(0.5, standardDropout, 1337)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := op.Do(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask := op.Mask().Data().([]float64)
+	for i, v := range out.Data().([]float64) {
+		if mask[i] != 0 && mask[i] != 2 {
+			t.Fatalf("Expected the mask to be 0 or 1/(1-p). Got %v", mask[i])
+		}
+		if v != mask[i]*x.Float64s()[i] {
+			t.Fatalf("Expected %d to be %v. Got %v", i, mask[i]*x.Float64s()[i], v)
+		}
+	}
+
+	// same seed, same mask
+	op2, _ := newDropoutOp(0.5, standardDropout, 1337)
+	out2, err := op2.Do(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Shape().Eq(out2.Shape()) {
+		t.Fatalf("Expected the same shape")
+	}
+	for i, v := range out2.Data().([]float64) {
+		if v != out.Data().([]float64)[i] {
+			t.Fatalf("Expected two DropoutOps with the same seed to drop the same elements")
+		}
+	}
+
+	// the gradient is the mask
+	grad := tensor.New(tensor.WithShape(2, 3, 4, 5), tensor.WithBacking(tensor.Range(tensor.Float64, 0, 120)))
+	dx, err := (&dropoutDiffOp{op}).Do(grad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range dx.Data().([]float64) {
+		if v != mask[i]*float64(i) {
+			t.Fatalf("Expected gradient %d to be %v. Got %v", i, mask[i]*float64(i), v)
+		}
+	}
+
+	// testing is an identity
+	op.SetTesting()
+	if out, err = op.Do(x); err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range out.Data().([]float64) {
+		if v != x.Float64s()[i] {
+			t.Fatalf("Expected dropout to be an identity when testing")
+		}
+	}
+}
+
+func TestSpatialDropoutOp(t *testing.T) {
+	x := tensor.New(tensor.WithShape(2, 8, 3, 3), tensor.WithBacking(tensor.Range(tensor.Float32, 1, 145)))
+	op, err := newDropoutOp(0.5, spatialDropout, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = op.Do(x); err != nil {
+		t.Fatal(err)
+	}
+	mask := op.Mask().Data().([]float32)
+	for c := 0; c < 16; c++ {
+		for _, m := range mask[c*9 : (c+1)*9] {
+			if m != mask[c*9] {
+				t.Fatalf("Expected channel %d to be dropped or kept as a whole. Got %v", c, mask[c*9:(c+1)*9])
+			}
+		}
+	}
+}
+
+func TestAlphaDropoutOp(t *testing.T) {
+	// alpha dropout keeps the zero mean and unit variance of the outputs of a SELU network
+	const n = 100000
+	x := tensor.New(tensor.WithShape(n), tensor.WithBacking(randFloat64s(rand.New(rand.NewSource(1337)), n)))
+	for _, p := range []float64{0.1, 0.2, 0.5} {
+		op, err := newDropoutOp(p, alphaDropout, 42)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := op.Do(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var mean, variance float64
+		for _, v := range out.Data().([]float64) {
+			mean += v
+		}
+		mean /= n
+		for _, v := range out.Data().([]float64) {
+			variance += (v - mean) * (v - mean)
+		}
+		variance /= n
+		if math.Abs(mean) > 0.02 || math.Abs(variance-1) > 0.03 {
+			t.Errorf("p %v: expected a mean of 0 and a variance of 1. Got %v and %v", p, mean, variance)
+		}
+
+		var dropped int
+		for _, m := range op.Mask().Data().([]float64) {
+			if m == 0 {
+				dropped++
+			}
+		}
+		if frac := float64(dropped) / n; math.Abs(frac-p) > 0.01 {
+			t.Errorf("p %v: expected a fraction %v of the elements to be dropped. Got %v", p, p, frac)
+		}
+	}
+}
+
+func TestDropoutOp_Rematerialize(t *testing.T) {
+	g := exprgraph.NewGraph()
+	node := func(name string, inputs ...*exprgraph.Node) *exprgraph.Node {
+		n := g.NewVertex()
+		n.Name = name
+		g.AddNode(n)
+		for i, in := range inputs {
+			g.SetWeightedEdge(g.NewWeightedEdge(n, in, float64(i)))
+		}
+		return n
+	}
+	op, err := newDropoutOp(0.5, standardDropout, 1337)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a dropout between the checkpoints h1 and h4
+	x := node("x")
+	h1 := node("h1", x)
+	h1.Checkpoint = true
+	h2 := node("h2", h1)
+	drop := node("dropout", h2)
+	drop.Op = op
+	h3 := node("h3", drop)
+	h4 := node("h4", h3)
+	h4.Checkpoint = true
+	loss := node("loss", h4)
+	dh3 := node("dh3", loss, h3)
+	ddrop := node("ddropout", dh3, drop)
+
+	recomputed := g.Rematerialize(loss)
+	for _, n := range recomputed {
+		if n.Op == op {
+			t.Fatal("Expected the dropout not to be recomputed, as it would draw another mask")
+		}
+	}
+	if g.InputsOf(ddrop)[1] != drop {
+		t.Error("Expected the gradient of the dropout to take the output of the dropout")
+	}
+	if c3 := g.InputsOf(dh3)[1]; c3 == h3 || g.InputsOf(c3)[0] != drop {
+		t.Error("Expected h3 to be recomputed from the output of the dropout")
+	}
+}
@@ -0,0 +1,294 @@
+package nnops
+
+import (
+	"fmt"
+	"hash"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/constructor"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &embeddingOp{}
+	_ ops.Op = &embeddingDiffOp{}
+)
+
+// embeddingOp looks up rows of a (V, D) weight matrix. Its inputs are the weight and a tensor of integer indices,
+// and it returns a tensor of shape (indices..., D) - each index is replaced by the row it points to.
+//
+// The gradient with regards to the weight is a *value.SparseRows, which only holds the rows that were looked up.
+type embeddingOp struct {
+	dims int // dims of the indices
+}
+
+func newEmbeddingOp(dims int) (*embeddingOp, error) {
+	if dims < 1 {
+		return nil, errors.Errorf("Embedding expects a tensor of indices. Got indices with %d dimensions", dims)
+	}
+	return &embeddingOp{dims: dims}, nil
+}
+
+// Arity ...
+func (op *embeddingOp) Arity() int { return 2 }
+
+// embeddingOp has this type:
+//		op :: Matrix a → Tensor-d b → Tensor-(d+1) a
+func (op *embeddingOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	w := constructor.NewTensorType(2, a)
+	idx := constructor.NewTensorType(op.dims, hm.TypeVariable('b'))
+	return hm.NewFnType(w, idx, constructor.NewTensorType(op.dims+1, a))
+}
+
+// InferShape ...
+func (op *embeddingOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "embedding")
+	}
+	w, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	idx, ok := ns[1].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	if err := op.checkShapes(w, idx); err != nil {
+		return nil, err
+	}
+	return op.retShape(w, idx), nil
+}
+
+// Do ...
+func (op *embeddingOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	w, idx, err := op.checkInput(values...)
+	if err != nil {
+		return nil, err
+	}
+	out := tensor.New(tensor.Of(w.Dtype()), tensor.WithShape(op.retShape(w.Shape(), idx.Shape())...))
+	return op.UsePreallocDo(out, w, idx)
+}
+
+// ReturnsPtr ...
+func (op *embeddingOp) ReturnsPtr() bool { return true }
+
+// CallsExtern ...
+func (op *embeddingOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *embeddingOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *embeddingOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *embeddingOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *embeddingOp) String() string { return fmt.Sprintf("Embedding{%d}", op.dims) }
+
+// DiffWRT ...
+func (op *embeddingOp) DiffWRT(inputs int) []bool { return []bool{true, false} }
+
+// SymDiff ...
+func (op *embeddingOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	diff := &embeddingDiffOp{op}
+
+	var ret *Node
+	if ret, err = ApplyOp(diff, inputs[0], inputs[1], grad); err != nil {
+		return nil, err
+	}
+	return Nodes{ret, nil}, nil
+}
+
+// DoDiff adds the gradient to the derivative of the weight. If the derivative is a *value.SparseRows (or nil),
+// the gradient is kept sparse. If it is dense, the rows are added to it.
+func (op *embeddingOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	wdv, ydv := getDV(inputs[0], output)
+
+	diff := &embeddingDiffOp{op}
+	var grad value.Value
+	if grad, err = diff.Do(wdv.Value, inputs[1].Value(), ydv.D); err != nil {
+		return errors.Wrapf(err, doFail, diff)
+	}
+	sparse := grad.(*value.SparseRows)
+	switch d := wdv.D.(type) {
+	case nil:
+		wdv.D = sparse
+		return nil
+	case *value.SparseRows:
+		return d.Append(sparse)
+	case *tensor.Dense:
+		return sparse.ScatterAdd(d)
+	}
+	return errors.Errorf("Cannot add the gradient of %v to a derivative of type %T", op, wdv.D)
+}
+
+// UsePreallocDo ...
+func (op *embeddingOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	w, idx, err := op.checkInput(inputs...)
+	if err != nil {
+		return nil, err
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	var indices []int
+	if indices, err = op.indicesOf(idx, w.Shape()[0]); err != nil {
+		return nil, err
+	}
+
+	size := w.Shape()[1]
+	switch w.Dtype() {
+	case Float64:
+		src, dst := w.Float64s(), out.Float64s()
+		for i, row := range indices {
+			copy(dst[i*size:(i+1)*size], src[row*size:(row+1)*size])
+		}
+	case Float32:
+		src, dst := w.Float32s(), out.Float32s()
+		for i, row := range indices {
+			copy(dst[i*size:(i+1)*size], src[row*size:(row+1)*size])
+		}
+	default:
+		return nil, nyi("Embedding Do", w.Dtype())
+	}
+	return out, nil
+}
+
+func (op *embeddingOp) checkShapes(w, idx tensor.Shape) error {
+	if w.Dims() != 2 {
+		return errors.Errorf("%v expects a (V, D) weight matrix. Got %v", op, w)
+	}
+	if idx.Dims() != op.dims {
+		return errors.Errorf("%v expects indices with %d dimensions. Got %v", op, op.dims, idx)
+	}
+	return nil
+}
+
+func (op *embeddingOp) retShape(w, idx tensor.Shape) tensor.Shape {
+	return append(idx.Clone(), w[1])
+}
+
+func (op *embeddingOp) checkInput(inputs ...value.Value) (w *tensor.Dense, idx tensor.Tensor, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	var ok bool
+	if w, ok = inputs[0].(*tensor.Dense); !ok {
+		err = errors.Errorf("Expected weight to be a *tensor.Dense. Got %T instead", inputs[0])
+		return
+	}
+	if idx, ok = inputs[1].(tensor.Tensor); !ok {
+		err = errors.Errorf("Expected indices to be a tensor. Got %T instead", inputs[1])
+		return
+	}
+	err = op.checkShapes(w.Shape(), idx.Shape())
+	return
+}
+
+// indicesOf returns the indices as []int, checking that they are all valid rows.
+func (op *embeddingOp) indicesOf(idx tensor.Tensor, rows int) ([]int, error) {
+	indices, err := labelsOf(idx)
+	if err != nil {
+		return nil, err
+	}
+	for _, i := range indices {
+		if i < 0 || i >= rows {
+			return nil, errors.Errorf("%v: index %d is out of bounds for a weight with %d rows", op, i, rows)
+		}
+	}
+	return indices, nil
+}
+
+// embeddingDiffOp computes the gradient of an embeddingOp with regards to the weight.
+// Its inputs are the weight, the indices and the gradient of the output, and it returns a *value.SparseRows:
+// the gradient of the ith index is row i of the gradient of the output.
+type embeddingDiffOp struct{ *embeddingOp }
+
+// Arity ...
+func (op *embeddingDiffOp) Arity() int { return 3 }
+
+// Type ...
+func (op *embeddingDiffOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	w := constructor.NewTensorType(2, a)
+	idx := constructor.NewTensorType(op.dims, hm.TypeVariable('b'))
+	return hm.NewFnType(w, idx, constructor.NewTensorType(op.dims+1, a), w)
+}
+
+// InferShape ...
+func (op *embeddingDiffOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "embeddingDiff")
+	}
+	return ns[0].(tensor.Shape).Clone(), nil
+}
+
+// Do ...
+func (op *embeddingDiffOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "embeddingDiff Do")
+	}
+	w, idx, err := op.checkInput(values[:2]...)
+	if err != nil {
+		return nil, err
+	}
+	grad, ok := values[2].(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected grad to be a *tensor.Dense. Got %T instead", values[2])
+	}
+	var indices []int
+	if indices, err = op.indicesOf(idx, w.Shape()[0]); err != nil {
+		return nil, err
+	}
+	size := w.Shape()[1]
+	if grad.Shape().TotalSize() != len(indices)*size {
+		return nil, errors.Errorf("Expected the gradient to have the shape %v. Got %v", op.retShape(w.Shape(), idx.Shape()), grad.Shape())
+	}
+
+	rows := grad.Clone().(*tensor.Dense)
+	if err = rows.Reshape(len(indices), size); err != nil {
+		return nil, err
+	}
+	return value.NewSparseRows(w.Shape(), append([]int(nil), indices...), rows)
+}
+
+// WriteHash ...
+func (op *embeddingDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *embeddingDiffOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *embeddingDiffOp) String() string { return op.embeddingOp.String() + "Diff" }
+
+// UsePreallocDo writes the gradient into prealloc. If prealloc is a *tensor.Dense, it is zeroed and the rows are added to it.
+func (op *embeddingDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if retVal, err = op.Do(inputs...); err != nil {
+		return nil, err
+	}
+	sparse := retVal.(*value.SparseRows)
+	switch p := prealloc.(type) {
+	case *value.SparseRows:
+		*p = *sparse
+		return p, nil
+	case *tensor.Dense:
+		p.Zero()
+		if err = sparse.ScatterAdd(p); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	return nil, errors.Errorf("Expected prealloc to be a *value.SparseRows or a *tensor.Dense. Got %T instead", prealloc)
+}
@@ -0,0 +1,60 @@
+package nnops
+
+import (
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+func TestEmbeddingOp(t *testing.T) {
+	w := tensor.New(tensor.WithShape(4, 2), tensor.WithBacking([]float32{0, 1, 10, 11, 20, 21, 30, 31}))
+	idx := tensor.New(tensor.WithShape(2, 2), tensor.WithBacking([]int64{3, 0, 3, 2}))
+
+	op, err := newEmbeddingOp(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := op.Do(w, idx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Shape().Eq(tensor.Shape{2, 2, 2}) {
+		t.Errorf("Expected shape (2, 2, 2). Got %v", out.Shape())
+	}
+	correct := []float32{30, 31, 0, 1, 30, 31, 20, 21}
+	for i, v := range out.Data().([]float32) {
+		if v != correct[i] {
+			t.Errorf("Expected %v. Got %v", correct, out.Data())
+			break
+		}
+	}
+
+	// the gradient only holds the rows that were looked up
+	grad := tensor.New(tensor.WithShape(2, 2, 2), tensor.WithBacking([]float32{1, 1, 2, 2, 3, 3, 4, 4}))
+	diff := &embeddingDiffOp{op}
+	d, err := diff.Do(w, idx, grad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sparse, ok := d.(*value.SparseRows)
+	if !ok {
+		t.Fatalf("Expected a *value.SparseRows. Got %T", d)
+	}
+	if len(sparse.Indices) != 4 || !sparse.Shape().Eq(w.Shape()) {
+		t.Errorf("Expected 4 rows of a (4, 2) gradient. Got %v", sparse)
+	}
+
+	// a dense prealloc gets the rows scattered into it
+	dense := tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(4, 2))
+	if _, err = diff.UsePreallocDo(dense, w, idx, grad); err != nil {
+		t.Fatal(err)
+	}
+	correct = []float32{2, 2, 0, 0, 4, 4, 4, 4}
+	for i, v := range dense.Float32s() {
+		if v != correct[i] {
+			t.Errorf("Expected %v. Got %v", correct, dense.Float32s())
+			break
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package nnops
+
+import (
+	"fmt"
+	"hash"
+	"math"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/constructor"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &fakeQuantOp{}
+	_ ops.Op = &fakeQuantDiffOp{}
+)
+
+// fakeQuantOp simulates the int8 quantization of a float tensor for quantization-aware training: x is quantized and dequantized right away,
+//		y = (clamp(round(x/s) + z, -128, 127) - z)·s
+// so the model learns to cope with the rounding, while staying in floats.
+//
+// The rounding has no useful gradient, so the gradient of x is estimated with a straight-through estimator:
+// the gradient of y goes through where x is in the range of int8, and is 0 where x was clamped.
+//
+// The scales are either fixed by the params, or learnable, in which case they are the second input of the op (one per channel),
+// and their gradient is that of LSQ, as described by Esser et al. (2019) - https://arxiv.org/abs/1902.08153
+// As in the paper, the gradient of a scale is multiplied by 1/√(N·Qp), where N is the number of elements the scale applies to
+// and Qp = 127 is the largest level of int8, so that the scales learn at the same pace as the weights.
+// The zero points are always fixed by the params.
+type fakeQuantOp struct {
+	p         QuantParams
+	dims      int
+	learnable bool
+}
+
+func newFakeQuantOp(p QuantParams, dims int, learnable bool) (*fakeQuantOp, error) {
+	if p.Axis >= dims {
+		return nil, errors.Errorf("Axis %d of the params is out of bounds for a tensor with %d dimensions", p.Axis, dims)
+	}
+	if len(p.Scale) == 0 || len(p.Scale) != len(p.ZeroPoint) {
+		return nil, errors.Errorf("Expected as many scales as zero points. Got %d and %d", len(p.Scale), len(p.ZeroPoint))
+	}
+	return &fakeQuantOp{p: p, dims: dims, learnable: learnable}, nil
+}
+
+// Arity ...
+func (op *fakeQuantOp) Arity() int {
+	if op.learnable {
+		return 2
+	}
+	return 1
+}
+
+// fakeQuantOp has this type:
+//		op :: Tensor-d a → (Vector a) → Tensor-d a
+// Only an op with learnable scales takes the scales.
+func (op *fakeQuantOp) Type() hm.Type {
+	x, s := op.types()
+	if op.learnable {
+		return hm.NewFnType(x, s, x)
+	}
+	return hm.NewFnType(x, x)
+}
+
+// InferShape ...
+func (op *fakeQuantOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "fakeQuant")
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	if err := op.p.check(s); err != nil {
+		return nil, err
+	}
+	return s.Clone(), nil
+}
+
+// Do ...
+func (op *fakeQuantOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "fakeQuant Do")
+	}
+	var out value.Value
+	if out, err = value.CloneValue(values[0]); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values...)
+}
+
+// ReturnsPtr ...
+func (op *fakeQuantOp) ReturnsPtr() bool { return true }
+
+// CallsExtern ...
+func (op *fakeQuantOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *fakeQuantOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *fakeQuantOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *fakeQuantOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *fakeQuantOp) String() string {
+	if op.learnable {
+		return fmt.Sprintf("FakeQuantize{%d, axis: %d, zeros: %v, learnable}", op.dims, op.p.Axis, op.p.ZeroPoint)
+	}
+	return fmt.Sprintf("FakeQuantize{%d, %v}", op.dims, op.p)
+}
+
+// DiffWRT ...
+func (op *fakeQuantOp) DiffWRT(inputs int) []bool {
+	retVal := make([]bool, inputs)
+	for i := range retVal {
+		retVal[i] = true
+	}
+	return retVal
+}
+
+// SymDiff ...
+func (op *fakeQuantOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	args := append(append(Nodes{}, inputs...), grad)
+	retVal = make(Nodes, len(inputs))
+	for i := range inputs {
+		diff := &fakeQuantDiffOp{fakeQuantOp: op, wrt: i}
+		if retVal[i], err = ApplyOp(diff, args...); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// DoDiff ...
+func (op *fakeQuantOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	_, ydv := getDV(inputs[0], output)
+	values := make([]value.Value, 0, len(inputs)+1)
+	for _, n := range inputs {
+		_, dv := getDV(inputs[0], n)
+		values = append(values, dv.Value)
+	}
+	values = append(values, ydv.D)
+
+	for i := range inputs {
+		_, dv := getDV(inputs[0], inputs[i])
+		diff := &fakeQuantDiffOp{fakeQuantOp: op, wrt: i}
+		if _, err = diff.UsePreallocDo(dv.D, values...); err != nil {
+			return errors.Wrapf(err, doFail, diff)
+		}
+	}
+	return nil
+}
+
+// UsePreallocDo ...
+func (op *fakeQuantOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "fakeQuant UsePreallocDo")
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	x, p, err := op.checkInput(inputs)
+	if err != nil {
+		return nil, err
+	}
+	chanOf := p.channels(inputs[0].Shape())
+	y := make([]float64, len(x))
+	for i, v := range x {
+		s, z := p.at(chanOf(i))
+		y[i] = float64(int32(quantize(v, s, z))-z) * s
+	}
+	if err = setFloat64s(out, y); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (op *fakeQuantOp) types() (x, scale hm.Type) {
+	a := hm.TypeVariable('a')
+	return constructor.NewTensorType(op.dims, a), constructor.NewTensorType(1, a)
+}
+
+// checkInput returns the data of x, and the params with the learnable scales if there are any
+func (op *fakeQuantOp) checkInput(inputs []value.Value) (x []float64, p QuantParams, err error) {
+	xt, ok := inputs[0].(tensor.Tensor)
+	if !ok {
+		return nil, p, errors.Errorf("Expected x to be a tensor. Got %T instead", inputs[0])
+	}
+	if x, err = float64sOf(tensor.Materialize(xt)); err != nil {
+		return nil, p, err
+	}
+	p = op.p
+	if op.learnable {
+		st, ok := inputs[1].(tensor.Tensor)
+		if !ok {
+			return nil, p, errors.Errorf("Expected the scales to be a tensor. Got %T instead", inputs[1])
+		}
+		if p.Scale, err = float64sOf(tensor.Materialize(st)); err != nil {
+			return nil, p, err
+		}
+	}
+	if err = p.check(xt.Shape()); err != nil {
+		return nil, p, err
+	}
+	return x, p, nil
+}
+
+// fakeQuantDiffOp computes the gradient of x, or of the learnable scales, of a fakeQuantOp.
+// It takes the inputs of the fakeQuantOp and the gradient of its output.
+type fakeQuantDiffOp struct {
+	*fakeQuantOp
+	wrt int // 0 for x, 1 for the scales
+}
+
+// Arity ...
+func (op *fakeQuantDiffOp) Arity() int { return op.fakeQuantOp.Arity() + 1 }
+
+// Type ...
+func (op *fakeQuantDiffOp) Type() hm.Type {
+	x, s := op.types()
+	if !op.learnable {
+		return hm.NewFnType(x, x, x)
+	}
+	if op.wrt == 0 {
+		return hm.NewFnType(x, s, x, x)
+	}
+	return hm.NewFnType(x, s, x, s)
+}
+
+// InferShape ...
+func (op *fakeQuantDiffOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "fakeQuantDiff")
+	}
+	s, ok := ns[op.wrt].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	return s.Clone(), nil
+}
+
+// Do ...
+func (op *fakeQuantDiffOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "fakeQuantDiff Do")
+	}
+	var out value.Value
+	if out, err = value.CloneValue(values[op.wrt]); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values...)
+}
+
+// WriteHash ...
+func (op *fakeQuantDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *fakeQuantDiffOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *fakeQuantDiffOp) String() string { return fmt.Sprintf("%vDiff%d", op.fakeQuantOp, op.wrt) }
+
+// DiffWRT ...
+func (op *fakeQuantDiffOp) DiffWRT(inputs int) []bool { return make([]bool, inputs) }
+
+// SymDiff ...
+func (op *fakeQuantDiffOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	return nil, errors.Errorf("%v is not differentiable", op)
+}
+
+// DoDiff ...
+func (op *fakeQuantDiffOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) error {
+	return errors.Errorf("%v is not differentiable", op)
+}
+
+// UsePreallocDo ...
+func (op *fakeQuantDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "fakeQuantDiff UsePreallocDo")
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	x, p, err := op.checkInput(inputs)
+	if err != nil {
+		return nil, err
+	}
+	gt, ok := inputs[len(inputs)-1].(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected grad to be a tensor. Got %T instead", inputs[len(inputs)-1])
+	}
+	var g []float64
+	if g, err = float64sOf(tensor.Materialize(gt)); err != nil {
+		return nil, err
+	}
+	if len(g) != len(x) {
+		return nil, errors.Errorf("Expected the gradient to have %d elements. Got %v", len(x), gt.Shape())
+	}
+
+	chanOf := p.channels(inputs[0].Shape())
+	var grads []float64
+	switch op.wrt {
+	case 0:
+		// straight through where x is not clamped
+		grads = make([]float64, len(x))
+		for i, v := range x {
+			s, z := p.at(chanOf(i))
+			if q := math.RoundToEven(v/s) + float64(z); q >= math.MinInt8 && q <= math.MaxInt8 {
+				grads[i] = g[i]
+			}
+		}
+	case 1:
+		// ∂y/∂s is round(x/s) - x/s where x is not clamped, and the clamped q - z where it is.
+		// The sum is then scaled by 1/√(N·Qp)
+		grads = make([]float64, p.Channels())
+		for i, v := range x {
+			ch := chanOf(i)
+			s, z := p.at(ch)
+			q := math.RoundToEven(v/s) + float64(z)
+			var d float64
+			switch {
+			case q < math.MinInt8:
+				d = float64(math.MinInt8 - z)
+			case q > math.MaxInt8:
+				d = float64(math.MaxInt8 - z)
+			default:
+				d = math.RoundToEven(v/s) - v/s
+			}
+			grads[ch] += g[i] * d
+		}
+		gradScale := 1 / math.Sqrt(float64(len(x)/len(grads))*math.MaxInt8)
+		for i := range grads {
+			grads[i] *= gradScale
+		}
+	default:
+		return nil, errors.Errorf("%v has no input %d", op.fakeQuantOp, op.wrt)
+	}
+	if err = setFloat64s(out, grads); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// FakeQuantRule tells InsertFakeQuant whether to fake-quantize the input of a node, and how.
+// in is the input at index i of the node n. A scale that is learnable becomes a new input of the inserted node.
+type FakeQuantRule func(n, in *exprgraph.Node, i int) (p QuantParams, learnable, ok bool)
+
+// InsertFakeQuant inserts a FakeQuantize node between the nodes of g and the inputs the rule asks for, e.g. before the weights
+// and the activations that feed the MatMuls and Convs of a model. An input that feeds several nodes is fake-quantized once,
+// with the params the rule gives for its first consumer.
+//
+// It returns the inserted nodes, and the nodes that hold the learnable scales, which are the parameters to train along with the model.
+func InsertFakeQuant(g *exprgraph.ExprGraph, rule FakeQuantRule) (inserted, scales []*exprgraph.Node, err error) {
+	// the graph is only changed once the edges to rewire are known
+	type edge struct {
+		from, to *exprgraph.Node
+		i        int
+	}
+	var edges []edge
+	nodes := g.Nodes()
+	for nodes.Next() {
+		n, ok := nodes.Node().(*exprgraph.Node)
+		if !ok || n.Op == nil {
+			continue
+		}
+		if _, isFQ := n.Op.(*fakeQuantOp); isFQ {
+			continue
+		}
+		ins := g.From(n.ID())
+		for ins.Next() {
+			in, ok := ins.Node().(*exprgraph.Node)
+			if !ok {
+				continue
+			}
+			if _, isFQ := in.Op.(*fakeQuantOp); isFQ {
+				continue
+			}
+			w, _ := g.Weight(n.ID(), in.ID())
+			edges = append(edges, edge{from: n, to: in, i: int(w)})
+		}
+	}
+
+	fq := make(map[int64]*exprgraph.Node)
+	for _, e := range edges {
+		q, ok := fq[e.to.ID()]
+		if !ok {
+			p, learnable, want := rule(e.from, e.to, e.i)
+			if !want {
+				continue
+			}
+			var s *exprgraph.Node
+			if q, s, err = insertFakeQuant(g, e.to, p, learnable); err != nil {
+				return nil, nil, errors.Wrapf(err, "Cannot fake-quantize input %d of %v", e.i, e.from.Name)
+			}
+			fq[e.to.ID()] = q
+			inserted = append(inserted, q)
+			if s != nil {
+				scales = append(scales, s)
+			}
+		}
+		g.RemoveEdge(e.from.ID(), e.to.ID())
+		g.SetWeightedEdge(g.NewWeightedEdge(e.from, q, float64(e.i)))
+	}
+	return inserted, scales, nil
+}
+
+// insertFakeQuant adds a FakeQuantize node of in to g, with a node that holds the scales if they are learnable
+func insertFakeQuant(g *exprgraph.ExprGraph, in *exprgraph.Node, p QuantParams, learnable bool) (q, scale *exprgraph.Node, err error) {
+	var op *fakeQuantOp
+	if op, err = newFakeQuantOp(p, in.Shape.Dims(), learnable); err != nil {
+		return nil, nil, err
+	}
+	if err = p.check(in.Shape); err != nil {
+		return nil, nil, err
+	}
+	q = g.NewVertex()
+	q.Op, q.T, q.Shape = op, in.T, in.Shape.Clone()
+	q.Name = "FakeQuantize(" + in.Name + ")"
+	g.AddNode(q)
+	g.SetWeightedEdge(g.NewWeightedEdge(q, in, 0))
+	if !learnable {
+		return q, nil, nil
+	}
+
+	var dt tensor.Dtype
+	if dt, err = dtypeOf(in.T); err != nil {
+		return nil, nil, err
+	}
+	sv := tensor.New(tensor.Of(dt), tensor.WithShape(p.Channels()))
+	if err = setFloat64s(sv, p.Scale); err != nil {
+		return nil, nil, err
+	}
+	scale = g.NewVertex()
+	scale.Name = q.Name + ".scale"
+	if err = scale.ApplyData(sv); err != nil {
+		return nil, nil, err
+	}
+	g.AddNode(scale)
+	g.SetWeightedEdge(g.NewWeightedEdge(q, scale, 1))
+	return q, scale, nil
+}
@@ -0,0 +1,173 @@
+package nnops
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/tensor"
+)
+
+func TestFakeQuantOp(t *testing.T) {
+	// x/s: 0.4, 0.6, 1.48, -2.2, 200 (clamped), -200 (clamped), 126.6
+	x := tensor.New(tensor.WithShape(7), tensor.WithBacking([]float64{0.2, 0.3, 0.74, -1.1, 100, -100, 63.3}))
+	g := tensor.New(tensor.WithShape(7), tensor.WithBacking([]float64{1, 1, 1, 1, 1, 1, 1}))
+	scale := tensor.New(tensor.WithShape(1), tensor.WithBacking([]float64{0.5}))
+
+	op, err := newFakeQuantOp(PerTensor(1, 0), 1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := op.Do(x, scale)
+	if err != nil {
+		t.Fatal(err)
+	}
+	correct := []float64{0, 0.5, 0.5, -1, 63.5, -64, 63.5}
+	for i, v := range y.Data().([]float64) {
+		if math.Abs(v-correct[i]) > 1e-12 {
+			t.Errorf("Expected %v. Got %v", correct, y.Data())
+			break
+		}
+	}
+
+	// straight through, except where x is clamped
+	dx, err := (&fakeQuantDiffOp{fakeQuantOp: op, wrt: 0}).Do(x, scale, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	correct = []float64{1, 1, 1, 1, 0, 0, 1}
+	for i, v := range dx.Data().([]float64) {
+		if v != correct[i] {
+			t.Errorf("Expected %v. Got %v", correct, dx.Data())
+			break
+		}
+	}
+
+	// LSQ: Σ round(x/s) - x/s where x is not clamped, and the clamped q - z where it is, scaled by 1/√(N·Qp) = 1/√(7·127)
+	ds, err := (&fakeQuantDiffOp{fakeQuantOp: op, wrt: 1}).Do(x, scale, g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ds.Shape().Eq(tensor.Shape{1}) {
+		t.Fatalf("Expected the gradient of the scale to have the shape of the scale. Got %v", ds.Shape())
+	}
+	if got, want := ds.Data().([]float64)[0], -0.88/math.Sqrt(7*127); math.Abs(got-want) > 1e-9 {
+		t.Errorf("Expected a gradient of %v. Got %v", want, got)
+	}
+
+	// per channel along axis 0, with zero points and float32s
+	p := QuantParams{Scale: []float64{0.1, 1}, ZeroPoint: []int32{10, -5}, Axis: 0}
+	if op, err = newFakeQuantOp(p, 2, false); err != nil {
+		t.Fatal(err)
+	}
+	x = tensor.New(tensor.WithShape(2, 2), tensor.WithBacking([]float32{0.25, -20, 3.7, 200}))
+	if y, err = op.Do(x); err != nil {
+		t.Fatal(err)
+	}
+	correct32 := []float32{0.2, -13.8, 4, 132}
+	for i, v := range y.Data().([]float32) {
+		if math.Abs(float64(v-correct32[i])) > 1e-5 {
+			t.Errorf("Expected %v. Got %v", correct32, y.Data())
+			break
+		}
+	}
+	g = tensor.New(tensor.WithShape(2, 2), tensor.WithBacking([]float32{1, 2, 3, 4}))
+	if dx, err = (&fakeQuantDiffOp{fakeQuantOp: op}).Do(x, g); err != nil {
+		t.Fatal(err)
+	}
+	correct32 = []float32{1, 0, 3, 0}
+	for i, v := range dx.Data().([]float32) {
+		if v != correct32[i] {
+			t.Errorf("Expected %v. Got %v", correct32, dx.Data())
+			break
+		}
+	}
+
+	// per channel, N is the number of elements of a channel
+	if op, err = newFakeQuantOp(QuantParams{Scale: []float64{1, 1}, ZeroPoint: []int32{0, 0}, Axis: 0}, 2, true); err != nil {
+		t.Fatal(err)
+	}
+	x = tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]float64{0.2, 0.3, 0.7, 1.4, 2.5, -0.6}))
+	scale = tensor.New(tensor.WithShape(2), tensor.WithBacking([]float64{1, 1}))
+	g = tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]float64{1, 1, 1, 1, 1, 1}))
+	if ds, err = (&fakeQuantDiffOp{fakeQuantOp: op, wrt: 1}).Do(x, scale, g); err != nil {
+		t.Fatal(err)
+	}
+	correct = []float64{-0.2 / math.Sqrt(3*127), -1.3 / math.Sqrt(3*127)}
+	for i, v := range ds.Data().([]float64) {
+		if math.Abs(v-correct[i]) > 1e-9 {
+			t.Errorf("Expected %v. Got %v", correct, ds.Data())
+			break
+		}
+	}
+}
+
+func TestInsertFakeQuant(t *testing.T) {
+	g := exprgraph.NewGraph()
+	leaf := func(name string, shape ...int) *exprgraph.Node {
+		n := g.NewVertex()
+		n.Name = name
+		if err := n.ApplyData(tensor.New(tensor.Of(Float64), tensor.WithShape(shape...))); err != nil {
+			t.Fatal(err)
+		}
+		g.AddNode(n)
+		return n
+	}
+	// the ops only mark the nodes as results of ops
+	result := func(name string, inputs ...*exprgraph.Node) *exprgraph.Node {
+		n := g.NewVertex()
+		n.Name, n.Op = name, newSoftmaxOp(-1, false)
+		g.AddNode(n)
+		for i, in := range inputs {
+			g.SetWeightedEdge(g.NewWeightedEdge(n, in, float64(i)))
+		}
+		return n
+	}
+	x, w1, w2 := leaf("x", 4, 3), leaf("w1", 3, 2), leaf("w2", 3, 2)
+	y1, y2 := result("y1", x, w1), result("y2", x, w2)
+
+	// the weights are fake-quantized per column with learnable scales, the activations per tensor
+	rule := func(n, in *exprgraph.Node, i int) (QuantParams, bool, bool) {
+		if i == 1 {
+			return QuantParams{Scale: []float64{0.1, 0.2}, ZeroPoint: []int32{0, 0}, Axis: 1}, true, true
+		}
+		return PerTensor(0.05, -3), false, true
+	}
+	inserted, scales, err := InsertFakeQuant(g, rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inserted) != 3 || len(scales) != 2 {
+		t.Fatalf("Expected x, w1 and w2 to be fake-quantized, with 2 learnable scales. Got %d and %d", len(inserted), len(scales))
+	}
+
+	for _, c := range []struct {
+		n, in *exprgraph.Node
+		i     float64
+	}{{y1, x, 0}, {y1, w1, 1}, {y2, x, 0}, {y2, w2, 1}} {
+		if g.HasEdgeFromTo(c.n.ID(), c.in.ID()) {
+			t.Errorf("Expected %v to no longer take %v directly", c.n.Name, c.in.Name)
+		}
+		var fq *exprgraph.Node
+		for _, q := range inserted {
+			if g.HasEdgeFromTo(q.ID(), c.in.ID()) {
+				fq = q
+			}
+		}
+		if fq == nil {
+			t.Errorf("Expected %v to be fake-quantized", c.in.Name)
+			continue
+		}
+		if w, ok := g.Weight(c.n.ID(), fq.ID()); !ok || w != c.i {
+			t.Errorf("Expected %v to be input %v of %v. Got %v", fq.Name, c.i, c.n.Name, w)
+		}
+		if !fq.Shape.Eq(c.in.Shape) {
+			t.Errorf("Expected %v to have the shape %v. Got %v", fq.Name, c.in.Shape, fq.Shape)
+		}
+	}
+
+	// inserting again is a no-op
+	if inserted, _, err = InsertFakeQuant(g, rule); err != nil || len(inserted) != 0 {
+		t.Errorf("Expected nothing more to be fake-quantized. Got %d nodes and %v", len(inserted), err)
+	}
+}
@@ -0,0 +1,478 @@
+package nnops
+
+import (
+	"fmt"
+	"hash"
+	"math/cmplx"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/dsp/fourier"
+	"gorgonia.org/gorgonia/internal/constructor"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &fftOp{}
+	_ ops.Op = &fftDiffOp{}
+)
+
+// fftKind is the kind of discrete Fourier transform an fftOp computes
+type fftKind byte
+
+const (
+	forwardFFT     fftKind = iota // complex → complex
+	inverseFFT                    // complex → complex, scaled by 1/n
+	realFFT                       // real → the n/2+1 non-negative frequencies
+	inverseRealFFT                // the n/2+1 non-negative frequencies → real, scaled by 1/n
+)
+
+func (k fftKind) String() string { return [...]string{"FFT", "IFFT", "RFFT", "IRFFT"}[k] }
+
+// fftOp computes the discrete Fourier transform of the lanes of a tensor along an axis, with gonum's dsp/fourier.
+// The frequencies of a real signal are the complex conjugates of one another, so RFFT only returns the n/2+1
+// non-negative ones, and IRFFT takes those back to a real signal of length n. The imaginary parts of the frequency 0
+// (and of the frequency n/2 if n is even) are dropped by IRFFT, as they are not part of any real signal.
+//
+// The inverses are scaled by 1/n, so that IFFT(FFT(x)) = x. Complex numbers are computed in complex128.
+//
+// The transforms are linear: the gradient of the input is the adjoint of the transform applied to the gradient of the output,
+// following the conjugate Wirtinger convention of the complex operators.
+type fftOp struct {
+	kind fftKind
+	dims int // dims of the input
+	axis int
+	n    int // length of the signal along the axis: the length of the input of FFT, IFFT and RFFT, and of the output of IRFFT
+}
+
+func newFFTOp(kind fftKind, dims, axis, n int) (*fftOp, error) {
+	if axis < 0 || axis >= dims {
+		return nil, errors.Errorf("%v: axis %d is out of bounds for a tensor with %d dimensions", kind, axis, dims)
+	}
+	if n < 1 {
+		return nil, errors.Errorf("%v expects a signal of length 1 or more. Got %d", kind, n)
+	}
+	return &fftOp{kind: kind, dims: dims, axis: axis, n: n}, nil
+}
+
+// Arity ...
+func (op *fftOp) Arity() int { return 1 }
+
+// fftOp has this type:
+//		op :: Tensor-d a → Tensor-d b
+// where a and b are the same complex type for FFT and IFFT.
+func (op *fftOp) Type() hm.Type {
+	in, out := op.types()
+	return hm.NewFnType(in, out)
+}
+
+// InferShape ...
+func (op *fftOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "fft")
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	if err := op.checkShape(s, op.inLen()); err != nil {
+		return nil, err
+	}
+	return op.retShape(s, op.outLen()), nil
+}
+
+// Do ...
+func (op *fftOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "fft Do")
+	}
+	var dt tensor.Dtype
+	if dt, err = op.retDtype(values[0].Dtype(), false); err != nil {
+		return nil, err
+	}
+	out := tensor.New(tensor.Of(dt), tensor.WithShape(op.retShape(values[0].Shape(), op.outLen())...))
+	return op.UsePreallocDo(out, values...)
+}
+
+// ReturnsPtr ...
+func (op *fftOp) ReturnsPtr() bool { return true }
+
+// CallsExtern ...
+func (op *fftOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *fftOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *fftOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *fftOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *fftOp) String() string {
+	return fmt.Sprintf("%v{%d, axis: %d, n: %d}", op.kind, op.dims, op.axis, op.n)
+}
+
+// DiffWRT ...
+func (op *fftOp) DiffWRT(inputs int) []bool { return []bool{true} }
+
+// SymDiff ...
+func (op *fftOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	diff := &fftDiffOp{op}
+
+	var ret *Node
+	if ret, err = ApplyOp(diff, grad); err != nil {
+		return nil, err
+	}
+	return Nodes{ret}, nil
+}
+
+// DoDiff adds the adjoint of the gradient of the output to the derivative of the input.
+func (op *fftOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	xdv, ydv := getDV(inputs[0], output)
+
+	diff := &fftDiffOp{op}
+	var grad value.Value
+	if grad, err = diff.Do(ydv.D); err != nil {
+		return errors.Wrapf(err, doFail, diff)
+	}
+	d, ok := xdv.D.(*tensor.Dense)
+	if !ok {
+		return errors.Errorf("Expected the derivative of the input to be a *tensor.Dense. Got %T instead", xdv.D)
+	}
+	_, err = tensor.Add(d, grad, tensor.UseUnsafe())
+	return err
+}
+
+// UsePreallocDo ...
+func (op *fftOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "fft UsePreallocDo")
+	}
+	return op.transform(prealloc, inputs[0], false)
+}
+
+func (op *fftOp) types() (in, out hm.Type) {
+	a, b := hm.TypeVariable('a'), hm.TypeVariable('b')
+	if op.kind == forwardFFT || op.kind == inverseFFT {
+		b = a
+	}
+	return constructor.NewTensorType(op.dims, a), constructor.NewTensorType(op.dims, b)
+}
+
+// inLen is the length of the input along the axis
+func (op *fftOp) inLen() int {
+	if op.kind == inverseRealFFT {
+		return op.n/2 + 1
+	}
+	return op.n
+}
+
+// outLen is the length of the output along the axis
+func (op *fftOp) outLen() int {
+	if op.kind == realFFT {
+		return op.n/2 + 1
+	}
+	return op.n
+}
+
+func (op *fftOp) checkShape(s tensor.Shape, length int) error {
+	if s.Dims() != op.dims {
+		return errors.Errorf("%v expects a tensor with %d dimensions. Got %v", op, op.dims, s)
+	}
+	if s[op.axis] != length {
+		return errors.Errorf("%v expects a length of %d along axis %d. Got %v", op, length, op.axis, s)
+	}
+	return nil
+}
+
+func (op *fftOp) retShape(s tensor.Shape, length int) tensor.Shape {
+	retVal := s.Clone()
+	retVal[op.axis] = length
+	return retVal
+}
+
+// retDtype returns the dtype of the output given the dtype of the input, or that of the gradient of the input if adjoint is set.
+// RFFT takes floats to the complex numbers of the same precision, IRFFT the other way around.
+func (op *fftOp) retDtype(dt tensor.Dtype, adjoint bool) (tensor.Dtype, error) {
+	fromReal, toReal := op.kind == realFFT, op.kind == inverseRealFFT
+	if adjoint {
+		fromReal, toReal = toReal, fromReal
+	}
+	switch {
+	case fromReal && dt == Float64, !fromReal && !toReal && dt == tensor.Complex128:
+		return tensor.Complex128, nil
+	case fromReal && dt == Float32, !fromReal && !toReal && dt == tensor.Complex64:
+		return tensor.Complex64, nil
+	case toReal && dt == tensor.Complex128:
+		return Float64, nil
+	case toReal && dt == tensor.Complex64:
+		return Float32, nil
+	}
+	if fromReal {
+		return dt, errors.Errorf("%v expects floats. Got %v", op, dt)
+	}
+	return dt, errors.Errorf("%v expects complex numbers. Got %v", op, dt)
+}
+
+// transform writes the transform of x into prealloc. If adjoint is set, x is the gradient of the output,
+// and the adjoint of the transform, which is the gradient of the input, is written instead.
+func (op *fftOp) transform(prealloc, x value.Value, adjoint bool) (retVal value.Value, err error) {
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	t, ok := x.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected a tensor. Got %T instead", x)
+	}
+	inLen, outLen := op.inLen(), op.outLen()
+	if adjoint {
+		inLen, outLen = outLen, inLen
+	}
+	if err = op.checkShape(t.Shape(), inLen); err != nil {
+		return nil, err
+	}
+	var dt tensor.Dtype
+	if dt, err = op.retDtype(t.Dtype(), adjoint); err != nil {
+		return nil, err
+	}
+	retShape := op.retShape(t.Shape(), outLen)
+	if out.Dtype() != dt || !out.Shape().Eq(retShape) {
+		return nil, errors.Errorf("Expected prealloc to be a tensor of %v of shape %v. Got %v of shape %v", dt, retShape, out.Dtype(), out.Shape())
+	}
+
+	var data []complex128
+	if data, err = complex128sOf(t); err != nil {
+		return nil, err
+	}
+	res := alongAxis(data, t.Shape(), op.axis, outLen, op.lane(adjoint))
+	if err = setComplex128s(out, res); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// lane returns the function that transforms one lane along the axis. The adjoints are:
+//		FFT:   n·IFFT
+//		IFFT:  FFT/n
+//		RFFT:  the real part of n·IFFT of the frequencies padded with zeroes to n
+//		IRFFT: RFFT/n, with the frequencies that have a conjugate counted twice
+func (op *fftOp) lane(adjoint bool) func(dst, src []complex128) {
+	n := op.n
+	fft := fourier.NewCmplxFFT(n)
+	full := make([]complex128, n)
+	scale := complex(1/float64(n), 0)
+
+	switch {
+	case op.kind == forwardFFT && !adjoint:
+		return func(dst, src []complex128) { fft.Coefficients(dst, src) }
+	case op.kind == forwardFFT:
+		return func(dst, src []complex128) { fft.Sequence(dst, src) }
+	case op.kind == inverseFFT && !adjoint:
+		return func(dst, src []complex128) {
+			fft.Sequence(dst, src)
+			for i := range dst {
+				dst[i] *= scale
+			}
+		}
+	case op.kind == inverseFFT:
+		return func(dst, src []complex128) {
+			fft.Coefficients(dst, src)
+			for i := range dst {
+				dst[i] *= scale
+			}
+		}
+	case op.kind == realFFT && !adjoint:
+		return func(dst, src []complex128) {
+			fft.Coefficients(full, src)
+			copy(dst, full)
+		}
+	case op.kind == realFFT:
+		return func(dst, src []complex128) {
+			copy(full, src)
+			for i := len(src); i < n; i++ {
+				full[i] = 0
+			}
+			fft.Sequence(dst, full)
+			for i, v := range dst {
+				dst[i] = complex(real(v), 0)
+			}
+		}
+	case !adjoint: // inverseRealFFT
+		return func(dst, src []complex128) {
+			// the negative frequencies are the conjugates of the positive ones
+			copy(full, src)
+			for i := len(src); i < n; i++ {
+				full[i] = cmplx.Conj(src[n-i])
+			}
+			fft.Sequence(dst, full)
+			for i, v := range dst {
+				dst[i] = complex(real(v)/float64(n), 0)
+			}
+		}
+	}
+	return func(dst, src []complex128) {
+		fft.Coefficients(full, src)
+		for i := range dst {
+			dst[i] = full[i] * scale
+			if i > 0 && 2*i != n {
+				dst[i] *= 2
+			}
+		}
+	}
+}
+
+// alongAxis transforms every lane of data, which has the shape s, along the axis.
+// fn reads a lane of length s[axis] and writes a lane of length outLen.
+func alongAxis(data []complex128, s tensor.Shape, axis, outLen int, fn func(dst, src []complex128)) []complex128 {
+	outer, inner := 1, 1
+	for _, d := range s[:axis] {
+		outer *= d
+	}
+	for _, d := range s[axis+1:] {
+		inner *= d
+	}
+	inLen := s[axis]
+
+	retVal := make([]complex128, outer*outLen*inner)
+	src, dst := make([]complex128, inLen), make([]complex128, outLen)
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			for k := range src {
+				src[k] = data[(o*inLen+k)*inner+i]
+			}
+			fn(dst, src)
+			for k, v := range dst {
+				retVal[(o*outLen+k)*inner+i] = v
+			}
+		}
+	}
+	return retVal
+}
+
+// complex128sOf returns the data of a tensor of floats or complex numbers as a []complex128
+func complex128sOf(t tensor.Tensor) ([]complex128, error) {
+	switch data := tensor.Materialize(t).Data().(type) {
+	case []complex128:
+		return data, nil
+	case []complex64:
+		retVal := make([]complex128, len(data))
+		for i, v := range data {
+			retVal[i] = complex128(v)
+		}
+		return retVal, nil
+	case []float64:
+		retVal := make([]complex128, len(data))
+		for i, v := range data {
+			retVal[i] = complex(v, 0)
+		}
+		return retVal, nil
+	case []float32:
+		retVal := make([]complex128, len(data))
+		for i, v := range data {
+			retVal[i] = complex(float64(v), 0)
+		}
+		return retVal, nil
+	}
+	return nil, nyi("complex128sOf", t.Dtype())
+}
+
+// setComplex128s copies data into out. Floats take the real part of data.
+func setComplex128s(out *tensor.Dense, data []complex128) error {
+	switch d := out.Data().(type) {
+	case []complex128:
+		copy(d, data)
+	case []complex64:
+		for i, v := range data {
+			d[i] = complex64(v)
+		}
+	case []float64:
+		for i, v := range data {
+			d[i] = real(v)
+		}
+	case []float32:
+		for i, v := range data {
+			d[i] = float32(real(v))
+		}
+	default:
+		return nyi("setComplex128s", out.Dtype())
+	}
+	return nil
+}
+
+// fftDiffOp computes the gradient of the input of an fftOp, which is the adjoint of the transform applied to the gradient of the output.
+// As the transforms are linear, it only takes the gradient of the output.
+type fftDiffOp struct{ *fftOp }
+
+// Type ...
+func (op *fftDiffOp) Type() hm.Type {
+	in, out := op.types()
+	return hm.NewFnType(out, in)
+}
+
+// InferShape ...
+func (op *fftDiffOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "fftDiff")
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	if err := op.checkShape(s, op.outLen()); err != nil {
+		return nil, err
+	}
+	return op.retShape(s, op.inLen()), nil
+}
+
+// Do ...
+func (op *fftDiffOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "fftDiff Do")
+	}
+	var dt tensor.Dtype
+	if dt, err = op.retDtype(values[0].Dtype(), true); err != nil {
+		return nil, err
+	}
+	out := tensor.New(tensor.Of(dt), tensor.WithShape(op.retShape(values[0].Shape(), op.inLen())...))
+	return op.UsePreallocDo(out, values...)
+}
+
+// WriteHash ...
+func (op *fftDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *fftDiffOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *fftDiffOp) String() string { return op.fftOp.String() + "Diff" }
+
+// DiffWRT ...
+func (op *fftDiffOp) DiffWRT(inputs int) []bool { return []bool{false} }
+
+// SymDiff ...
+func (op *fftDiffOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	return nil, errors.Errorf("%v is not differentiable", op)
+}
+
+// DoDiff ...
+func (op *fftDiffOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) error {
+	return errors.Errorf("%v is not differentiable", op)
+}
+
+// UsePreallocDo ...
+func (op *fftDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "fftDiff UsePreallocDo")
+	}
+	return op.transform(prealloc, inputs[0], true)
+}
@@ -0,0 +1,194 @@
+package nnops
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+
+	"gorgonia.org/tensor"
+)
+
+// naiveDFT computes the discrete Fourier transform of xs by its definition
+func naiveDFT(xs []complex128, sign float64) []complex128 {
+	n := len(xs)
+	retVal := make([]complex128, n)
+	for k := range retVal {
+		for j, x := range xs {
+			retVal[k] += x * cmplx.Exp(complex(0, sign*2*math.Pi*float64(j*k)/float64(n)))
+		}
+	}
+	return retVal
+}
+
+func closeComplex(a, b []complex128) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if cmplx.Abs(a[i]-b[i]) > 1e-9 {
+			return false
+		}
+	}
+	return true
+}
+
+func TestFFTOp(t *testing.T) {
+	for _, n := range []int{5, 6} {
+		signal := make([]complex128, n)
+		reals := make([]float64, n)
+		for i := range signal {
+			reals[i] = float64(i*i%7) - 2
+			signal[i] = complex(reals[i], float64(i)/2)
+		}
+
+		// a (2, n, 1) tensor, transformed along its middle axis
+		data := append(append([]complex128{}, signal...), signal...)
+		x := tensor.New(tensor.WithShape(2, n, 1), tensor.WithBacking(data))
+		fft, err := newFFTOp(forwardFFT, 3, 1, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, err := fft.Do(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !y.Shape().Eq(tensor.Shape{2, n, 1}) {
+			t.Errorf("FFT: expected shape (2, %d, 1). Got %v", n, y.Shape())
+		}
+		correct := naiveDFT(signal, -1)
+		got := y.Data().([]complex128)
+		if !closeComplex(got[:n], correct) || !closeComplex(got[n:], correct) {
+			t.Errorf("FFT: expected %v. Got %v", correct, got)
+		}
+
+		ifft, _ := newFFTOp(inverseFFT, 3, 1, n)
+		back, err := ifft.Do(y)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !closeComplex(back.Data().([]complex128), data) {
+			t.Errorf("IFFT(FFT(x)): expected %v. Got %v", data, back.Data())
+		}
+
+		// real signals
+		rx := tensor.New(tensor.WithShape(n), tensor.WithBacking(reals))
+		rfft, _ := newFFTOp(realFFT, 1, 0, n)
+		ry, err := rfft.Do(rx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ry.Shape().Eq(tensor.Shape{n/2 + 1}) || ry.Dtype() != tensor.Complex128 {
+			t.Fatalf("RFFT: expected %d complex numbers. Got %v of shape %v", n/2+1, ry.Dtype(), ry.Shape())
+		}
+		rs := make([]complex128, n)
+		for i, v := range reals {
+			rs[i] = complex(v, 0)
+		}
+		correct = naiveDFT(rs, -1)[:n/2+1]
+		if !closeComplex(ry.Data().([]complex128), correct) {
+			t.Errorf("RFFT: expected %v. Got %v", correct, ry.Data())
+		}
+
+		irfft, _ := newFFTOp(inverseRealFFT, 1, 0, n)
+		rback, err := irfft.Do(ry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rback.Dtype() != Float64 {
+			t.Errorf("IRFFT: expected Float64. Got %v", rback.Dtype())
+		}
+		for i, v := range rback.Data().([]float64) {
+			if math.Abs(v-reals[i]) > 1e-9 {
+				t.Errorf("IRFFT(RFFT(x)): expected %v. Got %v", reals, rback.Data())
+				break
+			}
+		}
+	}
+
+	// RFFT only takes floats
+	rfft, _ := newFFTOp(realFFT, 1, 0, 2)
+	if _, err := rfft.Do(tensor.New(tensor.WithShape(2), tensor.WithBacking([]complex128{1, 2}))); err == nil {
+		t.Error("Expected RFFT of complex numbers to fail")
+	}
+}
+
+// TestFFTOp_Grads checks that the gradients are the adjoints of the transforms: Re<g, Ax> = Re<A*g, x>
+func TestFFTOp_Grads(t *testing.T) {
+	inner := func(a, b []complex128) float64 {
+		var s complex128
+		for i := range a {
+			s += cmplx.Conj(a[i]) * b[i]
+		}
+		return real(s)
+	}
+	seq := func(n int, seed float64) []complex128 {
+		retVal := make([]complex128, n)
+		for i := range retVal {
+			retVal[i] = complex(math.Sin(seed*float64(i+1)), math.Cos(seed*float64(i+2)))
+		}
+		return retVal
+	}
+	dropImag := func(cs []complex128) []complex128 {
+		for i, c := range cs {
+			cs[i] = complex(real(c), 0)
+		}
+		return cs
+	}
+	asFloats := func(cs []complex128) []float64 {
+		retVal := make([]float64, len(cs))
+		for i, c := range cs {
+			retVal[i] = real(c)
+		}
+		return retVal
+	}
+
+	for _, kind := range []fftKind{forwardFFT, inverseFFT, realFFT, inverseRealFFT} {
+		for _, n := range []int{5, 6} {
+			op, err := newFFTOp(kind, 2, 1, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the real signals have no imaginary parts
+			xs, gs := seq(3*op.inLen(), 1.3), seq(3*op.outLen(), 0.7)
+			switch kind {
+			case realFFT:
+				xs = dropImag(xs)
+			case inverseRealFFT:
+				gs = dropImag(gs)
+			}
+			x := tensor.New(tensor.WithShape(3, op.inLen()), tensor.WithBacking(xs))
+			g := tensor.New(tensor.WithShape(3, op.outLen()), tensor.WithBacking(gs))
+			switch kind {
+			case realFFT:
+				x = tensor.New(tensor.WithShape(3, op.inLen()), tensor.WithBacking(asFloats(xs)))
+			case inverseRealFFT:
+				g = tensor.New(tensor.WithShape(3, op.outLen()), tensor.WithBacking(asFloats(gs)))
+			}
+
+			y, err := op.Do(x)
+			if err != nil {
+				t.Fatalf("%v: %v", op, err)
+			}
+			d, err := (&fftDiffOp{op}).Do(g)
+			if err != nil {
+				t.Fatalf("%v: %v", op, err)
+			}
+			if !d.Shape().Eq(x.Shape()) || d.Dtype() != x.Dtype() {
+				t.Errorf("%v: expected the gradient to be %v of shape %v. Got %v of shape %v", op, x.Dtype(), x.Shape(), d.Dtype(), d.Shape())
+				continue
+			}
+
+			ys, err := complex128sOf(y.(tensor.Tensor))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ds, err := complex128sOf(d.(tensor.Tensor))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lhs, rhs := inner(gs, ys), inner(ds, xs); math.Abs(lhs-rhs) > 1e-9 {
+				t.Errorf("%v: expected <g, Ax> = <A*g, x>. Got %v and %v", op, lhs, rhs)
+			}
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package nnops
+
+import (
+	"math"
+
+	"gorgonia.org/tensor"
+)
+
+// sines makes test data that is deterministic and spread out without being random:
+// the kth value it makes is amp·sin(freq·k), for k = 1, 2, 3…
+type sines struct {
+	amp, freq float64
+	k         float64 // the number of values made so far
+}
+
+func newSines(amp, freq float64) *sines { return &sines{amp: amp, freq: freq} }
+
+// next returns the next n values.
+func (s *sines) next(n int) []float64 {
+	retVal := make([]float64, n)
+	for i := range retVal {
+		s.k++
+		retVal[i] = s.amp * math.Sin(s.freq*s.k)
+	}
+	return retVal
+}
+
+// dense returns a tensor of the given shape, filled with the next values.
+func (s *sines) dense(shape ...int) *tensor.Dense {
+	return tensor.New(tensor.WithShape(shape...), tensor.WithBacking(s.next(tensor.Shape(shape).TotalSize())))
+}
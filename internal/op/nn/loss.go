@@ -0,0 +1,503 @@
+package nnops
+
+import (
+	"fmt"
+	"hash"
+	"math"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/constructor"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &lossOp{}
+	_ ops.Op = &lossDiffOp{}
+)
+
+// Reduction is how the per element losses are reduced into the value returned by a loss.
+type Reduction byte
+
+const (
+	// ReduceNone returns the loss of each element (or of each example, for SoftmaxCrossEntropy).
+	ReduceNone Reduction = iota
+	// ReduceSum returns the sum of the losses.
+	ReduceSum
+	// ReduceMean returns the mean of the losses.
+	ReduceMean
+)
+
+func (r Reduction) String() string {
+	switch r {
+	case ReduceNone:
+		return "none"
+	case ReduceSum:
+		return "sum"
+	case ReduceMean:
+		return "mean"
+	}
+	return fmt.Sprintf("Reduction(%d)", byte(r))
+}
+
+type lossKind byte
+
+const (
+	softmaxCrossEntropyLoss lossKind = iota
+	bceWithLogitsLoss
+	mseLoss
+	huberLoss
+	klDivLoss
+)
+
+var lossNames = [...]string{"SoftmaxCrossEntropy", "BCEWithLogits", "MSE", "Huber", "KLDiv"}
+
+// lossOp is a fused loss. It takes a prediction and a target, and returns the loss reduced by its Reduction.
+//
+// Every loss except the softmax cross entropy is an elementwise loss: the prediction and the target have the same shape.
+// The softmax cross entropy takes (N, C) logits and N integer class labels, and has a loss for each of the N examples.
+//
+// All the losses are computed in float64 precision, even for float32 inputs.
+type lossOp struct {
+	kind      lossKind
+	reduction Reduction
+	dims      int     // dims of the prediction
+	delta     float64 // only used in the Huber loss
+}
+
+func newLossOp(kind lossKind, reduction Reduction, dims int, delta float64) (*lossOp, error) {
+	if reduction > ReduceMean {
+		return nil, errors.Errorf("Unknown reduction %v", reduction)
+	}
+	if kind == softmaxCrossEntropyLoss && dims != 2 {
+		return nil, errors.Errorf("SoftmaxCrossEntropy expects (N, C) logits. Got logits with %d dimensions", dims)
+	}
+	if kind == huberLoss && delta <= 0 {
+		return nil, errors.Errorf("Huber loss expects a positive delta. Got %v", delta)
+	}
+	return &lossOp{kind: kind, reduction: reduction, dims: dims, delta: delta}, nil
+}
+
+// Arity ...
+func (op *lossOp) Arity() int { return 2 }
+
+// lossOp has this type:
+//		op :: Tensor-d a → Tensor-d a → a
+// or, for the softmax cross entropy:
+//		op :: Matrix a → Vector b → a
+// When the reduction is ReduceNone, the return type is the type of the losses before reduction.
+func (op *lossOp) Type() hm.Type {
+	pred, target, ret := op.types()
+	return hm.NewFnType(pred, target, ret)
+}
+
+// InferShape ...
+func (op *lossOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "loss")
+	}
+	pred, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	target, ok := ns[1].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	if err := op.checkShapes(pred, target); err != nil {
+		return nil, err
+	}
+	return op.retShape(pred), nil
+}
+
+// Do ...
+func (op *lossOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	pred, target, err := op.checkInput(values...)
+	if err != nil {
+		return nil, err
+	}
+	out := tensor.New(tensor.Of(pred.Dtype()), tensor.WithShape(op.retShape(pred.Shape())...))
+	return op.UsePreallocDo(out, pred, target)
+}
+
+// ReturnsPtr ...
+func (op *lossOp) ReturnsPtr() bool { return true }
+
+// CallsExtern ...
+func (op *lossOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *lossOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *lossOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *lossOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *lossOp) String() string {
+	if op.kind == huberLoss {
+		return fmt.Sprintf("%s{%v, %v}", lossNames[op.kind], op.reduction, op.delta)
+	}
+	return fmt.Sprintf("%s{%v}", lossNames[op.kind], op.reduction)
+}
+
+// DiffWRT only returns true for the prediction. The targets are treated as constants.
+func (op *lossOp) DiffWRT(inputs int) []bool { return []bool{true, false} }
+
+// SymDiff ...
+func (op *lossOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	diff := &lossDiffOp{op}
+
+	var ret *Node
+	if ret, err = ApplyOp(diff, inputs[0], inputs[1], grad); err != nil {
+		return nil, err
+	}
+	return Nodes{ret, nil}, nil
+}
+
+// DoDiff ...
+func (op *lossOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) error {
+	diff := &lossDiffOp{op}
+	xdv, tdv := getDV(inputs[0], inputs[1])
+	_, ydv := getDV(inputs[0], output)
+	_, err := diff.UsePreallocDo(xdv.D, xdv.Value, tdv.Value, ydv.D)
+	return err
+}
+
+// UsePreallocDo ...
+func (op *lossOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	pred, target, err := op.checkInput(inputs...)
+	if err != nil {
+		return nil, err
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+
+	var losses []float64
+	if losses, err = op.losses(pred, target, nil); err != nil {
+		return nil, err
+	}
+	if op.reduction != ReduceNone {
+		var sum float64
+		for _, l := range losses {
+			sum += l
+		}
+		if op.reduction == ReduceMean {
+			sum /= float64(len(losses))
+		}
+		losses = []float64{sum}
+	}
+
+	switch out.Dtype() {
+	case Float64:
+		copy(out.Float64s(), losses)
+	case Float32:
+		data := out.Float32s()
+		for i, l := range losses {
+			data[i] = float32(l)
+		}
+	default:
+		return nil, nyi("Loss Do", out.Dtype())
+	}
+	return out, nil
+}
+
+// types returns the types of the prediction, the target and the loss
+func (op *lossOp) types() (pred, target, ret hm.Type) {
+	a := hm.TypeVariable('a')
+	pred = constructor.NewTensorType(op.dims, a)
+	target, ret = pred, pred
+	if op.kind == softmaxCrossEntropyLoss {
+		target = constructor.NewTensorType(1, hm.TypeVariable('b'))
+		ret = constructor.NewTensorType(1, a)
+	}
+	if op.reduction != ReduceNone {
+		ret = a
+	}
+	return
+}
+
+func (op *lossOp) checkShapes(pred, target tensor.Shape) error {
+	if pred.Dims() != op.dims {
+		return errors.Errorf("%v expects a prediction with %d dimensions. Got %v", op, op.dims, pred)
+	}
+	if op.kind == softmaxCrossEntropyLoss {
+		if target.TotalSize() != pred[0] {
+			return errors.Errorf("%v expects %d labels. Got %v", op, pred[0], target)
+		}
+		return nil
+	}
+	if !pred.Eq(target) {
+		return errors.Errorf("%v expects the prediction and the target to have the same shape. Got %v and %v", op, pred, target)
+	}
+	return nil
+}
+
+func (op *lossOp) retShape(pred tensor.Shape) tensor.Shape {
+	switch {
+	case op.reduction != ReduceNone:
+		return tensor.ScalarShape()
+	case op.kind == softmaxCrossEntropyLoss:
+		return tensor.Shape{pred[0]}
+	}
+	return pred.Clone()
+}
+
+func (op *lossOp) checkInput(inputs ...value.Value) (pred, target tensor.Tensor, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	var ok bool
+	if pred, ok = inputs[0].(tensor.Tensor); !ok {
+		err = errors.Errorf("Expected prediction to be a tensor. Got %T instead", inputs[0])
+		return
+	}
+	if target, ok = inputs[1].(tensor.Tensor); !ok {
+		err = errors.Errorf("Expected target to be a tensor. Got %T instead", inputs[1])
+		return
+	}
+	err = op.checkShapes(pred.Shape(), target.Shape())
+	return
+}
+
+// losses computes the loss of each element (or example). If grads is not nil, the derivative of each loss
+// with regards to each element of the prediction is written into it.
+func (op *lossOp) losses(pred, target tensor.Tensor, grads []float64) (losses []float64, err error) {
+	var x []float64
+	if x, err = float64sOf(pred); err != nil {
+		return nil, err
+	}
+
+	if op.kind == softmaxCrossEntropyLoss {
+		var labels []int
+		if labels, err = labelsOf(target); err != nil {
+			return nil, err
+		}
+		return softmaxCrossEntropy(x, labels, pred.Shape()[1], grads)
+	}
+
+	var t []float64
+	if t, err = float64sOf(target); err != nil {
+		return nil, err
+	}
+	losses = make([]float64, len(x))
+	var l, g float64
+	for i := range x {
+		switch op.kind {
+		case bceWithLogitsLoss:
+			// max(x, 0) - xt + log(1 + exp(-|x|)) is log(1 + exp(x)) - xt, without the overflow
+			l = math.Max(x[i], 0) - x[i]*t[i] + math.Log1p(math.Exp(-math.Abs(x[i])))
+			g = stableSigmoid(x[i]) - t[i]
+		case mseLoss:
+			d := x[i] - t[i]
+			l, g = d*d, 2*d
+		case huberLoss:
+			d := x[i] - t[i]
+			if math.Abs(d) <= op.delta {
+				l, g = 0.5*d*d, d
+			} else {
+				l, g = op.delta*(math.Abs(d)-0.5*op.delta), math.Copysign(op.delta, d)
+			}
+		case klDivLoss:
+			// the prediction is log(q), the target is p. 0 log 0 is taken to be 0.
+			l, g = 0, -t[i]
+			if t[i] > 0 {
+				l = t[i] * (math.Log(t[i]) - x[i])
+			}
+		}
+		losses[i] = l
+		if grads != nil {
+			grads[i] = g
+		}
+	}
+	return losses, nil
+}
+
+// softmaxCrossEntropy computes -log(softmax(x)[label]) for each row of x. If grads is not nil, softmax(x) - onehot(label) is written into it.
+func softmaxCrossEntropy(x []float64, labels []int, classes int, grads []float64) ([]float64, error) {
+	losses := make([]float64, len(labels))
+	for n, label := range labels {
+		if label < 0 || label >= classes {
+			return nil, errors.Errorf("Label %d of example %d is out of range of the %d classes", label, n, classes)
+		}
+		row := x[n*classes : (n+1)*classes]
+		max := math.Inf(-1)
+		for _, v := range row {
+			max = math.Max(max, v)
+		}
+		var sum float64
+		for _, v := range row {
+			sum += math.Exp(v - max)
+		}
+		logSum := math.Log(sum) + max
+		losses[n] = logSum - row[label]
+
+		if grads != nil {
+			g := grads[n*classes : (n+1)*classes]
+			for c, v := range row {
+				g[c] = math.Exp(v - logSum)
+			}
+			g[label]--
+		}
+	}
+	return losses, nil
+}
+
+func stableSigmoid(x float64) float64 {
+	if x >= 0 {
+		return 1 / (1 + math.Exp(-x))
+	}
+	e := math.Exp(x)
+	return e / (1 + e)
+}
+
+// float64sOf returns the data of a float tensor as a []float64. Float32 data is copied.
+func float64sOf(t tensor.Tensor) ([]float64, error) {
+	switch data := t.Data().(type) {
+	case float64:
+		return []float64{data}, nil
+	case float32:
+		return []float64{float64(data)}, nil
+	case []float64:
+		return data, nil
+	case []float32:
+		retVal := make([]float64, len(data))
+		for i, v := range data {
+			retVal[i] = float64(v)
+		}
+		return retVal, nil
+	}
+	return nil, nyi("float64sOf", t.Dtype())
+}
+
+// labelsOf returns the data of a tensor of class labels as a []int.
+func labelsOf(t tensor.Tensor) ([]int, error) {
+	switch data := t.Data().(type) {
+	case []int:
+		return data, nil
+	case []int64:
+		retVal := make([]int, len(data))
+		for i, v := range data {
+			retVal[i] = int(v)
+		}
+		return retVal, nil
+	case []int32:
+		retVal := make([]int, len(data))
+		for i, v := range data {
+			retVal[i] = int(v)
+		}
+		return retVal, nil
+	}
+	return nil, errors.Errorf("Expected integer labels. Got %v instead", t.Dtype())
+}
+
+// lossDiffOp computes the gradient of a loss with regards to the prediction.
+// Its inputs are the prediction, the target and the gradient of the loss.
+type lossDiffOp struct{ *lossOp }
+
+// Arity ...
+func (op *lossDiffOp) Arity() int { return 3 }
+
+// Type ...
+func (op *lossDiffOp) Type() hm.Type {
+	pred, target, ret := op.types()
+	return hm.NewFnType(pred, target, ret, pred)
+}
+
+// InferShape ...
+func (op *lossDiffOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "lossDiff")
+	}
+	return ns[0].(tensor.Shape).Clone(), nil
+}
+
+// Do ...
+func (op *lossDiffOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "lossDiff Do")
+	}
+	var out value.Value
+	if out, err = value.CloneValue(values[0]); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values...)
+}
+
+// WriteHash ...
+func (op *lossDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *lossDiffOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *lossDiffOp) String() string { return op.lossOp.String() + "Diff" }
+
+// UsePreallocDo ...
+func (op *lossDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "lossDiff UsePreallocDo")
+	}
+	pred, target, err := op.checkInput(inputs[:2]...)
+	if err != nil {
+		return nil, err
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+
+	var g []float64
+	switch gv := inputs[2].(type) {
+	case *value.F64:
+		g = []float64{float64(*gv)}
+	case *value.F32:
+		g = []float64{float64(*gv)}
+	case tensor.Tensor:
+		if g, err = float64sOf(gv); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("Expected the gradient of the loss to be a float. Got %T instead", inputs[2])
+	}
+
+	grads := make([]float64, pred.Shape().TotalSize())
+	var losses []float64
+	if losses, err = op.losses(pred, target, grads); err != nil {
+		return nil, err
+	}
+
+	// chain rule: scale the derivative of each element by the gradient of its loss
+	perLoss := len(grads) / len(losses)
+	for i := range grads {
+		switch op.reduction {
+		case ReduceNone:
+			grads[i] *= g[i/perLoss]
+		case ReduceSum:
+			grads[i] *= g[0]
+		case ReduceMean:
+			grads[i] *= g[0] / float64(len(losses))
+		}
+	}
+
+	switch out.Dtype() {
+	case Float64:
+		copy(out.Float64s(), grads)
+	case Float32:
+		data := out.Float32s()
+		for i, v := range grads {
+			data[i] = float32(v)
+		}
+	default:
+		return nil, nyi("LossDiff Do", out.Dtype())
+	}
+	return out, nil
+}
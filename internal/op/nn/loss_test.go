@@ -0,0 +1,85 @@
+package nnops
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+func TestSoftmaxOp(t *testing.T) {
+	// large values would overflow a naive softmax
+	x := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]float64{1000, 1001, 1002, -1, 0, 1}))
+	correct := []float64{0.09003057317038046, 0.24472847105479764, 0.6652409557748219}
+
+	for _, log := range []bool{false, true} {
+		op := newSoftmaxOp(-1, log)
+		out, err := op.Do(x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, v := range out.Data().([]float64) {
+			want := correct[i%3]
+			if log {
+				want = math.Log(want)
+			}
+			if math.Abs(v-want) > 1e-12 {
+				t.Errorf("%v: expected %v at %d. Got %v", op, want, i, v)
+			}
+		}
+	}
+}
+
+func TestLossOp_Gradients(t *testing.T) {
+	logits := []float64{0.5, -1, 2, 30, -20, 0.1}
+	labels := tensor.New(tensor.WithShape(2), tensor.WithBacking([]int{2, 0}))
+	targets := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]float64{0.2, 0.3, 0.5, 1, 0, 0}))
+
+	lossTests := []struct {
+		kind   lossKind
+		target tensor.Tensor
+	}{
+		{softmaxCrossEntropyLoss, labels},
+		{bceWithLogitsLoss, targets},
+		{mseLoss, targets},
+		{huberLoss, targets},
+		{klDivLoss, targets},
+	}
+
+	for _, lt := range lossTests {
+		for _, reduction := range []Reduction{ReduceSum, ReduceMean} {
+			op, err := newLossOp(lt.kind, reduction, 2, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pred := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking(append([]float64(nil), logits...)))
+			grad := value.NewF64(1)
+			dx, err := (&lossDiffOp{op}).Do(pred, lt.target, grad)
+			if err != nil {
+				t.Errorf("%v: %v", op, err)
+				continue
+			}
+
+			const eps = 1e-6
+			data := pred.Data().([]float64)
+			for i, v := range dx.Data().([]float64) {
+				orig := data[i]
+				data[i] = orig + eps
+				lp, _ := op.Do(pred, lt.target)
+				data[i] = orig - eps
+				lm, _ := op.Do(pred, lt.target)
+				data[i] = orig
+
+				lpv, lmv := lp.(*tensor.Dense).Float64s()[0], lm.(*tensor.Dense).Float64s()[0]
+				if math.IsNaN(lpv) || math.IsInf(lpv, 0) {
+					t.Errorf("%v: loss is %v", op, lpv)
+					break
+				}
+				if ng := (lpv - lmv) / (2 * eps); math.Abs(ng-v) > 1e-5 {
+					t.Errorf("%v: gradient %d - expected %v. Got %v", op, i, ng, v)
+				}
+			}
+		}
+	}
+}
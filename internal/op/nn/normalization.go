@@ -0,0 +1,500 @@
+package nnops
+
+import (
+	"fmt"
+	"hash"
+	"math"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/constructor"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &normOp{}
+	_ ops.Op = &normDiffOp{}
+)
+
+// normKind is the kind of normalization a normOp performs
+type normKind byte
+
+const (
+	// layerNorm normalizes over the trailing axes of each example, as described by Ba et al. (2016) - https://arxiv.org/abs/1607.06450
+	layerNorm normKind = iota
+	// groupNorm normalizes over groups of channels of a (N, C, ...) input, as described by Wu and He (2018) - https://arxiv.org/abs/1803.08494
+	groupNorm
+	// instanceNorm normalizes each channel of each example of a (N, C, ...) input. It is a groupNorm with one channel per group.
+	instanceNorm
+	// rmsNorm scales the trailing axes of each example by their root mean square, without centering them,
+	// as described by Zhang and Sennrich (2019) - https://arxiv.org/abs/1910.07467
+	rmsNorm
+)
+
+func (k normKind) String() string {
+	switch k {
+	case layerNorm:
+		return "LayerNorm"
+	case groupNorm:
+		return "GroupNorm"
+	case instanceNorm:
+		return "InstanceNorm"
+	case rmsNorm:
+		return "RMSNorm"
+	}
+	return fmt.Sprintf("normKind(%d)", byte(k))
+}
+
+// normOp normalizes groups of elements of its input to a zero mean and a unit variance (or, for RMSNorm, to a unit root mean square),
+// then scales and shifts each normalized element by the learnable γ and β of its feature.
+//
+// The inputs are x, γ and β (RMSNorm has no β). For LayerNorm and RMSNorm, γ and β have the shape of the normalized trailing axes.
+// For GroupNorm and InstanceNorm, γ and β have one element per channel.
+//
+// Unlike BatchNorm, the statistics are computed over each example on its own, so there are no running statistics,
+// and training and inference compute the same thing. All the statistics are computed in float64 precision, even for float32 inputs.
+type normOp struct {
+	kind   normKind
+	dims   int // dims of the input
+	axes   int // number of trailing axes normalized over. Only used by LayerNorm and RMSNorm
+	groups int // number of groups of channels. 1 for LayerNorm and RMSNorm
+	eps    float64
+}
+
+func newNormOp(kind normKind, shape tensor.Shape, axes, groups int, eps float64) (*normOp, error) {
+	op := &normOp{kind: kind, dims: shape.Dims(), axes: axes, groups: groups, eps: eps}
+	if eps <= 0 {
+		return nil, errors.Errorf("%v expects a positive epsilon. Got %v", kind, eps)
+	}
+	switch kind {
+	case layerNorm, rmsNorm:
+		if axes <= 0 || axes > op.dims {
+			return nil, errors.Errorf("Cannot normalize over the last %d axes of a tensor of shape %v", axes, shape)
+		}
+		op.groups = 1
+	case instanceNorm:
+		if op.dims < 3 {
+			return nil, errors.Errorf("%v expects a (N, C, ...) input. Got %v", kind, shape)
+		}
+		op.groups = shape[1]
+	case groupNorm:
+		if op.dims < 3 {
+			return nil, errors.Errorf("%v expects a (N, C, ...) input. Got %v", kind, shape)
+		}
+		if groups <= 0 || shape[1]%groups != 0 {
+			return nil, errors.Errorf("Cannot split %d channels into %d groups", shape[1], groups)
+		}
+	default:
+		return nil, errors.Errorf("Unknown normalization %v", kind)
+	}
+	return op, nil
+}
+
+// Arity ...
+func (op *normOp) Arity() int {
+	if op.kind == rmsNorm {
+		return 2
+	}
+	return 3
+}
+
+// normOp has this type:
+//		op :: Tensor-d a → Tensor-k a → Tensor-k a → Tensor-d a
+// where k is the number of normalized axes for LayerNorm, and 1 for GroupNorm and InstanceNorm. RMSNorm has no β.
+func (op *normOp) Type() hm.Type {
+	x, param := op.types()
+	if op.kind == rmsNorm {
+		return hm.NewFnType(x, param, x)
+	}
+	return hm.NewFnType(x, param, param, x)
+}
+
+// InferShape ...
+func (op *normOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "norm")
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	if _, _, _, err := op.layout(s); err != nil {
+		return nil, err
+	}
+	return s.Clone(), nil
+}
+
+// Do ...
+func (op *normOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "norm Do")
+	}
+	var out value.Value
+	if out, err = value.CloneValue(values[0]); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values...)
+}
+
+// ReturnsPtr ...
+func (op *normOp) ReturnsPtr() bool { return true }
+
+// CallsExtern ...
+func (op *normOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *normOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *normOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *normOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *normOp) String() string {
+	switch op.kind {
+	case groupNorm:
+		return fmt.Sprintf("%v{%d, %v}", op.kind, op.groups, op.eps)
+	case instanceNorm:
+		return fmt.Sprintf("%v{%v}", op.kind, op.eps)
+	}
+	return fmt.Sprintf("%v{%d, %v}", op.kind, op.axes, op.eps)
+}
+
+// DiffWRT ...
+func (op *normOp) DiffWRT(inputs int) []bool {
+	retVal := make([]bool, inputs)
+	for i := range retVal {
+		retVal[i] = true
+	}
+	return retVal
+}
+
+// SymDiff ...
+func (op *normOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	retVal = make(Nodes, len(inputs))
+	for i := range inputs {
+		diff := &normDiffOp{normOp: op, wrt: i}
+		if retVal[i], err = ApplyOp(diff, inputs[0], inputs[1], grad); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// DoDiff ...
+func (op *normOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	xdv, scaledv := getDV(inputs[0], inputs[1])
+	_, ydv := getDV(inputs[0], output)
+
+	for i := range inputs {
+		_, dv := getDV(inputs[0], inputs[i])
+		diff := &normDiffOp{normOp: op, wrt: i}
+		if _, err = diff.UsePreallocDo(dv.D, xdv.Value, scaledv.Value, ydv.D); err != nil {
+			return errors.Wrapf(err, doFail, diff)
+		}
+	}
+	return nil
+}
+
+// UsePreallocDo ...
+func (op *normOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "norm UsePreallocDo")
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	var x, scale, bias []float64
+	var rows, size, inner int
+	if x, scale, rows, size, inner, err = op.checkInput(inputs[0], inputs[1]); err != nil {
+		return nil, err
+	}
+	if op.kind != rmsNorm {
+		if bias, err = op.paramOf(inputs[2], "β", len(scale)); err != nil {
+			return nil, err
+		}
+	}
+
+	xhat, _ := op.normalize(x, rows, size)
+	for i, v := range xhat {
+		p := op.param(i, size, inner)
+		xhat[i] = scale[p] * v
+		if bias != nil {
+			xhat[i] += bias[p]
+		}
+	}
+	if err = setFloat64s(out, xhat); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// types returns the types of the input and of γ and β
+func (op *normOp) types() (x, param hm.Type) {
+	a := hm.TypeVariable('a')
+	x = constructor.NewTensorType(op.dims, a)
+	switch op.kind {
+	case layerNorm, rmsNorm:
+		param = constructor.NewTensorType(op.axes, a)
+	default:
+		param = constructor.NewTensorType(1, a)
+	}
+	return
+}
+
+// layout returns how the input is split into groups of elements that are normalized together:
+// there are rows groups of size elements each, and the elements of a group are contiguous.
+// The elements of a group that share the same γ and β come in runs of inner elements.
+func (op *normOp) layout(s tensor.Shape) (rows, size, inner int, err error) {
+	if s.Dims() != op.dims {
+		return 0, 0, 0, errors.Errorf("%v expects an input with %d dimensions. Got %v", op, op.dims, s)
+	}
+	total := s.TotalSize()
+	switch op.kind {
+	case layerNorm, rmsNorm:
+		size = tensor.Shape(s[op.dims-op.axes:]).TotalSize()
+		return total / size, size, 1, nil
+	}
+	if s[1]%op.groups != 0 {
+		return 0, 0, 0, errors.Errorf("Cannot split %d channels into %d groups", s[1], op.groups)
+	}
+	inner = tensor.Shape(s[2:]).TotalSize()
+	size = s[1] / op.groups * inner
+	return total / size, size, inner, nil
+}
+
+// paramShape returns the expected shape of γ and β for an input of shape s.
+func (op *normOp) paramShape(s tensor.Shape) tensor.Shape {
+	switch op.kind {
+	case layerNorm, rmsNorm:
+		return tensor.Shape(s[op.dims-op.axes:]).Clone()
+	}
+	return tensor.Shape{s[1]}
+}
+
+// param returns the index into γ and β of the ith element of the input.
+func (op *normOp) param(i, size, inner int) int {
+	row, j := i/size, i%size
+	return ((row%op.groups)*size + j) / inner
+}
+
+func (op *normOp) checkInput(xv, scalev value.Value) (x, scale []float64, rows, size, inner int, err error) {
+	xt, ok := xv.(tensor.Tensor)
+	if !ok {
+		err = errors.Errorf("Expected input to be a tensor. Got %T instead", xv)
+		return
+	}
+	if rows, size, inner, err = op.layout(xt.Shape()); err != nil {
+		return
+	}
+	if x, err = float64sOf(xt); err != nil {
+		return
+	}
+	scale, err = op.paramOf(scalev, "γ", op.paramShape(xt.Shape()).TotalSize())
+	return
+}
+
+func (op *normOp) paramOf(v value.Value, name string, size int) ([]float64, error) {
+	t, ok := v.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected %s to be a tensor. Got %T instead", name, v)
+	}
+	if t.Shape().TotalSize() != size {
+		return nil, errors.Errorf("%v expects %s to have %d elements. Got %v", op, name, size, t.Shape())
+	}
+	return float64sOf(t)
+}
+
+// normalize returns the normalized input x̂, and 1/σ of each group.
+func (op *normOp) normalize(x []float64, rows, size int) (xhat, rstd []float64) {
+	xhat = make([]float64, len(x))
+	rstd = make([]float64, rows)
+	n := float64(size)
+	for r := 0; r < rows; r++ {
+		row := x[r*size : (r+1)*size]
+
+		var mean float64
+		if op.kind != rmsNorm {
+			for _, v := range row {
+				mean += v
+			}
+			mean /= n
+		}
+		var variance float64
+		for _, v := range row {
+			variance += (v - mean) * (v - mean)
+		}
+		variance /= n
+
+		rstd[r] = 1 / math.Sqrt(variance+op.eps)
+		for j, v := range row {
+			xhat[r*size+j] = (v - mean) * rstd[r]
+		}
+	}
+	return
+}
+
+// normDiffOp computes the gradient of a normOp with regards to one of its inputs.
+// Its inputs are x, γ and the gradient of the output.
+//
+// With ĝ = g⊙γ, the gradient with regards to the input of each group is
+//	dx = (ĝ - mean(ĝ) - x̂ mean(ĝ⊙x̂)) / σ
+// where mean(ĝ) is left out for RMSNorm. The gradients of γ and β are Σ g⊙x̂ and Σ g, summed over all the elements sharing them.
+type normDiffOp struct {
+	*normOp
+	wrt int // 0 for x, 1 for γ and 2 for β
+}
+
+// Arity ...
+func (op *normDiffOp) Arity() int { return 3 }
+
+// Type ...
+func (op *normDiffOp) Type() hm.Type {
+	x, param := op.types()
+	if op.wrt == 0 {
+		return hm.NewFnType(x, param, x, x)
+	}
+	return hm.NewFnType(x, param, x, param)
+}
+
+// InferShape ...
+func (op *normDiffOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "normDiff")
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	if op.wrt == 0 {
+		return s.Clone(), nil
+	}
+	return ns[1].(tensor.Shape).Clone(), nil
+}
+
+// Do ...
+func (op *normDiffOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "normDiff Do")
+	}
+	// the gradient of β has the shape of γ
+	in := values[0]
+	if op.wrt > 0 {
+		in = values[1]
+	}
+	var out value.Value
+	if out, err = value.CloneValue(in); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values...)
+}
+
+// WriteHash ...
+func (op *normDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *normDiffOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *normDiffOp) String() string { return fmt.Sprintf("%vDiff%d", op.normOp, op.wrt) }
+
+// UsePreallocDo ...
+func (op *normDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "normDiff UsePreallocDo")
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	x, scale, rows, size, inner, err := op.checkInput(inputs[0], inputs[1])
+	if err != nil {
+		return nil, err
+	}
+	gt, ok := inputs[2].(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected grad to be a tensor. Got %T instead", inputs[2])
+	}
+	var g []float64
+	if g, err = float64sOf(gt); err != nil {
+		return nil, err
+	}
+	if len(g) != len(x) {
+		return nil, errors.Errorf("Expected the gradient to have %d elements. Got %v", len(x), gt.Shape())
+	}
+
+	xhat, rstd := op.normalize(x, rows, size)
+	var grads []float64
+	switch op.wrt {
+	case 0:
+		grads = op.dx(xhat, rstd, scale, g, size, inner)
+	case 1, 2:
+		grads = make([]float64, len(scale))
+		for i, v := range g {
+			if op.wrt == 1 {
+				v *= xhat[i]
+			}
+			grads[op.param(i, size, inner)] += v
+		}
+	default:
+		return nil, errors.Errorf("%v has no input %d", op.normOp, op.wrt)
+	}
+	if err = setFloat64s(out, grads); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (op *normDiffOp) dx(xhat, rstd, scale, g []float64, size, inner int) []float64 {
+	dx := make([]float64, len(xhat))
+	n := float64(size)
+	for r := range rstd {
+		start := r * size
+
+		var meanG, meanGX float64
+		for j := 0; j < size; j++ {
+			i := start + j
+			dxhat := g[i] * scale[op.param(i, size, inner)]
+			dx[i] = dxhat
+			meanG += dxhat
+			meanGX += dxhat * xhat[i]
+		}
+		meanG /= n
+		meanGX /= n
+		if op.kind == rmsNorm {
+			meanG = 0
+		}
+
+		for j := 0; j < size; j++ {
+			i := start + j
+			dx[i] = (dx[i] - meanG - xhat[i]*meanGX) * rstd[r]
+		}
+	}
+	return dx
+}
+
+// setFloat64s writes data into a float tensor, converting it to float32 if need be.
+func setFloat64s(out *tensor.Dense, data []float64) error {
+	switch out.Dtype() {
+	case Float64:
+		copy(out.Float64s(), data)
+	case Float32:
+		f32s := out.Float32s()
+		for i, v := range data {
+			f32s[i] = float32(v)
+		}
+	default:
+		return nyi("setFloat64s", out.Dtype())
+	}
+	return nil
+}
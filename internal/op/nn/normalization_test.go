@@ -0,0 +1,80 @@
+package nnops
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+func TestNormOp(t *testing.T) {
+	normTests := []struct {
+		kind         normKind
+		shape        tensor.Shape
+		axes, groups int
+		paramShape   tensor.Shape
+	}{
+		{layerNorm, tensor.Shape{2, 3, 4}, 2, 0, tensor.Shape{3, 4}},
+		{rmsNorm, tensor.Shape{2, 3, 4}, 1, 0, tensor.Shape{4}},
+		{groupNorm, tensor.Shape{2, 6, 5}, 0, 3, tensor.Shape{6}},
+		{instanceNorm, tensor.Shape{2, 3, 2, 2}, 0, 0, tensor.Shape{3}},
+	}
+
+	for _, nt := range normTests {
+		op, err := newNormOp(nt.kind, nt.shape, nt.axes, nt.groups, 1e-5)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		xData := newSines(3, 1.7).next(nt.shape.TotalSize())
+		gData := newSines(1, 0.9).next(len(xData))
+		x := tensor.New(tensor.WithShape(nt.shape...), tensor.WithBacking(xData))
+		grad := tensor.New(tensor.WithShape(nt.shape...), tensor.WithBacking(gData))
+
+		scaleData := make([]float64, nt.paramShape.TotalSize())
+		biasData := make([]float64, len(scaleData))
+		for i := range scaleData {
+			scaleData[i] = 0.5 + 0.3*float64(i)
+			biasData[i] = 0.1 * float64(i)
+		}
+		scale := tensor.New(tensor.WithShape(nt.paramShape...), tensor.WithBacking(scaleData))
+		bias := tensor.New(tensor.WithShape(nt.paramShape...), tensor.WithBacking(biasData))
+		inputs := []value.Value{x, scale, bias}[:op.Arity()]
+
+		// the gradients are checked against the numerical gradients of Σ y⊙grad
+		loss := func() float64 {
+			y, err := op.Do(inputs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sum float64
+			for i, v := range y.Data().([]float64) {
+				sum += v * gData[i]
+			}
+			return sum
+		}
+
+		for wrt, in := range inputs {
+			diff := &normDiffOp{normOp: op, wrt: wrt}
+			d, err := diff.Do(x, scale, grad)
+			if err != nil {
+				t.Errorf("%v: %v", diff, err)
+				continue
+			}
+			const eps = 1e-6
+			data := in.(*tensor.Dense).Float64s()
+			for i, v := range d.Data().([]float64) {
+				orig := data[i]
+				data[i] = orig + eps
+				lp := loss()
+				data[i] = orig - eps
+				lm := loss()
+				data[i] = orig
+				if ng := (lp - lm) / (2 * eps); math.Abs(ng-v) > 1e-6 {
+					t.Errorf("%v: gradient %d - expected %v. Got %v", diff, i, ng, v)
+				}
+			}
+		}
+	}
+}
@@ -3,20 +3,14 @@ package nnops
 import (
 	"fmt"
 	"hash"
-	"time"
 
 	"github.com/chewxy/hm"
-	rng "github.com/leesper/go_rng"
 	"github.com/pkg/errors"
-	"gonum.org/v1/gonum/blas"
-	"gorgonia.org/gorgonia/distro"
 	"gorgonia.org/gorgonia/internal/constructor"
 	"gorgonia.org/gorgonia/internal/execution"
 	"gorgonia.org/gorgonia/internal/value"
 	"gorgonia.org/gorgonia/ops"
 	"gorgonia.org/tensor"
-	"gorgonia.org/vecf32"
-	"gorgonia.org/vecf64"
 )
 
 var (
@@ -24,8 +18,6 @@ var (
 	_ ops.Op = col2imOp{}
 	_ ops.Op = &maxPoolOp{}
 	_ ops.Op = &maxPoolDiffOp{}
-	_ ops.Op = &BatchNormOp{}
-	_ ops.Op = &batchnormDiffOp{}
 )
 
 /*
@@ -37,30 +29,31 @@ var (
 	See also: nn.go for functions that relate to neural networks
 */
 
-type Randomness byte
-
-const (
-	Uniform Randomness = iota
-	Gaussian
-	Binomial
-)
-
+// RandomOp draws a scalar or a tensor from a distribution. It has no inputs.
+//
+// The numbers are drawn from a RandomStream, so a graph whose RandomOps share a stream with a fixed seed is reproducible.
 type RandomOp struct {
-	which randomness
-	shape tensor.Shape
-	dt    tensor.Dtype
+	which  Randomness
+	shape  tensor.Shape
+	dt     tensor.Dtype
+	stream *RandomStream
 
-	a, b float64 // when uniform, a,b = low, high; when gaussian, a,b = mean, stdev
+	a, b float64 // when uniform, a,b = low, high; when gaussian, a,b = mean, stdev; when binomial, a,b = trials, probability
 }
 
-func MakeRandomOp(which Randomness, dt tensor.Dtype, a, b float64, shape ...int) RandomOp {
-	return RandomOp{
-		which: which,
-		shape: tensor.Shape(shape),
-		dt:    dt,
-		a:     a,
-		b:     b,
+// MakeRandomOp creates a RandomOp drawing from stream. The stream is required, so that what the op draws only depends on a seed the caller chose.
+func MakeRandomOp(which Randomness, dt tensor.Dtype, a, b float64, stream *RandomStream, shape ...int) (RandomOp, error) {
+	if stream == nil {
+		return RandomOp{}, errors.Errorf("A RandomOp needs a RandomStream to draw from. Got nil")
 	}
+	return RandomOp{
+		which:  which,
+		shape:  tensor.Shape(shape),
+		dt:     dt,
+		stream: stream,
+		a:      a,
+		b:      b,
+	}, nil
 }
 
 func (op RandomOp) Arity() int { return 0 }
@@ -78,68 +71,38 @@ func (op RandomOp) Type() hm.Type {
 func (op RandomOp) InferShape(...ops.DimSizer) (tensor.Shape, error) { return op.shape, nil }
 
 func (op RandomOp) Do(...value.Value) (retVal value.Value, err error) {
+	if op.stream == nil {
+		return nil, errors.Errorf("%v has no RandomStream. Use MakeRandomOp to create it", op)
+	}
+	data := make([]float64, op.shape.TotalSize())
+	if op.shape.IsScalar() {
+		data = make([]float64, 1)
+	}
+	if err = op.stream.draw(op.which, op.a, op.b, data); err != nil {
+		return nil, err
+	}
+
 	if op.shape.IsScalar() {
 		var v interface{}
 		switch op.dt {
 		case Float64:
-			switch op.which {
-			case uniform:
-				rand := rng.NewUniformGenerator(time.Now().UnixNano())
-				v = rand.Float64Range(op.a, op.b)
-			case gaussian:
-				rand := rng.NewGaussianGenerator(time.Now().UnixNano())
-				v = rand.Gaussian(op.a, op.b)
-			case binomial:
-				rand := rng.NewBinomialGenerator(time.Now().UnixNano())
-				v = float64(rand.Binomial(int64(op.a), op.b))
-			}
+			v = data[0]
 		case Float32:
-			switch op.which {
-			case uniform:
-				rand := rng.NewUniformGenerator(time.Now().UnixNano())
-				v = rand.Float32Range(float32(op.a), float32(op.b))
-			case gaussian:
-				rand := rng.NewGaussianGenerator(time.Now().UnixNano())
-				v = float32(rand.Gaussian(op.a, op.b))
-			case binomial:
-				rand := rng.NewBinomialGenerator(time.Now().UnixNano())
-				v = float32(rand.Binomial(int64(op.a), op.b))
-			}
+			v = float32(data[0])
 		default:
 			return nil, errors.Errorf(nyiFail, "RandomOp.do()", op.dt)
 		}
-
 		retVal, _ = value.AnyToScalar(v)
 		return
 	}
 
 	switch op.dt {
-	case Float64:
-		switch op.which {
-		case uniform:
-			backing := distro.Uniform64(op.a, op.b, op.shape...)
-			retVal = tensor.New(tensor.WithBacking(backing), tensor.WithShape(op.shape...))
-		case gaussian:
-			backing := distro.Gaussian64(op.a, op.b, op.shape...)
-			retVal = tensor.New(tensor.WithBacking(backing), tensor.WithShape(op.shape...))
-		case binomial:
-			backing := distro.Binomial64(op.a, op.b, op.shape...)
-			retVal = tensor.New(tensor.WithBacking(backing), tensor.WithShape(op.shape...))
+	case Float64, Float32:
+		t := tensor.New(tensor.Of(op.dt), tensor.WithShape(op.shape...))
+		if err = setFloat64s(t, data); err != nil {
+			return nil, err
 		}
-		return
-	case Float32:
-		switch op.which {
-		case uniform:
-			backing := distro.Uniform32(op.a, op.b, op.shape...)
-			retVal = tensor.New(tensor.WithBacking(backing), tensor.WithShape(op.shape...))
-		case gaussian:
-			backing := distro.Gaussian32(op.a, op.b, op.shape...)
-			retVal = tensor.New(tensor.WithBacking(backing), tensor.WithShape(op.shape...))
-		case binomial:
-			backing := distro.Binomial32(op.a, op.b, op.shape...)
-			retVal = tensor.New(tensor.WithBacking(backing), tensor.WithShape(op.shape...))
-		}
-		return
+		return t, nil
 	default:
 		return nil, errors.Errorf(nyiFail, "RandomOp.do() for non-scalar", op.dt)
 	}
@@ -148,6 +111,7 @@ func (op RandomOp) Do(...value.Value) (retVal value.Value, err error) {
 func (op RandomOp) ReturnsPtr() bool     { return false }
 func (op RandomOp) CallsExtern() bool    { return false }
 func (op RandomOp) OverwritesInput() int { return -1 }
+func (op RandomOp) IsStateful() bool     { return true }
 func (op RandomOp) WriteHash(h hash.Hash) {
 	fmt.Fprintf(h, "%d%v%f%f", op.which, op.shape, op.a, op.b)
 }
@@ -366,10 +330,12 @@ func (op im2colOp) do(prealloc, input value.Value) (retVal value.Value, err erro
 }
 
 func (op im2colOp) f64s(chans, height, width, chanStride, inRowStride, retHeight, retWidth int, im, col []float64) {
+	// col is laid out as (retHeight, retWidth, chans*kernelHeight*kernelWidth), which is what calcShape promises.
 	var colIdx int
-	for ch := 0; ch < chans; ch, im = ch+1, im[chanStride:] {
-		for r := 0; r < retHeight; r++ {
-			for c := 0; c < retWidth; c++ {
+	for r := 0; r < retHeight; r++ {
+		for c := 0; c < retWidth; c++ {
+			for ch := 0; ch < chans; ch++ {
+				chanStart := ch * chanStride
 				for kr := 0; kr < op.h; kr++ {
 					inRow := -op.padH + kr*op.dilationH + r*op.strideH
 					for kc := 0; kc < op.w; kc++ {
@@ -377,12 +343,10 @@ func (op im2colOp) f64s(chans, height, width, chanStride, inRowStride, retHeight
 						var val float64
 
 						switch {
-						case inRow < 0:
-						case inCol < 0:
-						case inRow*inRowStride+inCol >= len(im):
-						case inCol >= inRowStride:
+						case inRow < 0, inRow >= height:
+						case inCol < 0, inCol >= width:
 						default:
-							val = im[inRow*inRowStride+inCol]
+							val = im[chanStart+inRow*inRowStride+inCol]
 						}
 
 						col[colIdx] = val
@@ -395,10 +359,12 @@ func (op im2colOp) f64s(chans, height, width, chanStride, inRowStride, retHeight
 }
 
 func (op im2colOp) f32s(chans, height, width, chanStride, inRowStride, retHeight, retWidth int, im, col []float32) {
+	// col is laid out as (retHeight, retWidth, chans*kernelHeight*kernelWidth), which is what calcShape promises.
 	var colIdx int
-	for ch := 0; ch < chans; ch, im = ch+1, im[chanStride:] {
-		for r := 0; r < retHeight; r++ {
-			for c := 0; c < retWidth; c++ {
+	for r := 0; r < retHeight; r++ {
+		for c := 0; c < retWidth; c++ {
+			for ch := 0; ch < chans; ch++ {
+				chanStart := ch * chanStride
 				for kr := 0; kr < op.h; kr++ {
 					inRow := -op.padH + kr*op.dilationH + r*op.strideH
 					for kc := 0; kc < op.w; kc++ {
@@ -406,12 +372,10 @@ func (op im2colOp) f32s(chans, height, width, chanStride, inRowStride, retHeight
 						var val float32
 
 						switch {
-						case inRow < 0:
-						case inCol < 0:
-						case inRow*inRowStride+inCol >= len(im):
-						case inCol >= inRowStride:
+						case inRow < 0, inRow >= height:
+						case inCol < 0, inCol >= width:
 						default:
-							val = im[inRow*inRowStride+inCol]
+							val = im[chanStart+inRow*inRowStride+inCol]
 						}
 
 						col[colIdx] = val
@@ -539,6 +503,8 @@ func (op col2imOp) do(prealloc, input value.Value) (retVal value.Value, err erro
 	return prealloc, nil
 }
 
+// f64s is the inverse of im2colOp.f64s: every column element is accumulated back onto the pixel it was read from.
+// height and width are the dimensions of the image, while retHeight and retWidth are the spatial dimensions of col.
 func (op col2imOp) f64s(chans, height, width, chanStride, imRowStride, retHeight, retWidth int, col, im []float64) {
 	// memset im to 0
 	for i := range im {
@@ -546,82 +512,60 @@ func (op col2imOp) f64s(chans, height, width, chanStride, imRowStride, retHeight
 	}
 
 	var colIdx int
-	for ch := chans; ch > 0; ch, im = ch-1, im[chanStride:] {
-		for kernelRow := 0; kernelRow < op.h; kernelRow++ {
-			for kernelCol := 0; kernelCol < op.w; kernelCol++ {
-				inRow := -op.padH + kernelRow*op.dilationH
-				for outRow := retHeight; outRow > 0; outRow-- {
-					if !(inRow >= 0 && inRow < height) {
-						colIdx += retWidth
-					} else {
-						inCol := -op.padW + kernelCol*op.dilationW
-						for outCol := retWidth; outCol > 0; outCol-- {
-							if inCol >= 0 && inCol < width {
-								im[inRow*width+inCol] += col[colIdx]
-							}
-							colIdx++
-							inCol += op.strideW
+	for r := 0; r < retHeight; r++ {
+		for c := 0; c < retWidth; c++ {
+			for ch := 0; ch < chans; ch++ {
+				chanStart := ch * chanStride
+				for kr := 0; kr < op.h; kr++ {
+					inRow := -op.padH + kr*op.dilationH + r*op.strideH
+					for kc := 0; kc < op.w; kc++ {
+						inCol := -op.padW + kc*op.dilationW + c*op.strideW
+
+						switch {
+						case inRow < 0, inRow >= height:
+						case inCol < 0, inCol >= width:
+						default:
+							im[chanStart+inRow*imRowStride+inCol] += col[colIdx]
 						}
+
+						colIdx++
 					}
-					inRow += op.strideH
 				}
 			}
 		}
 	}
 }
+
+// f32s is the inverse of im2colOp.f32s. See f64s for the meaning of the parameters.
 func (op col2imOp) f32s(chans, height, width, chanStride, imRowStride, retHeight, retWidth int, col, im []float32) {
 	// memset im to 0
 	for i := range im {
 		im[i] = 0
 	}
+
 	var colIdx int
-	for ch := 0; ch < chans; ch, im = ch+1, im[chanStride:] {
-		for r := 0; r < retHeight; r++ {
-			for c := 0; c < retWidth; c++ {
+	for r := 0; r < retHeight; r++ {
+		for c := 0; c < retWidth; c++ {
+			for ch := 0; ch < chans; ch++ {
+				chanStart := ch * chanStride
 				for kr := 0; kr < op.h; kr++ {
 					inRow := -op.padH + kr*op.dilationH + r*op.strideH
 					for kc := 0; kc < op.w; kc++ {
 						inCol := -op.padW + kc*op.dilationW + c*op.strideW
 
 						switch {
-						case inRow < 0:
-						case inCol < 0:
-						case inRow*imRowStride+inCol >= len(im):
-						case inCol >= imRowStride:
+						case inRow < 0, inRow >= height:
+						case inCol < 0, inCol >= width:
 						default:
-							im[inRow*imRowStride+inCol] += col[colIdx]
+							im[chanStart+inRow*imRowStride+inCol] += col[colIdx]
 						}
 
 						colIdx++
 					}
-
 				}
-
 			}
 		}
 	}
-	// for ch := chans; ch > 0; ch, im = ch-1, im[chanStride:] {
-	// 	for kernelRow := 0; kernelRow < op.h; kernelRow++ {
-	// 		for kernelCol := 0; kernelCol < op.w; kernelCol++ {
-	// 			inRow := -op.padH + kernelRow*op.dilationH
-	// 			for outRow := retHeight; outRow > 0; outRow-- {
-	// 				if !(inRow >= 0 && inRow < height) {
-	// 					colIdx += retWidth
-	// 				} else {
-	// 					inCol := -op.padW + kernelCol*op.dilationW
-	// 					for outCol := retWidth; outCol > 0; outCol-- {
-	// 						if inCol >= 0 && inCol < width {
-	// 							im[inRow*width+inCol] += col[colIdx]
-	// 						}
-	// 						colIdx++
-	// 						inCol += op.strideW
-	// 					}
-	// 				}
-	// 				inRow += op.strideH
-	// 			}
-	// 		}
-	// 	}
-	// }
 }
 
 // It's important to note that this op actually produces TWO values - one argmax, which will be used
@@ -1095,518 +1039,3 @@ func (op *clampOp) WriteHash(h hash.Hash) { fmt.Fprintf(h, "ConstClamp{%f, %f}()
 
 func (op *clampOp) Hashcode() uint32 { return simpleHash(op) }
 func (op *clampOp) String() string   { return fmt.Sprintf("ConstClamp{%f, %f}()", op.min, op.max) }
-
-// BatchNormOp is a batch normalization process as described by Ioffe and Szegedy (2015) -
-// http://arxiv.org/abs/1502.03167
-//
-// Normalization is done as:
-// 	γ(x - μ) / σ + β
-// γ is the scaling factor and β is the offset factor. These are created by BatchNorm()
-type BatchNormOp struct {
-	momentum float64 // momentum for the moving average
-	epsilon  float64 // small variance to be added to avoid dividing by 0
-
-	// learnables
-	mean, variance, ma *tensor.Dense
-
-	// scratch space
-	meanT, varianceT, tmpT, xNorm                        *tensor.Dense
-	batchSumMultiplier, numByChans, spatialSumMultiplier *tensor.Dense
-
-	// training? if training then update movingMean and movingVar
-	training bool
-}
-
-// Arity ...
-func (op *BatchNormOp) Arity() int { return 1 }
-
-// Type ...
-func (op *BatchNormOp) Type() hm.Type {
-	t := constructor.TensorType{Dims: 4, Of: hm.TypeVariable('a')}
-	return hm.NewFnType(t, t)
-}
-
-// InferShape ...
-func (op *BatchNormOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
-	if err := ops.CheckArity(op, len(ns)); err != nil {
-		return nil, errors.Wrapf(err, "batchNorm")
-	}
-
-	return ns[0].(tensor.Shape).Clone(), nil
-}
-
-// Do ...
-func (op *BatchNormOp) Do(values ...value.Value) (retVal value.Value, err error) {
-	if err := ops.CheckArity(op, len(values)); err != nil {
-		return nil, errors.Wrapf(err, "batchNorm Do")
-	}
-	var v, out value.Value
-	v = values[0]
-	if out, err = value.CloneValue(v); err != nil {
-		return nil, err
-	}
-	return op.UsePreallocDo(out, v)
-}
-
-// ReturnsPtr ...
-func (op *BatchNormOp) ReturnsPtr() bool { return true }
-
-// CallsExtern ...
-func (op *BatchNormOp) CallsExtern() bool { return false }
-
-// OverwritesInput ...
-func (op *BatchNormOp) OverwritesInput() int { return -1 }
-
-// WriteHash ...
-func (op *BatchNormOp) WriteHash(h hash.Hash) {
-	fmt.Fprintf(h, "batchnorm-%1.1f-%1.1f", op.momentum, op.epsilon)
-}
-
-// Hashcode ...
-func (op *BatchNormOp) Hashcode() uint32 { return simpleHash(op) }
-
-func (op *BatchNormOp) String() string {
-	return fmt.Sprintf("batchnorm-%1.1f-%1.1f", op.momentum, op.epsilon)
-}
-
-// DoDiff ...
-func (op *BatchNormOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) error {
-	diff := &batchnormDiffOp{op}
-	xdv, ydv := getDV(inputs[0], output)
-	_, err := diff.UsePreallocDo(xdv.D, xdv.Value, ydv.D)
-	return err
-}
-
-// DiffWRT ...
-func (op *BatchNormOp) DiffWRT(inputs int) []bool { return []bool{true} }
-
-// SymDiff ...
-func (op *BatchNormOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
-	if err = ops.CheckArity(op, len(inputs)); err != nil {
-		return
-	}
-	input := inputs[0]
-	diff := &batchnormDiffOp{op}
-
-	var ret *Node
-	if ret, err = ApplyOp(diff, input, grad); err != nil {
-		return nil, err
-	}
-	return Nodes{ret}, nil
-}
-
-// UsePreallocDo ...
-func (op *BatchNormOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
-	v := inputs[0]
-	switch v.Dtype() {
-	case Float64:
-		err = op.f64s(v.(*tensor.Dense), prealloc.(*tensor.Dense))
-	case Float32:
-		err = op.f32s(v.(*tensor.Dense), prealloc.(*tensor.Dense))
-	default:
-		return nil, nyi("BatchNorm Do", v.Dtype())
-	}
-	return prealloc, err
-}
-
-// SetTraining ...
-func (op *BatchNormOp) SetTraining() { op.Reset(); op.training = true }
-
-// SetTesting ...
-func (op *BatchNormOp) SetTesting() { op.training = false }
-
-// Reset ...
-func (op *BatchNormOp) Reset() error {
-	dt := op.ma.Dtype()
-	var uno interface{}
-	switch dt {
-	case Float64:
-		uno = float64(1)
-	case Float32:
-		uno = float32(1)
-	}
-
-	if err := op.spatialSumMultiplier.Memset(uno); err != nil {
-		return err
-	}
-
-	if err := op.batchSumMultiplier.Memset(uno); err != nil {
-		return err
-	}
-
-	op.mean.Zero()
-	op.variance.Zero()
-	op.ma.Zero()
-	op.meanT.Zero()
-	op.varianceT.Zero()
-	op.tmpT.Zero()
-	op.numByChans.Zero()
-	return nil
-}
-
-func (op *BatchNormOp) f64s(input, output *tensor.Dense) (err error) {
-	n := input.Shape()[0]
-	channels := input.Shape()[1]
-	nc := channels * n
-	spatialDim := input.Shape().TotalSize() / (nc)
-
-	inputF64s := input.Float64s()
-	outputF64s := output.Float64s()
-	copy(outputF64s, inputF64s)
-
-	meanT := op.meanT.Float64s()
-	mean := op.mean.Float64s()
-	varianceT := op.varianceT.Float64s()
-	variance := op.variance.Float64s()
-	tmp := op.tmpT.Float64s()
-	ssm := op.spatialSumMultiplier.Float64s()
-	nbc := op.numByChans.Float64s()
-	bsm := op.batchSumMultiplier.Float64s()
-
-	momentum := op.momentum
-	eps := op.epsilon
-
-	if !op.training {
-		// use stored mean/variance estimates
-		scaleFactor := float64(1)
-		if fst := op.ma.Float64s()[0]; fst != 1 {
-			scaleFactor = fst
-		}
-		copy(meanT, mean)
-		whichblas.Dscal(len(meanT), scaleFactor, meanT, 1)
-		copy(varianceT, variance)
-		whichblas.Dscal(len(varianceT), scaleFactor, varianceT, 1)
-	} else {
-		// compute mean
-		alpha := 1.0 / float64(n*spatialDim)
-		whichblas.Dgemv(blas.NoTrans, nc, spatialDim, alpha, inputF64s, spatialDim, ssm, 1, 0, nbc, 1)
-		whichblas.Dgemv(blas.Trans, n, channels, 1, nbc, channels, bsm, 1, 0, meanT, 1)
-	}
-
-	// subtract mean
-	whichblas.Dgemm(blas.NoTrans, blas.NoTrans, n, channels, 1, 1, bsm, 1, meanT, channels, 0, nbc, channels)
-	whichblas.Dgemm(blas.NoTrans, blas.NoTrans, nc, spatialDim, 1, -1, nbc, 1, ssm, spatialDim, 1, outputF64s, spatialDim)
-
-	if op.training {
-		// compute variance using var(X) = E(X-EX)^2)
-		copy(tmp, outputF64s)
-		vecf64.Mul(tmp, tmp) // (X-EX) ^ 2
-
-		whichblas.Dgemv(blas.NoTrans, nc, spatialDim, 1.0/(float64(n*spatialDim)), tmp, spatialDim, ssm, 1, 0, nbc, 1)
-		whichblas.Dgemv(blas.Trans, n, channels, 1.0, nbc, channels, bsm, 1, 0, varianceT, 1) // E((X_EX)^2)
-
-		// compute and save moving average
-		op.ma.Float64s()[0] *= momentum
-		op.ma.Float64s()[0]++
-
-		// TODO: write axpby for gonum
-		whichblas.Dscal(len(mean), momentum, mean, 1)
-		whichblas.Daxpy(len(meanT), 1.0, meanT, 1, mean, 1)
-
-		m := len(inputF64s) / channels
-		correctionFactor := float64(1)
-		if m > 1 {
-			correctionFactor = float64(m) / (float64(m - 1))
-		}
-		whichblas.Dscal(len(variance), momentum, variance, 1)
-		whichblas.Daxpy(len(varianceT), correctionFactor, varianceT, 1, variance, 1)
-	}
-
-	// normalize variance
-	vecf64.Trans(varianceT, eps)
-	vecf64.Sqrt(varianceT)
-
-	// replicate variance to inputsize
-	whichblas.Dgemm(blas.NoTrans, blas.NoTrans, n, channels, 1, 1, bsm, 1, varianceT, channels, 0, nbc, channels)
-	whichblas.Dgemm(blas.NoTrans, blas.NoTrans, nc, spatialDim, 1, 1, nbc, 1, ssm, spatialDim, 0, tmp, spatialDim)
-	vecf64.Div(outputF64s, tmp)
-	copy(op.xNorm.Float64s(), outputF64s) // caching
-
-	return nil
-}
-
-func (op *BatchNormOp) f32s(input, output *tensor.Dense) (err error) {
-	n := input.Shape()[0]
-	channels := input.Shape()[1]
-	nc := channels * n
-	spatialDim := input.Shape().TotalSize() / (nc)
-
-	inputF32s := input.Float32s()
-	outputF32s := output.Float32s()
-	copy(outputF32s, inputF32s)
-
-	meanT := op.meanT.Float32s()
-	mean := op.mean.Float32s()
-	varianceT := op.varianceT.Float32s()
-	variance := op.variance.Float32s()
-	tmp := op.tmpT.Float32s()
-	ssm := op.spatialSumMultiplier.Float32s()
-	nbc := op.numByChans.Float32s()
-	bsm := op.batchSumMultiplier.Float32s()
-
-	momentum := float32(op.momentum)
-	eps := float32(op.epsilon)
-
-	if !op.training {
-		// use stored mean/variance estimates
-		scaleFactor := float32(1)
-		if fst := op.ma.Float32s()[0]; fst != 1 {
-			scaleFactor = fst
-		}
-		copy(meanT, mean)
-		whichblas.Sscal(len(meanT), scaleFactor, meanT, 1)
-		copy(varianceT, variance)
-		whichblas.Sscal(len(varianceT), scaleFactor, varianceT, 1)
-	} else {
-		// compute mean
-		alpha := 1.0 / float32(n*spatialDim)
-		whichblas.Sgemv(blas.NoTrans, nc, spatialDim, alpha, inputF32s, spatialDim, ssm, 1, 0, nbc, 1)
-		whichblas.Sgemv(blas.Trans, n, channels, 1, nbc, channels, bsm, 1, 0, meanT, 1)
-	}
-
-	// subtract mean
-	whichblas.Sgemm(blas.NoTrans, blas.NoTrans, n, channels, 1, 1, bsm, 1, meanT, channels, 0, nbc, channels)
-	whichblas.Sgemm(blas.NoTrans, blas.NoTrans, nc, spatialDim, 1, -1, nbc, 1, ssm, spatialDim, 1, outputF32s, spatialDim)
-
-	if op.training {
-		// compute variance using var(X) = E(X-EX)^2)
-		copy(tmp, outputF32s)
-		vecf32.Mul(tmp, tmp) // (X-EX) ^ 2
-
-		whichblas.Sgemv(blas.NoTrans, nc, spatialDim, 1.0/(float32(n*spatialDim)), tmp, spatialDim, ssm, 1, 0, nbc, 1)
-		whichblas.Sgemv(blas.Trans, n, channels, 1.0, nbc, channels, bsm, 1, 0, varianceT, 1) // E((X_EX)^2)
-
-		// compute and save moving average
-		op.ma.Float32s()[0] *= momentum
-		op.ma.Float32s()[0]++
-
-		// TODO: write axpby for gonum
-		whichblas.Sscal(len(mean), momentum, mean, 1)
-		whichblas.Saxpy(len(meanT), 1.0, meanT, 1, mean, 1)
-
-		m := len(inputF32s) / channels
-		correctionFactor := float32(1)
-		if m > 1 {
-			correctionFactor = float32(m) / (float32(m - 1))
-		}
-		whichblas.Sscal(len(variance), momentum, variance, 1)
-		whichblas.Saxpy(len(varianceT), correctionFactor, varianceT, 1, variance, 1)
-	}
-
-	// normalize variance
-	vecf32.Trans(varianceT, eps)
-	vecf32.Sqrt(varianceT)
-
-	// replicate variance to inputsize
-	whichblas.Sgemm(blas.NoTrans, blas.NoTrans, n, channels, 1, 1, bsm, 1, varianceT, channels, 0, nbc, channels)
-	whichblas.Sgemm(blas.NoTrans, blas.NoTrans, nc, spatialDim, 1, 1, nbc, 1, ssm, spatialDim, 0, tmp, spatialDim)
-	vecf32.Div(outputF32s, tmp)
-	copy(op.xNorm.Float32s(), outputF32s) // caching
-
-	return nil
-}
-
-type batchnormDiffOp struct{ *BatchNormOp }
-
-func (op *batchnormDiffOp) Arity() int { return 2 }
-
-func (op *batchnormDiffOp) Type() hm.Type {
-	t := constructor.TensorType{Dims: 4, Of: hm.TypeVariable('a')}
-	return hm.NewFnType(t, t, t)
-}
-
-func (op *batchnormDiffOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
-	if err := ops.CheckArity(op, len(ns)); err != nil {
-		return nil, errors.Wrapf(err, "batchNorm")
-	}
-
-	return ns[0].(tensor.Shape).Clone(), nil
-}
-
-func (op *batchnormDiffOp) Do(values ...value.Value) (value.Value, error) {
-	input := values[0].(*tensor.Dense)
-	grad := values[1].(*tensor.Dense)
-	inputGrad := input.Clone().(*tensor.Dense)
-	return op.UsePreallocDo(inputGrad, input, grad)
-}
-
-// ReturnsPtr is the same exact characteristics of batchnorm
-// CallsExtern is the same exact characteristics of batchnorm
-// OverwritesInput is the same exact characteristics of batchnorm
-
-func (op *batchnormDiffOp) WriteHash(h hash.Hash) {
-	fmt.Fprintf(h, "batchnormdiff-%1.1f-%1.1f", op.momentum, op.epsilon)
-}
-
-func (op *batchnormDiffOp) Hashcode() uint32 { return simpleHash(op) }
-
-func (op *batchnormDiffOp) String() string {
-	return fmt.Sprintf("batchnormdiff-%1.1f-%1.1f", op.momentum, op.epsilon)
-}
-
-func (op *batchnormDiffOp) DiffWRT(inputs int) []bool {
-	// god help those who want to  do 2nd order differentiation on batchnorm
-	return []bool{false, false}
-}
-
-func (op *batchnormDiffOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
-	// god help those who want to  do 2nd order differentiation on batchnorm
-	return nil, nyi("SymDiff", "batchNormDiffOp")
-}
-
-func (op *batchnormDiffOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) error {
-	// god help those who want to  do 2nd order differentiation on batchnorm
-	return nyi("DoDiff", "batchnormDiffOp")
-}
-
-func (op *batchnormDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
-	input := inputs[0].(*tensor.Dense)
-	inGrad := prealloc.(*tensor.Dense)
-	outGrad := inputs[1].(*tensor.Dense)
-
-	switch input.Dtype() {
-	case Float64:
-		err = op.f64s(input, inGrad, outGrad)
-	case Float32:
-		err = op.f32s(input, inGrad, outGrad)
-	default:
-		return nil, nyi("batchnormDiffOp", "Do")
-	}
-	return prealloc, err
-}
-
-func (op *batchnormDiffOp) f64s(input, inGrad, outGrad *tensor.Dense) (err error) {
-	in := input.Float64s()
-	ig := inGrad.Float64s()
-	og := outGrad.Float64s()
-	tmp := op.tmpT.Float64s()
-	out := op.xNorm.Float64s()
-	ssm := op.spatialSumMultiplier.Float64s()
-	nbc := op.numByChans.Float64s()
-	bsm := op.batchSumMultiplier.Float64s()
-	meanT := op.meanT.Float64s()
-
-	if !op.training {
-		copy(ig, og)
-		vecf64.Div(og, tmp)
-		return nil
-	}
-
-	n := input.Shape()[0]
-	channels := input.Shape()[1]
-	nc := n * channels
-	spatialDim := len(in) / nc
-
-	// if Y = (X-mean(X))/(sqrt(var(X)+eps)), then
-	//
-	// dE(Y)/dX =
-	//   (dE/dY - mean(dE/dY) - mean(dE/dY \cdot Y) \cdot Y)
-	//     ./ sqrt(var(X) + eps)
-	//
-	// where \cdot and ./ are hadamard product and elementwise division,
-	// respectively, dE/dY is the top diff, and mean/var/sum are all computed
-	// along all dimensions except the channels dimension.  In the above
-	// equation, the operations allow for expansion (i.e. broadcast) along all
-	// dimensions except the channels dimension where required.
-
-	// sum(dE/dY \cdot Y)
-	copy(ig, out)
-	vecf64.Mul(ig, og)
-	whichblas.Dgemv(blas.NoTrans, nc, spatialDim, 1, ig, spatialDim, ssm, 1, 0, nbc, 1)
-	whichblas.Dgemv(blas.Trans, n, channels, 1, nbc, channels, bsm, 1, 0, meanT, 1)
-
-	// reshape (broadcast) the above
-	whichblas.Dgemm(blas.NoTrans, blas.NoTrans, n, channels, 1, 1, bsm, 1, meanT, channels, 0, nbc, channels)
-	whichblas.Dgemm(blas.NoTrans, blas.NoTrans, nc, spatialDim, 1, 1, nbc, 1, ssm, spatialDim, 0, ig, spatialDim)
-
-	// sum(dE/dY \cdot Y) \cdot Y
-	vecf64.Mul(ig, out)
-
-	// sum(dE/dY)-sum(dE/dY \cdot Y) \cdot Y
-	whichblas.Dgemv(blas.NoTrans, nc, spatialDim, 1, og, spatialDim, ssm, 1, 0, nbc, 1)
-	whichblas.Dgemv(blas.Trans, n, channels, 1, nbc, channels, bsm, 1, 0, meanT, 1)
-
-	// reshape (broadcast) the above to make
-	// sum(dE/dY)-sum(dE/dY \cdot Y) \cdot Y
-	whichblas.Dgemm(blas.NoTrans, blas.NoTrans, n, channels, 1, 1, bsm, 1, meanT, channels, 0, nbc, channels)
-	whichblas.Dgemm(blas.NoTrans, blas.NoTrans, nc, spatialDim, 1, 1, nbc, 1, ssm, spatialDim, 1, ig, spatialDim)
-
-	// dE/dY - mean(dE/dY)-mean(dE/dY \cdot Y) \cdot Y
-	beta := (-1.0 / float64(nc))
-	vecf64.Scale(ig, beta)
-	vecf64.Add(ig, og)
-
-	// note: temp_ still contains sqrt(var(X)+eps), computed during the forward
-	// pass.
-	vecf64.Div(ig, tmp)
-	return nil
-
-}
-
-func (op *batchnormDiffOp) f32s(input, inGrad, outGrad *tensor.Dense) (err error) {
-	in := input.Float32s()
-	ig := inGrad.Float32s()
-	og := outGrad.Float32s()
-	tmp := op.tmpT.Float32s()
-	out := op.xNorm.Float32s()
-	ssm := op.spatialSumMultiplier.Float32s()
-	nbc := op.numByChans.Float32s()
-	bsm := op.batchSumMultiplier.Float32s()
-	meanT := op.meanT.Float32s()
-
-	if !op.training {
-		copy(ig, og)
-		vecf32.Div(og, tmp)
-		return nil
-	}
-
-	n := input.Shape()[0]
-	channels := input.Shape()[1]
-	nc := n * channels
-	spatialDim := len(in) / nc
-
-	// if Y = (X-mean(X))/(sqrt(var(X)+eps)), then
-	//
-	// dE(Y)/dX =
-	//   (dE/dY - mean(dE/dY) - mean(dE/dY \cdot Y) \cdot Y)
-	//     ./ sqrt(var(X) + eps)
-	//
-	// where \cdot and ./ are hadamard product and elementwise division,
-	// respectively, dE/dY is the top diff, and mean/var/sum are all computed
-	// along all dimensions except the channels dimension.  In the above
-	// equation, the operations allow for expansion (i.e. broadcast) along all
-	// dimensions except the channels dimension where required.
-
-	// sum(dE/dY \cdot Y)
-	copy(ig, out)
-	vecf32.Mul(ig, og)
-	whichblas.Sgemv(blas.NoTrans, nc, spatialDim, 1, ig, spatialDim, ssm, 1, 0, nbc, 1)
-	whichblas.Sgemv(blas.Trans, n, channels, 1, nbc, channels, bsm, 1, 0, meanT, 1)
-
-	// reshape (broadcast) the above
-	whichblas.Sgemm(blas.NoTrans, blas.NoTrans, n, channels, 1, 1, bsm, 1, meanT, channels, 0, nbc, channels)
-	whichblas.Sgemm(blas.NoTrans, blas.NoTrans, nc, spatialDim, 1, 1, nbc, 1, ssm, spatialDim, 0, ig, spatialDim)
-
-	// sum(dE/dY \cdot Y) \cdot Y
-	vecf32.Mul(ig, out)
-
-	// sum(dE/dY)-sum(dE/dY \cdot Y) \cdot Y
-	whichblas.Sgemv(blas.NoTrans, nc, spatialDim, 1, og, spatialDim, ssm, 1, 0, nbc, 1)
-	whichblas.Sgemv(blas.Trans, n, channels, 1, nbc, channels, bsm, 1, 0, meanT, 1)
-
-	// reshape (broadcast) the above to make
-	// sum(dE/dY)-sum(dE/dY \cdot Y) \cdot Y
-	whichblas.Sgemm(blas.NoTrans, blas.NoTrans, n, channels, 1, 1, bsm, 1, meanT, channels, 0, nbc, channels)
-	whichblas.Sgemm(blas.NoTrans, blas.NoTrans, nc, spatialDim, 1, 1, nbc, 1, ssm, spatialDim, 1, ig, spatialDim)
-
-	// dE/dY - mean(dE/dY)-mean(dE/dY \cdot Y) \cdot Y
-	beta := (-1.0 / float32(n*spatialDim))
-	vecf32.Scale(ig, beta)
-	vecf32.Add(ig, og)
-
-	// note: temp_ still contains sqrt(var(X)+eps), computed during the forward
-	// pass.
-	vecf32.Div(ig, tmp)
-	return nil
-
-}
@@ -0,0 +1,1149 @@
+package nnops
+
+import (
+	"fmt"
+	"hash"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/constructor"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &avgPoolOp{}
+	_ ops.Op = &avgPoolDiffOp{}
+	_ ops.Op = &globalPoolOp{}
+	_ ops.Op = &globalPoolDiffOp{}
+	_ ops.Op = &adaptiveAvgPoolOp{}
+	_ ops.Op = &adaptiveAvgPoolDiffOp{}
+	_ ops.Op = &maxUnpoolOp{}
+	_ ops.Op = &maxUnpoolDiffOp{}
+)
+
+/*
+	This file contains the pooling ops that are not max pooling. The max pooling op lives in op_nn.go.
+
+	All the pooling ops work on tensors in NCHW format.
+*/
+
+// checkPoolInput is the input check shared by all the pooling ops
+func checkPoolInput(op ops.Op, inputs ...value.Value) (tensor.Tensor, error) {
+	if err := ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, err
+	}
+
+	in, ok := inputs[0].(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected input to be a tensor")
+	}
+	if in.Shape().Dims() != 4 {
+		return nil, errors.Errorf("Expected input to have 4 dimensions")
+	}
+	return in, nil
+}
+
+// avgPoolOp is the average pooling operation.
+//
+// When countPad is true, the padded zeroes are counted in the denominator of the average
+// (i.e. every full window is divided by h × w). Otherwise only the elements of the input that
+// are in the window are counted.
+type avgPoolOp struct {
+	// Shape of Input
+	unpaddedB int
+	unpaddedC int
+	unpaddedH int
+	unpaddedW int
+
+	h, w             int // patch height and width
+	padH, padW       int
+	strideH, strideW int
+
+	countPad bool
+}
+
+func newAvgPoolOp(inputShape, kernel tensor.Shape, pad, stride []int, countPad bool) (*avgPoolOp, error) {
+	if err := checkPoolParams(inputShape, kernel, pad, stride); err != nil {
+		return nil, err
+	}
+	return &avgPoolOp{
+		unpaddedB: inputShape[0],
+		unpaddedC: inputShape[1],
+		unpaddedH: inputShape[2],
+		unpaddedW: inputShape[3],
+
+		h:       kernel[0],
+		w:       kernel[1],
+		padH:    pad[0],
+		padW:    pad[1],
+		strideH: stride[0],
+		strideW: stride[1],
+
+		countPad: countPad,
+	}, nil
+}
+
+func checkPoolParams(inputShape, kernel tensor.Shape, pad, stride []int) error {
+	if inputShape.Dims() != 4 {
+		return errors.Errorf("Expected input to have 4 dimensions. Got %v instead", inputShape)
+	}
+	if len(kernel) != 2 || len(pad) != 2 || len(stride) != 2 {
+		return errors.Errorf("Pooling expects 2 dimensional kernel, pad and stride. Got %v, %v, %v", kernel, pad, stride)
+	}
+	if err := CheckConvolutionParams(pad, stride, nil); err != nil {
+		return err
+	}
+	for i, k := range kernel {
+		if k <= 0 {
+			return errors.Errorf("Cannot use kernel of less than or equal 0: %v", kernel)
+		}
+		if pad[i] >= k {
+			return errors.Errorf("Padding %v has to be smaller than the kernel %v", pad, kernel)
+		}
+		if size := inputShape[2+i] + 2*pad[i]; k > size {
+			return errors.Errorf("Kernel %v does not fit in the padded input %v with padding %v", kernel, inputShape, pad)
+		}
+	}
+	return nil
+}
+
+func (op *avgPoolOp) Arity() int { return 1 }
+
+// avgPoolOp has this type:
+// 		op :: Tensor-4 a → Tensor-4 a
+func (op *avgPoolOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	t := constructor.NewTensorType(4, a)
+	return hm.NewFnType(t, t)
+}
+
+func (op *avgPoolOp) InferShape(inputs ...ops.DimSizer) (tensor.Shape, error) {
+	if s, ok := inputs[0].(tensor.Shape); ok {
+		return op.calcShape(s), nil
+	}
+	return nil, errors.Errorf("Expected a shape")
+}
+
+func (op *avgPoolOp) Do(inputs ...value.Value) (retVal value.Value, err error) {
+	var in, out tensor.Tensor
+	if in, err = checkPoolInput(op, inputs...); err != nil {
+		return nil, err
+	}
+	out = tensor.New(tensor.Of(in.Dtype()), tensor.WithShape(op.calcShape(in.Shape())...), tensor.WithEngine(in.Engine()))
+	if err = op.do(out, in); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (op *avgPoolOp) ReturnsPtr() bool      { return false }
+func (op *avgPoolOp) CallsExtern() bool     { return false }
+func (op *avgPoolOp) OverwritesInput() int  { return -1 }
+func (op *avgPoolOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+func (op *avgPoolOp) Hashcode() uint32      { return simpleHash(op) }
+
+func (op *avgPoolOp) String() string {
+	return fmt.Sprintf("AvgPool{%d, %d, %d, %d}(kernel: (%d, %d), pad: (%d, %d), stride: (%d, %d), countPad: %t)",
+		op.unpaddedB, op.unpaddedC, op.unpaddedH, op.unpaddedW,
+		op.h, op.w, op.padH, op.padW, op.strideH, op.strideW, op.countPad)
+}
+
+func (op *avgPoolOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (value.Value, error) {
+	in, err := checkPoolInput(op, inputs...)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := prealloc.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a tensor")
+	}
+	if err = op.do(p, in); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (op *avgPoolOp) DiffWRT(inputs int) []bool { return []bool{true} }
+
+func (op *avgPoolOp) SymDiff(inputs Nodes, output, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	diff := &avgPoolDiffOp{*op}
+
+	var ret *Node
+	if ret, err = ApplyOp(diff, inputs[0], grad); err != nil {
+		return nil, err
+	}
+	return Nodes{ret}, nil
+}
+
+func (op *avgPoolOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	inputDV, outDV := getDV(inputs[0], output)
+	diff := &avgPoolDiffOp{*op}
+	if _, err = diff.UsePreallocDo(inputDV.D, inputDV.Value, outDV.D); err != nil {
+		return errors.Wrapf(err, doFail, diff)
+	}
+	return
+}
+
+// calcShape calculates the output shape given an input shape
+func (op *avgPoolOp) calcShape(s tensor.Shape) tensor.Shape {
+	b, c, h, w := s[0], s[1], s[2], s[3]
+	pooledH := (h+2*op.padH-op.h)/op.strideH + 1
+	pooledW := (w+2*op.padW-op.w)/op.strideW + 1
+	return tensor.Shape{b, c, pooledH, pooledW}
+}
+
+// window returns the bounds of the window in the input for the pooled element (ph, pw), as well as the divisor of the average.
+func (op *avgPoolOp) window(ph, pw, inH, inW int) (hStart, hEnd, wStart, wEnd, poolSize int) {
+	hStart = ph*op.strideH - op.padH
+	wStart = pw*op.strideW - op.padW
+	hEnd = minInt(hStart+op.h, inH+op.padH)
+	wEnd = minInt(wStart+op.w, inW+op.padW)
+	poolSize = (hEnd - hStart) * (wEnd - wStart)
+
+	hStart = maxInt(hStart, 0)
+	wStart = maxInt(wStart, 0)
+	hEnd = minInt(hEnd, inH)
+	wEnd = minInt(wEnd, inW)
+	if !op.countPad {
+		poolSize = (hEnd - hStart) * (wEnd - wStart)
+	}
+	return
+}
+
+// do prepares the data, and then dispatches it to the correct (computation) kernel.
+// out is the preallocated tensor
+func (op *avgPoolOp) do(out, in tensor.Tensor) error {
+	outShape := out.Shape()
+	inShape := in.Shape()
+	b, c, h, w := outShape[0], outShape[1], outShape[2], outShape[3]
+	inH, inW := inShape[2], inShape[3]
+
+	switch in.Dtype() {
+	case tensor.Float64:
+		op.f64s(b, c, h, w, inH, inW, out.Data().([]float64), in.Data().([]float64))
+	case tensor.Float32:
+		op.f32s(b, c, h, w, inH, inW, out.Data().([]float32), in.Data().([]float32))
+	default:
+		return errors.Errorf(nyiFail, "AvgPool", in.Dtype())
+	}
+	return nil
+}
+
+func (op *avgPoolOp) f64s(batches, channels, outH, outW, inH, inW int, outData, inData []float64) {
+	inStride := inH * inW
+	outStride := outH * outW
+	for b := 0; b < batches; b++ {
+		for c := 0; c < channels; c++ {
+			for ph := 0; ph < outH; ph++ {
+				for pw := 0; pw < outW; pw++ {
+					hStart, hEnd, wStart, wEnd, poolSize := op.window(ph, pw, inH, inW)
+					var sum float64
+					for hi := hStart; hi < hEnd; hi++ {
+						for wi := wStart; wi < wEnd; wi++ {
+							sum += inData[hi*inW+wi]
+						}
+					}
+					outData[ph*outW+pw] = sum / float64(poolSize)
+				}
+			}
+			// skip by strides
+			inData = inData[inStride:]
+			outData = outData[outStride:]
+		}
+	}
+}
+
+func (op *avgPoolOp) f32s(batches, channels, outH, outW, inH, inW int, outData, inData []float32) {
+	inStride := inH * inW
+	outStride := outH * outW
+	for b := 0; b < batches; b++ {
+		for c := 0; c < channels; c++ {
+			for ph := 0; ph < outH; ph++ {
+				for pw := 0; pw < outW; pw++ {
+					hStart, hEnd, wStart, wEnd, poolSize := op.window(ph, pw, inH, inW)
+					var sum float32
+					for hi := hStart; hi < hEnd; hi++ {
+						for wi := wStart; wi < wEnd; wi++ {
+							sum += inData[hi*inW+wi]
+						}
+					}
+					outData[ph*outW+pw] = sum / float32(poolSize)
+				}
+			}
+			// skip by strides
+			inData = inData[inStride:]
+			outData = outData[outStride:]
+		}
+	}
+}
+
+// avgPoolDiffOp distributes the gradient of each pooled element evenly over the elements of its window
+type avgPoolDiffOp struct {
+	avgPoolOp
+}
+
+func (op *avgPoolDiffOp) Arity() int { return 2 }
+
+func (op *avgPoolDiffOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	t := constructor.NewTensorType(4, a)
+	return hm.NewFnType(t, t, t)
+}
+
+func (op *avgPoolDiffOp) InferShape(inputs ...ops.DimSizer) (tensor.Shape, error) {
+	s := inputs[0].(tensor.Shape).Clone()
+	return s, nil
+}
+
+func (op *avgPoolDiffOp) Do(inputs ...value.Value) (value.Value, error) {
+	in, pooledGrad, err := op.checkInput(inputs...)
+	if err != nil {
+		return nil, err
+	}
+	out := tensor.New(tensor.Of(in.Dtype()), tensor.WithShape(in.Shape().Clone()...), tensor.WithEngine(in.Engine()))
+	if err = op.do(out, pooledGrad); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (op *avgPoolDiffOp) ReturnsPtr() bool      { return true }
+func (op *avgPoolDiffOp) CallsExtern() bool     { return false }
+func (op *avgPoolDiffOp) OverwritesInput() int  { return -1 }
+func (op *avgPoolDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+func (op *avgPoolDiffOp) Hashcode() uint32      { return simpleHash(op) }
+
+func (op *avgPoolDiffOp) String() string {
+	return fmt.Sprintf("AvgPoolDiff{%d, %d, %d, %d}(kernel: (%d, %d), pad: (%d, %d), stride: (%d, %d), countPad: %t)",
+		op.unpaddedB, op.unpaddedC, op.unpaddedH, op.unpaddedW,
+		op.h, op.w, op.padH, op.padW, op.strideH, op.strideW, op.countPad)
+}
+
+func (op *avgPoolDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (value.Value, error) {
+	_, pooledGrad, err := op.checkInput(inputs...)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := prealloc.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Cannot do with PreallocDo - expected PreAlloc to be tensor")
+	}
+	if err = op.do(p, pooledGrad); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (op *avgPoolDiffOp) checkInput(inputs ...value.Value) (in, pooledGrad tensor.Tensor, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	var ok bool
+	if in, ok = inputs[0].(tensor.Tensor); !ok {
+		err = errors.Errorf("Expected input to be a tensor")
+		return
+	}
+	if in.Shape().Dims() != 4 {
+		err = errors.Errorf("Expected input to have 4 dimensions")
+		return
+	}
+	if pooledGrad, ok = inputs[1].(tensor.Tensor); !ok {
+		err = errors.Errorf("Expected pooledGrad to be a tensor")
+		return
+	}
+	return
+}
+
+func (op *avgPoolDiffOp) do(inGrad, pooledGrad tensor.Tensor) error {
+	pooledShape := pooledGrad.Shape()
+	inShape := inGrad.Shape()
+	b, c, h, w := pooledShape[0], pooledShape[1], pooledShape[2], pooledShape[3]
+	inH, inW := inShape[2], inShape[3]
+
+	switch inGrad.Dtype() {
+	case tensor.Float64:
+		op.f64s(b, c, h, w, inH, inW, inGrad.Data().([]float64), pooledGrad.Data().([]float64))
+	case tensor.Float32:
+		op.f32s(b, c, h, w, inH, inW, inGrad.Data().([]float32), pooledGrad.Data().([]float32))
+	default:
+		return errors.Errorf(nyiFail, "AvgPoolDiff", inGrad.Dtype())
+	}
+	return nil
+}
+
+func (op *avgPoolDiffOp) f64s(batches, channels, pooledH, pooledW, inH, inW int, inDiffData, outDiffData []float64) {
+	for i := range inDiffData {
+		inDiffData[i] = 0
+	}
+
+	inStride := inH * inW
+	outStride := pooledH * pooledW
+	for b := 0; b < batches; b++ {
+		for c := 0; c < channels; c++ {
+			for ph := 0; ph < pooledH; ph++ {
+				for pw := 0; pw < pooledW; pw++ {
+					hStart, hEnd, wStart, wEnd, poolSize := op.window(ph, pw, inH, inW)
+					g := outDiffData[ph*pooledW+pw] / float64(poolSize)
+					for hi := hStart; hi < hEnd; hi++ {
+						for wi := wStart; wi < wEnd; wi++ {
+							inDiffData[hi*inW+wi] += g
+						}
+					}
+				}
+			}
+			outDiffData = outDiffData[outStride:]
+			inDiffData = inDiffData[inStride:]
+		}
+	}
+}
+
+func (op *avgPoolDiffOp) f32s(batches, channels, pooledH, pooledW, inH, inW int, inDiffData, outDiffData []float32) {
+	for i := range inDiffData {
+		inDiffData[i] = 0
+	}
+
+	inStride := inH * inW
+	outStride := pooledH * pooledW
+	for b := 0; b < batches; b++ {
+		for c := 0; c < channels; c++ {
+			for ph := 0; ph < pooledH; ph++ {
+				for pw := 0; pw < pooledW; pw++ {
+					hStart, hEnd, wStart, wEnd, poolSize := op.window(ph, pw, inH, inW)
+					g := outDiffData[ph*pooledW+pw] / float32(poolSize)
+					for hi := hStart; hi < hEnd; hi++ {
+						for wi := wStart; wi < wEnd; wi++ {
+							inDiffData[hi*inW+wi] += g
+						}
+					}
+				}
+			}
+			outDiffData = outDiffData[outStride:]
+			inDiffData = inDiffData[inStride:]
+		}
+	}
+}
+
+// globalPoolOp pools over the entire spatial extent of each channel. It turns a (N, C, H, W) tensor into a (N, C) tensor.
+//
+// Like maxPoolOp, the global max pool keeps the argmax of each channel as an internal state, to be used by globalPoolDiffOp.
+type globalPoolOp struct {
+	max bool
+
+	// execution state
+	// the mask is only filled at execution time, and only used in max pooling
+	mask []int
+}
+
+func newGlobalPoolOp(max bool) *globalPoolOp { return &globalPoolOp{max: max} }
+
+func (op *globalPoolOp) Arity() int { return 1 }
+
+// globalPoolOp has this type:
+// 		op :: Tensor-4 a → Matrix a
+func (op *globalPoolOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	return hm.NewFnType(constructor.NewTensorType(4, a), constructor.NewTensorType(2, a))
+}
+
+func (op *globalPoolOp) InferShape(inputs ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, err
+	}
+	s, ok := inputs[0].(tensor.Shape)
+	if !ok || s.Dims() != 4 {
+		return nil, errors.Errorf("Expected a 4D shape. Got %v instead", inputs[0])
+	}
+	return tensor.Shape{s[0], s[1]}, nil
+}
+
+func (op *globalPoolOp) Do(inputs ...value.Value) (retVal value.Value, err error) {
+	var in tensor.Tensor
+	if in, err = checkPoolInput(op, inputs...); err != nil {
+		return nil, err
+	}
+	s := in.Shape()
+	out := tensor.New(tensor.Of(in.Dtype()), tensor.WithShape(s[0], s[1]), tensor.WithEngine(in.Engine()))
+	if err = op.do(out, in); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (op *globalPoolOp) ReturnsPtr() bool      { return false }
+func (op *globalPoolOp) CallsExtern() bool     { return false }
+func (op *globalPoolOp) OverwritesInput() int  { return -1 }
+func (op *globalPoolOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+func (op *globalPoolOp) Hashcode() uint32      { return simpleHash(op) }
+
+func (op *globalPoolOp) String() string {
+	if op.max {
+		return "GlobalMaxPool"
+	}
+	return "GlobalAvgPool"
+}
+
+func (op *globalPoolOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (value.Value, error) {
+	in, err := checkPoolInput(op, inputs...)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := prealloc.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a tensor")
+	}
+	if err = op.do(p, in); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (op *globalPoolOp) DiffWRT(inputs int) []bool { return []bool{true} }
+
+func (op *globalPoolOp) SymDiff(inputs Nodes, output, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	diff := &globalPoolDiffOp{op}
+
+	var ret *Node
+	if ret, err = ApplyOp(diff, inputs[0], grad); err != nil {
+		return nil, err
+	}
+	return Nodes{ret}, nil
+}
+
+func (op *globalPoolOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	inputDV, outDV := getDV(inputs[0], output)
+	diff := &globalPoolDiffOp{op}
+	if _, err = diff.UsePreallocDo(inputDV.D, inputDV.Value, outDV.D); err != nil {
+		return errors.Wrapf(err, doFail, diff)
+	}
+	return
+}
+
+func (op *globalPoolOp) do(out, in tensor.Tensor) error {
+	s := in.Shape()
+	planes := s[0] * s[1]
+	spatial := s[2] * s[3]
+	if op.max && len(op.mask) != planes {
+		op.mask = make([]int, planes)
+	}
+
+	switch in.Dtype() {
+	case tensor.Float64:
+		op.f64s(planes, spatial, out.Data().([]float64), in.Data().([]float64))
+	case tensor.Float32:
+		op.f32s(planes, spatial, out.Data().([]float32), in.Data().([]float32))
+	default:
+		return errors.Errorf(nyiFail, op.String(), in.Dtype())
+	}
+	return nil
+}
+
+func (op *globalPoolOp) f64s(planes, spatial int, outData, inData []float64) {
+	for p := 0; p < planes; p++ {
+		plane := inData[p*spatial : (p+1)*spatial]
+		if op.max {
+			maxIdx := 0
+			for i, v := range plane {
+				if v > plane[maxIdx] {
+					maxIdx = i
+				}
+			}
+			outData[p] = plane[maxIdx]
+			op.mask[p] = maxIdx
+			continue
+		}
+
+		var sum float64
+		for _, v := range plane {
+			sum += v
+		}
+		outData[p] = sum / float64(spatial)
+	}
+}
+
+func (op *globalPoolOp) f32s(planes, spatial int, outData, inData []float32) {
+	for p := 0; p < planes; p++ {
+		plane := inData[p*spatial : (p+1)*spatial]
+		if op.max {
+			maxIdx := 0
+			for i, v := range plane {
+				if v > plane[maxIdx] {
+					maxIdx = i
+				}
+			}
+			outData[p] = plane[maxIdx]
+			op.mask[p] = maxIdx
+			continue
+		}
+
+		var sum float32
+		for _, v := range plane {
+			sum += v
+		}
+		outData[p] = sum / float32(spatial)
+	}
+}
+
+// globalPoolDiffOp sends the gradient of global max pooling to the argmax of each channel,
+// and spreads the gradient of global average pooling evenly over each channel.
+type globalPoolDiffOp struct {
+	*globalPoolOp
+}
+
+func (op *globalPoolDiffOp) Arity() int { return 2 }
+
+func (op *globalPoolDiffOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	t := constructor.NewTensorType(4, a)
+	return hm.NewFnType(t, constructor.NewTensorType(2, a), t)
+}
+
+func (op *globalPoolDiffOp) InferShape(inputs ...ops.DimSizer) (tensor.Shape, error) {
+	return inputs[0].(tensor.Shape).Clone(), nil
+}
+
+func (op *globalPoolDiffOp) Do(inputs ...value.Value) (value.Value, error) {
+	if err := ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, err
+	}
+	in := inputs[0].(tensor.Tensor)
+	out := tensor.New(tensor.Of(in.Dtype()), tensor.WithShape(in.Shape().Clone()...), tensor.WithEngine(in.Engine()))
+	return op.UsePreallocDo(out, inputs...)
+}
+
+func (op *globalPoolDiffOp) ReturnsPtr() bool      { return true }
+func (op *globalPoolDiffOp) CallsExtern() bool     { return false }
+func (op *globalPoolDiffOp) OverwritesInput() int  { return -1 }
+func (op *globalPoolDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+func (op *globalPoolDiffOp) Hashcode() uint32      { return simpleHash(op) }
+func (op *globalPoolDiffOp) String() string        { return op.globalPoolOp.String() + "Diff" }
+
+func (op *globalPoolDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (value.Value, error) {
+	if err := ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, err
+	}
+	inGrad, ok := prealloc.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Cannot do with PreallocDo - expected PreAlloc to be tensor")
+	}
+	pooledGrad, ok := inputs[1].(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected pooledGrad to be a tensor")
+	}
+
+	s := inGrad.Shape()
+	planes := s[0] * s[1]
+	spatial := s[2] * s[3]
+	if op.max && len(op.mask) != planes {
+		return nil, errors.Errorf("%v has not been executed. There is no argmax to differentiate with", op.globalPoolOp)
+	}
+
+	switch inGrad.Dtype() {
+	case tensor.Float64:
+		ig, pg := inGrad.Data().([]float64), pooledGrad.Data().([]float64)
+		for i := range ig {
+			ig[i] = 0
+		}
+		for p := 0; p < planes; p++ {
+			plane := ig[p*spatial : (p+1)*spatial]
+			if op.max {
+				plane[op.mask[p]] = pg[p]
+				continue
+			}
+			g := pg[p] / float64(spatial)
+			for i := range plane {
+				plane[i] = g
+			}
+		}
+	case tensor.Float32:
+		ig, pg := inGrad.Data().([]float32), pooledGrad.Data().([]float32)
+		for i := range ig {
+			ig[i] = 0
+		}
+		for p := 0; p < planes; p++ {
+			plane := ig[p*spatial : (p+1)*spatial]
+			if op.max {
+				plane[op.mask[p]] = pg[p]
+				continue
+			}
+			g := pg[p] / float32(spatial)
+			for i := range plane {
+				plane[i] = g
+			}
+		}
+	default:
+		return nil, errors.Errorf(nyiFail, op.String(), inGrad.Dtype())
+	}
+	return inGrad, nil
+}
+
+// adaptiveAvgPoolOp average pools its input into a fixed output size, regardless of the input size.
+//
+// The window for the output element i along an axis of size in (pooled to size out) is
+//	[⌊i·in/out⌋, ⌈(i+1)·in/out⌉)
+// which is the same definition used by PyTorch, so the windows may overlap.
+type adaptiveAvgPoolOp struct {
+	outH, outW int
+}
+
+func newAdaptiveAvgPoolOp(outH, outW int) (*adaptiveAvgPoolOp, error) {
+	if outH <= 0 || outW <= 0 {
+		return nil, errors.Errorf("Cannot adaptively pool to a size less than or equal 0: (%d, %d)", outH, outW)
+	}
+	return &adaptiveAvgPoolOp{outH: outH, outW: outW}, nil
+}
+
+func adaptiveStart(i, in, out int) int { return (i * in) / out }
+func adaptiveEnd(i, in, out int) int   { return ((i+1)*in + out - 1) / out }
+
+func (op *adaptiveAvgPoolOp) Arity() int { return 1 }
+
+// adaptiveAvgPoolOp has this type:
+// 		op :: Tensor-4 a → Tensor-4 a
+func (op *adaptiveAvgPoolOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	t := constructor.NewTensorType(4, a)
+	return hm.NewFnType(t, t)
+}
+
+func (op *adaptiveAvgPoolOp) InferShape(inputs ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, err
+	}
+	s, ok := inputs[0].(tensor.Shape)
+	if !ok || s.Dims() != 4 {
+		return nil, errors.Errorf("Expected a 4D shape. Got %v instead", inputs[0])
+	}
+	return tensor.Shape{s[0], s[1], op.outH, op.outW}, nil
+}
+
+func (op *adaptiveAvgPoolOp) Do(inputs ...value.Value) (retVal value.Value, err error) {
+	var in tensor.Tensor
+	if in, err = checkPoolInput(op, inputs...); err != nil {
+		return nil, err
+	}
+	s := in.Shape()
+	out := tensor.New(tensor.Of(in.Dtype()), tensor.WithShape(s[0], s[1], op.outH, op.outW), tensor.WithEngine(in.Engine()))
+	if err = op.do(out, in, false); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (op *adaptiveAvgPoolOp) ReturnsPtr() bool      { return false }
+func (op *adaptiveAvgPoolOp) CallsExtern() bool     { return false }
+func (op *adaptiveAvgPoolOp) OverwritesInput() int  { return -1 }
+func (op *adaptiveAvgPoolOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+func (op *adaptiveAvgPoolOp) Hashcode() uint32      { return simpleHash(op) }
+func (op *adaptiveAvgPoolOp) String() string {
+	return fmt.Sprintf("AdaptiveAvgPool(%d, %d)", op.outH, op.outW)
+}
+
+func (op *adaptiveAvgPoolOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (value.Value, error) {
+	in, err := checkPoolInput(op, inputs...)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := prealloc.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a tensor")
+	}
+	if err = op.do(p, in, false); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (op *adaptiveAvgPoolOp) DiffWRT(inputs int) []bool { return []bool{true} }
+
+func (op *adaptiveAvgPoolOp) SymDiff(inputs Nodes, output, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	diff := &adaptiveAvgPoolDiffOp{*op}
+
+	var ret *Node
+	if ret, err = ApplyOp(diff, inputs[0], grad); err != nil {
+		return nil, err
+	}
+	return Nodes{ret}, nil
+}
+
+func (op *adaptiveAvgPoolOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	inputDV, outDV := getDV(inputs[0], output)
+	diff := &adaptiveAvgPoolDiffOp{*op}
+	if _, err = diff.UsePreallocDo(inputDV.D, inputDV.Value, outDV.D); err != nil {
+		return errors.Wrapf(err, doFail, diff)
+	}
+	return
+}
+
+// do performs the forward pass (out is pooled, in is the image) or,
+// if backwards is true, the backwards pass (out is the pooled gradient, in is the image gradient).
+func (op *adaptiveAvgPoolOp) do(out, in tensor.Tensor, backwards bool) error {
+	s := in.Shape()
+	planes := s[0] * s[1]
+	inH, inW := s[2], s[3]
+
+	switch in.Dtype() {
+	case tensor.Float64:
+		op.f64s(planes, inH, inW, out.Data().([]float64), in.Data().([]float64), backwards)
+	case tensor.Float32:
+		op.f32s(planes, inH, inW, out.Data().([]float32), in.Data().([]float32), backwards)
+	default:
+		return errors.Errorf(nyiFail, op.String(), in.Dtype())
+	}
+	return nil
+}
+
+func (op *adaptiveAvgPoolOp) f64s(planes, inH, inW int, outData, inData []float64, backwards bool) {
+	if backwards {
+		for i := range inData {
+			inData[i] = 0
+		}
+	}
+	for p := 0; p < planes; p++ {
+		for oh := 0; oh < op.outH; oh++ {
+			hStart, hEnd := adaptiveStart(oh, inH, op.outH), adaptiveEnd(oh, inH, op.outH)
+			for ow := 0; ow < op.outW; ow++ {
+				wStart, wEnd := adaptiveStart(ow, inW, op.outW), adaptiveEnd(ow, inW, op.outW)
+				poolSize := float64((hEnd - hStart) * (wEnd - wStart))
+				outIdx := ow + op.outW*oh
+
+				if backwards {
+					g := outData[outIdx] / poolSize
+					for hi := hStart; hi < hEnd; hi++ {
+						for wi := wStart; wi < wEnd; wi++ {
+							inData[hi*inW+wi] += g
+						}
+					}
+					continue
+				}
+
+				var sum float64
+				for hi := hStart; hi < hEnd; hi++ {
+					for wi := wStart; wi < wEnd; wi++ {
+						sum += inData[hi*inW+wi]
+					}
+				}
+				outData[outIdx] = sum / poolSize
+			}
+		}
+		inData = inData[inH*inW:]
+		outData = outData[op.outH*op.outW:]
+	}
+}
+
+func (op *adaptiveAvgPoolOp) f32s(planes, inH, inW int, outData, inData []float32, backwards bool) {
+	if backwards {
+		for i := range inData {
+			inData[i] = 0
+		}
+	}
+	for p := 0; p < planes; p++ {
+		for oh := 0; oh < op.outH; oh++ {
+			hStart, hEnd := adaptiveStart(oh, inH, op.outH), adaptiveEnd(oh, inH, op.outH)
+			for ow := 0; ow < op.outW; ow++ {
+				wStart, wEnd := adaptiveStart(ow, inW, op.outW), adaptiveEnd(ow, inW, op.outW)
+				poolSize := float32((hEnd - hStart) * (wEnd - wStart))
+				outIdx := ow + op.outW*oh
+
+				if backwards {
+					g := outData[outIdx] / poolSize
+					for hi := hStart; hi < hEnd; hi++ {
+						for wi := wStart; wi < wEnd; wi++ {
+							inData[hi*inW+wi] += g
+						}
+					}
+					continue
+				}
+
+				var sum float32
+				for hi := hStart; hi < hEnd; hi++ {
+					for wi := wStart; wi < wEnd; wi++ {
+						sum += inData[hi*inW+wi]
+					}
+				}
+				outData[outIdx] = sum / poolSize
+			}
+		}
+		inData = inData[inH*inW:]
+		outData = outData[op.outH*op.outW:]
+	}
+}
+
+// adaptiveAvgPoolDiffOp is the gradient of adaptiveAvgPoolOp
+type adaptiveAvgPoolDiffOp struct {
+	adaptiveAvgPoolOp
+}
+
+func (op *adaptiveAvgPoolDiffOp) Arity() int { return 2 }
+
+func (op *adaptiveAvgPoolDiffOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	t := constructor.NewTensorType(4, a)
+	return hm.NewFnType(t, t, t)
+}
+
+func (op *adaptiveAvgPoolDiffOp) InferShape(inputs ...ops.DimSizer) (tensor.Shape, error) {
+	return inputs[0].(tensor.Shape).Clone(), nil
+}
+
+func (op *adaptiveAvgPoolDiffOp) Do(inputs ...value.Value) (value.Value, error) {
+	if err := ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, err
+	}
+	in := inputs[0].(tensor.Tensor)
+	out := tensor.New(tensor.Of(in.Dtype()), tensor.WithShape(in.Shape().Clone()...), tensor.WithEngine(in.Engine()))
+	return op.UsePreallocDo(out, inputs...)
+}
+
+func (op *adaptiveAvgPoolDiffOp) ReturnsPtr() bool      { return true }
+func (op *adaptiveAvgPoolDiffOp) CallsExtern() bool     { return false }
+func (op *adaptiveAvgPoolDiffOp) OverwritesInput() int  { return -1 }
+func (op *adaptiveAvgPoolDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+func (op *adaptiveAvgPoolDiffOp) Hashcode() uint32      { return simpleHash(op) }
+func (op *adaptiveAvgPoolDiffOp) String() string {
+	return fmt.Sprintf("AdaptiveAvgPoolDiff(%d, %d)", op.outH, op.outW)
+}
+
+func (op *adaptiveAvgPoolDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (value.Value, error) {
+	if err := ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, err
+	}
+	inGrad, ok := prealloc.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Cannot do with PreallocDo - expected PreAlloc to be tensor")
+	}
+	pooledGrad, ok := inputs[1].(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected pooledGrad to be a tensor")
+	}
+	if err := op.do(pooledGrad, inGrad, true); err != nil {
+		return nil, err
+	}
+	return inGrad, nil
+}
+
+// maxUnpoolOp is the partial inverse of maxPoolOp. Each pooled value is written back to the position
+// where maxPoolOp found it (using the argmax mask maxPoolOp keeps), and every other element is zero.
+//
+// Because the mask is only filled at execution time, the maxPoolOp has to be executed before the maxUnpoolOp.
+type maxUnpoolOp struct {
+	pool *maxPoolOp
+}
+
+func newMaxUnpoolOp(pool *maxPoolOp) *maxUnpoolOp { return &maxUnpoolOp{pool} }
+
+func (op *maxUnpoolOp) Arity() int { return 1 }
+
+// maxUnpoolOp has this type:
+// 		op :: Tensor-4 a → Tensor-4 a
+func (op *maxUnpoolOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	t := constructor.NewTensorType(4, a)
+	return hm.NewFnType(t, t)
+}
+
+func (op *maxUnpoolOp) InferShape(inputs ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, err
+	}
+	return op.unpooledShape(), nil
+}
+
+func (op *maxUnpoolOp) Do(inputs ...value.Value) (retVal value.Value, err error) {
+	var pooled tensor.Tensor
+	if pooled, err = checkPoolInput(op, inputs...); err != nil {
+		return nil, err
+	}
+	out := tensor.New(tensor.Of(pooled.Dtype()), tensor.WithShape(op.unpooledShape()...), tensor.WithEngine(pooled.Engine()))
+	if err = op.do(out, pooled, false); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (op *maxUnpoolOp) ReturnsPtr() bool      { return false }
+func (op *maxUnpoolOp) CallsExtern() bool     { return false }
+func (op *maxUnpoolOp) OverwritesInput() int  { return -1 }
+func (op *maxUnpoolOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+func (op *maxUnpoolOp) Hashcode() uint32      { return simpleHash(op) }
+func (op *maxUnpoolOp) String() string        { return fmt.Sprintf("MaxUnpool(%v)", op.pool) }
+
+func (op *maxUnpoolOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (value.Value, error) {
+	pooled, err := checkPoolInput(op, inputs...)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := prealloc.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a tensor")
+	}
+	if err = op.do(p, pooled, false); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (op *maxUnpoolOp) DiffWRT(inputs int) []bool { return []bool{true} }
+
+func (op *maxUnpoolOp) SymDiff(inputs Nodes, output, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	diff := &maxUnpoolDiffOp{op}
+
+	var ret *Node
+	if ret, err = ApplyOp(diff, grad); err != nil {
+		return nil, err
+	}
+	return Nodes{ret}, nil
+}
+
+func (op *maxUnpoolOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	inputDV, outDV := getDV(inputs[0], output)
+	diff := &maxUnpoolDiffOp{op}
+	if _, err = diff.UsePreallocDo(inputDV.D, outDV.D); err != nil {
+		return errors.Wrapf(err, doFail, diff)
+	}
+	return
+}
+
+func (op *maxUnpoolOp) unpooledShape() tensor.Shape {
+	return tensor.Shape{op.pool.unpaddedB, op.pool.unpaddedC, op.pool.unpaddedH, op.pool.unpaddedW}
+}
+
+// do scatters pooled into unpooled when gather is false.
+// When gather is true (i.e. the backwards pass), it gathers unpooled into pooled instead.
+// Either way the whole destination is written: a window with no element of the input (mask < 0) has a zero gradient.
+func (op *maxUnpoolOp) do(unpooled, pooled tensor.Tensor, gather bool) error {
+	if op.pool.mask == nil {
+		return errors.Errorf("%v has not been executed. There is no mask to unpool with", op.pool)
+	}
+	pooledShape := pooled.Shape()
+	planes := pooledShape[0] * pooledShape[1]
+	pooledSize := pooledShape[2] * pooledShape[3]
+	unpooledSize := op.pool.unpaddedH * op.pool.unpaddedW
+	maskStride := op.pool.mask.Strides()[1]
+	maskData := op.pool.mask.Data().([]int)
+
+	switch pooled.Dtype() {
+	case tensor.Float64:
+		up, p := unpooled.Data().([]float64), pooled.Data().([]float64)
+		if !gather {
+			for i := range up {
+				up[i] = 0
+			}
+		}
+		for plane := 0; plane < planes; plane++ {
+			mask := maskData[plane*maskStride:]
+			upPlane := up[plane*unpooledSize : (plane+1)*unpooledSize]
+			pPlane := p[plane*pooledSize : (plane+1)*pooledSize]
+			for i := range pPlane {
+				if mask[i] < 0 {
+					if gather {
+						pPlane[i] = 0
+					}
+					continue
+				}
+				if gather {
+					pPlane[i] = upPlane[mask[i]]
+					continue
+				}
+				upPlane[mask[i]] = pPlane[i]
+			}
+		}
+	case tensor.Float32:
+		up, p := unpooled.Data().([]float32), pooled.Data().([]float32)
+		if !gather {
+			for i := range up {
+				up[i] = 0
+			}
+		}
+		for plane := 0; plane < planes; plane++ {
+			mask := maskData[plane*maskStride:]
+			upPlane := up[plane*unpooledSize : (plane+1)*unpooledSize]
+			pPlane := p[plane*pooledSize : (plane+1)*pooledSize]
+			for i := range pPlane {
+				if mask[i] < 0 {
+					if gather {
+						pPlane[i] = 0
+					}
+					continue
+				}
+				if gather {
+					pPlane[i] = upPlane[mask[i]]
+					continue
+				}
+				upPlane[mask[i]] = pPlane[i]
+			}
+		}
+	default:
+		return errors.Errorf(nyiFail, "MaxUnpool", pooled.Dtype())
+	}
+	return nil
+}
+
+// maxUnpoolDiffOp is the gradient of maxUnpoolOp. It gathers the gradient from the positions the pooled values were scattered to.
+type maxUnpoolDiffOp struct {
+	*maxUnpoolOp
+}
+
+func (op *maxUnpoolDiffOp) Arity() int { return 1 }
+
+func (op *maxUnpoolDiffOp) InferShape(inputs ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, err
+	}
+	return op.pool.calcShape(op.unpooledShape()), nil
+}
+
+func (op *maxUnpoolDiffOp) Do(inputs ...value.Value) (value.Value, error) {
+	unpooledGrad, err := checkPoolInput(op, inputs...)
+	if err != nil {
+		return nil, err
+	}
+	out := tensor.New(tensor.Of(unpooledGrad.Dtype()), tensor.WithShape(op.pool.calcShape(op.unpooledShape())...), tensor.WithEngine(unpooledGrad.Engine()))
+	return op.UsePreallocDo(out, unpooledGrad)
+}
+
+func (op *maxUnpoolDiffOp) ReturnsPtr() bool      { return true }
+func (op *maxUnpoolDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+func (op *maxUnpoolDiffOp) Hashcode() uint32      { return simpleHash(op) }
+func (op *maxUnpoolDiffOp) String() string        { return fmt.Sprintf("MaxUnpoolDiff(%v)", op.pool) }
+
+func (op *maxUnpoolDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (value.Value, error) {
+	unpooledGrad, err := checkPoolInput(op, inputs...)
+	if err != nil {
+		return nil, err
+	}
+	p, ok := prealloc.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Cannot do with PreallocDo - expected PreAlloc to be tensor")
+	}
+	if err = op.do(unpooledGrad, p, true); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
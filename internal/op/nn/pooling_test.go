@@ -0,0 +1,198 @@
+package nnops
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/tensor"
+)
+
+func TestAvgPoolOp(t *testing.T) {
+	in := tensor.New(tensor.WithShape(1, 1, 4, 4), tensor.WithBacking(tensor.Range(tensor.Float64, 1, 17)))
+	correct := map[bool][]float64{
+		true:  {14.0 / 9, 30.0 / 9, 57.0 / 9, 99.0 / 9},
+		false: {14.0 / 4, 30.0 / 6, 57.0 / 6, 99.0 / 9},
+	}
+	for countPad, want := range correct {
+		op, err := newAvgPoolOp(in.Shape(), tensor.Shape{3, 3}, []int{1, 1}, []int{2, 2}, countPad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out, err := op.Do(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !out.Shape().Eq(tensor.Shape{1, 1, 2, 2}) {
+			t.Errorf("countPad %t: expected shape (1, 1, 2, 2). Got %v", countPad, out.Shape())
+		}
+		for i, v := range out.Data().([]float64) {
+			if math.Abs(v-want[i]) > 1e-12 {
+				t.Errorf("countPad %t: expected %v. Got %v", countPad, want, out.Data())
+				break
+			}
+		}
+
+		// the gradient of the sum of all the pooled values is the number of windows an element is in, divided by their sizes.
+		grad := tensor.New(tensor.WithShape(1, 1, 2, 2), tensor.WithBacking([]float64{1, 1, 1, 1}))
+		diff := &avgPoolDiffOp{*op}
+		dIn, err := diff.Do(in, grad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !dIn.Shape().Eq(in.Shape()) {
+			t.Errorf("countPad %t: expected the gradient to have the shape %v. Got %v", countPad, in.Shape(), dIn.Shape())
+		}
+		wantGrad := correctAvgPoolGrad[countPad]
+		for i, v := range dIn.Data().([]float64) {
+			if math.Abs(v-wantGrad[i]) > 1e-12 {
+				t.Errorf("countPad %t: expected the gradient %v. Got %v", countPad, wantGrad, dIn.Data())
+				break
+			}
+		}
+	}
+}
+
+// correctAvgPoolGrad is the gradient of the sum of a 3×3 average pooling of a 4×4 input, with a padding of 1 and a stride of 2.
+// Rows (and columns) 0 and 2-3 are in one window each, and row 1 is in both.
+// When the padding is counted, every window has a size of 9. Otherwise the windows have sizes 4, 6, 6 and 9.
+var correctAvgPoolGrad = map[bool][]float64{
+	true: {
+		1.0 / 9, 2.0 / 9, 1.0 / 9, 1.0 / 9,
+		2.0 / 9, 4.0 / 9, 2.0 / 9, 2.0 / 9,
+		1.0 / 9, 2.0 / 9, 1.0 / 9, 1.0 / 9,
+		1.0 / 9, 2.0 / 9, 1.0 / 9, 1.0 / 9,
+	},
+	false: {
+		1.0 / 4, 5.0 / 12, 1.0 / 6, 1.0 / 6,
+		5.0 / 12, 25.0 / 36, 5.0 / 18, 5.0 / 18,
+		1.0 / 6, 5.0 / 18, 1.0 / 9, 1.0 / 9,
+		1.0 / 6, 5.0 / 18, 1.0 / 9, 1.0 / 9,
+	},
+}
+
+func TestCheckPoolParams(t *testing.T) {
+	in := tensor.Shape{1, 1, 4, 4}
+	for _, pt := range []struct {
+		kernel      tensor.Shape
+		pad, stride []int
+		ok          bool
+	}{
+		{tensor.Shape{3, 3}, []int{1, 1}, []int{2, 2}, true},
+		{tensor.Shape{4, 4}, []int{0, 0}, []int{1, 1}, true},  // the kernel covers the input
+		{tensor.Shape{6, 6}, []int{1, 1}, []int{1, 1}, true},  // the kernel covers the padded input
+		{tensor.Shape{5, 2}, []int{0, 0}, []int{1, 1}, false}, // the kernel is taller than the input
+		{tensor.Shape{7, 3}, []int{1, 1}, []int{1, 1}, false}, // the kernel is taller than the padded input
+		{tensor.Shape{3, 0}, []int{0, 0}, []int{1, 1}, false},
+		{tensor.Shape{3, 3}, []int{3, 0}, []int{1, 1}, false},
+	} {
+		err := checkPoolParams(in, pt.kernel, pt.pad, pt.stride)
+		if pt.ok && err != nil {
+			t.Errorf("kernel %v, pad %v: %v", pt.kernel, pt.pad, err)
+		}
+		if !pt.ok && err == nil {
+			t.Errorf("Expected a kernel of %v with a padding of %v not to fit %v", pt.kernel, pt.pad, in)
+		}
+	}
+}
+
+func TestMaxUnpoolOp(t *testing.T) {
+	in := tensor.New(tensor.WithShape(1, 1, 4, 4), tensor.WithBacking([]float64{
+		1, 5, 2, 0,
+		3, 4, 8, 1,
+		0, 0, 1, 2,
+		9, 0, 3, 4,
+	}))
+	pool := newMaxPoolOp(in.Shape(), tensor.Shape{2, 2}, []int{0, 0}, []int{2, 2})
+	pooled, err := pool.Do(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := newMaxUnpoolOp(pool)
+
+	// the preallocated values are garbage that has to be overwritten
+	prealloc := tensor.New(tensor.WithShape(1, 1, 4, 4), tensor.WithBacking(fill(16, 99)))
+	unpooled, err := op.UsePreallocDo(prealloc, pooled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	correct := []float64{
+		0, 5, 0, 0,
+		0, 0, 8, 0,
+		0, 0, 0, 0,
+		9, 0, 0, 4,
+	}
+	for i, v := range unpooled.Data().([]float64) {
+		if v != correct[i] {
+			t.Errorf("Expected %v. Got %v", correct, unpooled.Data())
+			break
+		}
+	}
+
+	// a window with no element of the input (i.e. one that is all padding) has no position to gather the gradient from
+	pool.mask.Data().([]int)[1] = -1
+	grad := tensor.New(tensor.WithShape(1, 1, 4, 4), tensor.WithBacking(tensor.Range(tensor.Float64, 1, 17)))
+	diff := &maxUnpoolDiffOp{op}
+	dPooled, err := diff.UsePreallocDo(tensor.New(tensor.WithShape(1, 1, 2, 2), tensor.WithBacking(fill(4, 99))), grad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	correctGrad := []float64{2, 0, 13, 16}
+	for i, v := range dPooled.Data().([]float64) {
+		if v != correctGrad[i] {
+			t.Errorf("Expected the gradient %v. Got %v", correctGrad, dPooled.Data())
+			break
+		}
+	}
+}
+
+func fill(n int, v float64) []float64 {
+	retVal := make([]float64, n)
+	for i := range retVal {
+		retVal[i] = v
+	}
+	return retVal
+}
+
+func TestAdaptiveAvgPoolOp(t *testing.T) {
+	in := tensor.New(tensor.WithShape(1, 2, 3, 5), tensor.WithBacking(tensor.Range(tensor.Float32, 0, 30)))
+	op, err := newAdaptiveAvgPoolOp(2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := op.Do(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rows [0, 2) and [1, 3), columns [0, 3) and [2, 5)
+	correct := []float32{3.5, 5.5, 8.5, 10.5, 18.5, 20.5, 23.5, 25.5}
+	for i, v := range out.Data().([]float32) {
+		if v != correct[i] {
+			t.Errorf("Expected %v. Got %v", correct, out.Data())
+			break
+		}
+	}
+}
+
+func TestGlobalPoolOp(t *testing.T) {
+	in := tensor.New(tensor.WithShape(2, 1, 2, 2), tensor.WithBacking([]float64{1, 4, 3, 2, -1, -2, -3, -4}))
+	for _, max := range []bool{true, false} {
+		op := newGlobalPoolOp(max)
+		out, err := op.Do(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		correct := []float64{2.5, -2.5}
+		if max {
+			correct = []float64{4, -1}
+		}
+		if !out.Shape().Eq(tensor.Shape{2, 1}) {
+			t.Errorf("%v: expected shape (2, 1). Got %v", op, out.Shape())
+		}
+		for i, v := range out.Data().([]float64) {
+			if math.Abs(v-correct[i]) > 1e-12 {
+				t.Errorf("%v: expected %v. Got %v", op, correct, out.Data())
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,872 @@
+package nnops
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"hash"
+	"math"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/encoding"
+	"gorgonia.org/gorgonia/internal/constructor"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &quantizeOp{}
+	_ ops.Op = &dequantizeOp{}
+	_ ops.Op = &quantizedMatMulOp{}
+	_ ops.Op = &quantizedConvOp{}
+)
+
+// QuantizeValue maps a float tensor to a tensor of int8 with the params
+func QuantizeValue(v value.Value, p QuantParams) (*tensor.Dense, error) {
+	t, ok := v.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected a tensor. Got %T instead", v)
+	}
+	if err := p.check(t.Shape()); err != nil {
+		return nil, err
+	}
+	data, err := float64sOf(tensor.Materialize(t))
+	if err != nil {
+		return nil, err
+	}
+	chanOf := p.channels(t.Shape())
+	retVal := make([]int8, len(data))
+	for i, x := range data {
+		scale, zero := p.at(chanOf(i))
+		retVal[i] = quantize(x, scale, zero)
+	}
+	return tensor.New(tensor.WithShape(t.Shape().Clone()...), tensor.WithBacking(retVal)), nil
+}
+
+// DequantizeValue maps a tensor of int8 back to a float tensor of the given dtype with the params
+func DequantizeValue(v value.Value, p QuantParams, dt tensor.Dtype) (*tensor.Dense, error) {
+	qs, s, err := int8sOf(v)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.check(s); err != nil {
+		return nil, err
+	}
+	chanOf := p.channels(s)
+	data := make([]float64, len(qs))
+	for i, q := range qs {
+		scale, zero := p.at(chanOf(i))
+		data[i] = float64(int32(q)-zero) * scale
+	}
+	retVal := tensor.New(tensor.Of(dt), tensor.WithShape(s.Clone()...))
+	if err = setFloat64s(retVal, data); err != nil {
+		return nil, err
+	}
+	return retVal, nil
+}
+
+// QuantizeBias maps the float bias of a MatMul or a Conv to int32, so that it can be added to the int32 accumulators.
+// The scale of the bias of channel i is the scale of the input times the scale of the weights of channel i, and its zero point is 0.
+func QuantizeBias(b value.Value, x, w QuantParams) (*tensor.Dense, error) {
+	t, ok := b.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected a tensor. Got %T instead", b)
+	}
+	if t.Shape().Dims() != 1 || (!w.IsPerTensor() && t.Shape()[0] != w.Channels()) {
+		return nil, errors.Errorf("Expected the bias to be a vector with an element per channel of the weights %v. Got %v", w, t.Shape())
+	}
+	if !x.IsPerTensor() {
+		return nil, errors.Errorf("Expected the input to be quantized per tensor. Got %v", x)
+	}
+	data, err := float64sOf(tensor.Materialize(t))
+	if err != nil {
+		return nil, err
+	}
+	retVal := make([]int32, len(data))
+	for i, v := range data {
+		ws, _ := w.at(i)
+		q := math.RoundToEven(v / (x.Scale[0] * ws))
+		retVal[i] = int32(math.Max(math.MinInt32, math.Min(math.MaxInt32, q)))
+	}
+	return tensor.New(tensor.WithShape(len(retVal)), tensor.WithBacking(retVal)), nil
+}
+
+// int8sOf returns the data and the shape of a tensor of int8
+func int8sOf(v value.Value) ([]int8, tensor.Shape, error) {
+	t, ok := v.(tensor.Tensor)
+	if !ok {
+		return nil, nil, errors.Errorf("Expected a tensor. Got %T instead", v)
+	}
+	data, ok := tensor.Materialize(t).Data().([]int8)
+	if !ok {
+		return nil, nil, errors.Errorf("Expected a tensor of Int8. Got %v instead", t.Dtype())
+	}
+	return data, t.Shape(), nil
+}
+
+// int32sOf returns the data of a tensor of int32, such as a quantized bias
+func int32sOf(v value.Value) ([]int32, error) {
+	t, ok := v.(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected a tensor. Got %T instead", v)
+	}
+	data, ok := tensor.Materialize(t).Data().([]int32)
+	if !ok {
+		return nil, errors.Errorf("Expected a tensor of Int32. Got %v instead", t.Dtype())
+	}
+	return data, nil
+}
+
+// requantize maps an int32 accumulator, whose real value is acc·scale, to an int8 of the output params
+func requantize(acc int32, scale float64, out QuantParams) int8 {
+	return quantize(float64(acc)*scale, out.Scale[0], out.ZeroPoint[0])
+}
+
+// quantizeOp maps a float tensor to int8. It is where a quantized graph leaves the floats.
+type quantizeOp struct {
+	p    QuantParams
+	dims int
+}
+
+func newQuantizeOp(p QuantParams, dims int) (*quantizeOp, error) {
+	if p.Axis >= dims {
+		return nil, errors.Errorf("Axis %d of the params is out of bounds for a tensor with %d dimensions", p.Axis, dims)
+	}
+	return &quantizeOp{p: p, dims: dims}, nil
+}
+
+// Arity ...
+func (op *quantizeOp) Arity() int { return 1 }
+
+// quantizeOp has this type:
+//		op :: Tensor-d a → Tensor-d Int8
+func (op *quantizeOp) Type() hm.Type {
+	return hm.NewFnType(constructor.NewTensorType(op.dims, hm.TypeVariable('a')), constructor.NewTensorType(op.dims, tensor.Int8))
+}
+
+// InferShape ...
+func (op *quantizeOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "quantize")
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	if err := op.p.check(s); err != nil {
+		return nil, err
+	}
+	return s.Clone(), nil
+}
+
+// Do ...
+func (op *quantizeOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "quantize Do")
+	}
+	return QuantizeValue(values[0], op.p)
+}
+
+// ReturnsPtr ...
+func (op *quantizeOp) ReturnsPtr() bool { return false }
+
+// CallsExtern ...
+func (op *quantizeOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *quantizeOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *quantizeOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *quantizeOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *quantizeOp) String() string { return fmt.Sprintf("Quantize%v", op.p) }
+
+// DiffWRT ...
+func (op *quantizeOp) DiffWRT(inputs int) []bool { return []bool{false} }
+
+// SymDiff ...
+func (op *quantizeOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	return nil, errors.Errorf("%v is not differentiable", op)
+}
+
+// DoDiff ...
+func (op *quantizeOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) error {
+	return errors.Errorf("%v is not differentiable", op)
+}
+
+// dequantizeOp maps a tensor of int8 back to floats. It is where a quantized graph goes back to the floats.
+type dequantizeOp struct {
+	p    QuantParams
+	to   tensor.Dtype
+	dims int
+}
+
+func newDequantizeOp(p QuantParams, to tensor.Dtype, dims int) (*dequantizeOp, error) {
+	if to != Float64 && to != Float32 {
+		return nil, errors.Errorf("Cannot dequantize to %v", to)
+	}
+	if p.Axis >= dims {
+		return nil, errors.Errorf("Axis %d of the params is out of bounds for a tensor with %d dimensions", p.Axis, dims)
+	}
+	return &dequantizeOp{p: p, to: to, dims: dims}, nil
+}
+
+// Arity ...
+func (op *dequantizeOp) Arity() int { return 1 }
+
+// dequantizeOp has this type:
+//		op :: Tensor-d Int8 → Tensor-d a
+func (op *dequantizeOp) Type() hm.Type {
+	return hm.NewFnType(constructor.NewTensorType(op.dims, tensor.Int8), constructor.NewTensorType(op.dims, op.to))
+}
+
+// InferShape ...
+func (op *dequantizeOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "dequantize")
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	if err := op.p.check(s); err != nil {
+		return nil, err
+	}
+	return s.Clone(), nil
+}
+
+// Do ...
+func (op *dequantizeOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "dequantize Do")
+	}
+	return DequantizeValue(values[0], op.p, op.to)
+}
+
+// ReturnsPtr ...
+func (op *dequantizeOp) ReturnsPtr() bool { return false }
+
+// CallsExtern ...
+func (op *dequantizeOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *dequantizeOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *dequantizeOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *dequantizeOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *dequantizeOp) String() string { return fmt.Sprintf("Dequantize%v→%v", op.p, op.to) }
+
+// DiffWRT ...
+func (op *dequantizeOp) DiffWRT(inputs int) []bool { return []bool{false} }
+
+// SymDiff ...
+func (op *dequantizeOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	return nil, errors.Errorf("%v is not differentiable", op)
+}
+
+// DoDiff ...
+func (op *dequantizeOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) error {
+	return errors.Errorf("%v is not differentiable", op)
+}
+
+// quantized holds the params of a quantized MatMul or Conv: the input and the output are quantized per tensor,
+// and the weights either per tensor or per output channel. The bias is optional, and is quantized with QuantizeBias.
+//
+// The products are accumulated in int32, then requantized to the output:
+//		y = clamp(round(acc·sx·sw/sy) + zy, -128, 127)
+//		acc = Σ (x - zx)·(w - zw) + bias
+type quantized struct {
+	x, w, y QuantParams
+	hasBias bool
+}
+
+func makeQuantized(x, w, y QuantParams, wAxis int, hasBias bool) (quantized, error) {
+	if !x.IsPerTensor() || !y.IsPerTensor() {
+		return quantized{}, errors.Errorf("Expected the input and the output to be quantized per tensor. Got %v and %v", x, y)
+	}
+	if !w.IsPerTensor() && w.Axis != wAxis {
+		return quantized{}, errors.Errorf("Expected the weights to be quantized per tensor or per output channel (axis %d). Got %v", wAxis, w)
+	}
+	return quantized{x: x, w: w, y: y, hasBias: hasBias}, nil
+}
+
+func (q quantized) arity() int {
+	if q.hasBias {
+		return 3
+	}
+	return 2
+}
+
+// types returns the types of the inputs and the output of a quantized op on tensors with the given dims
+func (q quantized) types(dims int) hm.Types {
+	t := constructor.NewTensorType(dims, tensor.Int8)
+	if q.hasBias {
+		return hm.Types{t, t, constructor.NewTensorType(1, tensor.Int32), t}
+	}
+	return hm.Types{t, t, t}
+}
+
+// bias returns the quantized bias of the n output channels, or zeroes if there is none
+func (q quantized) bias(n int, inputs []value.Value) ([]int32, error) {
+	if !q.hasBias {
+		return make([]int32, n), nil
+	}
+	b, err := int32sOf(inputs[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "bias")
+	}
+	if len(b) != n {
+		return nil, errors.Errorf("Expected a bias of %d elements. Got %d", n, len(b))
+	}
+	return b, nil
+}
+
+func (q quantized) String() string {
+	return fmt.Sprintf("x: %v, w: %v, y: %v, bias: %t", q.x, q.w, q.y, q.hasBias)
+}
+
+// quantizedMatMulOp multiplies a (M, K) int8 input by (K, N) int8 weights, and requantizes the result to a (M, N) int8 output.
+// The weights may be quantized per column.
+type quantizedMatMulOp struct {
+	quantized
+}
+
+func newQuantizedMatMulOp(x, w, y QuantParams, hasBias bool) (*quantizedMatMulOp, error) {
+	q, err := makeQuantized(x, w, y, 1, hasBias)
+	if err != nil {
+		return nil, err
+	}
+	return &quantizedMatMulOp{q}, nil
+}
+
+// Arity ...
+func (op *quantizedMatMulOp) Arity() int { return op.arity() }
+
+// quantizedMatMulOp has this type:
+//		op :: Matrix Int8 → Matrix Int8 → (Vector Int32) → Matrix Int8
+// Only an op with a bias takes the bias.
+func (op *quantizedMatMulOp) Type() hm.Type { return hm.NewFnType(op.types(2)...) }
+
+// InferShape ...
+func (op *quantizedMatMulOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "quantizedMatMul")
+	}
+	shapes, err := ops.DimSizersToShapes(ns)
+	if err != nil {
+		return nil, err
+	}
+	if err = op.checkShapes(shapes[0], shapes[1]); err != nil {
+		return nil, err
+	}
+	return tensor.Shape{shapes[0][0], shapes[1][1]}, nil
+}
+
+func (op *quantizedMatMulOp) checkShapes(x, w tensor.Shape) error {
+	if x.Dims() != 2 || w.Dims() != 2 || x[1] != w[0] {
+		return errors.Errorf("%v expects a (M, K) input and (K, N) weights. Got %v and %v", op, x, w)
+	}
+	return op.w.check(w)
+}
+
+// Do ...
+func (op *quantizedMatMulOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "quantizedMatMul Do")
+	}
+	var xs, ws []int8
+	var xShape, wShape tensor.Shape
+	if xs, xShape, err = int8sOf(values[0]); err != nil {
+		return nil, errors.Wrap(err, "input")
+	}
+	if ws, wShape, err = int8sOf(values[1]); err != nil {
+		return nil, errors.Wrap(err, "weights")
+	}
+	if err = op.checkShapes(xShape, wShape); err != nil {
+		return nil, err
+	}
+	m, k, n := xShape[0], xShape[1], wShape[1]
+	var bias []int32
+	if bias, err = op.bias(n, values); err != nil {
+		return nil, err
+	}
+
+	// the zero points are taken off first
+	zx := op.x.ZeroPoint[0]
+	w := make([]int32, len(ws))
+	for i, v := range ws {
+		_, zw := op.w.at(i % n)
+		w[i] = int32(v) - zw
+	}
+	scales := make([]float64, n)
+	for j := range scales {
+		sw, _ := op.w.at(j)
+		scales[j] = op.x.Scale[0] * sw
+	}
+
+	out := make([]int8, m*n)
+	acc := make([]int32, n)
+	for i := 0; i < m; i++ {
+		copy(acc, bias)
+		for l := 0; l < k; l++ {
+			xv := int32(xs[i*k+l]) - zx
+			if xv == 0 {
+				continue
+			}
+			for j, wv := range w[l*n : (l+1)*n] {
+				acc[j] += xv * wv
+			}
+		}
+		for j, a := range acc {
+			out[i*n+j] = requantize(a, scales[j], op.y)
+		}
+	}
+	return tensor.New(tensor.WithShape(m, n), tensor.WithBacking(out)), nil
+}
+
+// ReturnsPtr ...
+func (op *quantizedMatMulOp) ReturnsPtr() bool { return false }
+
+// CallsExtern ...
+func (op *quantizedMatMulOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *quantizedMatMulOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *quantizedMatMulOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *quantizedMatMulOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *quantizedMatMulOp) String() string { return fmt.Sprintf("QuantizedMatMul{%v}", op.quantized) }
+
+// DiffWRT ...
+func (op *quantizedMatMulOp) DiffWRT(inputs int) []bool { return make([]bool, inputs) }
+
+// SymDiff ...
+func (op *quantizedMatMulOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	return nil, errors.Errorf("%v is not differentiable", op)
+}
+
+// DoDiff ...
+func (op *quantizedMatMulOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) error {
+	return errors.Errorf("%v is not differentiable", op)
+}
+
+// quantizedConvOp is the int8 counterpart of the convolution op: a NCHW int8 image is convolved with KCHW int8 filters,
+// which may be quantized per output channel, and the result is requantized to int8.
+// Like the convolution op, it is computed as an im2col followed by a GEMM per group, with int32 accumulators.
+type quantizedConvOp struct {
+	im2colOp
+	quantized
+
+	groups               int
+	inShape, filterShape tensor.Shape
+}
+
+func newQuantizedConvOp(inShape, filterShape tensor.Shape, pad, stride, dilation []int, groups int, x, w, y QuantParams, hasBias bool) (*quantizedConvOp, error) {
+	if err := CheckConvolutionParams(pad, stride, dilation); err != nil {
+		return nil, err
+	}
+	if len(pad) != 2 || len(stride) != 2 || len(dilation) != 2 {
+		return nil, errors.Errorf("QuantizedConv2d expects 2 dimensional pad, stride and dilation. Got %v, %v, %v", pad, stride, dilation)
+	}
+	if inShape.Dims() != 4 || filterShape.Dims() != 4 {
+		return nil, errors.Errorf("QuantizedConv2d expects a NCHW image and KCHW filters. Got %v and %v", inShape, filterShape)
+	}
+	if groups <= 0 || inShape[1]%groups != 0 || filterShape[0]%groups != 0 || filterShape[1] != inShape[1]/groups {
+		return nil, errors.Errorf("Cannot split %v images and %v filters into %d groups", inShape, filterShape, groups)
+	}
+	q, err := makeQuantized(x, w, y, 0, hasBias)
+	if err != nil {
+		return nil, err
+	}
+	if err = w.check(filterShape); err != nil {
+		return nil, err
+	}
+	return &quantizedConvOp{
+		im2colOp:  makeIm2ColOp(filterShape[2], filterShape[3], pad[0], pad[1], stride[0], stride[1], dilation[0], dilation[1]),
+		quantized: q,
+
+		groups:      groups,
+		inShape:     inShape.Clone(),
+		filterShape: filterShape.Clone(),
+	}, nil
+}
+
+// Arity ...
+func (op *quantizedConvOp) Arity() int { return op.arity() }
+
+// quantizedConvOp has this type:
+//		op :: Tensor-4 Int8 → Tensor-4 Int8 → (Vector Int32) → Tensor-4 Int8
+// Only an op with a bias takes the bias.
+func (op *quantizedConvOp) Type() hm.Type { return hm.NewFnType(op.types(4)...) }
+
+// InferShape ...
+func (op *quantizedConvOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "quantizedConv")
+	}
+	return op.outShape(), nil
+}
+
+func (op *quantizedConvOp) outShape() tensor.Shape {
+	h, w := op.retHW(op.inShape[2], op.inShape[3])
+	return tensor.Shape{op.inShape[0], op.filterShape[0], h, w}
+}
+
+// Do ...
+func (op *quantizedConvOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "quantizedConv Do")
+	}
+	var im, filter []int8
+	var imShape, filterShape tensor.Shape
+	if im, imShape, err = int8sOf(values[0]); err != nil {
+		return nil, errors.Wrap(err, "image")
+	}
+	if filter, filterShape, err = int8sOf(values[1]); err != nil {
+		return nil, errors.Wrap(err, "filter")
+	}
+	if !imShape.Eq(op.inShape) || !filterShape.Eq(op.filterShape) {
+		return nil, errors.Errorf("%v expects an image of shape %v and filters of shape %v. Got %v and %v", op, op.inShape, op.filterShape, imShape, filterShape)
+	}
+	kernels := op.filterShape[0]
+	var bias []int32
+	if bias, err = op.bias(kernels, values); err != nil {
+		return nil, err
+	}
+
+	batches, channels, imH, imW := op.inShape[0], op.inShape[1], op.inShape[2], op.inShape[3]
+	outH, outW := op.retHW(imH, imW)
+	patches, colWidth := outH*outW, channels*op.h*op.w
+	groupK, groupCols := kernels/op.groups, colWidth/op.groups
+	imStride := channels * imH * imW
+
+	// the zero points are taken off first, so that the padding is a real 0
+	zx := op.x.ZeroPoint[0]
+	centered := make([]int32, imStride)
+	col := make([]int32, patches*colWidth)
+	w := make([]int32, len(filter))
+	for i, v := range filter {
+		_, zw := op.w.at(i / groupCols)
+		w[i] = int32(v) - zw
+	}
+
+	out := make([]int8, batches*kernels*patches)
+	for b := 0; b < batches; b++ {
+		for i, v := range im[b*imStride : (b+1)*imStride] {
+			centered[i] = int32(v) - zx
+		}
+		op.i32s(channels, imH, imW, imH*imW, imW, outH, outW, centered, col)
+		for k := 0; k < kernels; k++ {
+			g := k / groupK
+			sw, _ := op.w.at(k)
+			scale := op.x.Scale[0] * sw
+			filterRow := w[k*groupCols : (k+1)*groupCols]
+			o := out[(b*kernels+k)*patches : (b*kernels+k+1)*patches]
+			for p := range o {
+				acc := bias[k]
+				for c, v := range col[p*colWidth+g*groupCols : p*colWidth+(g+1)*groupCols] {
+					acc += filterRow[c] * v
+				}
+				o[p] = requantize(acc, scale, op.y)
+			}
+		}
+	}
+	return tensor.New(tensor.WithShape(op.outShape()...), tensor.WithBacking(out)), nil
+}
+
+// ReturnsPtr ...
+func (op *quantizedConvOp) ReturnsPtr() bool { return false }
+
+// CallsExtern ...
+func (op *quantizedConvOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *quantizedConvOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *quantizedConvOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *quantizedConvOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *quantizedConvOp) String() string {
+	return fmt.Sprintf("QuantizedConvolution:%v-%d{%v}", op.im2colOp, op.groups, op.quantized)
+}
+
+// DiffWRT ...
+func (op *quantizedConvOp) DiffWRT(inputs int) []bool { return make([]bool, inputs) }
+
+// SymDiff ...
+func (op *quantizedConvOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	return nil, errors.Errorf("%v is not differentiable", op)
+}
+
+// DoDiff ...
+func (op *quantizedConvOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) error {
+	return errors.Errorf("%v is not differentiable", op)
+}
+
+// i32s is the int32 counterpart of f64s, used by the quantized convolution
+func (op im2colOp) i32s(chans, height, width, chanStride, inRowStride, retHeight, retWidth int, im, col []int32) {
+	var colIdx int
+	for r := 0; r < retHeight; r++ {
+		for c := 0; c < retWidth; c++ {
+			for ch := 0; ch < chans; ch++ {
+				chanStart := ch * chanStride
+				for kr := 0; kr < op.h; kr++ {
+					inRow := -op.padH + kr*op.dilationH + r*op.strideH
+					for kc := 0; kc < op.w; kc++ {
+						inCol := -op.padW + kc*op.dilationW + c*op.strideW
+						var val int32
+
+						switch {
+						case inRow < 0, inRow >= height:
+						case inCol < 0, inCol >= width:
+						default:
+							val = im[chanStart+inRow*inRowStride+inCol]
+						}
+
+						col[colIdx] = val
+						colIdx++
+					}
+				}
+			}
+		}
+	}
+}
+
+// QuantizeGraph rewrites the nodes of a Calibration into int8 ops, with the params their observers give:
+//		y = MatMul(x, w)    becomes    y = Dequantize(QuantizedMatMul(Quantize(x), Quantize(w)))
+// and likewise for the Convs of this package, which keep their padding, stride, dilation and groups. Any other node is taken to be
+// a (M, K)×(K, N) MatMul. The nodes that took the output of a rewritten node take its Dequantize node instead, so the rest of
+// the graph stays in floats. An input that feeds several rewritten nodes with the same params is quantized once.
+//
+// It returns the Dequantize nodes, in the order of the nodes they replace. The replaced nodes are left in g, without consumers,
+// so that MeasureDrift can compare them to the Dequantize nodes. The rewritten graph is saved with encoding.Save.
+func QuantizeGraph(c *Calibration) (dequantized []*exprgraph.Node, err error) {
+	g := c.g
+	type edge struct {
+		from, to *exprgraph.Node
+		i        float64
+	}
+	quantized := make(map[string]*exprgraph.Node) // the Quantize nodes, by input and params
+	for _, n := range c.nodes {
+		// the consumers of n are known before its int8 counterpart is added.
+		// Those that are rewritten after n then quantize its Dequantize node.
+		var edges []edge
+		cs := g.To(n.ID())
+		for cs.Next() {
+			if from, ok := cs.Node().(*exprgraph.Node); ok {
+				w, _ := g.Weight(from.ID(), n.ID())
+				edges = append(edges, edge{from: from, to: n, i: w})
+			}
+		}
+
+		var d *exprgraph.Node
+		if d, err = quantizeNode(g, n, c.observers[n.ID()], quantized); err != nil {
+			return nil, errors.Wrapf(err, "Cannot quantize %v", n.Name)
+		}
+		for _, e := range edges {
+			g.RemoveEdge(e.from.ID(), e.to.ID())
+			g.SetWeightedEdge(g.NewWeightedEdge(e.from, d, e.i))
+		}
+		dequantized = append(dequantized, d)
+	}
+	return dequantized, nil
+}
+
+// quantizeNode adds the int8 counterpart of n to g, with the params of the observers of its activations, weights and output,
+// and returns the node that dequantizes its output
+func quantizeNode(g *exprgraph.ExprGraph, n *exprgraph.Node, observers [3]*Observer, quantized map[string]*exprgraph.Node) (*exprgraph.Node, error) {
+	var ps [3]QuantParams
+	var err error
+	for i, o := range observers {
+		if ps[i], err = o.Params(); err != nil {
+			return nil, err
+		}
+	}
+	xp, wp, yp := ps[0], ps[1], ps[2]
+	ins := g.InputsOf(n)
+	x, w := ins[0], ins[1]
+
+	var conv *quantizedConvOp
+	var mm *quantizedMatMulOp
+	if c, ok := n.Op.(*convolution); ok {
+		conv, err = newQuantizedConvOp(x.Shape, w.Shape, c.padding, c.stride, c.dilation, c.groups, xp, wp, yp, false)
+	} else if mm, err = newQuantizedMatMulOp(xp, wp, yp, false); err == nil {
+		err = mm.checkShapes(x.Shape, w.Shape)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var dt tensor.Dtype
+	if dt, err = dtypeOf(n.T); err != nil {
+		return nil, err
+	}
+	var deq *dequantizeOp
+	if deq, err = newDequantizeOp(yp, dt, n.Shape.Dims()); err != nil {
+		return nil, err
+	}
+	var qx, qw *exprgraph.Node
+	if qx, err = quantizeInput(g, x, xp, quantized); err != nil {
+		return nil, errors.Wrap(err, "input")
+	}
+	if qw, err = quantizeInput(g, w, wp, quantized); err != nil {
+		return nil, errors.Wrap(err, "weights")
+	}
+
+	q := g.NewVertex()
+	if conv != nil {
+		q.Op = conv
+	} else {
+		q.Op = mm
+	}
+	q.T, q.Shape = constructor.NewTensorType(n.Shape.Dims(), tensor.Int8), n.Shape.Clone()
+	q.Name = "Quantized(" + n.Name + ")"
+	g.AddNode(q)
+	g.SetWeightedEdge(g.NewWeightedEdge(q, qx, 0))
+	g.SetWeightedEdge(g.NewWeightedEdge(q, qw, 1))
+
+	d := g.NewVertex()
+	d.Op, d.T, d.Shape = deq, n.T, n.Shape.Clone()
+	d.Name = "Dequantize(" + n.Name + ")"
+	g.AddNode(d)
+	g.SetWeightedEdge(g.NewWeightedEdge(d, q, 0))
+	return d, nil
+}
+
+// quantizeInput returns the node that quantizes in with the params, and adds it to g unless it is in quantized already
+func quantizeInput(g *exprgraph.ExprGraph, in *exprgraph.Node, p QuantParams, quantized map[string]*exprgraph.Node) (*exprgraph.Node, error) {
+	key := fmt.Sprintf("%d:%v", in.ID(), p)
+	if q, ok := quantized[key]; ok {
+		return q, nil
+	}
+	if err := p.check(in.Shape); err != nil {
+		return nil, err
+	}
+	op, err := newQuantizeOp(p, in.Shape.Dims())
+	if err != nil {
+		return nil, err
+	}
+	q := g.NewVertex()
+	q.Op, q.T, q.Shape = op, constructor.NewTensorType(in.Shape.Dims(), tensor.Int8), in.Shape.Clone()
+	q.Name = "Quantize(" + in.Name + ")"
+	g.AddNode(q)
+	g.SetWeightedEdge(g.NewWeightedEdge(q, in, 0))
+	quantized[key] = q
+	return q, nil
+}
+
+/* Saving and loading */
+
+func init() {
+	encoding.RegisterOp(&quantizeOp{}, unmarshalQuantizeOp)
+	encoding.RegisterOp(&dequantizeOp{}, unmarshalDequantizeOp)
+	encoding.RegisterOp(&quantizedMatMulOp{}, unmarshalQuantizedMatMulOp)
+	encoding.RegisterOp(&quantizedConvOp{}, unmarshalQuantizedConvOp)
+}
+
+// quantAttrs are the attributes the int8 ops are saved with. Each op only uses the ones it needs.
+type quantAttrs struct {
+	X, W, Y QuantParams
+	HasBias bool
+
+	Dims int
+	To   string
+
+	InShape, FilterShape  []int
+	Pad, Stride, Dilation []int
+	Groups                int
+}
+
+func (a quantAttrs) marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(a); err != nil {
+		return nil, errors.Wrap(err, "Cannot encode the attributes")
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshalQuantAttrs(data []byte) (a quantAttrs, err error) {
+	err = errors.Wrap(gob.NewDecoder(bytes.NewReader(data)).Decode(&a), "Cannot decode the attributes")
+	return
+}
+
+// MarshalBinary ...
+func (op *quantizeOp) MarshalBinary() ([]byte, error) {
+	return quantAttrs{X: op.p, Dims: op.dims}.marshal()
+}
+
+func unmarshalQuantizeOp(data []byte) (ops.Op, error) {
+	a, err := unmarshalQuantAttrs(data)
+	if err != nil {
+		return nil, err
+	}
+	return newQuantizeOp(a.X, a.Dims)
+}
+
+// MarshalBinary ...
+func (op *dequantizeOp) MarshalBinary() ([]byte, error) {
+	return quantAttrs{Y: op.p, Dims: op.dims, To: op.to.Name()}.marshal()
+}
+
+func unmarshalDequantizeOp(data []byte) (ops.Op, error) {
+	a, err := unmarshalQuantAttrs(data)
+	if err != nil {
+		return nil, err
+	}
+	to, err := encoding.ParseDtype(a.To)
+	if err != nil {
+		return nil, err
+	}
+	return newDequantizeOp(a.Y, to, a.Dims)
+}
+
+// MarshalBinary ...
+func (op *quantizedMatMulOp) MarshalBinary() ([]byte, error) {
+	return quantAttrs{X: op.x, W: op.w, Y: op.y, HasBias: op.hasBias}.marshal()
+}
+
+func unmarshalQuantizedMatMulOp(data []byte) (ops.Op, error) {
+	a, err := unmarshalQuantAttrs(data)
+	if err != nil {
+		return nil, err
+	}
+	return newQuantizedMatMulOp(a.X, a.W, a.Y, a.HasBias)
+}
+
+// MarshalBinary ...
+func (op *quantizedConvOp) MarshalBinary() ([]byte, error) {
+	return quantAttrs{
+		X: op.x, W: op.w, Y: op.y, HasBias: op.hasBias,
+		InShape:     op.inShape,
+		FilterShape: op.filterShape,
+		Pad:         []int{op.padH, op.padW},
+		Stride:      []int{op.strideH, op.strideW},
+		Dilation:    []int{op.dilationH, op.dilationW},
+		Groups:      op.groups,
+	}.marshal()
+}
+
+func unmarshalQuantizedConvOp(data []byte) (ops.Op, error) {
+	a, err := unmarshalQuantAttrs(data)
+	if err != nil {
+		return nil, err
+	}
+	return newQuantizedConvOp(a.InShape, a.FilterShape, a.Pad, a.Stride, a.Dilation, a.Groups, a.X, a.W, a.Y, a.HasBias)
+}
@@ -0,0 +1,423 @@
+// +build !cuda
+
+package nnops
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/encoding"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+// calibrate returns the params a min/max observer finds for v
+func calibrate(t *testing.T, v value.Value, axis int, symmetric bool) QuantParams {
+	o := NewMinMaxObserver(axis, symmetric)
+	if err := o.Observe(v); err != nil {
+		t.Fatal(err)
+	}
+	p, err := o.Params()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestQuantizeValue(t *testing.T) {
+	x := tensor.New(tensor.WithShape(2, 3), tensor.WithBacking([]float32{1, -2, 0.5, -1, 4, 0.25}))
+	p := calibrate(t, x, 1, false)
+	q, err := QuantizeValue(x, p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Dtype() != tensor.Int8 {
+		t.Fatalf("Expected Int8. Got %v", q.Dtype())
+	}
+	d, err := DequantizeValue(q, p, Float32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chanOf := p.channels(x.Shape())
+	for i, v := range d.Float32s() {
+		if s := p.Scale[chanOf(i)]; math.Abs(float64(v-x.Float32s()[i])) > s/2+1e-6 {
+			t.Errorf("Expected %v to be within %v of %v", v, s/2, x.Float32s()[i])
+		}
+	}
+
+	// values out of the range are clamped
+	q, err = QuantizeValue(tensor.New(tensor.WithShape(2), tensor.WithBacking([]float64{1000, -1000})), PerTensor(0.1, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := q.Data().([]int8); got[0] != 127 || got[1] != -128 {
+		t.Errorf("Expected [127 -128]. Got %v", got)
+	}
+
+	if _, err = QuantizeValue(x, PerTensor(0, 0)); err == nil {
+		t.Error("Expected a scale of 0 to fail")
+	}
+	if _, err = QuantizeValue(x, QuantParams{Scale: []float64{1, 1}, ZeroPoint: []int32{0, 0}, Axis: 1}); err == nil {
+		t.Error("Expected params with the wrong number of channels to fail")
+	}
+}
+
+func TestQuantizedMatMulOp(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	m, k, n := 8, 16, 5
+	xData := make([]float64, m*k)
+	for i := range xData {
+		xData[i] = r.Float64()*3 - 0.5 // as after a ReLU6, say
+	}
+	wData := randFloat64s(r, k*n)
+	bData := randFloat64s(r, n)
+	x := tensor.New(tensor.WithShape(m, k), tensor.WithBacking(xData))
+	w := tensor.New(tensor.WithShape(k, n), tensor.WithBacking(wData))
+	b := tensor.New(tensor.WithShape(n), tensor.WithBacking(bData))
+
+	correct := make([]float64, m*n)
+	for i := 0; i < m; i++ {
+		for j := 0; j < n; j++ {
+			correct[i*n+j] = bData[j]
+			for l := 0; l < k; l++ {
+				correct[i*n+j] += xData[i*k+l] * wData[l*n+j]
+			}
+		}
+	}
+	y := tensor.New(tensor.WithShape(m, n), tensor.WithBacking(correct))
+
+	xp, wp, yp := calibrate(t, x, -1, false), calibrate(t, w, 1, true), calibrate(t, y, -1, false)
+	qx, err := QuantizeValue(x, xp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qw, err := QuantizeValue(w, wp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qb, err := QuantizeBias(b, xp, wp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	op, err := newQuantizedMatMulOp(xp, wp, yp, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := op.Do(qx, qw, qb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Shape().Eq(tensor.Shape{m, n}) {
+		t.Fatalf("Expected shape (%d, %d). Got %v", m, n, out.Shape())
+	}
+	deq, err := DequantizeValue(out, yp, Float64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var drift DriftReport
+	if err = drift.Add(y, deq); err != nil {
+		t.Fatal(err)
+	}
+	if drift.SQNR() < 30 {
+		t.Errorf("Expected a SQNR of 30 dB or more. Got %v", &drift)
+	}
+
+	// the input has to be int8
+	if _, err = op.Do(x, qw, qb); err == nil {
+		t.Error("Expected a float input to fail")
+	}
+	if _, err = newQuantizedMatMulOp(xp, calibrate(t, w, 0, true), yp, false); err == nil {
+		t.Error("Expected weights quantized along the wrong axis to fail")
+	}
+}
+
+func TestQuantizedConvOp(t *testing.T) {
+	r := rand.New(rand.NewSource(1337))
+	for _, ct := range convTests {
+		conv := makeTestConv(ct.b, ct.c, ct.h, ct.w, ct.k, ct.kh, ct.kw, ct.pad, ct.stride, ct.dilation, ct.groups)
+		imData := randFloat64s(r, conv.inShape.TotalSize())
+		filterData := randFloat64s(r, conv.filterShape.TotalSize())
+		bData := randFloat64s(r, ct.k)
+		im := tensor.New(tensor.WithShape(conv.inShape.Clone()...), tensor.WithBacking(imData))
+		filter := tensor.New(tensor.WithShape(conv.filterShape.Clone()...), tensor.WithBacking(filterData))
+		b := tensor.New(tensor.WithShape(ct.k), tensor.WithBacking(bData))
+
+		correct := naiveConv(conv, imData, filterData)
+		patches := len(correct) / (ct.b * ct.k)
+		for i := range correct {
+			correct[i] += bData[(i/patches)%ct.k]
+		}
+		y := tensor.New(tensor.WithShape(conv.outShape()...), tensor.WithBacking(correct))
+
+		xp, wp, yp := calibrate(t, im, -1, false), calibrate(t, filter, 0, true), calibrate(t, y, -1, false)
+		qim, err := QuantizeValue(im, xp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		qfilter, err := QuantizeValue(filter, wp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		qb, err := QuantizeBias(b, xp, wp)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		op, err := newQuantizedConvOp(conv.inShape, conv.filterShape, ct.pad, ct.stride, ct.dilation, ct.groups, xp, wp, yp, true)
+		if err != nil {
+			t.Errorf("%v: %v", ct.name, err)
+			continue
+		}
+		out, err := op.Do(qim, qfilter, qb)
+		if err != nil {
+			t.Errorf("%v: %v", ct.name, err)
+			continue
+		}
+		if !out.Shape().Eq(conv.outShape()) {
+			t.Errorf("%v: expected output shape %v. Got %v", ct.name, conv.outShape(), out.Shape())
+			continue
+		}
+		deq, err := DequantizeValue(out, yp, Float64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var drift DriftReport
+		if err = drift.Add(y, deq); err != nil {
+			t.Fatal(err)
+		}
+		if drift.SQNR() < 30 {
+			t.Errorf("%v: expected a SQNR of 30 dB or more. Got %v", ct.name, &drift)
+		}
+	}
+}
+
+func TestQuantizeGraph(t *testing.T) {
+	g := exprgraph.NewGraph()
+	r := rand.New(rand.NewSource(1337))
+	dense := func(shape ...int) *tensor.Dense {
+		return tensor.New(tensor.WithShape(shape...), tensor.WithBacking(randFloat64s(r, tensor.Shape(shape).TotalSize())))
+	}
+	leaf := func(name string, v *tensor.Dense) *exprgraph.Node {
+		n := g.NewVertex()
+		n.Name = name
+		if err := n.ApplyData(v); err != nil {
+			t.Fatal(err)
+		}
+		g.AddNode(n)
+		return n
+	}
+	// the ops only mark the nodes as results of ops. y1 and y2 are the MatMuls, whose values are computed by run
+	result := func(name string, inputs ...*exprgraph.Node) *exprgraph.Node {
+		n := g.NewVertex()
+		n.Name, n.Op, n.T, n.Shape = name, newSoftmaxOp(-1, false), Float64, tensor.Shape{4, 2}
+		g.AddNode(n)
+		for i, in := range inputs {
+			g.SetWeightedEdge(g.NewWeightedEdge(n, in, float64(i)))
+		}
+		return n
+	}
+	matMul := func(x, w *tensor.Dense) *tensor.Dense {
+		xs, ws := x.Float64s(), w.Float64s()
+		retVal := make([]float64, 4*2)
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 2; j++ {
+				for l := 0; l < 3; l++ {
+					retVal[i*2+j] += xs[i*3+l] * ws[l*2+j]
+				}
+			}
+		}
+		return tensor.New(tensor.WithShape(4, 2), tensor.WithBacking(retVal))
+	}
+	x, w1, w2 := leaf("x", dense(4, 3)), leaf("w1", dense(3, 2)), leaf("w2", dense(3, 2))
+	y1, y2 := result("y1", x, w1), result("y2", x, w2)
+	z := result("z", y1, y2)
+
+	batches := 0
+	run := func() error {
+		if batches == 3 {
+			return io.EOF
+		}
+		batches++
+		xv := dense(4, 3)
+		for _, err := range []error{
+			x.ApplyData(xv),
+			y1.ApplyData(matMul(xv, w1.Value().(*tensor.Dense))),
+			y2.ApplyData(matMul(xv, w2.Value().(*tensor.Dense))),
+		} {
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// the weights are quantized per column, the activations and the outputs per tensor
+	rule := func(n *exprgraph.Node) (*Observer, *Observer, *Observer, bool) {
+		if n != y1 && n != y2 {
+			return nil, nil, nil, false
+		}
+		return NewMinMaxObserver(-1, false), NewMinMaxObserver(1, true), NewMinMaxObserver(-1, false), true
+	}
+
+	if _, err := Calibrate(g, rule, func() error { return errors.New("no more data") }); err == nil {
+		t.Errorf("Expected a failed calibration batch to fail the calibration")
+	}
+	c, err := Calibrate(g, rule, run)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if batches != 3 {
+		t.Errorf("Expected 3 calibration batches to be run. Got %d", batches)
+	}
+	dequantized, err := QuantizeGraph(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dequantized) != 2 {
+		t.Fatalf("Expected y1 and y2 to be quantized. Got %d nodes", len(dequantized))
+	}
+	if ins := g.InputsOf(z); len(ins) != 2 || ins[0] != dequantized[0] || ins[1] != dequantized[1] {
+		t.Errorf("Expected z to take the dequantized y1 and y2. Got %v", ins)
+	}
+
+	var qx *exprgraph.Node
+	for i, tc := range []struct{ y, w *exprgraph.Node }{{y1, w1}, {y2, w2}} {
+		d := dequantized[i]
+		if g.To(tc.y.ID()).Len() != 0 {
+			t.Errorf("Expected %v to have no consumers left", tc.y.Name)
+		}
+		ins := g.InputsOf(d)
+		if len(ins) != 1 {
+			t.Fatalf("Expected %v to take a single input. Got %v", d.Name, ins)
+		}
+		q := ins[0]
+		if _, ok := q.Op.(*quantizedMatMulOp); !ok {
+			t.Fatalf("Expected %v to be a QuantizedMatMul. Got %v", q.Name, q.Op)
+		}
+		qs := g.InputsOf(q)
+		if len(qs) != 2 || g.InputsOf(qs[0])[0] != x || g.InputsOf(qs[1])[0] != tc.w {
+			t.Fatalf("Expected %v to take the quantized x and %v. Got %v", q.Name, tc.w.Name, qs)
+		}
+		if qx != nil && qs[0] != qx {
+			t.Errorf("Expected x to be quantized once")
+		}
+		qx = qs[0]
+
+		// the int8 path computes the last batch closely
+		xv, err := qx.Op.(*quantizeOp).Do(x.Value())
+		if err != nil {
+			t.Fatal(err)
+		}
+		wv, err := qs[1].Op.(*quantizeOp).Do(tc.w.Value())
+		if err != nil {
+			t.Fatal(err)
+		}
+		yv, err := q.Op.(*quantizedMatMulOp).Do(xv, wv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dv, err := d.Op.(*dequantizeOp).Do(yv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var drift DriftReport
+		if err = drift.Add(tc.y.Value(), dv); err != nil {
+			t.Fatal(err)
+		}
+		if drift.SQNR() < 30 {
+			t.Errorf("Expected a SQNR of 30 dB or more for %v. Got %v", tc.y.Name, &drift)
+		}
+	}
+
+	// the rewritten graph is saved with its weights, and computes the same once loaded
+	var eval func(g *exprgraph.ExprGraph, n *exprgraph.Node) (value.Value, error)
+	eval = func(g *exprgraph.ExprGraph, n *exprgraph.Node) (value.Value, error) {
+		if n.Op == nil {
+			return n.Value(), nil
+		}
+		var vals []value.Value
+		for _, in := range g.InputsOf(n) {
+			v, err := eval(g, in)
+			if err != nil {
+				return nil, err
+			}
+			vals = append(vals, v)
+		}
+		return n.Op.Do(vals...)
+	}
+	var buf bytes.Buffer
+	if err = encoding.Save(&buf, g, dequantized...); err != nil {
+		t.Fatal(err)
+	}
+	if err = encoding.Save(new(bytes.Buffer), g, z); err == nil {
+		t.Errorf("Expected a graph with an op that is not registered not to be saved")
+	}
+	g2, outputs, err := encoding.Load(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != len(dequantized) {
+		t.Fatalf("Expected %d outputs to be loaded. Got %d", len(dequantized), len(outputs))
+	}
+	if g2.Nodes().Len() != 8 {
+		t.Errorf("Expected x, w1, w2, their Quantize nodes, the QuantizedMatMuls and the Dequantize nodes to be saved. Got %d nodes", g2.Nodes().Len())
+	}
+	for i, d := range dequantized {
+		loaded := outputs[i]
+		if loaded.Name != d.Name || loaded.Op.String() != d.Op.String() || !loaded.Shape.Eq(d.Shape) || !loaded.T.Eq(d.T) {
+			t.Errorf("Expected %v %v of %v to be loaded. Got %v %v of %v", d.Name, d.Op, d.T, loaded.Name, loaded.Op, loaded.T)
+		}
+		want, err := eval(g, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := eval(g2, loaded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !got.(*tensor.Dense).Eq(want) {
+			t.Errorf("Expected the loaded %v to compute %v. Got %v", d.Name, want, got)
+		}
+	}
+
+	// the drift of the int8 path from the float MatMuls is reported for each output
+	batches = 0
+	measure := func() error {
+		if err := run(); err != nil {
+			return err
+		}
+		for _, d := range dequantized {
+			v, err := eval(g, d)
+			if err != nil {
+				return err
+			}
+			if err = d.ApplyData(v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err = MeasureDrift([]*exprgraph.Node{y1}, dequantized, measure); err == nil {
+		t.Errorf("Expected MeasureDrift to fail when the float and dequantized outputs do not pair up")
+	}
+	reports, err := MeasureDrift([]*exprgraph.Node{y1, y2}, dequantized, measure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("Expected a report for y1 and y2. Got %d", len(reports))
+	}
+	for i, report := range reports {
+		if report.Size != 3*4*2 {
+			t.Errorf("Expected the drift of output %d to be measured over 3 batches of 4×2. Got %d elements", i, report.Size)
+		}
+		if report.MaxAbsErr == 0 || report.SQNR() < 30 {
+			t.Errorf("Expected output %d to drift a little from the floats. Got %v", i, report)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+package nnops
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/pkg/errors"
+	G "gorgonia.org/gorgonia"
+	"gorgonia.org/tensor"
+)
+
+// Randomness is the distribution a RandomOp draws from
+type Randomness byte
+
+const (
+	// Uniform draws from [a, b)
+	Uniform Randomness = iota
+	// Gaussian draws from a normal distribution of mean a and standard deviation b
+	Gaussian
+	// Binomial draws the number of successes of a trials, each with a probability b of success
+	Binomial
+)
+
+func (r Randomness) String() string {
+	switch r {
+	case Uniform:
+		return "Uniform"
+	case Gaussian:
+		return "Gaussian"
+	case Binomial:
+		return "Binomial"
+	}
+	return fmt.Sprintf("Randomness(%d)", byte(r))
+}
+
+// RandomStream is a seedable stream of random numbers, meant to be shared by all the RandomOps and initializers of a graph.
+// Given the same seed, and the same draws in the same order, a stream returns the same numbers, which makes runs reproducible.
+//
+// A RandomStream is safe for concurrent use, although concurrent draws happen in no particular order.
+type RandomStream struct {
+	sync.Mutex
+	seed int64
+	rand *rand.Rand
+}
+
+// NewRandomStream creates a RandomStream seeded with seed.
+func NewRandomStream(seed int64) *RandomStream {
+	return &RandomStream{seed: seed, rand: rand.New(rand.NewSource(seed))}
+}
+
+// Seed returns the seed the stream was last seeded with.
+func (s *RandomStream) Seed() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.seed
+}
+
+// Reseed restarts the stream with the given seed.
+func (s *RandomStream) Reseed(seed int64) {
+	s.Lock()
+	s.seed = seed
+	s.rand = rand.New(rand.NewSource(seed))
+	s.Unlock()
+}
+
+// Reset restarts the stream with the seed it was last seeded with, so that it draws the same numbers again.
+func (s *RandomStream) Reset() { s.Reseed(s.Seed()) }
+
+// int63 draws a seed for the ops that keep their own random source, such as the DropoutOps.
+func (s *RandomStream) int63() int64 {
+	s.Lock()
+	defer s.Unlock()
+	return s.rand.Int63()
+}
+
+// draw fills data with numbers drawn from the given distribution.
+func (s *RandomStream) draw(which Randomness, a, b float64, data []float64) error {
+	s.Lock()
+	defer s.Unlock()
+	switch which {
+	case Uniform:
+		for i := range data {
+			data[i] = a + (b-a)*s.rand.Float64()
+		}
+	case Gaussian:
+		for i := range data {
+			data[i] = a + b*s.rand.NormFloat64()
+		}
+	case Binomial:
+		trials := int(a)
+		for i := range data {
+			var successes int
+			for t := 0; t < trials; t++ {
+				if s.rand.Float64() < b {
+					successes++
+				}
+			}
+			data[i] = float64(successes)
+		}
+	default:
+		return errors.Errorf("Unknown randomness %v", which)
+	}
+	return nil
+}
+
+// truncatedNormal fills data with numbers drawn from a normal distribution of mean μ and standard deviation σ.
+// Numbers further than 2σ from the mean are drawn again.
+func (s *RandomStream) truncatedNormal(μ, σ float64, data []float64) {
+	s.Lock()
+	defer s.Unlock()
+	for i := range data {
+		x := s.rand.NormFloat64()
+		for math.Abs(x) > 2 {
+			x = s.rand.NormFloat64()
+		}
+		data[i] = μ + σ*x
+	}
+}
+
+// fans returns the fan-in and the fan-out of a weight of the given shape.
+//
+// A matrix is expected to be a (in, out) weight, as in x·W. A tensor with more dimensions is expected to be a (out, in, kernel...)
+// convolution filter, where each input and output feature is multiplied by the size of the kernel. A vector has as many inputs as outputs.
+func fans(shape []int) (fanIn, fanOut float64, err error) {
+	switch len(shape) {
+	case 0:
+		return 0, 0, errors.Errorf("Cannot compute the fans of a scalar")
+	case 1:
+		return float64(shape[0]), float64(shape[0]), nil
+	case 2:
+		return float64(shape[0]), float64(shape[1]), nil
+	}
+	kernel := tensor.Shape(shape[2:]).TotalSize()
+	return float64(shape[1] * kernel), float64(shape[0] * kernel), nil
+}
+
+// initWith returns an initializer that fills a tensor of the given shape with fill.
+// The initializers panic on errors, as G.InitWFn has no way of returning them.
+func initWith(name string, fill func(shape []int, data []float64) error) G.InitWFn {
+	return func(dt tensor.Dtype, shape ...int) interface{} {
+		data := make([]float64, tensor.Shape(shape).TotalSize())
+		if err := fill(shape, data); err != nil {
+			panic(errors.Wrapf(err, "%s initializer", name))
+		}
+		switch dt {
+		case tensor.Float64:
+			return data
+		case tensor.Float32:
+			f32s := make([]float32, len(data))
+			for i, v := range data {
+				f32s[i] = float32(v)
+			}
+			return f32s
+		}
+		panic(nyi(name+" initializer", dt))
+	}
+}
+
+// fanInit returns an initializer drawing from the given distribution, whose scale is computed from the fans of the shape.
+func fanInit(name string, s *RandomStream, which Randomness, scale func(fanIn, fanOut float64) float64) G.InitWFn {
+	return initWith(name, func(shape []int, data []float64) error {
+		fanIn, fanOut, err := fans(shape)
+		if err != nil {
+			return err
+		}
+		x := scale(fanIn, fanOut)
+		if which == Uniform {
+			return s.draw(Uniform, -x, x, data)
+		}
+		return s.draw(Gaussian, 0, x, data)
+	})
+}
+
+// GlorotUniform draws from U(-x, x) where x = gain·√(6/(fanIn+fanOut)), as described by Glorot and Bengio (2010).
+// It is also known as Xavier uniform.
+func GlorotUniform(s *RandomStream, gain float64) G.InitWFn {
+	return fanInit("GlorotUniform", s, Uniform, func(fanIn, fanOut float64) float64 { return gain * math.Sqrt(6/(fanIn+fanOut)) })
+}
+
+// GlorotNormal draws from N(0, σ²) where σ = gain·√(2/(fanIn+fanOut)), as described by Glorot and Bengio (2010).
+// It is also known as Xavier normal.
+func GlorotNormal(s *RandomStream, gain float64) G.InitWFn {
+	return fanInit("GlorotNormal", s, Gaussian, func(fanIn, fanOut float64) float64 { return gain * math.Sqrt(2/(fanIn+fanOut)) })
+}
+
+// HeUniform draws from U(-x, x) where x = √(6/fanIn), as described by He et al. (2015) for ReLU networks.
+// It is also known as Kaiming uniform.
+func HeUniform(s *RandomStream) G.InitWFn {
+	return fanInit("HeUniform", s, Uniform, func(fanIn, _ float64) float64 { return math.Sqrt(6 / fanIn) })
+}
+
+// HeNormal draws from N(0, σ²) where σ = √(2/fanIn), as described by He et al. (2015) for ReLU networks.
+// It is also known as Kaiming normal.
+func HeNormal(s *RandomStream) G.InitWFn {
+	return fanInit("HeNormal", s, Gaussian, func(fanIn, _ float64) float64 { return math.Sqrt(2 / fanIn) })
+}
+
+// LeCunUniform draws from U(-x, x) where x = √(3/fanIn). It is the initialization to use with SELU activations.
+func LeCunUniform(s *RandomStream) G.InitWFn {
+	return fanInit("LeCunUniform", s, Uniform, func(fanIn, _ float64) float64 { return math.Sqrt(3 / fanIn) })
+}
+
+// LeCunNormal draws from N(0, σ²) where σ = √(1/fanIn). It is the initialization to use with SELU activations.
+func LeCunNormal(s *RandomStream) G.InitWFn {
+	return fanInit("LeCunNormal", s, Gaussian, func(fanIn, _ float64) float64 { return math.Sqrt(1 / fanIn) })
+}
+
+// TruncatedNormal draws from N(μ, σ²), drawing again the numbers further than 2σ from μ.
+func TruncatedNormal(s *RandomStream, μ, σ float64) G.InitWFn {
+	return initWith("TruncatedNormal", func(_ []int, data []float64) error {
+		s.truncatedNormal(μ, σ, data)
+		return nil
+	})
+}
+
+// Orthogonal fills a tensor with a (semi-)orthogonal matrix scaled by gain, as described by Saxe et al. (2013) - https://arxiv.org/abs/1312.6120.
+// The tensor is seen as a (shape[0], rest) matrix: its rows are orthonormal if there are fewer rows than columns, and its columns otherwise.
+func Orthogonal(s *RandomStream, gain float64) G.InitWFn {
+	return initWith("Orthogonal", func(shape []int, data []float64) error {
+		if len(shape) < 2 {
+			return errors.Errorf("Orthogonal expects at least 2 dimensions. Got %v", shape)
+		}
+		rows := shape[0]
+		cols := len(data) / rows
+
+		// orthonormalize the vectors of the smaller dimension, each stored contiguously
+		n, size := cols, rows
+		if rows < cols {
+			n, size = rows, cols
+		}
+		vecs := make([]float64, n*size)
+		if err := s.draw(Gaussian, 0, 1, vecs); err != nil {
+			return err
+		}
+		if err := gramSchmidt(vecs, n, size); err != nil {
+			return err
+		}
+		for i := 0; i < n; i++ {
+			for j := 0; j < size; j++ {
+				v := gain * vecs[i*size+j]
+				if rows < cols {
+					data[i*cols+j] = v // vector i is row i
+				} else {
+					data[j*cols+i] = v // vector i is column i
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// gramSchmidt orthonormalizes n vectors of the given size, stored one after the other, with the modified Gram-Schmidt process.
+func gramSchmidt(vecs []float64, n, size int) error {
+	for i := 0; i < n; i++ {
+		v := vecs[i*size : (i+1)*size]
+		for j := 0; j < i; j++ {
+			u := vecs[j*size : (j+1)*size]
+			var dot float64
+			for k := range v {
+				dot += u[k] * v[k]
+			}
+			for k := range v {
+				v[k] -= dot * u[k]
+			}
+		}
+		var norm float64
+		for _, x := range v {
+			norm += x * x
+		}
+		if norm = math.Sqrt(norm); norm == 0 {
+			return errors.Errorf("Cannot orthonormalize linearly dependent vectors")
+		}
+		for k := range v {
+			v[k] /= norm
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package nnops
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/tensor"
+)
+
+func TestRandomOp_Reproducible(t *testing.T) {
+	stream := NewRandomStream(1337)
+	op, err := MakeRandomOp(Gaussian, tensor.Float64, 0, 1, stream, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, err := op.Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := op.Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if equalFloat64s(first.Data().([]float64), second.Data().([]float64)) {
+		t.Errorf("Expected the stream to move on between draws")
+	}
+
+	stream.Reset()
+	again, err := op.Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalFloat64s(first.Data().([]float64), again.Data().([]float64)) {
+		t.Errorf("Expected a reset stream to draw the same numbers. Got %v and %v", first, again)
+	}
+
+	other, _ := MakeRandomOp(Gaussian, tensor.Float64, 0, 1, NewRandomStream(1337), 3, 4)
+	fromOther, err := other.Do()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalFloat64s(first.Data().([]float64), fromOther.Data().([]float64)) {
+		t.Errorf("Expected two streams with the same seed to draw the same numbers. Got %v and %v", first, fromOther)
+	}
+
+	if _, err = MakeRandomOp(Gaussian, tensor.Float64, 0, 1, nil, 3, 4); err == nil {
+		t.Errorf("Expected a RandomOp without a RandomStream to return an error")
+	}
+	if _, err = (RandomOp{}).Do(); err == nil {
+		t.Errorf("Expected a RandomOp without a RandomStream to fail to draw")
+	}
+}
+
+func TestInitializers(t *testing.T) {
+	stream := NewRandomStream(42)
+	shape := []int{200, 300}
+	fanIn, fanOut := 200.0, 300.0
+
+	initTests := []struct {
+		name  string
+		data  []float64
+		bound float64 // all values are expected to be within ±bound. 0 if unbounded
+		std   float64
+	}{
+		{"GlorotUniform", GlorotUniform(stream, 1)(tensor.Float64, shape...).([]float64), math.Sqrt(6 / (fanIn + fanOut)), math.Sqrt(2 / (fanIn + fanOut))},
+		{"GlorotNormal", GlorotNormal(stream, 1)(tensor.Float64, shape...).([]float64), 0, math.Sqrt(2 / (fanIn + fanOut))},
+		{"HeUniform", HeUniform(stream)(tensor.Float64, shape...).([]float64), math.Sqrt(6 / fanIn), math.Sqrt(2 / fanIn)},
+		{"HeNormal", HeNormal(stream)(tensor.Float64, shape...).([]float64), 0, math.Sqrt(2 / fanIn)},
+		{"LeCunUniform", LeCunUniform(stream)(tensor.Float64, shape...).([]float64), math.Sqrt(3 / fanIn), math.Sqrt(1 / fanIn)},
+		{"LeCunNormal", LeCunNormal(stream)(tensor.Float64, shape...).([]float64), 0, math.Sqrt(1 / fanIn)},
+		// a normal truncated at ±2σ has a standard deviation of about 0.88σ
+		{"TruncatedNormal", TruncatedNormal(stream, 0, 0.1)(tensor.Float64, shape...).([]float64), 0.2, 0.088},
+	}
+	for _, it := range initTests {
+		var sum, sumSq float64
+		for _, v := range it.data {
+			if it.bound > 0 && math.Abs(v) > it.bound {
+				t.Errorf("%v: %v is out of ±%v", it.name, v, it.bound)
+				break
+			}
+			sum += v
+			sumSq += v * v
+		}
+		n := float64(len(it.data))
+		mean := sum / n
+		std := math.Sqrt(sumSq/n - mean*mean)
+		if math.Abs(mean) > 0.05*it.std || math.Abs(std-it.std) > 0.05*it.std {
+			t.Errorf("%v: expected a mean of 0 and a standard deviation of %v. Got %v and %v", it.name, it.std, mean, std)
+		}
+	}
+}
+
+func TestOrthogonal(t *testing.T) {
+	stream := NewRandomStream(42)
+	for _, shape := range [][]int{{5, 3}, {3, 5}, {4, 2, 2}} {
+		data := Orthogonal(stream, 2)(tensor.Float64, shape...).([]float64)
+		rows := shape[0]
+		cols := len(data) / rows
+
+		// the vectors of the smaller dimension are orthogonal, with a norm of gain
+		n, stride, step := cols, cols, 1 // columns: element k of vector i is at k*cols+i
+		if rows < cols {
+			n, stride, step = rows, 1, cols // rows: element k of vector i is at i*cols+k
+		}
+		size := len(data) / n
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				var dot float64
+				for k := 0; k < size; k++ {
+					dot += data[k*stride+i*step] * data[k*stride+j*step]
+				}
+				expected := 0.0
+				if i == j {
+					expected = 4
+				}
+				if math.Abs(dot-expected) > 1e-9 {
+					t.Errorf("Orthogonal%v: the dot product of vectors %d and %d is %v. Expected %v", shape, i, j, dot, expected)
+				}
+			}
+		}
+	}
+}
+
+func equalFloat64s(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,764 @@
+package nnops
+
+import (
+	"fmt"
+	"hash"
+	"math"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/blas"
+	"gorgonia.org/gorgonia/internal/constructor"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &rnnOp{}
+	_ ops.Op = &rnnGradsOp{}
+	_ ops.Op = &rnnDiffOp{}
+)
+
+// rnnKind is the kind of recurrent cell an rnnOp runs
+type rnnKind byte
+
+const (
+	// lstmCell is a long short-term memory cell. Its gates come in the order i, f, g, o:
+	//	c' = σ(f)⊙c + σ(i)⊙tanh(g)
+	//	h' = σ(o)⊙tanh(c')
+	lstmCell rnnKind = iota
+	// gruCell is a gated recurrent unit. Its gates come in the order r, z, n:
+	//	n  = tanh(x·Wn + bn + σ(r)⊙(h·Un))
+	//	h' = (1 - σ(z))⊙n + σ(z)⊙h
+	gruCell
+)
+
+func (k rnnKind) String() string {
+	switch k {
+	case lstmCell:
+		return "LSTM"
+	case gruCell:
+		return "GRU"
+	}
+	return fmt.Sprintf("rnnKind(%d)", byte(k))
+}
+
+// gates returns the number of gates of the cell
+func (k rnnKind) gates() int {
+	if k == lstmCell {
+		return 4
+	}
+	return 3
+}
+
+// rnnOp is a fused recurrent cell. Each step computes all the gates with one matrix multiplication of the input and one of the hidden state,
+// followed by a single pointwise pass.
+//
+// The inputs are x, the state, W (I, G·H), U (H, G·H) and b (G·H), where G is the number of gates and H the size of the hidden state.
+// The state of a GRU is h (N, H). The state of an LSTM is h and c stacked into a (2, N, H) tensor.
+//
+// A cell takes a (N, I) input and returns the new state. A sequence takes a (T, N, I) input and an optional (T, N) mask,
+// runs the cell over the time axis (backwards if reverse is set), and returns the (T, N, H) hidden states of each step.
+// Where the mask is 0, the state is carried through unchanged and the output is 0, which allows for sequences of different lengths.
+//
+// All the computations are done in float64 precision, even for float32 inputs. The backward pass runs the forward pass again rather than keeping its intermediate results.
+type rnnOp struct {
+	kind    rnnKind
+	seq     bool // whether the op runs over a sequence rather than a single step
+	reverse bool // whether the sequence is run from the last step to the first
+	masked  bool // whether the op takes a mask
+}
+
+func newRNNOp(kind rnnKind, seq, reverse, masked bool) (*rnnOp, error) {
+	if kind != lstmCell && kind != gruCell {
+		return nil, errors.Errorf("Unknown recurrent cell %v", kind)
+	}
+	if !seq && (reverse || masked) {
+		return nil, errors.Errorf("Only a %v sequence can be reversed or masked", kind)
+	}
+	return &rnnOp{kind: kind, seq: seq, reverse: reverse, masked: masked}, nil
+}
+
+// Arity ...
+func (op *rnnOp) Arity() int {
+	if op.masked {
+		return 6
+	}
+	return 5
+}
+
+// rnnOp has this type:
+//		op :: Tensor-d a → Tensor-s a → Matrix a → Matrix a → Vector a → (Matrix a) → Tensor-r a
+// where d is 2 for a cell and 3 for a sequence, s is 3 for an LSTM and 2 for a GRU.
+// A cell returns a new state, while a sequence returns a Tensor-3 a. Only a masked sequence takes the mask.
+func (op *rnnOp) Type() hm.Type {
+	return hm.NewFnType(append(op.inputTypes(), op.retType())...)
+}
+
+// InferShape ...
+func (op *rnnOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "rnn")
+	}
+	shapes := make([]tensor.Shape, len(ns))
+	for i, n := range ns {
+		s, ok := n.(tensor.Shape)
+		if !ok {
+			return nil, errors.Errorf("Expected a shape")
+		}
+		shapes[i] = s
+	}
+	d, err := op.checkShapes(shapes...)
+	if err != nil {
+		return nil, err
+	}
+	if op.seq {
+		return tensor.Shape{d.steps, d.batch, d.hidden}, nil
+	}
+	return shapes[1].Clone(), nil
+}
+
+// Do ...
+func (op *rnnOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "rnn Do")
+	}
+	var in *rnnInputs
+	if in, err = op.checkInput(values...); err != nil {
+		return nil, err
+	}
+	shape := values[1].Shape().Clone()
+	if op.seq {
+		shape = tensor.Shape{in.steps, in.batch, in.hidden}
+	}
+	out := tensor.New(tensor.Of(values[0].Dtype()), tensor.WithShape(shape...))
+	return op.UsePreallocDo(out, values...)
+}
+
+// ReturnsPtr ...
+func (op *rnnOp) ReturnsPtr() bool { return true }
+
+// CallsExtern ...
+func (op *rnnOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *rnnOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *rnnOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *rnnOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *rnnOp) String() string {
+	if !op.seq {
+		return fmt.Sprintf("%vCell", op.kind)
+	}
+	return fmt.Sprintf("%v{reverse: %t, masked: %t}", op.kind, op.reverse, op.masked)
+}
+
+// DiffWRT ...
+func (op *rnnOp) DiffWRT(inputs int) []bool {
+	retVal := make([]bool, inputs)
+	for i := range retVal {
+		retVal[i] = i < 5 // the mask has no gradient
+	}
+	return retVal
+}
+
+// SymDiff runs the backward pass once, in an rnnGradsOp, and picks the gradient of each input out of its result.
+func (op *rnnOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	var grads *Node
+	if grads, err = ApplyOp(&rnnGradsOp{op}, append(append(Nodes(nil), inputs...), grad)...); err != nil {
+		return nil, err
+	}
+	args := append(Nodes{grads}, inputs[:5]...)
+	retVal = make(Nodes, len(inputs))
+	for i := 0; i < 5; i++ {
+		diff := &rnnDiffOp{rnnOp: op, wrt: i}
+		if retVal[i], err = ApplyOp(diff, args...); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// DoDiff runs the backward pass once, and adds the gradients to the derivatives of the inputs.
+func (op *rnnOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) (err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	values := make([]value.Value, len(inputs))
+	for i, n := range inputs {
+		values[i] = n.Value()
+	}
+	_, ydv := getDV(inputs[0], output)
+
+	var grads [5][]float64
+	if grads, err = op.grads(append(values, ydv.D)...); err != nil {
+		return errors.Wrapf(err, doFail, op)
+	}
+	for i, g := range grads {
+		_, dv := getDV(inputs[0], inputs[i])
+		d, ok := dv.D.(*tensor.Dense)
+		if !ok {
+			return errors.Errorf("Expected the derivative of input %d to be a *tensor.Dense. Got %T instead", i, dv.D)
+		}
+		if err = addFloat64s(d, g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UsePreallocDo ...
+func (op *rnnOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "rnn UsePreallocDo")
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	var in *rnnInputs
+	if in, err = op.checkInput(inputs...); err != nil {
+		return nil, err
+	}
+	ret, _ := op.forward(in)
+	if out.Shape().TotalSize() != len(ret) {
+		return nil, errors.Errorf("Expected prealloc to have %d elements. Got %v", len(ret), out.Shape())
+	}
+	if err = setFloat64s(out, ret); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (op *rnnOp) inputTypes() []hm.Type {
+	a := hm.TypeVariable('a')
+	xDims, stateDims := 2, 2
+	if op.seq {
+		xDims = 3
+	}
+	if op.kind == lstmCell {
+		stateDims = 3
+	}
+	matrix := constructor.NewTensorType(2, a)
+	retVal := []hm.Type{
+		constructor.NewTensorType(xDims, a),
+		constructor.NewTensorType(stateDims, a),
+		matrix,
+		matrix,
+		constructor.NewTensorType(1, a),
+	}
+	if op.masked {
+		retVal = append(retVal, matrix)
+	}
+	return retVal
+}
+
+func (op *rnnOp) retType() hm.Type {
+	if op.seq {
+		return constructor.NewTensorType(3, hm.TypeVariable('a'))
+	}
+	return op.inputTypes()[1]
+}
+
+// rnnDims are the sizes of the inputs of an rnnOp
+type rnnDims struct {
+	steps, batch, in, hidden int
+}
+
+func (op *rnnOp) checkShapes(shapes ...tensor.Shape) (d rnnDims, err error) {
+	x, state, w, u, b := shapes[0], shapes[1], shapes[2], shapes[3], shapes[4]
+	switch {
+	case op.seq && x.Dims() == 3:
+		d.steps, d.batch, d.in = x[0], x[1], x[2]
+	case !op.seq && x.Dims() == 2:
+		d.steps, d.batch, d.in = 1, x[0], x[1]
+	default:
+		return d, errors.Errorf("%v expects a (T, N, I) input for a sequence, or a (N, I) input for a cell. Got %v", op, x)
+	}
+
+	expected := tensor.Shape{d.batch, -1}
+	if op.kind == lstmCell {
+		expected = tensor.Shape{2, d.batch, -1}
+	}
+	if state.Dims() != len(expected) {
+		return d, errors.Errorf("%v expects a state of shape %v. Got %v", op, expected, state)
+	}
+	d.hidden = state[len(expected)-1]
+	expected[len(expected)-1] = d.hidden
+	if !state.Eq(expected) {
+		return d, errors.Errorf("%v expects a state of shape %v. Got %v", op, expected, state)
+	}
+
+	gh := op.kind.gates() * d.hidden
+	if !w.Eq(tensor.Shape{d.in, gh}) {
+		return d, errors.Errorf("%v expects W to have the shape %v. Got %v", op, tensor.Shape{d.in, gh}, w)
+	}
+	if !u.Eq(tensor.Shape{d.hidden, gh}) {
+		return d, errors.Errorf("%v expects U to have the shape %v. Got %v", op, tensor.Shape{d.hidden, gh}, u)
+	}
+	if b.TotalSize() != gh || b.Dims() != 1 {
+		return d, errors.Errorf("%v expects b to be a vector of %d elements. Got %v", op, gh, b)
+	}
+	if op.masked && !shapes[5].Eq(tensor.Shape{d.steps, d.batch}) {
+		return d, errors.Errorf("%v expects a mask of shape %v. Got %v", op, tensor.Shape{d.steps, d.batch}, shapes[5])
+	}
+	return d, nil
+}
+
+// rnnInputs are the inputs of an rnnOp, as float64s
+type rnnInputs struct {
+	rnnDims
+	x, h, c, w, u, b, mask []float64
+}
+
+func (op *rnnOp) checkInput(inputs ...value.Value) (in *rnnInputs, err error) {
+	shapes := make([]tensor.Shape, op.Arity())
+	data := make([][]float64, op.Arity())
+	for i := range shapes {
+		t, ok := inputs[i].(tensor.Tensor)
+		if !ok {
+			return nil, errors.Errorf("Expected input %d to be a tensor. Got %T instead", i, inputs[i])
+		}
+		if i > 0 && t.Dtype() != inputs[0].Dtype() {
+			return nil, errors.Errorf("Expected input %d to be of %v. Got %v instead", i, inputs[0].Dtype(), t.Dtype())
+		}
+		shapes[i] = t.Shape()
+		if data[i], err = float64sOf(t); err != nil {
+			return nil, err
+		}
+	}
+	in = &rnnInputs{x: data[0], h: data[1], w: data[2], u: data[3], b: data[4]}
+	if in.rnnDims, err = op.checkShapes(shapes...); err != nil {
+		return nil, err
+	}
+	if op.kind == lstmCell {
+		n := in.batch * in.hidden
+		in.h, in.c = data[1][:n], data[1][n:]
+	}
+	if op.masked {
+		in.mask = data[5]
+	}
+	return in, nil
+}
+
+// rnnStep holds what the backward pass needs from a step of the forward pass.
+type rnnStep struct {
+	h, c  []float64 // state going into the step
+	gates []float64 // activated gates, (N, G·H)
+	hn    []float64 // GRU only: h·Un, the part of the candidate that comes from the state
+	c2    []float64 // LSTM only: the cell state coming out of the step
+}
+
+// time returns the time step of the sth step run
+func (op *rnnOp) time(s int, in *rnnInputs) int {
+	if op.reverse {
+		return in.steps - 1 - s
+	}
+	return s
+}
+
+// keeps returns whether the nth example of time step t is run (i.e. is not masked out)
+func (in *rnnInputs) keeps(t, n int) bool { return in.mask == nil || in.mask[t*in.batch+n] != 0 }
+
+// forward runs the cell over the steps. It returns the output of the op, and the steps in the order they were run.
+func (op *rnnOp) forward(in *rnnInputs) (retVal []float64, steps []rnnStep) {
+	N, I, H := in.batch, in.in, in.hidden
+	G := op.kind.gates()
+	gh := G * H
+
+	h, c := in.h, in.c
+	var outputs []float64
+	if op.seq {
+		outputs = make([]float64, in.steps*N*H)
+	}
+	steps = make([]rnnStep, in.steps)
+	for s := range steps {
+		t := op.time(s, in)
+		xt := in.x[t*N*I : (t+1)*N*I]
+
+		// all the gates at once: ax = x·W + b and ah = h·U
+		ax := make([]float64, N*gh)
+		for n := 0; n < N; n++ {
+			copy(ax[n*gh:(n+1)*gh], in.b)
+		}
+		whichblas.Dgemm(blas.NoTrans, blas.NoTrans, N, gh, I, 1, xt, I, in.w, gh, 1, ax, gh)
+		ah := make([]float64, N*gh)
+		whichblas.Dgemm(blas.NoTrans, blas.NoTrans, N, gh, H, 1, h, H, in.u, gh, 0, ah, gh)
+
+		step := rnnStep{h: h, c: c, gates: ax}
+		h2 := make([]float64, N*H)
+		var c2 []float64
+		switch op.kind {
+		case lstmCell:
+			c2 = make([]float64, N*H)
+			for n := 0; n < N; n++ {
+				row := ax[n*gh : (n+1)*gh]
+				hrow := ah[n*gh : (n+1)*gh]
+				for j := 0; j < H; j++ {
+					i := stableSigmoid(row[j] + hrow[j])
+					f := stableSigmoid(row[H+j] + hrow[H+j])
+					g := math.Tanh(row[2*H+j] + hrow[2*H+j])
+					o := stableSigmoid(row[3*H+j] + hrow[3*H+j])
+					row[j], row[H+j], row[2*H+j], row[3*H+j] = i, f, g, o
+
+					k := n*H + j
+					c2[k] = f*c[k] + i*g
+					h2[k] = o * math.Tanh(c2[k])
+				}
+			}
+			step.c2 = c2
+		case gruCell:
+			step.hn = make([]float64, N*H)
+			for n := 0; n < N; n++ {
+				row := ax[n*gh : (n+1)*gh]
+				hrow := ah[n*gh : (n+1)*gh]
+				for j := 0; j < H; j++ {
+					k := n*H + j
+					step.hn[k] = hrow[2*H+j]
+					r := stableSigmoid(row[j] + hrow[j])
+					z := stableSigmoid(row[H+j] + hrow[H+j])
+					nn := math.Tanh(row[2*H+j] + r*step.hn[k])
+					row[j], row[H+j], row[2*H+j] = r, z, nn
+
+					h2[k] = (1-z)*nn + z*h[k]
+				}
+			}
+		}
+
+		for n := 0; n < N; n++ {
+			if !in.keeps(t, n) {
+				copy(h2[n*H:(n+1)*H], h[n*H:(n+1)*H])
+				if c2 != nil {
+					copy(c2[n*H:(n+1)*H], c[n*H:(n+1)*H])
+				}
+				continue
+			}
+			if op.seq {
+				copy(outputs[(t*N+n)*H:(t*N+n+1)*H], h2[n*H:(n+1)*H])
+			}
+		}
+		steps[s] = step
+		h, c = h2, c2
+	}
+
+	switch {
+	case op.seq:
+		return outputs, steps
+	case op.kind == lstmCell:
+		return append(append(make([]float64, 0, 2*len(h)), h...), c...), steps
+	}
+	return h, steps
+}
+
+// backward runs backpropagation through time, given the gradient of the output.
+// It returns the gradients of x, the state, W, U and b, in that order.
+func (op *rnnOp) backward(in *rnnInputs, steps []rnnStep, grad []float64) (grads [5][]float64) {
+	N, I, H := in.batch, in.in, in.hidden
+	gh := op.kind.gates() * H
+
+	dx := make([]float64, len(in.x))
+	dw := make([]float64, len(in.w))
+	du := make([]float64, len(in.u))
+	db := make([]float64, len(in.b))
+
+	// the gradients of the state coming out of the current step
+	dh := make([]float64, N*H)
+	var dc []float64
+	if op.kind == lstmCell {
+		dc = make([]float64, N*H)
+	}
+	if !op.seq {
+		copy(dh, grad[:N*H])
+		if dc != nil {
+			copy(dc, grad[N*H:])
+		}
+	}
+
+	for s := len(steps) - 1; s >= 0; s-- {
+		t := op.time(s, in)
+		st := steps[s]
+		xt := in.x[t*N*I : (t+1)*N*I]
+
+		dax := make([]float64, N*gh) // gradient of x·W + b
+		dah := dax                   // gradient of h·U
+		if op.kind == gruCell {
+			dah = make([]float64, N*gh)
+		}
+		dhPrev := make([]float64, N*H)
+		var dcPrev []float64
+		if dc != nil {
+			dcPrev = make([]float64, N*H)
+		}
+
+		for n := 0; n < N; n++ {
+			if !in.keeps(t, n) {
+				// the state went through unchanged
+				copy(dhPrev[n*H:(n+1)*H], dh[n*H:(n+1)*H])
+				if dc != nil {
+					copy(dcPrev[n*H:(n+1)*H], dc[n*H:(n+1)*H])
+				}
+				continue
+			}
+			if op.seq {
+				for j, g := range grad[(t*N+n)*H : (t*N+n+1)*H] {
+					dh[n*H+j] += g
+				}
+			}
+
+			gates := st.gates[n*gh : (n+1)*gh]
+			dxrow, dhrow := dax[n*gh:(n+1)*gh], dah[n*gh:(n+1)*gh]
+			for j := 0; j < H; j++ {
+				k := n*H + j
+				switch op.kind {
+				case lstmCell:
+					i, f, g, o := gates[j], gates[H+j], gates[2*H+j], gates[3*H+j]
+					tc := math.Tanh(st.c2[k])
+					dck := dc[k] + dh[k]*o*(1-tc*tc)
+					dxrow[j] = dck * g * i * (1 - i)
+					dxrow[H+j] = dck * st.c[k] * f * (1 - f)
+					dxrow[2*H+j] = dck * i * (1 - g*g)
+					dxrow[3*H+j] = dh[k] * tc * o * (1 - o)
+					dcPrev[k] = dck * f
+				case gruCell:
+					r, z, nn := gates[j], gates[H+j], gates[2*H+j]
+					dn := dh[k] * (1 - z) * (1 - nn*nn)
+					dr := dn * st.hn[k] * r * (1 - r)
+					dz := dh[k] * (st.h[k] - nn) * z * (1 - z)
+					dxrow[j], dxrow[H+j], dxrow[2*H+j] = dr, dz, dn
+					dhrow[j], dhrow[H+j], dhrow[2*H+j] = dr, dz, dn*r
+					dhPrev[k] = dh[k] * z
+				}
+			}
+		}
+
+		// dW += xᵀ·dax, dU += hᵀ·dah, dx = dax·Wᵀ and dh += dah·Uᵀ
+		whichblas.Dgemm(blas.Trans, blas.NoTrans, I, gh, N, 1, xt, I, dax, gh, 1, dw, gh)
+		whichblas.Dgemm(blas.Trans, blas.NoTrans, H, gh, N, 1, st.h, H, dah, gh, 1, du, gh)
+		whichblas.Dgemm(blas.NoTrans, blas.Trans, N, I, gh, 1, dax, gh, in.w, gh, 0, dx[t*N*I:(t+1)*N*I], I)
+		whichblas.Dgemm(blas.NoTrans, blas.Trans, N, H, gh, 1, dah, gh, in.u, gh, 1, dhPrev, H)
+		for i, v := range dax {
+			db[i%gh] += v
+		}
+		dh, dc = dhPrev, dcPrev
+	}
+
+	dstate := dh
+	if dc != nil {
+		dstate = append(dh, dc...)
+	}
+	return [5][]float64{dx, dstate, dw, du, db}
+}
+
+// grads computes the gradients of all the inputs. Its inputs are the inputs of the op followed by the gradient of the output.
+func (op *rnnOp) grads(inputs ...value.Value) (grads [5][]float64, err error) {
+	var in *rnnInputs
+	if in, err = op.checkInput(inputs[:op.Arity()]...); err != nil {
+		return
+	}
+	gt, ok := inputs[op.Arity()].(tensor.Tensor)
+	if !ok {
+		return grads, errors.Errorf("Expected grad to be a tensor. Got %T instead", inputs[op.Arity()])
+	}
+	var g []float64
+	if g, err = float64sOf(gt); err != nil {
+		return
+	}
+	ret, steps := op.forward(in)
+	if len(g) != len(ret) {
+		return grads, errors.Errorf("Expected the gradient to have %d elements. Got %v", len(ret), gt.Shape())
+	}
+	return op.backward(in, steps, g), nil
+}
+
+// rnnGradsOp runs backpropagation through time. It returns the gradients of x, the state, W, U and b, flattened and
+// concatenated into one vector. Its inputs are the inputs of the rnnOp followed by the gradient of the output.
+type rnnGradsOp struct {
+	*rnnOp
+}
+
+// Arity ...
+func (op *rnnGradsOp) Arity() int { return op.rnnOp.Arity() + 1 }
+
+// Type ...
+func (op *rnnGradsOp) Type() hm.Type {
+	ts := op.inputTypes()
+	return hm.NewFnType(append(ts, op.retType(), constructor.NewTensorType(1, hm.TypeVariable('a')))...)
+}
+
+// InferShape ...
+func (op *rnnGradsOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "rnnGrads")
+	}
+	var size int
+	for _, n := range ns[:5] {
+		s, ok := n.(tensor.Shape)
+		if !ok {
+			return nil, errors.Errorf("Expected a shape")
+		}
+		size += s.TotalSize()
+	}
+	return tensor.Shape{size}, nil
+}
+
+// Do ...
+func (op *rnnGradsOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "rnnGrads Do")
+	}
+	var size int
+	for _, v := range values[:5] {
+		size += v.Shape().TotalSize()
+	}
+	return op.UsePreallocDo(tensor.New(tensor.Of(values[0].Dtype()), tensor.WithShape(size)), values...)
+}
+
+// WriteHash ...
+func (op *rnnGradsOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *rnnGradsOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *rnnGradsOp) String() string { return fmt.Sprintf("%vGrads", op.rnnOp) }
+
+// UsePreallocDo ...
+func (op *rnnGradsOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "rnnGrads UsePreallocDo")
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	var grads [5][]float64
+	if grads, err = op.grads(inputs...); err != nil {
+		return nil, err
+	}
+	all := make([]float64, 0, out.Shape().TotalSize())
+	for _, g := range grads {
+		all = append(all, g...)
+	}
+	if len(all) != out.Shape().TotalSize() {
+		return nil, errors.Errorf("Expected prealloc to have %d elements. Got %v", len(all), out.Shape())
+	}
+	if err = setFloat64s(out, all); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// rnnDiffOp picks the gradient of one of the inputs of an rnnOp out of the result of an rnnGradsOp.
+// Its inputs are the result of the rnnGradsOp followed by x, the state, W, U and b, whose shapes locate the gradient.
+type rnnDiffOp struct {
+	*rnnOp
+	wrt int // 0 for x, 1 for the state, 2 for W, 3 for U and 4 for b
+}
+
+// Arity ...
+func (op *rnnDiffOp) Arity() int { return 6 }
+
+// Type ...
+func (op *rnnDiffOp) Type() hm.Type {
+	ts := op.inputTypes()[:5]
+	grads := constructor.NewTensorType(1, hm.TypeVariable('a'))
+	return hm.NewFnType(append(append([]hm.Type{grads}, ts...), ts[op.wrt])...)
+}
+
+// InferShape ...
+func (op *rnnDiffOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "rnnDiff")
+	}
+	s, ok := ns[1+op.wrt].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a shape")
+	}
+	return s.Clone(), nil
+}
+
+// Do ...
+func (op *rnnDiffOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "rnnDiff Do")
+	}
+	if op.wrt < 0 || op.wrt > 4 {
+		return nil, errors.Errorf("%v has no gradient for input %d", op.rnnOp, op.wrt)
+	}
+	var out value.Value
+	if out, err = value.CloneValue(values[1+op.wrt]); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values...)
+}
+
+// WriteHash ...
+func (op *rnnDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *rnnDiffOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *rnnDiffOp) String() string { return fmt.Sprintf("%vDiff%d", op.rnnOp, op.wrt) }
+
+// UsePreallocDo ...
+func (op *rnnDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "rnnDiff UsePreallocDo")
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+	if op.wrt < 0 || op.wrt > 4 {
+		return nil, errors.Errorf("%v has no gradient for input %d", op.rnnOp, op.wrt)
+	}
+	gt, ok := inputs[0].(tensor.Tensor)
+	if !ok {
+		return nil, errors.Errorf("Expected the gradients to be a tensor. Got %T instead", inputs[0])
+	}
+	var all []float64
+	if all, err = float64sOf(gt); err != nil {
+		return nil, err
+	}
+
+	// the gradients of the inputs before wrt come first
+	var start int
+	for _, v := range inputs[1 : 1+op.wrt] {
+		start += v.Shape().TotalSize()
+	}
+	end := start + inputs[1+op.wrt].Shape().TotalSize()
+	if end > len(all) {
+		return nil, errors.Errorf("Expected at least %d gradients. Got %d", end, len(all))
+	}
+	if err = setFloat64s(out, all[start:end]); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// addFloat64s adds data to a float tensor, converting it to float32 if need be.
+func addFloat64s(out *tensor.Dense, data []float64) error {
+	switch out.Dtype() {
+	case Float64:
+		f64s := out.Float64s()
+		for i, v := range data {
+			f64s[i] += v
+		}
+	case Float32:
+		f32s := out.Float32s()
+		for i, v := range data {
+			f32s[i] += float32(v)
+		}
+	default:
+		return nyi("addFloat64s", out.Dtype())
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package nnops
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+func TestRNNOp(t *testing.T) {
+	rnnTests := []struct {
+		kind                 rnnKind
+		seq, reverse, masked bool
+	}{
+		{lstmCell, false, false, false},
+		{gruCell, false, false, false},
+		{lstmCell, true, false, false},
+		{gruCell, true, true, false},
+		{lstmCell, true, true, true},
+		{gruCell, true, false, true},
+	}
+
+	const steps, batch, in, hidden = 3, 2, 3, 2
+	for _, rt := range rnnTests {
+		op, err := newRNNOp(rt.kind, rt.seq, rt.reverse, rt.masked)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		src := newSines(0.8, 1.3)
+		gh := rt.kind.gates() * hidden
+		x := src.dense(batch, in)
+		if rt.seq {
+			x = src.dense(steps, batch, in)
+		}
+		state := src.dense(batch, hidden)
+		if rt.kind == lstmCell {
+			state = src.dense(2, batch, hidden)
+		}
+		inputs := []value.Value{x, state, src.dense(in, gh), src.dense(hidden, gh), src.dense(gh)}
+		if rt.masked {
+			// the second sequence is one step shorter
+			mask := []float64{1, 1, 1, 1, 1, 0}
+			inputs = append(inputs, tensor.New(tensor.WithShape(steps, batch), tensor.WithBacking(mask)))
+		}
+
+		y, err := op.Do(inputs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rt.masked {
+			if out := y.Data().([]float64); out[(2*batch+1)*hidden] != 0 || out[(2*batch+1)*hidden+1] != 0 {
+				t.Errorf("%v: expected the masked step to output 0. Got %v", op, out)
+			}
+		}
+		grad := src.dense(y.Shape()...)
+		gData := grad.Float64s()
+
+		// the gradients are checked against the numerical gradients of Σ y⊙grad
+		loss := func() float64 {
+			y, err := op.Do(inputs...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var sum float64
+			for i, v := range y.Data().([]float64) {
+				sum += v * gData[i]
+			}
+			return sum
+		}
+
+		// the backward pass is run once, and each rnnDiffOp picks the gradient of its input out of its result
+		grads, err := (&rnnGradsOp{op}).Do(append(inputs, grad)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for wrt, in := range inputs[:5] {
+			diff := &rnnDiffOp{rnnOp: op, wrt: wrt}
+			d, err := diff.Do(append([]value.Value{grads}, inputs[:5]...)...)
+			if err != nil {
+				t.Errorf("%v: %v", diff, err)
+				continue
+			}
+			const eps = 1e-6
+			data := in.(*tensor.Dense).Float64s()
+			for i, v := range d.Data().([]float64) {
+				orig := data[i]
+				data[i] = orig + eps
+				lp := loss()
+				data[i] = orig - eps
+				lm := loss()
+				data[i] = orig
+				if ng := (lp - lm) / (2 * eps); math.Abs(ng-v) > 1e-6 {
+					t.Errorf("%v: gradient %d - expected %v. Got %v", diff, i, ng, v)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package nnops
+
+import (
+	"fmt"
+	"hash"
+	"math"
+
+	"github.com/chewxy/hm"
+	"github.com/chewxy/math32"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/execution"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/ops"
+	"gorgonia.org/tensor"
+)
+
+var (
+	_ ops.Op = &softmaxOp{}
+	_ ops.Op = &softmaxDiffOp{}
+)
+
+// softmaxOp computes the softmax (or the log softmax) along an axis.
+// The maximum along the axis is subtracted before exponentiating, so large inputs do not overflow.
+type softmaxOp struct {
+	axis int // negative axes count from the last axis
+	log  bool
+}
+
+func newSoftmaxOp(axis int, log bool) *softmaxOp { return &softmaxOp{axis: axis, log: log} }
+
+// Arity ...
+func (op *softmaxOp) Arity() int { return 1 }
+
+// Type ...
+func (op *softmaxOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	return hm.NewFnType(a, a)
+}
+
+// InferShape ...
+func (op *softmaxOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "softmax")
+	}
+	s := ns[0].(tensor.Shape)
+	if _, err := op.resolveAxis(s); err != nil {
+		return nil, err
+	}
+	return s.Clone(), nil
+}
+
+// Do ...
+func (op *softmaxOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "softmax Do")
+	}
+	var out value.Value
+	if out, err = value.CloneValue(values[0]); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values[0])
+}
+
+// ReturnsPtr ...
+func (op *softmaxOp) ReturnsPtr() bool { return true }
+
+// CallsExtern ...
+func (op *softmaxOp) CallsExtern() bool { return false }
+
+// OverwritesInput ...
+func (op *softmaxOp) OverwritesInput() int { return -1 }
+
+// WriteHash ...
+func (op *softmaxOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *softmaxOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *softmaxOp) String() string {
+	if op.log {
+		return fmt.Sprintf("LogSoftmax{%d}", op.axis)
+	}
+	return fmt.Sprintf("Softmax{%d}", op.axis)
+}
+
+// DiffWRT ...
+func (op *softmaxOp) DiffWRT(inputs int) []bool { return []bool{true} }
+
+// SymDiff ...
+func (op *softmaxOp) SymDiff(inputs Nodes, output *Node, grad *Node) (retVal Nodes, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return
+	}
+	diff := &softmaxDiffOp{op}
+
+	var ret *Node
+	if ret, err = ApplyOp(diff, output, grad); err != nil {
+		return nil, err
+	}
+	return Nodes{ret}, nil
+}
+
+// DoDiff ...
+func (op *softmaxOp) DoDiff(ctx execution.Context, inputs Nodes, output *Node) error {
+	diff := &softmaxDiffOp{op}
+	xdv, ydv := getDV(inputs[0], output)
+	_, err := diff.UsePreallocDo(xdv.D, ydv.Value, ydv.D)
+	return err
+}
+
+// UsePreallocDo ...
+func (op *softmaxOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "softmax UsePreallocDo")
+	}
+	x, ok := inputs[0].(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected input to be a *tensor.Dense. Got %T instead", inputs[0])
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+
+	outer, dim, inner, err := op.split(x.Shape())
+	if err != nil {
+		return nil, err
+	}
+	switch x.Dtype() {
+	case Float64:
+		op.f64s(outer, dim, inner, out.Float64s(), x.Float64s())
+	case Float32:
+		op.f32s(outer, dim, inner, out.Float32s(), x.Float32s())
+	default:
+		return nil, nyi("Softmax Do", x.Dtype())
+	}
+	return out, nil
+}
+
+func (op *softmaxOp) resolveAxis(s tensor.Shape) (int, error) {
+	axis := op.axis
+	if axis < 0 {
+		axis += s.Dims()
+	}
+	if axis < 0 || axis >= s.Dims() {
+		return -1, errors.Errorf("Cannot compute %v of a tensor of shape %v", op, s)
+	}
+	return axis, nil
+}
+
+// split returns the number of slices before the axis, the size of the axis and the number of elements after the axis.
+// The element d of the slice (o, i) is then found at (o*dim + d)*inner + i.
+func (op *softmaxOp) split(s tensor.Shape) (outer, dim, inner int, err error) {
+	var axis int
+	if axis, err = op.resolveAxis(s); err != nil {
+		return
+	}
+	outer, dim, inner = 1, s[axis], 1
+	for _, d := range s[:axis] {
+		outer *= d
+	}
+	for _, d := range s[axis+1:] {
+		inner *= d
+	}
+	return
+}
+
+func (op *softmaxOp) f64s(outer, dim, inner int, out, x []float64) {
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			start := o*dim*inner + i
+
+			max := math.Inf(-1)
+			for d := 0; d < dim; d++ {
+				if v := x[start+d*inner]; v > max {
+					max = v
+				}
+			}
+			var sum float64
+			for d := 0; d < dim; d++ {
+				idx := start + d*inner
+				out[idx] = math.Exp(x[idx] - max)
+				sum += out[idx]
+			}
+
+			if op.log {
+				logSum := math.Log(sum) + max
+				for d := 0; d < dim; d++ {
+					idx := start + d*inner
+					out[idx] = x[idx] - logSum
+				}
+				continue
+			}
+			for d := 0; d < dim; d++ {
+				out[start+d*inner] /= sum
+			}
+		}
+	}
+}
+
+func (op *softmaxOp) f32s(outer, dim, inner int, out, x []float32) {
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			start := o*dim*inner + i
+
+			max := math32.Inf(-1)
+			for d := 0; d < dim; d++ {
+				if v := x[start+d*inner]; v > max {
+					max = v
+				}
+			}
+			var sum float32
+			for d := 0; d < dim; d++ {
+				idx := start + d*inner
+				out[idx] = math32.Exp(x[idx] - max)
+				sum += out[idx]
+			}
+
+			if op.log {
+				logSum := math32.Log(sum) + max
+				for d := 0; d < dim; d++ {
+					idx := start + d*inner
+					out[idx] = x[idx] - logSum
+				}
+				continue
+			}
+			for d := 0; d < dim; d++ {
+				out[start+d*inner] /= sum
+			}
+		}
+	}
+}
+
+// softmaxDiffOp computes the gradient of a softmax from its output y and the gradient of its output g:
+//	softmax:    y ⊙ (g - Σ g⊙y)
+//	logsoftmax: g - exp(y) Σ g
+// The sums are along the axis of the softmax.
+type softmaxDiffOp struct{ *softmaxOp }
+
+// Arity ...
+func (op *softmaxDiffOp) Arity() int { return 2 }
+
+// Type ...
+func (op *softmaxDiffOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	return hm.NewFnType(a, a, a)
+}
+
+// InferShape ...
+func (op *softmaxDiffOp) InferShape(ns ...ops.DimSizer) (tensor.Shape, error) {
+	if err := ops.CheckArity(op, len(ns)); err != nil {
+		return nil, errors.Wrapf(err, "softmaxDiff")
+	}
+	return ns[0].(tensor.Shape).Clone(), nil
+}
+
+// Do ...
+func (op *softmaxDiffOp) Do(values ...value.Value) (retVal value.Value, err error) {
+	if err := ops.CheckArity(op, len(values)); err != nil {
+		return nil, errors.Wrapf(err, "softmaxDiff Do")
+	}
+	var out value.Value
+	if out, err = value.CloneValue(values[0]); err != nil {
+		return nil, err
+	}
+	return op.UsePreallocDo(out, values...)
+}
+
+// WriteHash ...
+func (op *softmaxDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, op.String()) }
+
+// Hashcode ...
+func (op *softmaxDiffOp) Hashcode() uint32 { return simpleHash(op) }
+
+func (op *softmaxDiffOp) String() string { return op.softmaxOp.String() + "Diff" }
+
+// UsePreallocDo ...
+func (op *softmaxDiffOp) UsePreallocDo(prealloc value.Value, inputs ...value.Value) (retVal value.Value, err error) {
+	if err = ops.CheckArity(op, len(inputs)); err != nil {
+		return nil, errors.Wrapf(err, "softmaxDiff UsePreallocDo")
+	}
+	y, ok := inputs[0].(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected output to be a *tensor.Dense. Got %T instead", inputs[0])
+	}
+	grad, ok := inputs[1].(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected grad to be a *tensor.Dense. Got %T instead", inputs[1])
+	}
+	out, ok := prealloc.(*tensor.Dense)
+	if !ok {
+		return nil, errors.Errorf("Expected prealloc to be a *tensor.Dense. Got %T instead", prealloc)
+	}
+
+	outer, dim, inner, err := op.split(y.Shape())
+	if err != nil {
+		return nil, err
+	}
+	switch y.Dtype() {
+	case Float64:
+		op.f64s(outer, dim, inner, out.Float64s(), y.Float64s(), grad.Float64s())
+	case Float32:
+		op.f32s(outer, dim, inner, out.Float32s(), y.Float32s(), grad.Float32s())
+	default:
+		return nil, nyi("SoftmaxDiff Do", y.Dtype())
+	}
+	return out, nil
+}
+
+func (op *softmaxDiffOp) f64s(outer, dim, inner int, dx, y, g []float64) {
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			start := o*dim*inner + i
+
+			var sum float64
+			for d := 0; d < dim; d++ {
+				idx := start + d*inner
+				if op.log {
+					sum += g[idx]
+				} else {
+					sum += g[idx] * y[idx]
+				}
+			}
+			for d := 0; d < dim; d++ {
+				idx := start + d*inner
+				if op.log {
+					dx[idx] = g[idx] - math.Exp(y[idx])*sum
+				} else {
+					dx[idx] = y[idx] * (g[idx] - sum)
+				}
+			}
+		}
+	}
+}
+
+func (op *softmaxDiffOp) f32s(outer, dim, inner int, dx, y, g []float32) {
+	for o := 0; o < outer; o++ {
+		for i := 0; i < inner; i++ {
+			start := o*dim*inner + i
+
+			var sum float32
+			for d := 0; d < dim; d++ {
+				idx := start + d*inner
+				if op.log {
+					sum += g[idx]
+				} else {
+					sum += g[idx] * y[idx]
+				}
+			}
+			for d := 0; d < dim; d++ {
+				idx := start + d*inner
+				if op.log {
+					dx[idx] = g[idx] - math32.Exp(y[idx])*sum
+				} else {
+					dx[idx] = y[idx] * (g[idx] - sum)
+				}
+			}
+		}
+	}
+}
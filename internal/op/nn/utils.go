@@ -80,3 +80,10 @@ func CheckConvolutionParams(pad, stride, dilation []int) error {
 	}
 	return nil
 }
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -112,6 +112,14 @@ type NoRetOp interface {
 	ReturnsNothing() bool
 }
 
+// A StatefulOp is an Op whose output is not a function of its inputs alone, such as an op that draws random numbers.
+// Executing it again does not compute the same value, so it is never recomputed.
+type StatefulOp interface {
+	Op
+
+	IsStateful() bool
+}
+
 // ReductionOp changes the shape of the node
 type ReductionOp interface {
 	Op
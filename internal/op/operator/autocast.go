@@ -0,0 +1,220 @@
+package operator
+
+import (
+	"fmt"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gonum.org/v1/gonum/graph"
+	"gonum.org/v1/gonum/graph/topo"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/op"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// AutocastPolicy says which ops run in low precision in mixed-precision training. The ops are keyed by OpType.
+//
+// The ops in Allow run in Low: their float inputs are cast to Low. The ops in Deny run in at least Float32: their inputs in
+// half precision are cast to Float32. The other ops run in the widest dtype of their float inputs, so they follow what feeds them.
+//
+// Typically the MatMuls and Convs are allowed, and the reductions, the normalizations, Exp, Log and the losses are denied.
+type AutocastPolicy struct {
+	Low   tensor.Dtype // Float16 or BFloat16
+	Allow map[string]bool
+	Deny  map[string]bool
+}
+
+// OpType returns the key of an op in an AutocastPolicy: the name of the operator for the pointwise ops (e.g. "+" or "exp"),
+// and the Go type of the op otherwise (e.g. "*nnops.convolution").
+func OpType(o op.Op) string {
+	switch ot := o.(type) {
+	case interface{ binOpType() ʘBinaryOperatorType }:
+		return ot.binOpType().String()
+	case interface{ unaryOpType() ʘUnaryOperatorType }:
+		return ot.unaryOpType().String()
+	}
+	return fmt.Sprintf("%T", o)
+}
+
+// Autocast inserts Cast nodes in g so that the ops run in the precision the policy asks for, and sets the types of the nodes
+// that change precision. The leaves are left as they are: float32 weights stay the master copy, and as the gradient of a Cast
+// is cast back to the dtype of its input, their gradients are float32 too. An input cast to a dtype is cast once, whatever the
+// number of nodes it feeds.
+//
+// Ops built for a dtype, such as LeakyRelu, are rebuilt for the precision they run in. Ops of other packages whose type
+// names their dtypes cannot be rebuilt: they keep their precision, and their inputs are cast back to it.
+//
+// The output of the graph (e.g. the loss) is in the precision of the op that computes it, so that op is usually denied.
+// Autocast returns the inserted nodes.
+func Autocast(g *exprgraph.ExprGraph, policy AutocastPolicy) (inserted []*exprgraph.Node, err error) {
+	if !factory.IsHalf(policy.Low) {
+		return nil, errors.Errorf("Expected Float16 or BFloat16 as the low precision. Got %v", policy.Low)
+	}
+
+	// the edges go from a node to its inputs, so the inputs come last
+	var sorted []graph.Node
+	if sorted, err = topo.Sort(g); err != nil {
+		return nil, errors.Wrap(err, "Cannot autocast")
+	}
+
+	// the dtypes the nodes were built for, before any of them change precision
+	built := make(map[int64]tensor.Dtype)
+	for _, sn := range sorted {
+		if n, ok := sn.(*exprgraph.Node); ok {
+			if dt, err := dtypeOf(n.T); err == nil {
+				built[n.ID()] = dt
+			}
+		}
+	}
+
+	type key struct {
+		id int64
+		dt tensor.Dtype
+	}
+	casts := make(map[key]*exprgraph.Node)
+	for i := len(sorted) - 1; i >= 0; i-- {
+		n, ok := sorted[i].(*exprgraph.Node)
+		if !ok || n.Op == nil {
+			continue
+		}
+		ins := g.InputsOf(n)
+		if o, isCast := n.Op.(*castOp); isCast {
+			// a cast keeps its output, and follows its input if that changed precision
+			if err = recast(n, o, ins); err != nil {
+				return nil, errors.Wrapf(err, "Cannot autocast %v", n.Name)
+			}
+			continue
+		}
+
+		dts := make([]tensor.Dtype, len(ins))
+		var floats []tensor.Dtype
+		for j, in := range ins {
+			if dts[j], err = dtypeOf(in.T); err != nil {
+				return nil, errors.Wrapf(err, "Cannot autocast input %d of %v", j, n.Name)
+			}
+			if c, _ := classOf(dts[j]); c == floatClass {
+				floats = append(floats, dts[j])
+			}
+		}
+		if len(floats) == 0 {
+			continue // integers, bools and complex numbers are not touched
+		}
+
+		var to tensor.Dtype
+		switch k := OpType(n.Op); {
+		case policy.Allow[k]:
+			to = policy.Low
+		case policy.Deny[k]:
+			to = widestFloat(append(floats, tensor.Float32)...)
+		default:
+			to = widestFloat(floats...)
+		}
+
+		// An op built for a dtype is rebuilt for the new one. The other ops whose type has no type variables cannot run in
+		// another precision, so their inputs are cast back to the dtypes they were built for.
+		o := n.Op
+		fixed := false
+		if d, ok := n.Op.(dtypedOp); ok {
+			if o, err = d.forDtype(to); err != nil {
+				return nil, errors.Wrapf(err, "Cannot autocast %v", n.Name)
+			}
+		} else {
+			fixed = len(n.Op.Type().FreeTypeVar()) == 0
+		}
+
+		for j, in := range ins {
+			want := to
+			if fixed {
+				want = built[in.ID()]
+			}
+			if c, _ := classOf(dts[j]); c != floatClass || dts[j] == want {
+				continue
+			}
+			c, ok := casts[key{in.ID(), want}]
+			if !ok {
+				if c, err = insertCast(g, in, dts[j], want); err != nil {
+					return nil, errors.Wrapf(err, "Cannot autocast input %d of %v", j, n.Name)
+				}
+				casts[key{in.ID(), want}] = c
+				inserted = append(inserted, c)
+			}
+			g.RemoveEdge(n.ID(), in.ID())
+			g.SetWeightedEdge(g.NewWeightedEdge(n, c, float64(j)))
+		}
+		if fixed {
+			continue
+		}
+
+		// ops that return floats (and not e.g. the bools of a comparison) now return them in the new precision
+		var dt tensor.Dtype
+		if dt, err = dtypeOf(n.T); err != nil {
+			return nil, errors.Wrapf(err, "Cannot autocast %v", n.Name)
+		}
+		if c, _ := classOf(dt); c == floatClass {
+			n.T = withDtype(n.T, to)
+		}
+		n.Op = o
+	}
+	return inserted, nil
+}
+
+// dtypedOp is an op that is built for the dtype of its inputs, rather than typed by type variables. Autocast rebuilds it
+// for the dtype its inputs are cast to.
+type dtypedOp interface {
+	op.Op
+	forDtype(dt tensor.Dtype) (op.Op, error)
+}
+
+// recast rebuilds the castOp o of n for the dtype of its input, which Autocast may have changed.
+func recast(n *exprgraph.Node, o *castOp, ins []*exprgraph.Node) (err error) {
+	if len(ins) != 1 {
+		return errors.Errorf("%v expects 1 input. Got %d", o, len(ins))
+	}
+	var from tensor.Dtype
+	if from, err = dtypeOf(ins[0].T); err != nil || from == o.from {
+		return err
+	}
+	n.Op, err = newCastOp(from, o.to, o.dims)
+	return err
+}
+
+// insertCast adds a node that casts in from one dtype to another to g
+func insertCast(g *exprgraph.ExprGraph, in *exprgraph.Node, from, to tensor.Dtype) (*exprgraph.Node, error) {
+	o, err := newCastOp(from, to, in.Shape.Dims())
+	if err != nil {
+		return nil, err
+	}
+	c := g.NewVertex()
+	c.Op, c.T, c.Shape = o, withDtype(in.T, to), in.Shape.Clone()
+	c.Name = fmt.Sprintf("Cast(%v, %v)", in.Name, to)
+	g.AddNode(c)
+	g.SetWeightedEdge(g.NewWeightedEdge(c, in, 0))
+	return c, nil
+}
+
+// withDtype returns the type t, with its dtype replaced by dt
+func withDtype(t hm.Type, dt tensor.Dtype) hm.Type {
+	switch tt := t.(type) {
+	case *factory.TensorType:
+		return factory.NewTensorType(tt.Dims, dt)
+	case factory.TensorType:
+		return factory.NewTensorType(tt.Dims, dt)
+	}
+	return dt
+}
+
+// widestFloat returns the float dtype that holds all the given ones. Float16 and BFloat16 are both held by Float32.
+func widestFloat(dts ...tensor.Dtype) tensor.Dtype {
+	widest := dts[0]
+	for _, dt := range dts[1:] {
+		switch {
+		case dt == widest:
+		case dt == tensor.Float64 || widest == tensor.Float64:
+			widest = tensor.Float64
+		default: // Float32, or the two halves
+			widest = tensor.Float32
+		}
+	}
+	return widest
+}
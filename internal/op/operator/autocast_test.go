@@ -0,0 +1,100 @@
+package operator
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"testing"
+
+	"github.com/chewxy/hm"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/op"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// f32Op stands for the ops of other packages that are built for float32, and cannot be rebuilt for another dtype.
+type f32Op struct{}
+
+func (o f32Op) Arity() int { return 1 }
+func (o f32Op) Type() hm.Type {
+	t := factory.NewTensorType(2, tensor.Float32)
+	return hm.NewFnType(t, t)
+}
+func (o f32Op) InferShape(ds ...op.DimSizer) (tensor.Shape, error) {
+	return ds[0].(tensor.Shape).Clone(), nil
+}
+func (o f32Op) Do(vs ...value.Value) (value.Value, error) { return vs[0], nil }
+func (o f32Op) ReturnsPtr() bool                          { return false }
+func (o f32Op) CallsExtern() bool                         { return false }
+func (o f32Op) OverwritesInput() int                      { return -1 }
+func (o f32Op) WriteHash(h hash.Hash)                     { fmt.Fprint(h, o.String()) }
+func (o f32Op) Hashcode() uint32 {
+	h := fnv.New32a()
+	o.WriteHash(h)
+	return h.Sum32()
+}
+func (o f32Op) String() string { return "f32" }
+
+func TestAutocast(t *testing.T) {
+	g := exprgraph.NewGraph()
+	x := g.NewVertex()
+	x.T, x.Shape, x.Name = factory.NewTensorType(2, tensor.Float32), tensor.Shape{2, 3}, "x"
+	g.AddNode(x)
+
+	y, err := LeakyRelu(x, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	z, err := opNode(f32Op{}, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := Cast(y, tensor.Float64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := AutocastPolicy{Low: factory.Float16, Allow: map[string]bool{OpType(y.Op): true}}
+	inserted, err := Autocast(g, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inserted) != 2 {
+		t.Errorf("Expected x to be cast to %v, and y back to %v. Got %d casts", factory.Float16, tensor.Float32, len(inserted))
+	}
+
+	// the activation is rebuilt for the low precision
+	if dt, _ := dtypeOf(y.T); dt != factory.Float16 {
+		t.Errorf("Expected y to be in %v. Got %v", factory.Float16, y.T)
+	}
+	if o, ok := y.Op.(*alphaActivationOp); !ok || o.dt != factory.Float16 || o.alpha != 0.1 {
+		t.Errorf("Expected the activation of y to be rebuilt for %v. Got %v", factory.Float16, y.Op)
+	}
+	if ins := g.InputsOf(y); len(ins) != 1 || ins[0] != inserted[0] || g.InputsOf(ins[0])[0] != x {
+		t.Errorf("Expected y to take the cast of x")
+	}
+
+	// an op that cannot be rebuilt keeps its dtype, and takes its inputs in it
+	if dt, _ := dtypeOf(z.T); dt != tensor.Float32 {
+		t.Errorf("Expected z to stay in %v. Got %v", tensor.Float32, z.T)
+	}
+	if ins := g.InputsOf(z); len(ins) != 1 || ins[0] == y || g.InputsOf(ins[0])[0] != y {
+		t.Errorf("Expected z to take y cast back to %v", tensor.Float32)
+	} else if dt, _ := dtypeOf(ins[0].T); dt != tensor.Float32 {
+		t.Errorf("Expected the input of z to be in %v. Got %v", tensor.Float32, ins[0].T)
+	}
+
+	// a cast follows its input
+	if o, ok := w.Op.(*castOp); !ok || o.from != factory.Float16 || o.to != tensor.Float64 {
+		t.Errorf("Expected w to cast from %v to %v. Got %v", factory.Float16, tensor.Float64, w.Op)
+	}
+	if dt, _ := dtypeOf(w.T); dt != tensor.Float64 {
+		t.Errorf("Expected w to stay in %v. Got %v", tensor.Float64, w.T)
+	}
+
+	if _, err = Autocast(g, AutocastPolicy{Low: tensor.Float32}); err == nil {
+		t.Errorf("Expected a low precision that is not a half to return an error")
+	}
+}
@@ -2,6 +2,7 @@ package operator
 
 import (
 	"math"
+	"math/cmplx"
 
 	"github.com/chewxy/math32"
 )
@@ -66,3 +67,253 @@ func _softplusf32(x float32) float32 {
 	}
 	return float32(math.Log1p(math.Exp(float64(x))))
 }
+
+/* ACTIVATION FUNCTIONS */
+
+const (
+	// DefaultLeakyReluAlpha is the usual slope of LeakyReLU for negative inputs. It's the same default as most other frameworks use.
+	DefaultLeakyReluAlpha = 0.01
+	// DefaultEluAlpha is the usual α of ELU. α = 1 makes ELU smooth at 0.
+	DefaultEluAlpha = 1.0
+
+	// seluLambda and seluAlpha are the fixed point values from "Self-Normalizing Neural Networks" (Klambauer et al. 2017)
+	seluLambda = 1.0507009873554804934193349852946
+	seluAlpha  = 1.6732632423543772848170429916717
+
+	// geluTanhC is √(2/π), used in the tanh approximation of GELU
+	geluTanhC = 0.7978845608028654
+	// geluTanhK is the coefficient of x³ in the tanh approximation of GELU
+	geluTanhK = 0.044715
+)
+
+func _reluf64(x float64) float64 {
+	if x > 0 {
+		return x
+	}
+	return 0
+}
+
+func _reluf32(x float32) float32 {
+	if x > 0 {
+		return x
+	}
+	return 0
+}
+
+func _reluDerivf64(x float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func _reluDerivf32(x float32) float32 {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func _relu6f64(x float64) float64 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 6:
+		return 6
+	}
+	return x
+}
+
+func _relu6f32(x float32) float32 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 6:
+		return 6
+	}
+	return x
+}
+
+func _relu6Derivf64(x float64) float64 {
+	if x > 0 && x < 6 {
+		return 1
+	}
+	return 0
+}
+
+func _relu6Derivf32(x float32) float32 {
+	if x > 0 && x < 6 {
+		return 1
+	}
+	return 0
+}
+
+// _leakyRelu and _elu are parameterized by α, so they are computed by an alphaActivationOp rather than a ʘUnaryOperator.
+// Float32s are computed in float64.
+
+func _leakyRelu(x, alpha float64) float64 {
+	if x > 0 {
+		return x
+	}
+	return alpha * x
+}
+
+func _leakyReluDeriv(x, alpha float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return alpha
+}
+
+func _elu(x, alpha float64) float64 {
+	if x > 0 {
+		return x
+	}
+	return alpha * math.Expm1(x)
+}
+
+func _eluDeriv(x, alpha float64) float64 {
+	if x > 0 {
+		return 1
+	}
+	return alpha * math.Exp(x)
+}
+
+func _seluf64(x float64) float64 {
+	if x > 0 {
+		return seluLambda * x
+	}
+	return seluLambda * seluAlpha * math.Expm1(x)
+}
+
+func _seluf32(x float32) float32 {
+	if x > 0 {
+		return seluLambda * x
+	}
+	return seluLambda * seluAlpha * math32.Expm1(x)
+}
+
+func _seluDerivf64(x float64) float64 {
+	if x > 0 {
+		return seluLambda
+	}
+	return seluLambda * seluAlpha * math.Exp(x)
+}
+
+func _seluDerivf32(x float32) float32 {
+	if x > 0 {
+		return seluLambda
+	}
+	return seluLambda * seluAlpha * math32.Exp(x)
+}
+
+// GELU(x) = xΦ(x), where Φ is the CDF of the standard normal distribution
+func _geluf64(x float64) float64 { return 0.5 * x * (1 + math.Erf(x/math.Sqrt2)) }
+func _geluf32(x float32) float32 { return float32(_geluf64(float64(x))) }
+
+// GELU'(x) = Φ(x) + xφ(x)
+func _geluDerivf64(x float64) float64 {
+	cdf := 0.5 * (1 + math.Erf(x/math.Sqrt2))
+	pdf := math.Exp(-0.5*x*x) / math.Sqrt(2*math.Pi)
+	return cdf + x*pdf
+}
+func _geluDerivf32(x float32) float32 { return float32(_geluDerivf64(float64(x))) }
+
+func _geluTanhf64(x float64) float64 {
+	return 0.5 * x * (1 + math.Tanh(geluTanhC*(x+geluTanhK*x*x*x)))
+}
+func _geluTanhf32(x float32) float32 { return float32(_geluTanhf64(float64(x))) }
+
+func _geluTanhDerivf64(x float64) float64 {
+	t := math.Tanh(geluTanhC * (x + geluTanhK*x*x*x))
+	return 0.5*(1+t) + 0.5*x*(1-t*t)*geluTanhC*(1+3*geluTanhK*x*x)
+}
+func _geluTanhDerivf32(x float32) float32 { return float32(_geluTanhDerivf64(float64(x))) }
+
+// Swish(x) = xσ(x). It's also known as SiLU.
+func _swishf64(x float64) float64 { return x * _sigmoidf64(x) }
+func _swishf32(x float32) float32 { return x * _sigmoidf32(x) }
+
+func _swishDerivf64(x float64) float64 {
+	s := _sigmoidf64(x)
+	return s + x*s*(1-s)
+}
+
+func _swishDerivf32(x float32) float32 {
+	s := _sigmoidf32(x)
+	return s + x*s*(1-s)
+}
+
+// Mish(x) = x tanh(softplus(x))
+func _mishf64(x float64) float64 { return x * math.Tanh(_softplusf64(x)) }
+func _mishf32(x float32) float32 { return float32(_mishf64(float64(x))) }
+
+func _mishDerivf64(x float64) float64 {
+	t := math.Tanh(_softplusf64(x))
+	return t + x*(1-t*t)*_sigmoidf64(x)
+}
+func _mishDerivf32(x float32) float32 { return float32(_mishDerivf64(float64(x))) }
+
+// HardSigmoid(x) = ReLU6(x+3)/6
+func _hardSigmoidf64(x float64) float64 { return _relu6f64(x+3) / 6 }
+func _hardSigmoidf32(x float32) float32 { return _relu6f32(x+3) / 6 }
+
+func _hardSigmoidDerivf64(x float64) float64 {
+	if x > -3 && x < 3 {
+		return 1.0 / 6.0
+	}
+	return 0
+}
+
+func _hardSigmoidDerivf32(x float32) float32 {
+	if x > -3 && x < 3 {
+		return 1.0 / 6.0
+	}
+	return 0
+}
+
+// HardSwish(x) = x ReLU6(x+3)/6
+func _hardSwishf64(x float64) float64 { return x * _relu6f64(x+3) / 6 }
+func _hardSwishf32(x float32) float32 { return x * _relu6f32(x+3) / 6 }
+
+func _hardSwishDerivf64(x float64) float64 {
+	switch {
+	case x <= -3:
+		return 0
+	case x >= 3:
+		return 1
+	}
+	return (2*x + 3) / 6
+}
+
+func _hardSwishDerivf32(x float32) float32 {
+	switch {
+	case x <= -3:
+		return 0
+	case x >= 3:
+		return 1
+	}
+	return (2*x + 3) / 6
+}
+
+/* COMPLEX UNARY OPS */
+
+// _signc128 is the complex sign: the point on the unit circle in the direction of x, or 0 if x is 0
+func _signc128(x complex128) complex128 {
+	if x == 0 {
+		return 0
+	}
+	return x / complex(cmplx.Abs(x), 0)
+}
+
+func _log2c128(x complex128) complex128        { return cmplx.Log(x) / math.Ln2 }
+func _negc128(x complex128) complex128         { return -x }
+func _squarec128(x complex128) complex128      { return x * x }
+func _cubec128(x complex128) complex128        { return x * x * x }
+func _inversec128(x complex128) complex128     { return 1 / x }
+func _inverseSqrtc128(x complex128) complex128 { return 1 / cmplx.Sqrt(x) }
+func _sigmoidc128(x complex128) complex128     { return 1 / (1 + cmplx.Exp(-x)) }
+func _log1pc128(x complex128) complex128       { return cmplx.Log(1 + x) }
+func _expm1c128(x complex128) complex128       { return cmplx.Exp(x) - 1 }
+func _softplusc128(x complex128) complex128    { return cmplx.Log(1 + cmplx.Exp(x)) }
+func _conjc128(x complex128) complex128        { return cmplx.Conj(x) }
@@ -0,0 +1,206 @@
+package operator
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/op"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// alphaActivation is an activation function parameterized by α
+type alphaActivation byte
+
+const (
+	leakyReluActivation alphaActivation = iota // x if x > 0, αx otherwise
+	eluActivation                              // x if x > 0, α(exp(x) - 1) otherwise
+)
+
+func (a alphaActivation) String() string { return [...]string{"leakyRelu", "elu"}[a] }
+
+func (a alphaActivation) of(x, alpha float64) float64 {
+	if a == leakyReluActivation {
+		return _leakyRelu(x, alpha)
+	}
+	return _elu(x, alpha)
+}
+
+func (a alphaActivation) deriv(x, alpha float64) float64 {
+	if a == leakyReluActivation {
+		return _leakyReluDeriv(x, alpha)
+	}
+	return _eluDeriv(x, alpha)
+}
+
+// alphaActivationOp applies an activation function parameterized by α to floats.
+// α is part of the op: ops with different αs have different hashes.
+type alphaActivationOp struct {
+	act   alphaActivation
+	alpha float64
+	dt    tensor.Dtype
+	dims  int
+}
+
+func newAlphaActivationOp(act alphaActivation, alpha float64, dt tensor.Dtype, dims int) (*alphaActivationOp, error) {
+	if c, err := classOf(dt); err != nil || c != floatClass {
+		return nil, errors.Errorf("%v expects floats. Got %v", act, dt)
+	}
+	return &alphaActivationOp{act: act, alpha: alpha, dt: dt, dims: dims}, nil
+}
+
+// forDtype returns the activation for values of dt
+func (o *alphaActivationOp) forDtype(dt tensor.Dtype) (op.Op, error) {
+	return newAlphaActivationOp(o.act, o.alpha, dt, o.dims)
+}
+
+func (o *alphaActivationOp) Arity() int { return 1 }
+
+func (o *alphaActivationOp) Type() hm.Type {
+	if o.dims == 0 {
+		return hm.NewFnType(o.dt, o.dt)
+	}
+	t := factory.NewTensorType(o.dims, o.dt)
+	return hm.NewFnType(t, t)
+}
+
+func (o *alphaActivationOp) InferShape(ns ...op.DimSizer) (tensor.Shape, error) {
+	if err := op.CheckArity(o, len(ns)); err != nil {
+		return nil, err
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a tensor.Shape. Got %v of %T instead", ns[0], ns[0])
+	}
+	return s.Clone(), nil
+}
+
+func (o *alphaActivationOp) Do(vals ...value.Value) (retVal value.Value, err error) {
+	if err = op.CheckArity(o, len(vals)); err != nil {
+		return nil, err
+	}
+	if vals[0].Dtype() != o.dt {
+		return nil, errors.Errorf("%v expects a value of %v. Got %v", o, o.dt, vals[0].Dtype())
+	}
+	return applyElems(vals[0], func(data interface{}) (interface{}, error) {
+		xs := widenFloats(data)
+		r := make([]float64, len(xs))
+		for i, x := range xs {
+			r[i] = o.act.of(x, o.alpha)
+		}
+		return narrowFloats(o.dt, r), nil
+	})
+}
+
+func (o *alphaActivationOp) ReturnsPtr() bool { return false }
+
+func (o *alphaActivationOp) CallsExtern() bool { return false }
+
+func (o *alphaActivationOp) OverwritesInput() int { return -1 }
+
+func (o *alphaActivationOp) WriteHash(h hash.Hash) { fmt.Fprint(h, o.String()) }
+
+func (o *alphaActivationOp) Hashcode() uint32 {
+	h := fnv.New32a()
+	o.WriteHash(h)
+	return h.Sum32()
+}
+
+func (o *alphaActivationOp) String() string { return fmt.Sprintf("%v{α=%v}", o.act, o.alpha) }
+
+func (o *alphaActivationOp) DiffWRT(inputs int) []bool { return []bool{true} }
+
+func (o *alphaActivationOp) SymDiff(inputs exprgraph.Nodes, output, grad *exprgraph.Node) (retVal exprgraph.Nodes, err error) {
+	if err = op.CheckArity(o, len(inputs)); err != nil {
+		return nil, err
+	}
+	diff := &alphaActivationDiffOp{o}
+	var d *exprgraph.Node
+	if d, err = opNode(diff, &inputs[0], grad); err != nil {
+		return nil, errors.Wrapf(err, "Failed to carry %v", diff)
+	}
+	return exprgraph.Nodes{*d}, nil
+}
+
+// alphaActivationDiffOp computes f'(x) ⊙ gradY, the gradient of the input x of an alphaActivationOp f, given the gradient of its output.
+type alphaActivationDiffOp struct{ *alphaActivationOp }
+
+func (o *alphaActivationDiffOp) Arity() int { return 2 }
+
+func (o *alphaActivationDiffOp) Type() hm.Type {
+	if o.dims == 0 {
+		return hm.NewFnType(o.dt, o.dt, o.dt)
+	}
+	t := factory.NewTensorType(o.dims, o.dt)
+	return hm.NewFnType(t, t, t)
+}
+
+func (o *alphaActivationDiffOp) InferShape(ns ...op.DimSizer) (tensor.Shape, error) {
+	if err := op.CheckArity(o, len(ns)); err != nil {
+		return nil, err
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a tensor.Shape. Got %v of %T instead", ns[0], ns[0])
+	}
+	return s.Clone(), nil
+}
+
+func (o *alphaActivationDiffOp) Do(vals ...value.Value) (retVal value.Value, err error) {
+	if err = op.CheckArity(o, len(vals)); err != nil {
+		return nil, err
+	}
+	input, grad := vals[0], vals[1]
+	if !input.Shape().Eq(grad.Shape()) {
+		return nil, errors.Errorf("%v expects the input and its gradient to have the same shape. Got %v and %v", o, input.Shape(), grad.Shape())
+	}
+
+	var gs []float64
+	if gt, ok := grad.(tensor.Tensor); ok {
+		gs = widenFloats(tensor.Materialize(gt).Data())
+	} else {
+		gs = widenFloats(grad.Data())
+	}
+	return applyElems(input, func(data interface{}) (interface{}, error) {
+		xs := widenFloats(data)
+		r := make([]float64, len(xs))
+		for i, x := range xs {
+			r[i] = o.act.deriv(x, o.alpha) * gs[i]
+		}
+		return narrowFloats(o.dt, r), nil
+	})
+}
+
+func (o *alphaActivationDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, o.String()) }
+
+func (o *alphaActivationDiffOp) Hashcode() uint32 {
+	h := fnv.New32a()
+	o.WriteHash(h)
+	return h.Sum32()
+}
+
+func (o *alphaActivationDiffOp) String() string { return fmt.Sprintf("%vDiff{α=%v}", o.act, o.alpha) }
+
+func (o *alphaActivationDiffOp) DiffWRT(inputs int) []bool { return make([]bool, inputs) }
+
+func (o *alphaActivationDiffOp) SymDiff(inputs exprgraph.Nodes, output, grad *exprgraph.Node) (retVal exprgraph.Nodes, err error) {
+	return nil, errors.Errorf("%v is not differentiable", o)
+}
+
+// alphaActivationNode applies the activation act with the given α to x
+func alphaActivationNode(act alphaActivation, alpha float64, x *exprgraph.Node) (*exprgraph.Node, error) {
+	dt, err := dtypeOf(x.T)
+	if err != nil {
+		return nil, err
+	}
+	o, err := newAlphaActivationOp(act, alpha, dt, x.Shape.Dims())
+	if err != nil {
+		return nil, err
+	}
+	return unaryOpNode(o, x)
+}
@@ -0,0 +1,189 @@
+package operator
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// centralDiff approximates f'(x) with a central finite difference
+func centralDiff(f func(float64) float64, x float64) float64 {
+	const h = 1e-6
+	return (f(x+h) - f(x-h)) / (2 * h)
+}
+
+var activationTests = []struct {
+	name  string
+	f, df func(float64) float64
+	f32   func(float32) float32
+	x, y  float64
+}{
+	{"relu", _reluf64, _reluDerivf64, _reluf32, -2, 0},
+	{"relu", _reluf64, _reluDerivf64, _reluf32, 3, 3},
+	{"relu6", _relu6f64, _relu6Derivf64, _relu6f32, 7, 6},
+	{"relu6", _relu6f64, _relu6Derivf64, _relu6f32, 2.5, 2.5},
+	{"selu", _seluf64, _seluDerivf64, _seluf32, 1, seluLambda},
+	{"selu", _seluf64, _seluDerivf64, _seluf32, -1, seluLambda * seluAlpha * math.Expm1(-1)},
+	{"gelu", _geluf64, _geluDerivf64, _geluf32, 1, 0.8413447460685429},
+	{"gelu", _geluf64, _geluDerivf64, _geluf32, -1, -0.15865525393145707},
+	{"geluTanh", _geluTanhf64, _geluTanhDerivf64, _geluTanhf32, 1, 0.8411919906082768},
+	{"swish", _swishf64, _swishDerivf64, _swishf32, 1, 1 / (1 + math.Exp(-1))},
+	{"swish", _swishf64, _swishDerivf64, _swishf32, -2, -2 / (1 + math.Exp(2))},
+	{"mish", _mishf64, _mishDerivf64, _mishf32, 1, math.Tanh(math.Log1p(math.E))},
+	{"hardSigmoid", _hardSigmoidf64, _hardSigmoidDerivf64, _hardSigmoidf32, 0, 0.5},
+	{"hardSigmoid", _hardSigmoidf64, _hardSigmoidDerivf64, _hardSigmoidf32, -4, 0},
+	{"hardSwish", _hardSwishf64, _hardSwishDerivf64, _hardSwishf32, 1, 2.0 / 3},
+	{"hardSwish", _hardSwishf64, _hardSwishDerivf64, _hardSwishf32, 4, 4},
+}
+
+func TestActivations(t *testing.T) {
+	for _, at := range activationTests {
+		if y := at.f(at.x); math.Abs(y-at.y) > 1e-12 {
+			t.Errorf("%v(%v): expected %v. Got %v", at.name, at.x, at.y, y)
+		}
+		if y := at.f32(float32(at.x)); math.Abs(float64(y)-at.y) > 1e-6 {
+			t.Errorf("%v(%v) in float32: expected %v. Got %v", at.name, at.x, at.y, y)
+		}
+		// the points are away from the kinks, so that the derivatives are defined
+		if d, want := at.df(at.x), centralDiff(at.f, at.x); math.Abs(d-want) > 1e-6 {
+			t.Errorf("%v'(%v): expected %v. Got %v", at.name, at.x, want, d)
+		}
+	}
+}
+
+func TestAlphaActivationOp(t *testing.T) {
+	for _, act := range []alphaActivation{leakyReluActivation, eluActivation} {
+		for _, alpha := range []float64{0.01, 0.3, 1, 2} {
+			for _, x := range []float64{-3, -0.5, 0.5, 2} {
+				f := func(x float64) float64 { return act.of(x, alpha) }
+				if d, want := act.deriv(x, alpha), centralDiff(f, x); math.Abs(d-want) > 1e-6 {
+					t.Errorf("%v'(%v) with α = %v: expected %v. Got %v", act, x, alpha, want, d)
+				}
+			}
+		}
+	}
+
+	o, err := newAlphaActivationOp(leakyReluActivation, 0.2, tensor.Float64, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := o.Do(tensor.New(tensor.WithBacking([]float64{-2, 0, 3})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Data().([]float64); got[0] != -0.4 || got[1] != 0 || got[2] != 3 {
+		t.Errorf("Expected [-0.4 0 3]. Got %v", got)
+	}
+
+	d, err := (&alphaActivationDiffOp{o}).Do(tensor.New(tensor.WithBacking([]float64{-2, 3})), tensor.New(tensor.WithBacking([]float64{10, 10})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Data().([]float64); got[0] != 2 || got[1] != 10 {
+		t.Errorf("Expected the gradient [2 10]. Got %v", got)
+	}
+
+	elu, _ := newAlphaActivationOp(eluActivation, 0.2, tensor.Float64, 1)
+	other, _ := newAlphaActivationOp(leakyReluActivation, 0.3, tensor.Float64, 1)
+	if o.Hashcode() == other.Hashcode() || o.String() == other.String() {
+		t.Errorf("Expected ops with different αs to have different hashes and names. Got %v and %v", o, other)
+	}
+	if o.Hashcode() == elu.Hashcode() {
+		t.Errorf("Expected %v and %v to have different hashes", o, elu)
+	}
+	if _, err = newAlphaActivationOp(eluActivation, 1, tensor.Int, 1); err == nil {
+		t.Errorf("Expected ELU on integers to return an error")
+	}
+}
+
+func TestLeakyRelu(t *testing.T) {
+	g := exprgraph.NewGraph()
+	x := g.NewVertex()
+	x.T, x.Shape, x.Name = factory.NewTensorType(2, tensor.Float32), tensor.Shape{2, 3}, "x"
+	g.AddNode(x)
+
+	y, err := LeakyRelu(x, 0.2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y.Graph() != g || !g.HasEdgeFromTo(y.ID(), x.ID()) {
+		t.Errorf("Expected LeakyRelu(x) to be a node of the graph of x, computed from x")
+	}
+	if dt, err := dtypeOf(y.T); err != nil || dt != tensor.Float32 || !y.Shape.Eq(x.Shape) {
+		t.Errorf("Expected LeakyRelu(x) to be a %v tensor of shape %v. Got %v of shape %v", tensor.Float32, x.Shape, y.T, y.Shape)
+	}
+	if o, ok := y.Op.(*alphaActivationOp); !ok || o.alpha != 0.2 {
+		t.Errorf("Expected the op of LeakyRelu(x, 0.2) to have α = 0.2. Got %v", y.Op)
+	}
+}
+
+func TestAlphaActivationOp_SymDiff(t *testing.T) {
+	g := exprgraph.NewGraph()
+	x := g.NewVertex()
+	x.T, x.Shape, x.Name = factory.NewTensorType(1, tensor.Float64), tensor.Shape{3}, "x"
+	g.AddNode(x)
+	y, err := Elu(x, 0.5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grad := g.NewVertex()
+	grad.T, grad.Shape, grad.Name = y.T, y.Shape.Clone(), "grad"
+	g.AddNode(grad)
+
+	dx, err := y.Op.(*alphaActivationOp).SymDiff(exprgraph.Nodes{*x}, y, grad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dx) != 1 {
+		t.Fatalf("Expected the gradient of x. Got %d nodes", len(dx))
+	}
+	if o, ok := dx[0].Op.(*alphaActivationDiffOp); !ok || o.alpha != 0.5 {
+		t.Errorf("Expected the gradient of Elu(x, 0.5) to be computed by an EluDiff with α = 0.5. Got %v", dx[0].Op)
+	}
+	if !g.HasEdgeFromTo(dx[0].ID(), x.ID()) || !g.HasEdgeFromTo(dx[0].ID(), grad.ID()) || !dx[0].Shape.Eq(x.Shape) {
+		t.Errorf("Expected the gradient to be computed from x and the gradient of y, with the shape of x")
+	}
+}
+
+func TestRelu(t *testing.T) {
+	g := exprgraph.NewGraph()
+	x := g.NewVertex()
+	x.T, x.Shape, x.Name = factory.NewTensorType(1, tensor.Float32), tensor.Shape{4}, "x"
+	g.AddNode(x)
+
+	y, err := Relu(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, ok := y.Op.(*elemUnaryOp)
+	if !ok || o.ʘUnaryOperatorType != reluOpType {
+		t.Fatalf("Expected Relu(x) to be a relu. Got %v", y.Op)
+	}
+	if !y.T.Eq(x.T) || !y.Shape.Eq(x.Shape) {
+		t.Errorf("Expected Relu(x) to be a %v of shape %v. Got %v of shape %v", x.T, x.Shape, y.T, y.Shape)
+	}
+
+	out, err := o.Do(tensor.New(tensor.WithBacking([]float32{-1, 0, 2, 7})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Data().([]float32); got[0] != 0 || got[1] != 0 || got[2] != 2 || got[3] != 7 {
+		t.Errorf("Expected [0 0 2 7]. Got %v", got)
+	}
+	relu6 := newElemUnaryOp(relu6OpType, x)
+	if out, err = relu6.Do(tensor.New(tensor.WithBacking([]float64{-1, 3, 7}))); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Data().([]float64); got[0] != 0 || got[1] != 3 || got[2] != 6 {
+		t.Errorf("Expected [0 3 6]. Got %v", got)
+	}
+	if o.Hashcode() == relu6.Hashcode() {
+		t.Errorf("Expected %v and %v to have different hashes", o, relu6)
+	}
+	if !o.DiffWRT(1)[0] || newElemUnaryOp(reluDerivOpType, x).DiffWRT(1)[0] {
+		t.Errorf("Expected relu to be differentiable, and its derivative not to be")
+	}
+}
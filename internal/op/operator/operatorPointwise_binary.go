@@ -1,15 +1,18 @@
 package operator
 
 import (
-	"math"
+	"fmt"
+	"hash"
+	"hash/fnv"
 
-	"github.com/chewxy/math32"
+	"github.com/chewxy/hm"
 	"github.com/pkg/errors"
 	gerrors "gorgonia.org/gorgonia/errors"
 	"gorgonia.org/gorgonia/internal/execution"
 	"gorgonia.org/gorgonia/internal/exprgraph"
 	"gorgonia.org/gorgonia/internal/op"
 	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/internal/value/factory"
 	"gorgonia.org/tensor"
 )
 
@@ -54,267 +57,7 @@ func (o scalarBinOp) Do(same bool, vals ...value.Value) (retVal value.Value, err
 		return
 	}
 
-	var r interface{} // float or bool only plz
-	switch a := vals[0].(type) {
-	case *value.F64:
-		b := vals[1].(*value.F64)
-		switch o.ʘBinaryOperatorType {
-		case addOpType:
-			r = value.NewF64(a.Any() + b.Any())
-		case subOpType:
-			r = value.NewF64(a.Any() - b.Any())
-		case mulOpType:
-			r = value.NewF64(a.Any() * b.Any())
-		case divOpType:
-			r = value.NewF64(a.Any() / b.Any())
-		case powOpType:
-			r = value.NewF64(math.Pow(a.Any(), b.Any()))
-		case ltOpType:
-			r = value.NewB(a.Any() < b.Any())
-		case gtOpType:
-			r = value.NewB(a.Any() > b.Any())
-		case lteOpType:
-			r = value.NewB(a.Any() <= b.Any())
-		case gteOpType:
-			r = value.NewB(a.Any() >= b.Any())
-		case eqOpType:
-			r = value.NewB(a.Any() == b.Any())
-		case neOpType:
-			r = value.NewB(a.Any() != b.Any())
-		default:
-
-			return nil, &gerrors.ErrNotYetImplemented{
-				Action:      "scalarBinOp.Do() - Float64",
-				Target:      o.ʘBinaryOperatorType,
-				IsTypeError: false,
-			}
-		}
-
-		if same && !o.isArith() {
-			if *(r.(*value.B)) {
-				r = value.NewF64(1.0)
-			} else {
-				r = value.NewF64(0.0)
-			}
-		}
-
-	case *value.F32:
-		b := vals[1].(*value.F32)
-		switch o.ʘBinaryOperatorType {
-		case addOpType:
-			r = value.NewF32(a.Any() + b.Any())
-		case subOpType:
-			r = value.NewF32(a.Any() - b.Any())
-		case mulOpType:
-			r = value.NewF32(a.Any() * b.Any())
-		case divOpType:
-			r = value.NewF32(a.Any() / b.Any())
-		case powOpType:
-			r = value.NewF32(math32.Pow(float32(a.Any()), float32(b.Any())))
-		case ltOpType:
-			r = value.NewB(a.Any() < b.Any())
-		case gtOpType:
-			r = value.NewB(a.Any() > b.Any())
-		case lteOpType:
-			r = value.NewB(a.Any() <= b.Any())
-		case gteOpType:
-			r = value.NewB(a.Any() >= b.Any())
-		case eqOpType:
-			r = value.NewB(a.Any() == b.Any())
-		case neOpType:
-			r = value.NewB(a.Any() != b.Any())
-		default:
-			return nil, &gerrors.ErrNotYetImplemented{
-				Action:      "scalarBinOp.Do() - Float32",
-				Target:      o.ʘBinaryOperatorType,
-				IsTypeError: false,
-			}
-		}
-
-		if same && !o.isArith() {
-			if *(r.(*value.B)) {
-				r = value.F32(1)
-			} else {
-				r = value.F32(0)
-			}
-		}
-
-	case *value.I:
-		b := vals[1].(*value.I)
-		switch o.ʘBinaryOperatorType {
-		case addOpType:
-			r = value.NewI(a.Any() + b.Any())
-		case subOpType:
-			r = value.NewI(a.Any() - b.Any())
-		case mulOpType:
-			r = value.NewI(a.Any() * b.Any())
-		case divOpType:
-			r = value.NewI(a.Any() / b.Any())
-		// case powOpType:
-		// 	r = math.Pow(a, b)
-		case ltOpType:
-			r = value.NewB(a.Any() < b.Any())
-		case gtOpType:
-			r = value.NewB(a.Any() > b.Any())
-		case lteOpType:
-			r = value.NewB(a.Any() <= b.Any())
-		case gteOpType:
-			r = value.NewB(a.Any() >= b.Any())
-		case eqOpType:
-			r = value.NewB(a.Any() == b.Any())
-		case neOpType:
-			r = value.NewB(a.Any() != b.Any())
-		default:
-			return nil, &gerrors.ErrNotYetImplemented{
-				Action:      "scalarBinOp.Do() - Int",
-				Target:      o.ʘBinaryOperatorType,
-				IsTypeError: false,
-			}
-		}
-
-		if same && !o.isArith() {
-			if *(r.(*value.B)) {
-				r = value.I(1)
-			} else {
-				r = value.I(0)
-			}
-		}
-	case *value.I32:
-		b := vals[1].(*value.I32)
-		switch o.ʘBinaryOperatorType {
-		case addOpType:
-			r = value.NewI32(a.Any() + b.Any())
-		case subOpType:
-			r = value.NewI32(a.Any() - b.Any())
-		case mulOpType:
-			r = value.NewI32(a.Any() * b.Any())
-		case divOpType:
-			r = value.NewI32(a.Any() / b.Any())
-		// case powOpType:
-		// 	r = math.Pow(a, b)
-		case ltOpType:
-			r = value.NewB(a.Any() < b.Any())
-		case gtOpType:
-			r = value.NewB(a.Any() > b.Any())
-		case lteOpType:
-			r = value.NewB(a.Any() <= b.Any())
-		case gteOpType:
-			r = value.NewB(a.Any() >= b.Any())
-		case eqOpType:
-			r = value.NewB(a.Any() == b.Any())
-		case neOpType:
-			r = value.NewB(a.Any() != b.Any())
-		default:
-			return nil, &gerrors.ErrNotYetImplemented{
-				Action:      "scalarBinOp.Do() - Int32",
-				Target:      o.ʘBinaryOperatorType,
-				IsTypeError: false,
-			}
-		}
-
-		if same && !o.isArith() {
-			if *(r.(*value.B)) {
-				r = value.I32(1)
-			} else {
-				r = value.I32(0)
-			}
-		}
-	case *value.I64:
-		b := vals[1].(*value.I64)
-		switch o.ʘBinaryOperatorType {
-		case addOpType:
-			r = value.NewI64(a.Any() + b.Any())
-		case subOpType:
-			r = value.NewI64(a.Any() - b.Any())
-		case mulOpType:
-			r = value.NewI64(a.Any() * b.Any())
-		case divOpType:
-			r = value.NewI64(a.Any() / b.Any())
-		// case powOpType:
-		// 	r = math.Pow(a, b)
-		case ltOpType:
-			r = value.NewB(a.Any() < b.Any())
-		case gtOpType:
-			r = value.NewB(a.Any() > b.Any())
-		case lteOpType:
-			r = value.NewB(a.Any() <= b.Any())
-		case gteOpType:
-			r = value.NewB(a.Any() >= b.Any())
-		case eqOpType:
-			r = value.NewB(a.Any() == b.Any())
-		case neOpType:
-			r = value.NewB(a.Any() != b.Any())
-		default:
-			return nil, &gerrors.ErrNotYetImplemented{
-				Action:      "scalarBinOp.Do() - Int64",
-				Target:      o.ʘBinaryOperatorType,
-				IsTypeError: false,
-			}
-		}
-
-		if same && !o.isArith() {
-			if *(r.(*value.B)) {
-				r = value.I64(1)
-			} else {
-				r = value.I64(0)
-			}
-		}
-	case *value.U8:
-		b := vals[1].(*value.U8)
-		switch o.ʘBinaryOperatorType {
-		case addOpType:
-			r = value.NewU8(a.Any() + b.Any())
-		case subOpType:
-			r = value.NewU8(a.Any() - b.Any())
-		case mulOpType:
-			r = value.NewU8(a.Any() * b.Any())
-		case divOpType:
-			r = value.NewU8(a.Any() / b.Any())
-		// case powOpType:
-		// 	r = math.Pow(a, b)
-		case ltOpType:
-			r = value.NewB(a.Any() < b.Any())
-		case gtOpType:
-			r = value.NewB(a.Any() > b.Any())
-		case lteOpType:
-			r = value.NewB(a.Any() <= b.Any())
-		case gteOpType:
-			r = value.NewB(a.Any() >= b.Any())
-		case eqOpType:
-			r = value.NewB(a.Any() == b.Any())
-		case neOpType:
-			r = value.NewB(a.Any() != b.Any())
-		default:
-			return nil, &gerrors.ErrNotYetImplemented{
-				Action:      "scalarBinOp.Do() - Byte",
-				Target:      o.ʘBinaryOperatorType,
-				IsTypeError: false,
-			}
-		}
-
-		if same && !o.isArith() {
-			if *(r.(*value.B)) {
-				r = value.U8(1)
-			} else {
-				r = value.U8(0)
-			}
-		}
-	case *value.B:
-		b := vals[1].(*value.B)
-		switch o.ʘBinaryOperatorType {
-		case eqOpType:
-			r = value.NewB(a.Any() == b.Any())
-		case neOpType:
-			r = value.NewB(a.Any() != b.Any())
-		default:
-			return nil, &gerrors.ErrNotYetImplemented{
-				Action:      "scalarBinOp.Do() - Bool",
-				Target:      o.ʘBinaryOperatorType,
-				IsTypeError: false,
-			}
-		}
-
-	default:
+	if _, ok := vals[0].(value.Scalar); !ok {
 		return nil, &gerrors.ErrNotYetImplemented{
 			Action:      "scalarBinOp.Do() - Unhandled Scalar Type",
 			Target:      o.t,
@@ -322,8 +65,12 @@ func (o scalarBinOp) Do(same bool, vals ...value.Value) (retVal value.Value, err
 		}
 	}
 
-	retVal, _ = value.AnyToScalar(r)
-	return retVal, nil
+	// see the scalar kernels for the result dtypes
+	var r interface{}
+	if r, err = binOpKernel(o.ʘBinaryOperatorType, vals[0].Dtype(), same, vals[0].Data(), vals[1].Data()); err != nil {
+		return nil, err
+	}
+	return firstScalar(r), nil
 }
 
 type tBinOp struct {
@@ -419,10 +166,8 @@ func (o tBinOp) do(vals []value.Value, opts ...tensor.FuncOpt) (retVal value.Val
 		// a = t
 
 		switch other := vals[1].(type) {
-		case *value.F64:
-			b = other.Any()
-		case *value.F32:
-			b = other.Any()
+		case value.Scalar:
+			b = other.Data()
 		case tensor.Tensor:
 			b = tensor.Materialize(other)
 		default:
@@ -436,10 +181,8 @@ func (o tBinOp) do(vals []value.Value, opts ...tensor.FuncOpt) (retVal value.Val
 		b = tensor.Materialize(t)
 
 		switch other := vals[0].(type) {
-		case *value.F64:
-			a = other.Any()
-		case *value.F32:
-			a = other.Any()
+		case value.Scalar:
+			a = other.Data()
 		case tensor.Tensor:
 			a = tensor.Materialize(other)
 		default:
@@ -447,21 +190,134 @@ func (o tBinOp) do(vals []value.Value, opts ...tensor.FuncOpt) (retVal value.Val
 		}
 	}
 
-	if o.isArith() {
-		fn := binOps[o.ʘBinaryOperatorType]
-		if fn == nil {
-			return nil, errors.Errorf("nil function returned for %v", o.ʘBinaryOperatorType)
-		}
-		retVal, err = (*fn)(a, b, opts...)
-	} else {
-		fn := cmpOps[o.ʘBinaryOperatorType]
-		if fn == nil {
-			return nil, errors.Errorf("nil function returned for %v", o.ʘBinaryOperatorType)
-		}
-		retVal, err = (*fn)(a, b, opts...)
+	if fn := o.tensorFn(d0); fn != nil {
+		return fn(a, b, opts...)
+	}
+	return applyBinOpKernel(o.ʘBinaryOperatorType, a, b, opts...)
+}
 
+// elemBinOp applies a binary operator to each pair of elements of its inputs. Either input may be a scalar, which is paired
+// with every element of the other; otherwise both inputs have the same shape. The function of the tensor package is used
+// where there is one, and the scalar kernels otherwise.
+type elemBinOp struct {
+	ʘBinaryOperatorType
+	aDims, bDims int
+}
+
+func newElemBinOp(op ʘBinaryOperatorType, a, b *exprgraph.Node) *elemBinOp {
+	return &elemBinOp{ʘBinaryOperatorType: op, aDims: a.Shape.Dims(), bDims: b.Shape.Dims()}
+}
+
+func (o *elemBinOp) Arity() int { return 2 }
+
+// elemBinOp has this type:
+//		op :: a → a → a
+// where either a may be a Tensor-n a. Comparisons return Bools.
+func (o *elemBinOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	var ret hm.Type = a
+	if o.isCmp() {
+		ret = tensor.Bool
 	}
-	return
+	dims := o.aDims
+	if o.bDims > dims {
+		dims = o.bDims
+	}
+	if dims > 0 {
+		ret = factory.NewTensorType(dims, ret)
+	}
+	return hm.NewFnType(o.operandType(o.aDims), o.operandType(o.bDims), ret)
+}
+
+func (o *elemBinOp) operandType(dims int) hm.Type {
+	if dims == 0 {
+		return hm.TypeVariable('a')
+	}
+	return factory.NewTensorType(dims, hm.TypeVariable('a'))
+}
+
+func (o *elemBinOp) InferShape(ns ...op.DimSizer) (tensor.Shape, error) {
+	if err := op.CheckArity(o, len(ns)); err != nil {
+		return nil, err
+	}
+	a, aok := ns[0].(tensor.Shape)
+	b, bok := ns[1].(tensor.Shape)
+	if !aok || !bok {
+		return nil, errors.Errorf("Expected tensor.Shapes. Got %v of %T and %v of %T instead", ns[0], ns[0], ns[1], ns[1])
+	}
+	switch {
+	case a.IsScalar():
+		return b.Clone(), nil
+	case b.IsScalar(), a.Eq(b):
+		return a.Clone(), nil
+	}
+	return nil, errors.Errorf("Shape mismatch for %v: %v and %v", o, a, b)
+}
+
+func (o *elemBinOp) Do(vals ...value.Value) (retVal value.Value, err error) {
+	if err = op.CheckArity(o, len(vals)); err != nil {
+		return nil, err
+	}
+	dt := vals[0].Dtype()
+	if vals[1].Dtype() != dt {
+		return nil, errors.Errorf("Dtype mismatch for %v: %v and %v", o, dt, vals[1].Dtype())
+	}
+
+	_, aok := vals[0].(tensor.Tensor)
+	_, bok := vals[1].(tensor.Tensor)
+	if !aok && !bok {
+		return scalarBinOp{o.ʘBinaryOperatorType, dt}.Do(false, vals...)
+	}
+	var a, b interface{}
+	if a, err = binOperand(vals[0]); err != nil {
+		return nil, err
+	}
+	if b, err = binOperand(vals[1]); err != nil {
+		return nil, err
+	}
+	if fn := o.tensorFn(dt); fn != nil {
+		return fn(a, b)
+	}
+	return applyBinOpKernel(o.ʘBinaryOperatorType, a, b)
+}
+
+func (o *elemBinOp) ReturnsPtr() bool { return true }
+
+func (o *elemBinOp) CallsExtern() bool { return false }
+
+func (o *elemBinOp) OverwritesInput() int { return -1 }
+
+func (o *elemBinOp) WriteHash(h hash.Hash) {
+	fmt.Fprintf(h, "%v-%d-%d", o.ʘBinaryOperatorType, o.aDims, o.bDims)
+}
+
+func (o *elemBinOp) Hashcode() uint32 {
+	h := fnv.New32a()
+	o.WriteHash(h)
+	return h.Sum32()
+}
+
+func (o *elemBinOp) DiffWRT(inputs int) []bool { return o.diffWRT(inputs) }
+
+func (o *elemBinOp) SymDiff(inputs exprgraph.Nodes, output, grad *exprgraph.Node) (retVal exprgraph.Nodes, err error) {
+	if err = op.CheckArity(o, len(inputs)); err != nil {
+		return nil, err
+	}
+	if retVal, err = ʘBinOpDiffExprs[o.ʘBinaryOperatorType](&inputs[0], &inputs[1], output, grad); err != nil {
+		return nil, errors.Wrapf(err, "Failed to differentiate %v", o)
+	}
+	return retVal, nil
+}
+
+// binOperand returns what the functions of the tensor package take for a value: a materialized tensor, or the data of a scalar.
+func binOperand(v value.Value) (interface{}, error) {
+	switch v := v.(type) {
+	case tensor.Tensor:
+		return tensor.Materialize(v), nil
+	case value.Scalar:
+		return v.Data(), nil
+	}
+	return nil, errors.Errorf("Expected a tensor or a scalar. Got %v of %T instead", v, v)
 }
 
 // type binDiffFn func(x, y, z, gradZ *exprgraph.Node) (exprgraph.Nodes, err error)
@@ -684,9 +540,18 @@ func subDiff(ctx execution.Context, x, y, z *exprgraph.Node) (err error) {
 }
 
 func hadamardProdDiffExpr(x, y, z, gradZ *exprgraph.Node) (retVal exprgraph.Nodes, err error) {
+	// the gradients of complex numbers are multiplied by the conjugates (see the unary differentiation expressions)
+	var cx, cy *exprgraph.Node
+	if cx, err = Conj(x); err != nil {
+		return nil, errors.Wrap(err, "Failed to carry Conj()")
+	}
+	if cy, err = Conj(y); err != nil {
+		return nil, errors.Wrap(err, "Failed to carry Conj()")
+	}
+
 	var dzdx, dzdy *exprgraph.Node
-	if dzdx, err = HadamardProd(y, gradZ); err == nil {
-		dzdy, err = HadamardProd(x, gradZ)
+	if dzdx, err = HadamardProd(cy, gradZ); err == nil {
+		dzdy, err = HadamardProd(cx, gradZ)
 		if err != nil {
 			return nil, errors.Wrap(err, "Failed to carry HadamardProd()")
 		}
@@ -846,10 +711,19 @@ end:
 }
 
 func hadamardDivDiffExpr(x, y, z, gradZ *exprgraph.Node) (retVal exprgraph.Nodes, err error) {
+	// the gradients of complex numbers are divided by the conjugates (see the unary differentiation expressions)
+	var cy, cz *exprgraph.Node
+	if cy, err = Conj(y); err != nil {
+		return nil, errors.Wrap(err, "Failed to carry Conj()")
+	}
+	if cz, err = Conj(z); err != nil {
+		return nil, errors.Wrap(err, "Failed to carry Conj()")
+	}
+
 	var dzdx, dzdy *exprgraph.Node
-	if dzdx, err = HadamardDiv(gradZ, y); err == nil {
+	if dzdx, err = HadamardDiv(gradZ, cy); err == nil {
 		WithGroupName(gradClust)(dzdx)
-		if dzdy, err = HadamardDiv(z, y); err == nil {
+		if dzdy, err = HadamardDiv(cz, cy); err == nil {
 			WithGroupName(gradClust)(dzdy)
 			if dzdy, err = Neg(dzdy); err == nil {
 				WithGroupName(gradClust)(dzdy)
@@ -0,0 +1,39 @@
+package operator
+
+import "gorgonia.org/gorgonia/internal/exprgraph"
+
+// Mod performs a pointwise modulo. The result takes the sign of b, so that a == FloorDiv(a, b)*b + Mod(a, b).
+// Integer modulo by zero returns an error.
+func Mod(a, b *exprgraph.Node) (*exprgraph.Node, error) {
+	return binOpNode(newElemBinOp(modOpType, a, b), a, b)
+}
+
+// FloorDiv performs a pointwise division rounded towards -∞. Integer division by zero returns an error.
+func FloorDiv(a, b *exprgraph.Node) (*exprgraph.Node, error) {
+	return binOpNode(newElemBinOp(floorDivOpType, a, b), a, b)
+}
+
+// And performs a pointwise bitwise and on integers, and a logical and on bools.
+func And(a, b *exprgraph.Node) (*exprgraph.Node, error) {
+	return binOpNode(newElemBinOp(andOpType, a, b), a, b)
+}
+
+// Or performs a pointwise bitwise or on integers, and a logical or on bools.
+func Or(a, b *exprgraph.Node) (*exprgraph.Node, error) {
+	return binOpNode(newElemBinOp(orOpType, a, b), a, b)
+}
+
+// Xor performs a pointwise bitwise exclusive or on integers, and a logical exclusive or on bools.
+func Xor(a, b *exprgraph.Node) (*exprgraph.Node, error) {
+	return binOpNode(newElemBinOp(xorOpType, a, b), a, b)
+}
+
+// Shl shifts the integers of a left by b bits. Negative shift counts return an error.
+func Shl(a, b *exprgraph.Node) (*exprgraph.Node, error) {
+	return binOpNode(newElemBinOp(shlOpType, a, b), a, b)
+}
+
+// Shr shifts the integers of a right by b bits. The shift is arithmetic for signed integers. Negative shift counts return an error.
+func Shr(a, b *exprgraph.Node) (*exprgraph.Node, error) {
+	return binOpNode(newElemBinOp(shrOpType, a, b), a, b)
+}
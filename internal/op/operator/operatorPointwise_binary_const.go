@@ -3,6 +3,7 @@ package operator
 import (
 	"gorgonia.org/gorgonia/internal/execution"
 	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value/factory"
 	"gorgonia.org/tensor"
 )
 
@@ -46,6 +47,17 @@ const (
 	eqOpType
 	neOpType
 
+	// arith, without a derivative
+	modOpType
+	floorDivOpType
+
+	// bitwise. On bools they are the logical ops
+	andOpType
+	orOpType
+	xorOpType
+	shlOpType
+	shrOpType
+
 	maxʘBinaryOpType // delimits the end of all possible binOpType
 )
 
@@ -70,6 +82,17 @@ var ʘBinOpStrs = [maxʘBinaryOpType]string{
 	">=",
 	"==",
 	"!=",
+
+	// arith ops without a derivative
+	"%",
+	"//",
+
+	// bitwise ops
+	"&",
+	"|",
+	"⊻",
+	"<<",
+	">>",
 }
 
 // ʘBinOpNames is the string representation for a binOpType
@@ -89,6 +112,17 @@ var ʘBinOpNames = [maxʘBinaryOpType]string{
 	"gte",
 	"eq",
 	"ne",
+
+	// arith ops without a derivative
+	"mod",
+	"floorDiv",
+
+	// bitwise ops
+	"and",
+	"or",
+	"xor",
+	"shl",
+	"shr",
 }
 
 // ʘBinOpCommutative is the array that stores whether a binary operator is commutative
@@ -96,16 +130,22 @@ var ʘBinOpNames = [maxʘBinaryOpType]string{
 var ʘBinOpCommutative = [maxʘBinaryOpType]bool{
 	true, false, true, false, false,
 	false, false, false, false, true, true,
+	false, false,
+	true, true, true, false, false,
 }
 
 var ʘBinOpDiffExprs = [maxʘBinaryOpType]func(x, y, z, gradZ *exprgraph.Node) (exprgraph.Nodes, error){
 	addDiffExpr, subDiffExpr, hadamardProdDiffExpr, hadamardDivDiffExpr, hadamardPowDiffExpr,
 	nondiffBinOpExpr, nondiffBinOpExpr, nondiffBinOpExpr, nondiffBinOpExpr, nondiffBinOpExpr, nondiffBinOpExpr,
+	nondiffBinOpExpr, nondiffBinOpExpr,
+	nondiffBinOpExpr, nondiffBinOpExpr, nondiffBinOpExpr, nondiffBinOpExpr, nondiffBinOpExpr,
 }
 
 var ʘBinOpDiffFns = [maxʘBinaryOpType]func(ctx execution.Context, x, y, z *exprgraph.Node) error{
 	addDiff, subDiff, hadamardProdDiff, hadamardDivDiff, hadamardPowDiff,
 	nondiffBinOp, nondiffBinOp, nondiffBinOp, nondiffBinOp, nondiffBinOp, nondiffBinOp,
+	nondiffBinOp, nondiffBinOp,
+	nondiffBinOp, nondiffBinOp, nondiffBinOp, nondiffBinOp, nondiffBinOp,
 }
 
 // isCommutative gives info about whether the operator is commutative
@@ -127,7 +167,8 @@ func (op ʘBinaryOperatorType) diffWRT(inputs int) []bool {
 		panic("binary operator only supports 2 inputs")
 	}
 
-	if op.isArith() {
+	switch op {
+	case addOpType, subOpType, mulOpType, divOpType, powOpType:
 		return []bool{true, true}
 	}
 	return []bool{false, false}
@@ -136,13 +177,52 @@ func (op ʘBinaryOperatorType) diffWRT(inputs int) []bool {
 // isArith indicates if the binary operator is an arithmetic type
 func (op ʘBinaryOperatorType) isArith() bool {
 	switch op {
-	case addOpType, subOpType, mulOpType, divOpType, powOpType:
+	case addOpType, subOpType, mulOpType, divOpType, powOpType, modOpType, floorDivOpType:
 		return true
 	default:
 		return false
 	}
 }
 
+// isCmp indicates if the binary operator is a comparison
+func (op ʘBinaryOperatorType) isCmp() bool {
+	return op >= ltOpType && op <= neOpType
+}
+
+// isBitwise indicates if the binary operator is a bitwise operator. They are only defined on integers and bools.
+func (op ʘBinaryOperatorType) isBitwise() bool {
+	return op >= andOpType && op <= shrOpType
+}
+
+// isComplexDefined indicates if the binary operator is defined on complex numbers, which have no order, remainder or bits.
+func (op ʘBinaryOperatorType) isComplexDefined() bool {
+	switch op {
+	case addOpType, subOpType, mulOpType, divOpType, powOpType, eqOpType, neOpType:
+		return true
+	}
+	return false
+}
+
+// tensorFn returns the function of the tensor package that performs the operator on tensors of dtype dt, or nil if there is none.
+// The tensor package only stores the half-precision floats, has no arithmetic or ordering for bools, raises integers to a power
+// through floats and has its own errors for integer division by zero. Those are left to the scalar kernels, along with the
+// operators that have no function at all.
+func (op ʘBinaryOperatorType) tensorFn(dt tensor.Dtype) func(a, b interface{}, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	switch {
+	case dt == tensor.Bool, factory.IsHalf(dt):
+		return nil
+	case factory.IsComplex(dt) && !op.isComplexDefined():
+		return nil
+	case (op == divOpType || op == powOpType) && dt != tensor.Float64 && dt != tensor.Float32 && !factory.IsComplex(dt):
+		return nil
+	case binOps[op] != nil:
+		return *binOps[op]
+	case cmpOps[op] != nil:
+		return *cmpOps[op]
+	}
+	return nil
+}
+
 var binOps = [maxʘBinaryOpType]*denseBinOp{
 	&tadd,
 	&tsub,
@@ -155,6 +235,13 @@ var binOps = [maxʘBinaryOpType]*denseBinOp{
 	nil, // gte
 	nil, // eq
 	nil, // ne
+	nil, // mod
+	nil, // floorDiv
+	nil, // and
+	nil, // or
+	nil, // xor
+	nil, // shl
+	nil, // shr
 }
 
 var cmpOps = [maxʘBinaryOpType]*denseCmpOp{
@@ -169,4 +256,11 @@ var cmpOps = [maxʘBinaryOpType]*denseCmpOp{
 	&tgte,
 	&teq,
 	&tne,
+	nil, // mod
+	nil, // floorDiv
+	nil, // and
+	nil, // or
+	nil, // xor
+	nil, // shl
+	nil, // shr
 }
@@ -0,0 +1,603 @@
+package operator
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/pkg/errors"
+	gerrors "gorgonia.org/gorgonia/errors"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/gorgonia/internal/value/half"
+	"gorgonia.org/tensor"
+)
+
+/*
+SCALAR KERNELS
+
+The binary operators are computed by four kernels: floats are computed in float64, complex numbers in complex128,
+integers (including bytes) in int64 and bools as bools. The results are narrowed back to the dtype of the operands, so integers wrap around as they would in Go.
+The half-precision floats are computed in float32: they are exact in float64, and their results are rounded through float32.
+
+The result dtypes are:
+	arith and bitwise ops: the dtype of the operands
+	cmp ops: Bool, or the dtype of the operands (1 for true and 0 for false) if the same type is asked for
+
+Integer division, Mod and FloorDiv by zero return an ErrDivisionByZero. Float division follows IEEE 754.
+Div truncates integers, as Go does, while FloorDiv rounds towards -∞ and Mod takes the sign of the divisor,
+so that a == FloorDiv(a, b)*b + Mod(a, b).
+Bitwise ops are only defined on integers and bools, on which they are the logical ops. Bools have no arithmetic, and false < true.
+Complex numbers have no order: the only cmp ops defined on them are eq and ne. Neither are Mod and FloorDiv.
+*/
+
+// dtypeClass is the class of dtypes that share a scalar kernel
+type dtypeClass byte
+
+const (
+	floatClass dtypeClass = iota
+	intClass
+	boolClass
+	complexClass
+)
+
+func classOf(dt tensor.Dtype) (dtypeClass, error) {
+	switch dt {
+	case tensor.Float64, tensor.Float32, factory.Float16, factory.BFloat16:
+		return floatClass, nil
+	case tensor.Int, tensor.Int64, tensor.Int32, tensor.Byte:
+		return intClass, nil
+	case tensor.Bool:
+		return boolClass, nil
+	case tensor.Complex64, tensor.Complex128:
+		return complexClass, nil
+	}
+	return 0, &gerrors.ErrNotYetImplemented{
+		Action:      "binary operators",
+		Target:      dt,
+		IsTypeError: false,
+	}
+}
+
+func binOpNYI(op ʘBinaryOperatorType, class dtypeClass) error {
+	actions := [...]string{"floats", "integers", "bools", "complex numbers"}
+	return &gerrors.ErrNotYetImplemented{
+		Action:      "binary operator on " + actions[class],
+		Target:      op,
+		IsTypeError: false,
+	}
+}
+
+// cmpKernel returns the result of the comparison op, given the order c of its operands: -1 if a < b, 0 if a == b and 1 if a > b
+func cmpKernel(op ʘBinaryOperatorType, c int) bool {
+	switch op {
+	case ltOpType:
+		return c < 0
+	case gtOpType:
+		return c > 0
+	case lteOpType:
+		return c <= 0
+	case gteOpType:
+		return c >= 0
+	case eqOpType:
+		return c == 0
+	}
+	return c != 0 // ne
+}
+
+func floatKernel(op ʘBinaryOperatorType, a, b float64) (float64, error) {
+	switch op {
+	case addOpType:
+		return a + b, nil
+	case subOpType:
+		return a - b, nil
+	case mulOpType:
+		return a * b, nil
+	case divOpType:
+		return a / b, nil
+	case powOpType:
+		return math.Pow(a, b), nil
+	case modOpType:
+		r := math.Mod(a, b)
+		if r != 0 && (r < 0) != (b < 0) {
+			r += b
+		}
+		return r, nil
+	case floorDivOpType:
+		return math.Floor(a / b), nil
+	}
+	return 0, binOpNYI(op, floatClass)
+}
+
+func complexKernel(op ʘBinaryOperatorType, a, b complex128) (complex128, error) {
+	switch op {
+	case addOpType:
+		return a + b, nil
+	case subOpType:
+		return a - b, nil
+	case mulOpType:
+		return a * b, nil
+	case divOpType:
+		return a / b, nil
+	case powOpType:
+		return cmplx.Pow(a, b), nil
+	}
+	return 0, binOpNYI(op, complexClass)
+}
+
+func intKernel(op ʘBinaryOperatorType, a, b int64) (int64, error) {
+	switch op {
+	case addOpType:
+		return a + b, nil
+	case subOpType:
+		return a - b, nil
+	case mulOpType:
+		return a * b, nil
+	case divOpType, modOpType, floorDivOpType:
+		if b == 0 {
+			return 0, &gerrors.ErrDivisionByZero{Action: op.String()}
+		}
+		if op == divOpType {
+			return a / b, nil
+		}
+		q, r := a/b, a%b
+		if r != 0 && (r < 0) != (b < 0) {
+			q, r = q-1, r+b
+		}
+		if op == modOpType {
+			return r, nil
+		}
+		return q, nil
+	case powOpType:
+		if b < 0 {
+			return 0, errors.Errorf("Cannot raise an integer to the negative power %d", b)
+		}
+		r := int64(1)
+		for ; b > 0; b >>= 1 {
+			if b&1 == 1 {
+				r *= a
+			}
+			a *= a
+		}
+		return r, nil
+	case andOpType:
+		return a & b, nil
+	case orOpType:
+		return a | b, nil
+	case xorOpType:
+		return a ^ b, nil
+	case shlOpType, shrOpType:
+		if b < 0 {
+			return 0, errors.Errorf("Cannot shift by the negative count %d", b)
+		}
+		if op == shlOpType {
+			return a << uint64(b), nil
+		}
+		return a >> uint64(b), nil
+	}
+	return 0, binOpNYI(op, intClass)
+}
+
+func boolKernel(op ʘBinaryOperatorType, a, b bool) (bool, error) {
+	switch op {
+	case andOpType:
+		return a && b, nil
+	case orOpType:
+		return a || b, nil
+	case xorOpType:
+		return a != b, nil
+	}
+	return false, binOpNYI(op, boolClass)
+}
+
+// binOpKernel applies op elementwise to a and b, which are either scalars or slices of dtype dt.
+// A scalar, or a slice of one element, is broadcast to the length of the other operand.
+// The result is a slice of the result dtype of op.
+func binOpKernel(op ʘBinaryOperatorType, dt tensor.Dtype, same bool, a, b interface{}) (retVal interface{}, err error) {
+	var class dtypeClass
+	if class, err = classOf(dt); err != nil {
+		return nil, err
+	}
+
+	var cmps []bool
+	switch class {
+	case floatClass:
+		as, bs := widenFloats(a), widenFloats(b)
+		n := maxInt(len(as), len(bs))
+		if op.isCmp() {
+			cmps = make([]bool, n)
+			for i := range cmps {
+				x, y := as[i%len(as)], bs[i%len(bs)]
+				switch {
+				case math.IsNaN(x) || math.IsNaN(y):
+					cmps[i] = op == neOpType
+				case x < y:
+					cmps[i] = cmpKernel(op, -1)
+				default:
+					cmps[i] = cmpKernel(op, boolToInt(x > y))
+				}
+			}
+			break
+		}
+		r := make([]float64, n)
+		for i := range r {
+			if r[i], err = floatKernel(op, as[i%len(as)], bs[i%len(bs)]); err != nil {
+				return nil, err
+			}
+		}
+		return narrowFloats(dt, r), nil
+	case intClass:
+		as, bs := widenInts(a), widenInts(b)
+		n := maxInt(len(as), len(bs))
+		if op.isCmp() {
+			cmps = make([]bool, n)
+			for i := range cmps {
+				x, y := as[i%len(as)], bs[i%len(bs)]
+				if x < y {
+					cmps[i] = cmpKernel(op, -1)
+				} else {
+					cmps[i] = cmpKernel(op, boolToInt(x > y))
+				}
+			}
+			break
+		}
+		r := make([]int64, n)
+		for i := range r {
+			if r[i], err = intKernel(op, as[i%len(as)], bs[i%len(bs)]); err != nil {
+				return nil, err
+			}
+		}
+		return narrowInts(dt, r), nil
+	case complexClass:
+		as, bs := widenComplexes(a), widenComplexes(b)
+		n := maxInt(len(as), len(bs))
+		if op.isCmp() {
+			if op != eqOpType && op != neOpType {
+				return nil, binOpNYI(op, complexClass)
+			}
+			cmps = make([]bool, n)
+			for i := range cmps {
+				cmps[i] = (as[i%len(as)] == bs[i%len(bs)]) == (op == eqOpType)
+			}
+			break
+		}
+		r := make([]complex128, n)
+		for i := range r {
+			if r[i], err = complexKernel(op, as[i%len(as)], bs[i%len(bs)]); err != nil {
+				return nil, err
+			}
+		}
+		return narrowComplexes(dt, r), nil
+	case boolClass:
+		as, bs := widenBools(a), widenBools(b)
+		r := make([]bool, maxInt(len(as), len(bs)))
+		for i := range r {
+			x, y := as[i%len(as)], bs[i%len(bs)]
+			if op.isCmp() {
+				r[i] = cmpKernel(op, boolToInt(x)-boolToInt(y))
+				continue
+			}
+			if r[i], err = boolKernel(op, x, y); err != nil {
+				return nil, err
+			}
+		}
+		return r, nil
+	}
+
+	if !same {
+		return cmps, nil
+	}
+	switch class {
+	case floatClass:
+		r := make([]float64, len(cmps))
+		for i, c := range cmps {
+			r[i] = float64(boolToInt(c))
+		}
+		return narrowFloats(dt, r), nil
+	case complexClass:
+		r := make([]complex128, len(cmps))
+		for i, c := range cmps {
+			r[i] = complex(float64(boolToInt(c)), 0)
+		}
+		return narrowComplexes(dt, r), nil
+	}
+	r := make([]int64, len(cmps))
+	for i, c := range cmps {
+		r[i] = int64(boolToInt(c))
+	}
+	return narrowInts(dt, r), nil
+}
+
+// applyBinOpKernel applies op elementwise with the scalar kernels, for the operators and dtypes that have no function in the tensor package.
+// a and b are tensors or scalars, at least one being a tensor. The FuncOpts are honoured as the tensor package would.
+func applyBinOpKernel(op ʘBinaryOperatorType, a, b interface{}, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	ta, aok := a.(tensor.Tensor)
+	tb, bok := b.(tensor.Tensor)
+	var t tensor.Tensor
+	switch {
+	case aok && bok:
+		if !ta.Shape().Eq(tb.Shape()) {
+			return nil, errors.Errorf("Shape mismatch for %v: %v and %v", op, ta.Shape(), tb.Shape())
+		}
+		t = ta
+		a, b = ta.Data(), tb.Data()
+	case aok:
+		t = ta
+		a = ta.Data()
+	case bok:
+		t = tb
+		b = tb.Data()
+	default:
+		return nil, errors.Errorf("Expected at least one tensor operand for %v. Got %T and %T", op, a, b)
+	}
+
+	fo := tensor.ParseFuncOpts(opts...)
+	var data interface{}
+	if data, err = binOpKernel(op, t.Dtype(), fo.Same(), a, b); err != nil {
+		return nil, err
+	}
+	return writeElems(t, data, fo)
+}
+
+// writeElems writes data, computed elementwise from t, where the FuncOpts ask for it: added to the incr tensor, copied to the reuse tensor,
+// copied to t when unsafe, or in a new tensor otherwise. The sums are computed with the scalar kernels, so that any dtype can be written.
+func writeElems(t tensor.Tensor, data interface{}, fo *tensor.OpOpt) (retVal tensor.Tensor, err error) {
+	res := tensor.New(tensor.WithShape(t.Shape().Clone()...), tensor.WithBacking(data))
+	reuse, incr := fo.IncrReuse()
+	switch {
+	case reuse != nil && incr:
+		var sum interface{}
+		if sum, err = binOpKernel(addOpType, reuse.Dtype(), false, reuse.Data(), data); err != nil {
+			return nil, err
+		}
+		res = tensor.New(tensor.WithShape(t.Shape().Clone()...), tensor.WithBacking(sum))
+		fallthrough
+	case reuse != nil:
+		if err = tensor.Copy(reuse, res); err != nil {
+			return nil, err
+		}
+		return reuse, nil
+	case !fo.Safe() && res.Dtype() == t.Dtype():
+		if err = tensor.Copy(t, res); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+	return res, nil
+}
+
+// notOperator is the ʘUnaryOperator of Not. Unlike the other unary operators it isn't a float function:
+// it flips the bits of integers and negates bools, as a xor with all the bits set.
+type notOperator struct{}
+
+func (notOperator) unaryOpType() ʘUnaryOperatorType { return notOpType }
+func (notOperator) String() string                  { return notOpType.String() }
+
+// onesOf returns a scalar of dtype dt with all its bits set
+func onesOf(dt tensor.Dtype) (interface{}, error) {
+	switch dt {
+	case tensor.Int:
+		return int(-1), nil
+	case tensor.Int64:
+		return int64(-1), nil
+	case tensor.Int32:
+		return int32(-1), nil
+	case tensor.Byte:
+		return byte(math.MaxUint8), nil
+	case tensor.Bool:
+		return true, nil
+	}
+	return nil, &gerrors.ErrNotYetImplemented{
+		Action:      "not",
+		Target:      dt,
+		IsTypeError: false,
+	}
+}
+
+func notTensor(t tensor.Tensor, opts ...tensor.FuncOpt) (tensor.Tensor, error) {
+	ones, err := onesOf(t.Dtype())
+	if err != nil {
+		return nil, err
+	}
+	return applyBinOpKernel(xorOpType, t, ones, opts...)
+}
+
+func widenFloats(x interface{}) []float64 {
+	switch x := x.(type) {
+	case float64:
+		return []float64{x}
+	case float32:
+		return []float64{float64(x)}
+	case []float64:
+		return x
+	case []float32:
+		r := make([]float64, len(x))
+		for i, v := range x {
+			r[i] = float64(v)
+		}
+		return r
+	case half.Float16:
+		return []float64{float64(x.Float32())}
+	case half.BFloat16:
+		return []float64{float64(x.Float32())}
+	case []half.Float16:
+		r := make([]float64, len(x))
+		for i, v := range x {
+			r[i] = float64(v.Float32())
+		}
+		return r
+	case []half.BFloat16:
+		r := make([]float64, len(x))
+		for i, v := range x {
+			r[i] = float64(v.Float32())
+		}
+		return r
+	}
+	panic(errors.Errorf("Cannot widen %T to floats", x))
+}
+
+func widenInts(x interface{}) []int64 {
+	switch x := x.(type) {
+	case int:
+		return []int64{int64(x)}
+	case int64:
+		return []int64{x}
+	case int32:
+		return []int64{int64(x)}
+	case byte:
+		return []int64{int64(x)}
+	case []int:
+		r := make([]int64, len(x))
+		for i, v := range x {
+			r[i] = int64(v)
+		}
+		return r
+	case []int64:
+		return x
+	case []int32:
+		r := make([]int64, len(x))
+		for i, v := range x {
+			r[i] = int64(v)
+		}
+		return r
+	case []byte:
+		r := make([]int64, len(x))
+		for i, v := range x {
+			r[i] = int64(v)
+		}
+		return r
+	}
+	panic(errors.Errorf("Cannot widen %T to integers", x))
+}
+
+func widenComplexes(x interface{}) []complex128 {
+	switch x := x.(type) {
+	case complex128:
+		return []complex128{x}
+	case complex64:
+		return []complex128{complex128(x)}
+	case []complex128:
+		return x
+	case []complex64:
+		r := make([]complex128, len(x))
+		for i, v := range x {
+			r[i] = complex128(v)
+		}
+		return r
+	}
+	panic(errors.Errorf("Cannot widen %T to complex numbers", x))
+}
+
+func widenBools(x interface{}) []bool {
+	switch x := x.(type) {
+	case bool:
+		return []bool{x}
+	case []bool:
+		return x
+	}
+	panic(errors.Errorf("Cannot widen %T to bools", x))
+}
+
+// narrowFloats narrows float64s to dt. The half-precision floats are rounded to float32 first, as they are computed in float32.
+func narrowFloats(dt tensor.Dtype, x []float64) interface{} {
+	switch dt {
+	case tensor.Float64:
+		return x
+	case factory.Float16:
+		r := make([]half.Float16, len(x))
+		for i, v := range x {
+			r[i] = half.NewFloat16(float32(v))
+		}
+		return r
+	case factory.BFloat16:
+		r := make([]half.BFloat16, len(x))
+		for i, v := range x {
+			r[i] = half.NewBFloat16(float32(v))
+		}
+		return r
+	}
+	r := make([]float32, len(x))
+	for i, v := range x {
+		r[i] = float32(v)
+	}
+	return r
+}
+
+func narrowComplexes(dt tensor.Dtype, x []complex128) interface{} {
+	if dt == tensor.Complex128 {
+		return x
+	}
+	r := make([]complex64, len(x))
+	for i, v := range x {
+		r[i] = complex64(v)
+	}
+	return r
+}
+
+func narrowInts(dt tensor.Dtype, x []int64) interface{} {
+	switch dt {
+	case tensor.Int:
+		r := make([]int, len(x))
+		for i, v := range x {
+			r[i] = int(v)
+		}
+		return r
+	case tensor.Int32:
+		r := make([]int32, len(x))
+		for i, v := range x {
+			r[i] = int32(v)
+		}
+		return r
+	case tensor.Byte:
+		r := make([]byte, len(x))
+		for i, v := range x {
+			r[i] = byte(v)
+		}
+		return r
+	}
+	return x
+}
+
+// firstScalar returns the first element of a slice returned by binOpKernel as a scalar value
+func firstScalar(x interface{}) value.Scalar {
+	var r interface{}
+	switch x := x.(type) {
+	case []float64:
+		r = x[0]
+	case []float32:
+		r = x[0]
+	case []int:
+		r = x[0]
+	case []int64:
+		r = x[0]
+	case []int32:
+		r = x[0]
+	case []byte:
+		r = x[0]
+	case []bool:
+		r = x[0]
+	case []half.Float16:
+		r = x[0]
+	case []half.BFloat16:
+		r = x[0]
+	case []complex64:
+		r = x[0]
+	case []complex128:
+		r = x[0]
+	}
+	retVal, _ := value.AnyToScalar(r)
+	return retVal
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
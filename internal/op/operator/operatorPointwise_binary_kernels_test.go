@@ -0,0 +1,227 @@
+package operator
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/chewxy/hm"
+	gerrors "gorgonia.org/gorgonia/errors"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+func TestIntKernel(t *testing.T) {
+	intKernelTests := []struct {
+		op   ʘBinaryOperatorType
+		a, b int64
+
+		want      int64
+		divByZero bool
+		err       bool
+	}{
+		// Mod takes the sign of the divisor
+		{modOpType, 7, 3, 1, false, false},
+		{modOpType, -7, 3, 2, false, false},
+		{modOpType, 7, -3, -2, false, false},
+		{modOpType, -7, -3, -1, false, false},
+		{modOpType, -6, 3, 0, false, false},
+
+		// FloorDiv rounds towards -∞, Div towards 0
+		{floorDivOpType, 7, 3, 2, false, false},
+		{floorDivOpType, -7, 3, -3, false, false},
+		{floorDivOpType, 7, -3, -3, false, false},
+		{floorDivOpType, -7, -3, 2, false, false},
+		{floorDivOpType, -6, 3, -2, false, false},
+		{divOpType, -7, 2, -3, false, false},
+
+		{divOpType, 1, 0, 0, true, true},
+		{modOpType, 1, 0, 0, true, true},
+		{floorDivOpType, -1, 0, 0, true, true},
+
+		{andOpType, 12, 10, 8, false, false},
+		{orOpType, 12, 10, 14, false, false},
+		{xorOpType, 12, 10, 6, false, false},
+		{andOpType, -1, 5, 5, false, false},
+		{shlOpType, 1, 4, 16, false, false},
+		{shrOpType, -16, 2, -4, false, false},
+		{shlOpType, 1, -1, 0, false, true},
+
+		{powOpType, 3, 4, 81, false, false},
+		{powOpType, -2, 3, -8, false, false},
+		{powOpType, 2, -1, 0, false, true},
+	}
+
+	for _, it := range intKernelTests {
+		got, err := intKernel(it.op, it.a, it.b)
+		switch {
+		case it.err && err == nil:
+			t.Errorf("%d %v %d: expected an error", it.a, it.op, it.b)
+		case !it.err && err != nil:
+			t.Errorf("%d %v %d: %v", it.a, it.op, it.b, err)
+		case got != it.want:
+			t.Errorf("%d %v %d: expected %d. Got %d", it.a, it.op, it.b, it.want, got)
+		}
+		if _, ok := err.(*gerrors.ErrDivisionByZero); ok != it.divByZero {
+			t.Errorf("%d %v %d: expected an ErrDivisionByZero: %t. Got %v", it.a, it.op, it.b, it.divByZero, err)
+		}
+	}
+
+	// a == FloorDiv(a, b)*b + Mod(a, b), and Mod(a, b) has the sign of b
+	for a := int64(-9); a <= 9; a++ {
+		for b := int64(-4); b <= 4; b++ {
+			if b == 0 {
+				continue
+			}
+			q, _ := intKernel(floorDivOpType, a, b)
+			r, _ := intKernel(modOpType, a, b)
+			if q*b+r != a || (r != 0 && (r < 0) != (b < 0)) {
+				t.Errorf("FloorDiv(%d, %d) = %d and Mod(%d, %d) = %d are inconsistent", a, b, q, a, b, r)
+			}
+		}
+	}
+}
+
+func TestBoolKernel(t *testing.T) {
+	bools := []bool{false, true}
+	for _, a := range bools {
+		for _, b := range bools {
+			for op, want := range map[ʘBinaryOperatorType]bool{andOpType: a && b, orOpType: a || b, xorOpType: a != b} {
+				got, err := boolKernel(op, a, b)
+				if err != nil || got != want {
+					t.Errorf("%t %v %t: expected %t. Got %t (%v)", a, op, b, want, got, err)
+				}
+			}
+		}
+	}
+	if _, err := boolKernel(addOpType, true, true); err == nil {
+		t.Errorf("Expected bools to have no arithmetic")
+	}
+}
+
+func TestBinOpKernel(t *testing.T) {
+	binOpKernelTests := []struct {
+		name string
+		op   ʘBinaryOperatorType
+		dt   tensor.Dtype
+		same bool
+		a, b interface{}
+
+		want      interface{}
+		divByZero bool
+		err       bool
+	}{
+		{"int32 mod broadcast", modOpType, tensor.Int32, false, []int32{-7, 7, -6}, int32(3), []int32{2, 1, 0}, false, false},
+		{"int floorDiv", floorDivOpType, tensor.Int, false, []int{-7, 7}, []int{2, -2}, []int{-4, -4}, false, false},
+		{"byte wraps around", addOpType, tensor.Byte, false, []byte{250, 1}, []byte{10, 1}, []byte{4, 2}, false, false},
+		{"int64 xor", xorOpType, tensor.Int64, false, []int64{5, -1}, int64(3), []int64{6, -4}, false, false},
+		{"int shr", shrOpType, tensor.Int, false, int(-8), []int{1, 3}, []int{-4, -1}, false, false},
+		{"int cmp", gtOpType, tensor.Int, false, []int{1, 5}, int(3), []bool{false, true}, false, false},
+		{"int cmp same", gtOpType, tensor.Int, true, []int{1, 5}, int(3), []int{0, 1}, false, false},
+		{"int div by zero", divOpType, tensor.Int, false, []int{1, 2}, []int{1, 0}, nil, true, true},
+		{"byte mod by zero", modOpType, tensor.Byte, false, []byte{1}, byte(0), nil, true, true},
+		{"int64 floorDiv by zero", floorDivOpType, tensor.Int64, false, []int64{-1}, []int64{0}, nil, true, true},
+
+		{"bool and", andOpType, tensor.Bool, false, []bool{true, true, false, false}, []bool{true, false, true, false}, []bool{true, false, false, false}, false, false},
+		{"bool or", orOpType, tensor.Bool, false, []bool{true, true, false, false}, []bool{true, false, true, false}, []bool{true, true, true, false}, false, false},
+		{"bool xor broadcast", xorOpType, tensor.Bool, false, []bool{true, false}, true, []bool{false, true}, false, false},
+		{"false < true", ltOpType, tensor.Bool, false, []bool{false, true}, true, []bool{true, false}, false, false},
+		{"bool has no arithmetic", addOpType, tensor.Bool, false, []bool{true}, true, nil, false, true},
+
+		{"float mod", modOpType, tensor.Float64, false, []float64{-7, 7}, 3.0, []float64{2, 1}, false, false},
+		{"float floorDiv", floorDivOpType, tensor.Float64, false, []float64{-7, 7}, 2.0, []float64{-4, 3}, false, false},
+		{"float div by zero", divOpType, tensor.Float64, false, []float64{1}, 0.0, []float64{math.Inf(1)}, false, false},
+		{"float bitwise", andOpType, tensor.Float64, false, []float64{1}, 1.0, nil, false, true},
+	}
+
+	for _, bt := range binOpKernelTests {
+		got, err := binOpKernel(bt.op, bt.dt, bt.same, bt.a, bt.b)
+		if _, ok := err.(*gerrors.ErrDivisionByZero); ok != bt.divByZero {
+			t.Errorf("%v: expected an ErrDivisionByZero: %t. Got %v", bt.name, bt.divByZero, err)
+		}
+		switch {
+		case bt.err:
+			if err == nil {
+				t.Errorf("%v: expected an error. Got %v", bt.name, got)
+			}
+		case err != nil:
+			t.Errorf("%v: %v", bt.name, err)
+		case !reflect.DeepEqual(got, bt.want):
+			t.Errorf("%v: expected %v. Got %v", bt.name, bt.want, got)
+		}
+	}
+}
+
+func TestElemBinOp(t *testing.T) {
+	g := exprgraph.NewGraph()
+	a := g.NewVertex()
+	a.T, a.Shape, a.Name = factory.NewTensorType(1, tensor.Int), tensor.Shape{3}, "a"
+	g.AddNode(a)
+	b := g.NewVertex()
+	b.T, b.Shape, b.Name = tensor.Int, tensor.ScalarShape(), "b"
+	g.AddNode(b)
+	c := g.NewVertex()
+	c.T, c.Shape, c.Name = a.T, a.Shape.Clone(), "c"
+	g.AddNode(c)
+
+	for _, f := range []func(a, b *exprgraph.Node) (*exprgraph.Node, error){Mod, FloorDiv, And, Or, Xor, Shl, Shr} {
+		n, err := f(a, b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !n.T.Eq(a.T) || !n.Shape.Eq(a.Shape) {
+			t.Errorf("%v: expected %v of shape %v. Got %v of shape %v", n.Op, a.T, a.Shape, n.T, n.Shape)
+		}
+	}
+	if _, err := Mod(a, c); err != nil {
+		t.Errorf("Expected tensors of the same shape to be applied. Got %v", err)
+	}
+
+	// the tensor package computes Mod of ints its own way, the scalar kernels compute it when either operand is a scalar
+	x := tensor.New(tensor.WithBacking([]int{-7, 7, -6}))
+	y := tensor.New(tensor.WithBacking([]int{3, -3, 4}))
+	mod := newElemBinOp(modOpType, a, b)
+	got, err := mod.Do(x, value.NewI(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, 1, 0}; !reflect.DeepEqual(got.Data(), want) {
+		t.Errorf("Expected %v. Got %v", want, got.Data())
+	}
+	if got, err = newElemBinOp(modOpType, a, c).Do(x, y); err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{2, -2, 2}; !reflect.DeepEqual(got.Data(), want) {
+		t.Errorf("Expected %v. Got %v", want, got.Data())
+	}
+	if got, err = newElemBinOp(shlOpType, b, b).Do(value.NewI(3), value.NewI(2)); err != nil {
+		t.Fatal(err)
+	}
+	if got.Data() != 12 {
+		t.Errorf("Expected 3 << 2 to be 12. Got %v", got.Data())
+	}
+	if _, err = mod.Do(x, value.NewI64(3)); err == nil {
+		t.Errorf("Expected operands of different dtypes to return an error")
+	}
+	if _, err = mod.InferShape(tensor.Shape{3}, tensor.Shape{2}); err == nil {
+		t.Errorf("Expected tensors of different shapes to return an error")
+	}
+
+	// comparisons return bools, and only the arithmetic operators are differentiable
+	lt := newElemBinOp(ltOpType, a, b)
+	want := hm.NewFnType(factory.NewTensorType(1, hm.TypeVariable('a')), hm.TypeVariable('a'), factory.NewTensorType(1, tensor.Bool))
+	if !lt.Type().Eq(want) {
+		t.Errorf("Expected %v to have the type %v. Got %v", lt, want, lt.Type())
+	}
+	if d := mod.DiffWRT(2); d[0] || d[1] {
+		t.Errorf("Expected %v not to be differentiable. Got %v", mod, d)
+	}
+	if d := newElemBinOp(addOpType, a, b).DiffWRT(2); !d[0] || !d[1] {
+		t.Errorf("Expected %v to be differentiable. Got %v", addOpType, d)
+	}
+	if mod.Hashcode() == newElemBinOp(modOpType, a, c).Hashcode() {
+		t.Errorf("Expected ops on operands of different dims to have different hashes")
+	}
+}
@@ -0,0 +1,201 @@
+package operator
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/op"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// castOp converts a value from one dtype to another, such as between the precisions of floats.
+// Floats are rounded to the nearest float of lower precision, and truncated towards zero when converted to integers, as Go does.
+// Numbers converted to bools are true if they aren't 0, and bools converted to numbers are 1 or 0.
+// Complex numbers are converted to real numbers by dropping their imaginary part (but are true if either part isn't 0),
+// and real numbers have no imaginary part.
+//
+// Casts between floats and complex numbers are differentiable: the gradient is cast back to the dtype of the input,
+// dropping its imaginary part when the input is real.
+type castOp struct {
+	from, to tensor.Dtype
+	dims     int
+}
+
+func newCastOp(from, to tensor.Dtype, dims int) (*castOp, error) {
+	for _, dt := range []tensor.Dtype{from, to} {
+		if _, err := classOf(dt); err != nil {
+			return nil, errors.Wrap(err, "Cannot cast")
+		}
+	}
+	return &castOp{from: from, to: to, dims: dims}, nil
+}
+
+func (o *castOp) Arity() int { return 1 }
+
+func (o *castOp) Type() hm.Type {
+	if o.dims == 0 {
+		return hm.NewFnType(o.from, o.to)
+	}
+	return hm.NewFnType(factory.NewTensorType(o.dims, o.from), factory.NewTensorType(o.dims, o.to))
+}
+
+func (o *castOp) InferShape(ns ...op.DimSizer) (tensor.Shape, error) {
+	if err := op.CheckArity(o, len(ns)); err != nil {
+		return nil, err
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a tensor.Shape. Got %v of %T instead", ns[0], ns[0])
+	}
+	return s.Clone(), nil
+}
+
+func (o *castOp) Do(vals ...value.Value) (retVal value.Value, err error) {
+	if err = op.CheckArity(o, len(vals)); err != nil {
+		return nil, err
+	}
+	v := vals[0]
+	if v.Dtype() != o.from {
+		return nil, errors.Errorf("%v expects a value of %v. Got %v", o, o.from, v.Dtype())
+	}
+	if o.from == o.to {
+		return value.CloneValue(v)
+	}
+	return applyElems(v, func(data interface{}) (interface{}, error) { return castElems(data, o.from, o.to) })
+}
+
+func (o *castOp) ReturnsPtr() bool { return false }
+
+func (o *castOp) CallsExtern() bool { return false }
+
+func (o *castOp) OverwritesInput() int { return -1 }
+
+func (o *castOp) WriteHash(h hash.Hash) { fmt.Fprint(h, o.String()) }
+
+func (o *castOp) Hashcode() uint32 {
+	h := fnv.New32a()
+	o.WriteHash(h)
+	return h.Sum32()
+}
+
+func (o *castOp) String() string { return fmt.Sprintf("Cast{%v→%v}", o.from, o.to) }
+
+// DiffWRT only allows casts between floats and complex numbers to be differentiated
+func (o *castOp) DiffWRT(inputs int) []bool {
+	from, _ := classOf(o.from)
+	to, _ := classOf(o.to)
+	diff := func(c dtypeClass) bool { return c == floatClass || c == complexClass }
+	return []bool{diff(from) && diff(to)}
+}
+
+func (o *castOp) SymDiff(inputs exprgraph.Nodes, output, grad *exprgraph.Node) (retVal exprgraph.Nodes, err error) {
+	if !o.DiffWRT(1)[0] {
+		return nil, errors.Errorf("%v is not differentiable", o)
+	}
+	d := grad
+	if factory.IsComplex(o.to) && !factory.IsComplex(o.from) {
+		if d, err = Real(d); err != nil {
+			return nil, errors.Wrap(err, "Failed to carry Real()")
+		}
+	}
+	if d, err = Cast(d, o.from); err != nil {
+		return nil, errors.Wrap(err, "Failed to carry Cast()")
+	}
+	return exprgraph.Nodes{*d}, nil
+}
+
+// castElems converts a scalar or a slice of dtype from to a slice of dtype to
+func castElems(data interface{}, from, to tensor.Dtype) (interface{}, error) {
+	fc, err := classOf(from)
+	if err != nil {
+		return nil, err
+	}
+	tc, err := classOf(to)
+	if err != nil {
+		return nil, err
+	}
+
+	// everything goes through the widest type of its class
+	var fs []float64
+	var is []int64
+	var bs []bool
+	var cs []complex128
+	switch fc {
+	case floatClass:
+		fs = widenFloats(data)
+	case intClass:
+		is = widenInts(data)
+	case boolClass:
+		bs = widenBools(data)
+	case complexClass:
+		cs = widenComplexes(data)
+		switch tc {
+		case complexClass:
+			return narrowComplexes(to, cs), nil
+		case boolClass:
+			bs = make([]bool, len(cs))
+			for i, c := range cs {
+				bs[i] = c != 0
+			}
+			return bs, nil
+		}
+		// the imaginary parts are dropped
+		fs = make([]float64, len(cs))
+		for i, c := range cs {
+			fs[i] = real(c)
+		}
+	}
+
+	switch {
+	case tc == complexClass:
+		cs = make([]complex128, len(fs)+len(is)+len(bs))
+		for i, x := range fs {
+			cs[i] = complex(x, 0)
+		}
+		for i, x := range is {
+			cs[i] = complex(float64(x), 0)
+		}
+		for i, b := range bs {
+			cs[i] = complex(float64(boolToInt(b)), 0)
+		}
+		return narrowComplexes(to, cs), nil
+	case tc == floatClass && fs == nil:
+		fs = make([]float64, len(is)+len(bs))
+		for i, x := range is {
+			fs[i] = float64(x)
+		}
+		for i, b := range bs {
+			fs[i] = float64(boolToInt(b))
+		}
+	case tc == intClass && is == nil:
+		is = make([]int64, len(fs)+len(bs))
+		for i, x := range fs {
+			is[i] = int64(x)
+		}
+		for i, b := range bs {
+			is[i] = int64(boolToInt(b))
+		}
+	case tc == boolClass && bs == nil:
+		bs = make([]bool, len(fs)+len(is))
+		for i, x := range fs {
+			bs[i] = x != 0
+		}
+		for i, x := range is {
+			bs[i] = x != 0
+		}
+	}
+
+	switch tc {
+	case floatClass:
+		return narrowFloats(to, fs), nil
+	case intClass:
+		return narrowInts(to, is), nil
+	}
+	return bs, nil
+}
@@ -0,0 +1,81 @@
+package operator
+
+import (
+	"reflect"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/gorgonia/internal/value/half"
+	"gorgonia.org/tensor"
+)
+
+func TestCastElems(t *testing.T) {
+	castTests := []struct {
+		name     string
+		data     interface{}
+		from, to tensor.Dtype
+		want     interface{}
+	}{
+		{"floats are truncated", []float64{1.5, -2.7}, tensor.Float64, tensor.Int, []int{1, -2}},
+		{"ints to bools", []int{0, 3}, tensor.Int, tensor.Bool, []bool{false, true}},
+		{"bools to floats", []bool{true, false}, tensor.Bool, tensor.Float32, []float32{1, 0}},
+		{"imaginary parts are dropped", []complex128{1 + 2i}, tensor.Complex128, tensor.Float32, []float32{1}},
+		{"reals have no imaginary part", []float64{-3}, tensor.Float64, tensor.Complex64, []complex64{-3}},
+		{"half", []float32{1, 65504}, tensor.Float32, factory.Float16, []half.Float16{half.NewFloat16(1), half.NewFloat16(65504)}},
+		{"scalar", int32(-1), tensor.Int32, tensor.Byte, []byte{255}},
+	}
+	for _, ct := range castTests {
+		got, err := castElems(ct.data, ct.from, ct.to)
+		if err != nil {
+			t.Errorf("%v: %v", ct.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, ct.want) {
+			t.Errorf("%v: expected %v. Got %v", ct.name, ct.want, got)
+		}
+	}
+}
+
+func TestCast(t *testing.T) {
+	g := exprgraph.NewGraph()
+	x := g.NewVertex()
+	x.T, x.Shape, x.Name = factory.NewTensorType(2, tensor.Float32), tensor.Shape{2, 3}, "x"
+	g.AddNode(x)
+
+	y, err := Cast(x, factory.Float16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y.Graph() != g || !g.HasEdgeFromTo(y.ID(), x.ID()) {
+		t.Errorf("Expected Cast(x) to be a node of the graph of x, computed from x")
+	}
+	if dt, err := dtypeOf(y.T); err != nil || dt != factory.Float16 || !y.Shape.Eq(x.Shape) {
+		t.Errorf("Expected Cast(x) to be a %v tensor of shape %v. Got %v of shape %v", factory.Float16, x.Shape, y.T, y.Shape)
+	}
+	if _, err = Cast(x, tensor.String); err == nil {
+		t.Errorf("Expected a cast to strings to return an error")
+	}
+
+	// the gradient is cast back to the dtype of the input
+	grad := g.NewVertex()
+	grad.T, grad.Shape, grad.Name = y.T, y.Shape.Clone(), "grad"
+	g.AddNode(grad)
+	dx, err := y.Op.(*castOp).SymDiff(exprgraph.Nodes{*x}, y, grad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dx) != 1 {
+		t.Fatalf("Expected one gradient. Got %d", len(dx))
+	}
+	if dt, err := dtypeOf(dx[0].T); err != nil || dt != tensor.Float32 || !dx[0].Shape.Eq(x.Shape) {
+		t.Errorf("Expected the gradient of x to be a %v tensor of shape %v. Got %v of shape %v", tensor.Float32, x.Shape, dx[0].T, dx[0].Shape)
+	}
+	i, err := Cast(x, tensor.Int)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = i.Op.(*castOp).SymDiff(exprgraph.Nodes{*x}, i, grad); err == nil {
+		t.Errorf("Expected a cast to ints not to be differentiable")
+	}
+}
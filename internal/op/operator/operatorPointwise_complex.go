@@ -0,0 +1,243 @@
+package operator
+
+import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math/cmplx"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/op"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// complexPart is a real function of a complex number
+type complexPart byte
+
+const (
+	realPart complexPart = iota
+	imagPart
+	absPart   // the modulus
+	anglePart // the argument, in (-π, π]
+)
+
+func (p complexPart) String() string { return [...]string{"real", "imag", "abs", "angle"}[p] }
+
+func (p complexPart) of(z complex128) float64 {
+	switch p {
+	case realPart:
+		return real(z)
+	case imagPart:
+		return imag(z)
+	case absPart:
+		return cmplx.Abs(z)
+	}
+	return cmplx.Phase(z)
+}
+
+// grad returns the gradient of z, given the gradient g of its part.
+// With z = x+iy, the gradient is ∂p/∂x·g + i∂p/∂y·g (see the unary differentiation expressions).
+// abs and angle have no gradient at 0, where 0 is returned.
+func (p complexPart) grad(z complex128, g float64) complex128 {
+	switch p {
+	case realPart:
+		return complex(g, 0)
+	case imagPart:
+		return complex(0, g)
+	}
+	r := cmplx.Abs(z)
+	if r == 0 {
+		return 0
+	}
+	if p == absPart {
+		return complex(g/r, 0) * z // g·z/|z|
+	}
+	return complex(0, g/(r*r)) * z // g·iz/|z|²
+}
+
+// complexPartOp takes a part of complex numbers: their real part, imaginary part, modulus or argument.
+// The result is a float of the precision of the parts: Float32 for Complex64 and Float64 for Complex128.
+type complexPartOp struct {
+	part complexPart
+	from tensor.Dtype
+	dims int
+}
+
+func newComplexPartOp(part complexPart, from tensor.Dtype, dims int) (*complexPartOp, error) {
+	if !factory.IsComplex(from) {
+		return nil, errors.Errorf("%v expects complex numbers. Got %v", part, from)
+	}
+	return &complexPartOp{part: part, from: from, dims: dims}, nil
+}
+
+func (o *complexPartOp) Arity() int { return 1 }
+
+func (o *complexPartOp) Type() hm.Type {
+	to := factory.RealOf(o.from)
+	if o.dims == 0 {
+		return hm.NewFnType(o.from, to)
+	}
+	return hm.NewFnType(factory.NewTensorType(o.dims, o.from), factory.NewTensorType(o.dims, to))
+}
+
+func (o *complexPartOp) InferShape(ns ...op.DimSizer) (tensor.Shape, error) {
+	if err := op.CheckArity(o, len(ns)); err != nil {
+		return nil, err
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a tensor.Shape. Got %v of %T instead", ns[0], ns[0])
+	}
+	return s.Clone(), nil
+}
+
+func (o *complexPartOp) Do(vals ...value.Value) (retVal value.Value, err error) {
+	if err = op.CheckArity(o, len(vals)); err != nil {
+		return nil, err
+	}
+	if vals[0].Dtype() != o.from {
+		return nil, errors.Errorf("%v expects a value of %v. Got %v", o, o.from, vals[0].Dtype())
+	}
+	return applyElems(vals[0], func(data interface{}) (interface{}, error) {
+		zs := widenComplexes(data)
+		r := make([]float64, len(zs))
+		for i, z := range zs {
+			r[i] = o.part.of(z)
+		}
+		return narrowFloats(factory.RealOf(o.from), r), nil
+	})
+}
+
+func (o *complexPartOp) ReturnsPtr() bool { return false }
+
+func (o *complexPartOp) CallsExtern() bool { return false }
+
+func (o *complexPartOp) OverwritesInput() int { return -1 }
+
+func (o *complexPartOp) WriteHash(h hash.Hash) { fmt.Fprint(h, o.String()) }
+
+func (o *complexPartOp) Hashcode() uint32 {
+	h := fnv.New32a()
+	o.WriteHash(h)
+	return h.Sum32()
+}
+
+func (o *complexPartOp) String() string { return fmt.Sprintf("%v{%v}", o.part, o.from) }
+
+func (o *complexPartOp) DiffWRT(inputs int) []bool { return []bool{true} }
+
+func (o *complexPartOp) SymDiff(inputs exprgraph.Nodes, output, grad *exprgraph.Node) (retVal exprgraph.Nodes, err error) {
+	if err = op.CheckArity(o, len(inputs)); err != nil {
+		return nil, err
+	}
+	diff := &complexPartDiffOp{o}
+	var d *exprgraph.Node
+	if d, err = binOpNode(diff, &inputs[0], grad); err != nil {
+		return nil, errors.Wrapf(err, "Failed to carry %v", diff)
+	}
+	return exprgraph.Nodes{*d}, nil
+}
+
+// complexPartDiffOp computes the gradient of the complex input of a complexPartOp, given the gradient of its output.
+type complexPartDiffOp struct{ *complexPartOp }
+
+func (o *complexPartDiffOp) Arity() int { return 2 }
+
+func (o *complexPartDiffOp) Type() hm.Type {
+	to := factory.RealOf(o.from)
+	if o.dims == 0 {
+		return hm.NewFnType(o.from, to, o.from)
+	}
+	t := factory.NewTensorType(o.dims, o.from)
+	return hm.NewFnType(t, factory.NewTensorType(o.dims, to), t)
+}
+
+func (o *complexPartDiffOp) InferShape(ns ...op.DimSizer) (tensor.Shape, error) {
+	if err := op.CheckArity(o, len(ns)); err != nil {
+		return nil, err
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a tensor.Shape. Got %v of %T instead", ns[0], ns[0])
+	}
+	return s.Clone(), nil
+}
+
+func (o *complexPartDiffOp) Do(vals ...value.Value) (retVal value.Value, err error) {
+	if err = op.CheckArity(o, len(vals)); err != nil {
+		return nil, err
+	}
+	input, grad := vals[0], vals[1]
+	if !input.Shape().Eq(grad.Shape()) {
+		return nil, errors.Errorf("%v expects the input and its gradient to have the same shape. Got %v and %v", o, input.Shape(), grad.Shape())
+	}
+
+	var gs []float64
+	if gt, ok := grad.(tensor.Tensor); ok {
+		gs = widenFloats(tensor.Materialize(gt).Data())
+	} else {
+		gs = widenFloats(grad.Data())
+	}
+	return applyElems(input, func(data interface{}) (interface{}, error) {
+		zs := widenComplexes(data)
+		r := make([]complex128, len(zs))
+		for i, z := range zs {
+			r[i] = o.part.grad(z, gs[i])
+		}
+		return narrowComplexes(o.from, r), nil
+	})
+}
+
+func (o *complexPartDiffOp) WriteHash(h hash.Hash) { fmt.Fprint(h, o.String()) }
+
+func (o *complexPartDiffOp) Hashcode() uint32 {
+	h := fnv.New32a()
+	o.WriteHash(h)
+	return h.Sum32()
+}
+
+func (o *complexPartDiffOp) String() string { return fmt.Sprintf("%vDiff{%v}", o.part, o.from) }
+
+func (o *complexPartDiffOp) DiffWRT(inputs int) []bool { return make([]bool, inputs) }
+
+func (o *complexPartDiffOp) SymDiff(inputs exprgraph.Nodes, output, grad *exprgraph.Node) (retVal exprgraph.Nodes, err error) {
+	return nil, errors.Errorf("%v is not differentiable", o)
+}
+
+// complexPartNode takes the part of the complex numbers of x
+func complexPartNode(part complexPart, x *exprgraph.Node) (*exprgraph.Node, error) {
+	from, err := dtypeOf(x.T)
+	if err != nil {
+		return nil, err
+	}
+	o, err := newComplexPartOp(part, from, x.Shape.Dims())
+	if err != nil {
+		return nil, err
+	}
+	return unaryOpNode(o, x)
+}
+
+// applyElems applies fn to the elements of a scalar or a tensor. The slice fn returns is put in a value of the same shape.
+func applyElems(v value.Value, fn func(data interface{}) (interface{}, error)) (retVal value.Value, err error) {
+	var data interface{}
+	switch vt := v.(type) {
+	case tensor.Tensor:
+		if data, err = fn(tensor.Materialize(vt).Data()); err != nil {
+			return nil, err
+		}
+		if vt.Shape().IsScalar() {
+			return firstScalar(data), nil
+		}
+		return tensor.New(tensor.WithShape(vt.Shape().Clone()...), tensor.WithBacking(data)), nil
+	case value.Scalar:
+		if data, err = fn(vt.Data()); err != nil {
+			return nil, err
+		}
+		return firstScalar(data), nil
+	}
+	return nil, errors.Errorf("Cannot apply a function to the elements of %T", v)
+}
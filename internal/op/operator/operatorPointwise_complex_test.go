@@ -0,0 +1,111 @@
+package operator
+
+import (
+	"math/cmplx"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// wirtingerDiff approximates the gradient ∂L/∂x + i∂L/∂y of a real function L of z = x+iy with central finite differences
+func wirtingerDiff(L func(complex128) float64, z complex128) complex128 {
+	const h = 1e-6
+	dx := (L(z+complex(h, 0)) - L(z-complex(h, 0))) / (2 * h)
+	dy := (L(z+complex(0, h)) - L(z-complex(0, h))) / (2 * h)
+	return complex(dx, dy)
+}
+
+// the points are away from 0 and from the negative real axis, where abs and angle have no gradient
+var complexDiffPoints = []complex128{1 + 2i, -3 + 0.5i, 0.25 - 4i, 2, 1i}
+
+func TestComplexPartDiff(t *testing.T) {
+	const g = 1.5 // the gradient of the part
+	for _, part := range []complexPart{realPart, imagPart, absPart, anglePart} {
+		o, err := newComplexPartOp(part, tensor.Complex128, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		grads := make([]float64, len(complexDiffPoints))
+		for i := range grads {
+			grads[i] = g
+		}
+		input := tensor.New(tensor.WithBacking(append([]complex128(nil), complexDiffPoints...)))
+		d, err := (&complexPartDiffOp{o}).Do(input, tensor.New(tensor.WithBacking(grads)))
+		if err != nil {
+			t.Errorf("%v: %v", part, err)
+			continue
+		}
+		got := d.Data().([]complex128)
+		for i, z := range complexDiffPoints {
+			want := wirtingerDiff(func(z complex128) float64 { return g * part.of(z) }, z)
+			if cmplx.Abs(got[i]-want) > 1e-6 {
+				t.Errorf("%v: expected the gradient %v at %v. Got %v", part, want, z, got[i])
+			}
+		}
+	}
+}
+
+func TestConjDiff(t *testing.T) {
+	// L(w) = Re(w)·Re(G) + Im(w)·Im(G), so that G is the gradient of w = conj(z)
+	conj := sc128UnaryOperators[conjOpType]
+	for _, gradY := range []complex128{1, 1i, 2 - 3i} {
+		L := func(z complex128) float64 {
+			w := conj(z)
+			return real(w)*real(gradY) + imag(w)*imag(gradY)
+		}
+		for _, z := range complexDiffPoints {
+			want := wirtingerDiff(L, z)
+			// conjDiff adds conj(gradY) to the gradient of x
+			if got := conj(gradY); cmplx.Abs(got-want) > 1e-6 {
+				t.Errorf("Conj at %v with the gradient %v: expected %v. Got %v", z, gradY, want, got)
+			}
+		}
+	}
+}
+
+func TestComplexParts(t *testing.T) {
+	g := exprgraph.NewGraph()
+	z := g.NewVertex()
+	z.T, z.Shape, z.Name = factory.NewTensorType(1, tensor.Complex64), tensor.Shape{4}, "z"
+	g.AddNode(z)
+	x := g.NewVertex()
+	x.T, x.Shape, x.Name = factory.NewTensorType(1, tensor.Float64), tensor.Shape{4}, "x"
+	g.AddNode(x)
+
+	for _, f := range []func(*exprgraph.Node) (*exprgraph.Node, error){Abs, Real, Imag, Angle} {
+		n, err := f(z)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if dt, err := dtypeOf(n.T); err != nil || dt != tensor.Float32 || !n.Shape.Eq(z.Shape) {
+			t.Errorf("Expected %v to be a %v tensor of shape %v. Got %v of shape %v", n.Op, tensor.Float32, z.Shape, n.T, n.Shape)
+		}
+	}
+	if _, err := Real(x); err == nil {
+		t.Errorf("Expected the real part of floats to return an error")
+	}
+	if n, err := Conj(x); err != nil || n != x {
+		t.Errorf("Expected the conjugate of floats to be themselves. Got %v (%v)", n, err)
+	}
+
+	// the gradient of a part is a complex gradient of z
+	y, err := Abs(z)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grad := g.NewVertex()
+	grad.T, grad.Shape, grad.Name = y.T, y.Shape.Clone(), "grad"
+	g.AddNode(grad)
+	dz, err := y.Op.(*complexPartOp).SymDiff(exprgraph.Nodes{*z}, y, grad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dz) != 1 {
+		t.Fatalf("Expected one gradient. Got %d", len(dz))
+	}
+	if dt, err := dtypeOf(dz[0].T); err != nil || dt != tensor.Complex64 || !dz[0].Shape.Eq(z.Shape) {
+		t.Errorf("Expected the gradient of z to be a %v tensor of shape %v. Got %v of shape %v", tensor.Complex64, z.Shape, dz[0].T, dz[0].Shape)
+	}
+}
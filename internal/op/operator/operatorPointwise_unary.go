@@ -1,8 +1,16 @@
 package operator
 
 import (
+	"fmt"
+	"hash"
+	"hash/fnv"
+
+	"github.com/chewxy/hm"
 	"github.com/pkg/errors"
 	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/op"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/gorgonia/internal/value/factory"
 	"gorgonia.org/tensor"
 )
 
@@ -66,6 +74,42 @@ func (f *sf32UnaryOperator) unaryOpType() ʘUnaryOperatorType {
 		return expm1OpType
 	case &softplusf32:
 		return softplusOpType
+	case &reluf32:
+		return reluOpType
+	case &relu6f32:
+		return relu6OpType
+	case &seluf32:
+		return seluOpType
+	case &geluf32:
+		return geluOpType
+	case &geluTanhf32:
+		return geluTanhOpType
+	case &swishf32:
+		return swishOpType
+	case &mishf32:
+		return mishOpType
+	case &hardSigmoidf32:
+		return hardSigmoidOpType
+	case &hardSwishf32:
+		return hardSwishOpType
+	case &reluDerivf32:
+		return reluDerivOpType
+	case &relu6Derivf32:
+		return relu6DerivOpType
+	case &seluDerivf32:
+		return seluDerivOpType
+	case &geluDerivf32:
+		return geluDerivOpType
+	case &geluTanhDerivf32:
+		return geluTanhDerivOpType
+	case &swishDerivf32:
+		return swishDerivOpType
+	case &mishDerivf32:
+		return mishDerivOpType
+	case &hardSigmoidDerivf32:
+		return hardSigmoidDerivOpType
+	case &hardSwishDerivf32:
+		return hardSwishDerivOpType
 	}
 	return maxʘUnaryOperator
 }
@@ -114,6 +158,42 @@ func (f *sf64UnaryOperator) unaryOpType() ʘUnaryOperatorType {
 		return expm1OpType
 	case &softplusf64:
 		return softplusOpType
+	case &reluf64:
+		return reluOpType
+	case &relu6f64:
+		return relu6OpType
+	case &seluf64:
+		return seluOpType
+	case &geluf64:
+		return geluOpType
+	case &geluTanhf64:
+		return geluTanhOpType
+	case &swishf64:
+		return swishOpType
+	case &mishf64:
+		return mishOpType
+	case &hardSigmoidf64:
+		return hardSigmoidOpType
+	case &hardSwishf64:
+		return hardSwishOpType
+	case &reluDerivf64:
+		return reluDerivOpType
+	case &relu6Derivf64:
+		return relu6DerivOpType
+	case &seluDerivf64:
+		return seluDerivOpType
+	case &geluDerivf64:
+		return geluDerivOpType
+	case &geluTanhDerivf64:
+		return geluTanhDerivOpType
+	case &swishDerivf64:
+		return swishDerivOpType
+	case &mishDerivf64:
+		return mishDerivOpType
+	case &hardSigmoidDerivf64:
+		return hardSigmoidDerivOpType
+	case &hardSwishDerivf64:
+		return hardSwishDerivOpType
 	}
 
 	return maxʘUnaryOperator
@@ -121,8 +201,121 @@ func (f *sf64UnaryOperator) unaryOpType() ʘUnaryOperatorType {
 
 func (f *sf64UnaryOperator) String() string { return f.unaryOpType().String() }
 
+// elemUnaryOp applies a unary operator to each element of its input, which is a scalar or a tensor of dims dimensions.
+// The operator of the dtype of the input is picked when the op is executed.
+type elemUnaryOp struct {
+	ʘUnaryOperatorType
+	dims int
+}
+
+func newElemUnaryOp(op ʘUnaryOperatorType, a *exprgraph.Node) *elemUnaryOp {
+	return &elemUnaryOp{ʘUnaryOperatorType: op, dims: a.Shape.Dims()}
+}
+
+func (o *elemUnaryOp) Arity() int { return 1 }
+
+// elemUnaryOp has this type:
+//		op :: a → a
+// or, for tensors:
+//		op :: Tensor-n a → Tensor-n a
+func (o *elemUnaryOp) Type() hm.Type {
+	a := hm.TypeVariable('a')
+	if o.dims == 0 {
+		return hm.NewFnType(a, a)
+	}
+	t := factory.NewTensorType(o.dims, a)
+	return hm.NewFnType(t, t)
+}
+
+func (o *elemUnaryOp) InferShape(ns ...op.DimSizer) (tensor.Shape, error) {
+	if err := op.CheckArity(o, len(ns)); err != nil {
+		return nil, err
+	}
+	s, ok := ns[0].(tensor.Shape)
+	if !ok {
+		return nil, errors.Errorf("Expected a tensor.Shape. Got %v of %T instead", ns[0], ns[0])
+	}
+	return s.Clone(), nil
+}
+
+func (o *elemUnaryOp) Do(vals ...value.Value) (retVal value.Value, err error) {
+	if err = op.CheckArity(o, len(vals)); err != nil {
+		return nil, err
+	}
+	switch v := vals[0].(type) {
+	case tensor.Tensor:
+		return unaryCheckApply(o.operator(v.Dtype()), v)
+	case value.Scalar:
+		// the operator is applied to a tensor holding the scalar
+		t := tensor.New(tensor.WithShape(1), tensor.Of(v.Dtype()))
+		if err = t.SetAt(v.Data(), 0); err != nil {
+			return nil, err
+		}
+		var r tensor.Tensor
+		if r, err = unaryCheckApply(o.operator(v.Dtype()), t); err != nil {
+			return nil, err
+		}
+		return firstScalar(r.Data()), nil
+	}
+	return nil, errors.Errorf("%v cannot be applied to %T", o, vals[0])
+}
+
+// operator returns the unary operator that computes the op on elements of dt
+func (o *elemUnaryOp) operator(dt tensor.Dtype) ʘUnaryOperator {
+	u := o.ʘUnaryOperatorType
+	if u < maxʘUnaryOperator {
+		switch {
+		case dt == tensor.Float32 && sf32UnaryOperators[u] != nil:
+			return sf32UnaryOperators[u]
+		case dt == tensor.Float64 && sf64UnaryOperators[u] != nil:
+			return sf64UnaryOperators[u]
+		}
+	}
+	// the half floats, complex numbers and bitwise operators are computed from the type of the operator
+	return u
+}
+
+func (o *elemUnaryOp) ReturnsPtr() bool { return true }
+
+func (o *elemUnaryOp) CallsExtern() bool { return false }
+
+func (o *elemUnaryOp) OverwritesInput() int { return -1 }
+
+func (o *elemUnaryOp) WriteHash(h hash.Hash) { fmt.Fprintf(h, "%v-%d", o.ʘUnaryOperatorType, o.dims) }
+
+func (o *elemUnaryOp) Hashcode() uint32 {
+	h := fnv.New32a()
+	o.WriteHash(h)
+	return h.Sum32()
+}
+
+func (o *elemUnaryOp) DiffWRT(inputs int) []bool {
+	return []bool{o.ʘUnaryOperatorType < maxʘUnaryOperator && ʘUnaryOpDifferentiable[o.ʘUnaryOperatorType]}
+}
+
+func (o *elemUnaryOp) SymDiff(inputs exprgraph.Nodes, output, grad *exprgraph.Node) (retVal exprgraph.Nodes, err error) {
+	if err = op.CheckArity(o, len(inputs)); err != nil {
+		return nil, err
+	}
+	if !o.DiffWRT(1)[0] {
+		return nil, errors.Errorf("%v is not differentiable", o)
+	}
+	var d *exprgraph.Node
+	if d, err = ʘUnaryOpDiffExprs[o.ʘUnaryOperatorType](&inputs[0], output, grad); err != nil {
+		return nil, errors.Wrapf(err, "Failed to differentiate %v", o)
+	}
+	return exprgraph.Nodes{*d}, nil
+}
+
 // unaryCheckApply checks in a interface is fulfilled. If it is, that engine is used instead
 func unaryCheckApply(op ʘUnaryOperator, t tensor.Tensor, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	switch {
+	case factory.IsHalf(t.Dtype()):
+		return halfApply(op, t, opts...)
+	case factory.IsComplex(t.Dtype()):
+		return complexApply(op, t, opts...)
+	}
+
 	e := t.Engine()
 	switch op.unaryOpType() {
 	case absOpType:
@@ -181,6 +374,10 @@ func unaryCheckApply(op ʘUnaryOperator, t tensor.Tensor, opts ...tensor.FuncOpt
 	case log1pOpType:
 	case expm1OpType:
 	case softplusOpType:
+	case reluOpType, relu6OpType, seluOpType,
+		geluOpType, geluTanhOpType, swishOpType, mishOpType, hardSigmoidOpType, hardSwishOpType:
+	case notOpType:
+		return notTensor(t, opts...)
 	}
 
 	//default case:
@@ -195,11 +392,47 @@ func unaryCheckApply(op ʘUnaryOperator, t tensor.Tensor, opts ...tensor.FuncOpt
 	return t.Apply(fn, opts...)
 }
 
+// halfApply applies the float32 version of a unary operator to a tensor of half-precision floats, which no engine computes
+func halfApply(op ʘUnaryOperator, t tensor.Tensor, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	u := op.unaryOpType()
+	if u >= maxʘUnaryOperator || sf32UnaryOperators[u] == nil {
+		return nil, errors.Errorf("%v is not defined on %v", op, t.Dtype())
+	}
+	fn := *sf32UnaryOperators[u]
+
+	xs := widenFloats(tensor.Materialize(t).Data())
+	ys := make([]float64, len(xs))
+	for i, x := range xs {
+		ys[i] = float64(fn(float32(x)))
+	}
+	return writeElems(t, narrowFloats(t.Dtype(), ys), tensor.ParseFuncOpts(opts...))
+}
+
+// complexApply applies the complex128 version of a unary operator to a tensor of complex numbers
+func complexApply(op ʘUnaryOperator, t tensor.Tensor, opts ...tensor.FuncOpt) (retVal tensor.Tensor, err error) {
+	u := op.unaryOpType()
+	if u >= maxʘUnaryOperator || sc128UnaryOperators[u] == nil {
+		return nil, errors.Errorf("%v is not defined on %v", op, t.Dtype())
+	}
+	fn := sc128UnaryOperators[u]
+
+	xs := widenComplexes(tensor.Materialize(t).Data())
+	ys := make([]complex128, len(xs))
+	for i, x := range xs {
+		ys[i] = fn(x)
+	}
+	return writeElems(t, narrowComplexes(t.Dtype(), ys), tensor.ParseFuncOpts(opts...))
+}
+
 /*
 DIFFERENTIATION EXPRESSIONS
 
 All the functions here are expressed in terms of *exprgraph.Node and/or exprgraph.Nodes
 
+The gradients of complex numbers follow the conjugate Wirtinger calculus: the gradient of x = a+bi is ∂L/∂a + i∂L/∂b,
+so that gradient descent works as it does on real numbers. For a holomorphic f, the gradient of x is gradY·conj(f'(x)).
+Conj is the identity on real numbers, so the same expressions serve both.
+
 */
 
 func nondiffUnaryOpExpr(x, y, gradY *exprgraph.Node) (*exprgraph.Node, error) {
@@ -321,7 +554,10 @@ func cosDiff(x, y *exprgraph.Node) (err error) {
 }
 
 func expDiffExpr(x, y, gradY *exprgraph.Node) (retVal *exprgraph.Node, err error) {
-	return HadamardProd(y, gradY)
+	if retVal, err = Conj(y); err != nil {
+		return nil, errors.Wrap(err, "Failed to carry Conj()")
+	}
+	return HadamardProd(retVal, gradY)
 }
 
 func expDiff(x, y *exprgraph.Node) (err error) {
@@ -336,9 +572,12 @@ func expDiff(x, y *exprgraph.Node) (err error) {
 }
 
 // solution is 1/x.
-// Upon multiplying with gradY for chain rule, it simply becomes gradY/x
+// Upon multiplying with gradY for chain rule, it simply becomes gradY/conj(x)
 func lnDiffExpr(x, y, gradY *exprgraph.Node) (retVal *exprgraph.Node, err error) {
-	return HadamardDiv(gradY, x)
+	if retVal, err = Conj(x); err != nil {
+		return nil, errors.Wrap(err, "Failed to carry Conj()")
+	}
+	return HadamardDiv(gradY, retVal)
 }
 
 func lnDiff(x, y *exprgraph.Node) (err error) {
@@ -413,6 +652,31 @@ func negDiff(x, y *exprgraph.Node) (err error) {
 	return
 }
 
+// conj isn't holomorphic: the gradient of x is conj(gradY)
+func conjDiffExpr(x, y, gradY *exprgraph.Node) (retVal *exprgraph.Node, err error) {
+	return Conj(gradY)
+}
+
+func conjDiff(x, y *exprgraph.Node) (err error) {
+	xdv, ydv := getDV(x, y)
+
+	conj := newElemUnaryOp(conjOpType, y)
+
+	var d Value
+	if d, err = conj.Do(ydv.d); err == nil {
+		if dT, ok := d.(tensor.Tensor); ok {
+			defer returnTensor(dT)
+		}
+
+		add := newElemBinOp(addOpType, x, y)
+		_, err = add.UnsafeDo(xdv.d, d)
+		if err = checkErrSetDeriv(err, xdv); err != nil {
+			return errors.Wrapf(err, autodiffFail, x)
+		}
+	}
+	return
+}
+
 func squareDiffExpr(x, y, gradY *exprgraph.Node) (retVal *exprgraph.Node, err error) {
 	var two *exprgraph.Node
 	if two, err = getConst(x, "two"); err != nil {
@@ -857,3 +1121,44 @@ func softplusDiff(x, y *exprgraph.Node) (err error) {
 	}
 	return
 }
+
+// activationDiffExpr creates the differentiation expression of an activation function f.
+// deriv is the unary operator that computes f'(x), so the expression is simply f'(x) ⊙ gradY
+func activationDiffExpr(deriv ʘUnaryOperatorType) func(x, y, gradY *exprgraph.Node) (*exprgraph.Node, error) {
+	return func(x, y, gradY *exprgraph.Node) (retVal *exprgraph.Node, err error) {
+		if retVal, err = unaryOpNode(newElemUnaryOp(deriv, x), x); err != nil {
+			return nil, errors.Wrapf(err, "Failed to carry %v", deriv)
+		}
+		WithGroupName(gradClust)(retVal)
+		if retVal, err = HadamardProd(retVal, gradY); err != nil {
+			return nil, errors.Wrap(err, hadamardProdFail)
+		}
+		return
+	}
+}
+
+// activationDiff creates the function that computes the derivative of an activation function f at runtime.
+// deriv is the unary operator that computes f'(x).
+func activationDiff(deriv ʘUnaryOperatorType) func(x, y *exprgraph.Node) error {
+	return func(x, y *exprgraph.Node) (err error) {
+		xdv, ydv := getDV(x, y)
+
+		op := newElemUnaryOp(deriv, x)
+
+		var d Value
+		if d, err = op.Do(xdv.Value); err != nil {
+			return errors.Wrapf(err, doFail, op)
+		}
+
+		if dT, ok := d.(tensor.Tensor); ok {
+			defer returnTensor(dT)
+		}
+
+		mul := newElemBinOp(mulOpType, x, y)
+		err = mul.IncrDo(xdv.d, d, ydv.d)
+		if err = checkErrSetDeriv(err, xdv); err != nil {
+			return errors.Wrapf(err, autodiffFail, x)
+		}
+		return
+	}
+}
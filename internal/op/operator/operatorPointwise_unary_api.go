@@ -0,0 +1,117 @@
+package operator
+
+import (
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// Relu performs a pointwise max(0, x).
+func Relu(x *exprgraph.Node) (*exprgraph.Node, error) {
+	return unaryOpNode(newElemUnaryOp(reluOpType, x), x)
+}
+
+// Relu6 performs a pointwise min(max(0, x), 6).
+func Relu6(x *exprgraph.Node) (*exprgraph.Node, error) {
+	return unaryOpNode(newElemUnaryOp(relu6OpType, x), x)
+}
+
+// LeakyRelu performs a pointwise leaky ReLU: x if x > 0, αx otherwise. DefaultLeakyReluAlpha is the usual α.
+func LeakyRelu(x *exprgraph.Node, alpha float64) (*exprgraph.Node, error) {
+	return alphaActivationNode(leakyReluActivation, alpha, x)
+}
+
+// Elu performs a pointwise exponential linear unit: x if x > 0, α(exp(x) - 1) otherwise. DefaultEluAlpha is the usual α.
+func Elu(x *exprgraph.Node, alpha float64) (*exprgraph.Node, error) {
+	return alphaActivationNode(eluActivation, alpha, x)
+}
+
+// Selu performs a pointwise scaled exponential linear unit, as described by Klambauer et al. (2017).
+func Selu(x *exprgraph.Node) (*exprgraph.Node, error) {
+	return unaryOpNode(newElemUnaryOp(seluOpType, x), x)
+}
+
+// Gelu performs a pointwise Gaussian error linear unit: xΦ(x), where Φ is the CDF of the standard normal distribution.
+func Gelu(x *exprgraph.Node) (*exprgraph.Node, error) {
+	return unaryOpNode(newElemUnaryOp(geluOpType, x), x)
+}
+
+// GeluTanh performs a pointwise GELU, using the tanh approximation of Φ.
+func GeluTanh(x *exprgraph.Node) (*exprgraph.Node, error) {
+	return unaryOpNode(newElemUnaryOp(geluTanhOpType, x), x)
+}
+
+// Swish performs a pointwise xσ(x). It is also known as SiLU.
+func Swish(x *exprgraph.Node) (*exprgraph.Node, error) {
+	return unaryOpNode(newElemUnaryOp(swishOpType, x), x)
+}
+
+// Silu is an alias of Swish.
+func Silu(x *exprgraph.Node) (*exprgraph.Node, error) { return Swish(x) }
+
+// Mish performs a pointwise x tanh(softplus(x)).
+func Mish(x *exprgraph.Node) (*exprgraph.Node, error) {
+	return unaryOpNode(newElemUnaryOp(mishOpType, x), x)
+}
+
+// HardSigmoid performs a pointwise piecewise linear approximation of the sigmoid: ReLU6(x + 3)/6.
+func HardSigmoid(x *exprgraph.Node) (*exprgraph.Node, error) {
+	return unaryOpNode(newElemUnaryOp(hardSigmoidOpType, x), x)
+}
+
+// HardSwish performs a pointwise x·HardSigmoid(x).
+func HardSwish(x *exprgraph.Node) (*exprgraph.Node, error) {
+	return unaryOpNode(newElemUnaryOp(hardSwishOpType, x), x)
+}
+
+// Not performs a pointwise bitwise not on integers, and a logical not on bools.
+func Not(x *exprgraph.Node) (*exprgraph.Node, error) {
+	return unaryOpNode(newElemUnaryOp(notOpType, x), x)
+}
+
+// Cast converts x to the dtype to, such as between Float32 and the half-precision floats.
+// Casts between floats and complex numbers are differentiable.
+func Cast(x *exprgraph.Node, to tensor.Dtype) (*exprgraph.Node, error) {
+	from, err := dtypeOf(x.T)
+	if err != nil {
+		return nil, err
+	}
+	op, err := newCastOp(from, to, x.Shape.Dims())
+	if err != nil {
+		return nil, err
+	}
+	return unaryOpNode(op, x)
+}
+
+// Abs performs a pointwise |x|. The modulus of complex numbers is a real number.
+func Abs(x *exprgraph.Node) (*exprgraph.Node, error) {
+	dt, err := dtypeOf(x.T)
+	if err != nil {
+		return nil, err
+	}
+	if factory.IsComplex(dt) {
+		return complexPartNode(absPart, x)
+	}
+	return unaryOpNode(newElemUnaryOp(absOpType, x), x)
+}
+
+// Real returns the real part of the complex numbers of x.
+func Real(x *exprgraph.Node) (*exprgraph.Node, error) { return complexPartNode(realPart, x) }
+
+// Imag returns the imaginary part of the complex numbers of x.
+func Imag(x *exprgraph.Node) (*exprgraph.Node, error) { return complexPartNode(imagPart, x) }
+
+// Angle returns the argument of the complex numbers of x, in (-π, π].
+func Angle(x *exprgraph.Node) (*exprgraph.Node, error) { return complexPartNode(anglePart, x) }
+
+// Conj performs a pointwise complex conjugate. Real numbers are their own conjugates: x is returned as it is.
+func Conj(x *exprgraph.Node) (*exprgraph.Node, error) {
+	dt, err := dtypeOf(x.T)
+	if err != nil {
+		return nil, err
+	}
+	if !factory.IsComplex(dt) {
+		return x, nil
+	}
+	return unaryOpNode(newElemUnaryOp(conjOpType, x), x)
+}
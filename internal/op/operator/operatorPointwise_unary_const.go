@@ -3,6 +3,7 @@ package operator
 import (
 	"fmt"
 	"math"
+	"math/cmplx"
 
 	"github.com/chewxy/math32"
 	"gorgonia.org/gorgonia/internal/exprgraph"
@@ -41,6 +42,28 @@ var (
 	// softplus isn't necessarily only a numerical stabilization op
 	// (you can use it elsewhere), but I included it under numerical optimization
 
+	// activation functions in the ReLU family
+	reluf64        = sf64UnaryOperator(_reluf64)
+	relu6f64       = sf64UnaryOperator(_relu6f64)
+	seluf64        = sf64UnaryOperator(_seluf64)
+	geluf64        = sf64UnaryOperator(_geluf64)
+	geluTanhf64    = sf64UnaryOperator(_geluTanhf64)
+	swishf64       = sf64UnaryOperator(_swishf64)
+	mishf64        = sf64UnaryOperator(_mishf64)
+	hardSigmoidf64 = sf64UnaryOperator(_hardSigmoidf64)
+	hardSwishf64   = sf64UnaryOperator(_hardSwishf64)
+
+	// derivatives of the activation functions above. They are not differentiable themselves
+	reluDerivf64        = sf64UnaryOperator(_reluDerivf64)
+	relu6Derivf64       = sf64UnaryOperator(_relu6Derivf64)
+	seluDerivf64        = sf64UnaryOperator(_seluDerivf64)
+	geluDerivf64        = sf64UnaryOperator(_geluDerivf64)
+	geluTanhDerivf64    = sf64UnaryOperator(_geluTanhDerivf64)
+	swishDerivf64       = sf64UnaryOperator(_swishDerivf64)
+	mishDerivf64        = sf64UnaryOperator(_mishDerivf64)
+	hardSigmoidDerivf64 = sf64UnaryOperator(_hardSigmoidDerivf64)
+	hardSwishDerivf64   = sf64UnaryOperator(_hardSwishDerivf64)
+
 	/* Float32 */
 
 	// non differentiable
@@ -70,6 +93,28 @@ var (
 	log1pf32    = sf32UnaryOperator(math32.Log1p)
 	expm1f32    = sf32UnaryOperator(math32.Expm1)
 	softplusf32 = sf32UnaryOperator(_softplusf32)
+
+	// activation functions in the ReLU family
+	reluf32        = sf32UnaryOperator(_reluf32)
+	relu6f32       = sf32UnaryOperator(_relu6f32)
+	seluf32        = sf32UnaryOperator(_seluf32)
+	geluf32        = sf32UnaryOperator(_geluf32)
+	geluTanhf32    = sf32UnaryOperator(_geluTanhf32)
+	swishf32       = sf32UnaryOperator(_swishf32)
+	mishf32        = sf32UnaryOperator(_mishf32)
+	hardSigmoidf32 = sf32UnaryOperator(_hardSigmoidf32)
+	hardSwishf32   = sf32UnaryOperator(_hardSwishf32)
+
+	// derivatives of the activation functions above. They are not differentiable themselves
+	reluDerivf32        = sf32UnaryOperator(_reluDerivf32)
+	relu6Derivf32       = sf32UnaryOperator(_relu6Derivf32)
+	seluDerivf32        = sf32UnaryOperator(_seluDerivf32)
+	geluDerivf32        = sf32UnaryOperator(_geluDerivf32)
+	geluTanhDerivf32    = sf32UnaryOperator(_geluTanhDerivf32)
+	swishDerivf32       = sf32UnaryOperator(_swishDerivf32)
+	mishDerivf32        = sf32UnaryOperator(_mishDerivf32)
+	hardSigmoidDerivf32 = sf32UnaryOperator(_hardSigmoidDerivf32)
+	hardSwishDerivf32   = sf32UnaryOperator(_hardSwishDerivf32)
 )
 
 type ʘUnaryOperatorType byte
@@ -102,6 +147,34 @@ const (
 	expm1OpType
 	softplusOpType
 
+	// activation functions in the ReLU family
+	reluOpType
+	relu6OpType
+	seluOpType
+	geluOpType
+	geluTanhOpType
+	swishOpType
+	mishOpType
+	hardSigmoidOpType
+	hardSwishOpType
+
+	// derivatives of the activation functions above. They are not differentiable
+	reluDerivOpType
+	relu6DerivOpType
+	seluDerivOpType
+	geluDerivOpType
+	geluTanhDerivOpType
+	swishDerivOpType
+	mishDerivOpType
+	hardSigmoidDerivOpType
+	hardSwishDerivOpType
+
+	// bitwise, defined on integers and bools only
+	notOpType
+
+	// complex conjugate, defined on complex numbers only
+	conjOpType
+
 	maxʘUnaryOperator // delimits end of all possible unary ops
 )
 
@@ -113,6 +186,9 @@ func (u ʘUnaryOperatorType) String() string {
 	return ʘUnaryOpStrs[u]
 }
 
+// unaryOpType makes a ʘUnaryOperatorType a ʘUnaryOperator, for the dtypes that are computed from the type of the operator alone.
+func (u ʘUnaryOperatorType) unaryOpType() ʘUnaryOperatorType { return u }
+
 // ʘUnaryOpStrs is the string representation for a unaryOpType
 // It should be held constant.
 var ʘUnaryOpStrs = [maxʘUnaryOperator]string{
@@ -123,6 +199,18 @@ var ʘUnaryOpStrs = [maxʘUnaryOperator]string{
 	"cube", "tanh", "sigmoid",
 
 	"log1p", "expm1", "softplus",
+
+	"relu", "relu6", "selu",
+	"gelu", "geluTanh", "swish", "mish",
+	"hardSigmoid", "hardSwish",
+
+	"reluDeriv", "relu6Deriv", "seluDeriv",
+	"geluDeriv", "geluTanhDeriv", "swishDeriv", "mishDeriv",
+	"hardSigmoidDeriv", "hardSwishDeriv",
+
+	"not",
+
+	"conj",
 }
 
 // ʘUnaryOpDifferentiable is the array of whether a unary operator is differentiable
@@ -135,6 +223,18 @@ var ʘUnaryOpDifferentiable = [maxʘUnaryOperator]bool{
 	true, true, true,
 
 	true, true, true,
+
+	true, true, true,
+	true, true, true, true,
+	true, true,
+
+	false, false, false,
+	false, false, false, false,
+	false, false,
+
+	false,
+
+	true,
 }
 
 var ʘUnaryOpDiffExprs = [maxʘUnaryOperator]func(x, y, gradY *exprgraph.Node) (*exprgraph.Node, error){
@@ -144,6 +244,19 @@ var ʘUnaryOpDiffExprs = [maxʘUnaryOperator]func(x, y, gradY *exprgraph.Node) (
 	inverseDiffExpr, inverseSqrtDiffExpr, cubeDiffExpr, tanhDiffExpr, sigmoidDiffExpr,
 
 	log1pDiffExpr, expm1DiffExpr, softplusDiffExpr,
+
+	activationDiffExpr(reluDerivOpType), activationDiffExpr(relu6DerivOpType), activationDiffExpr(seluDerivOpType),
+	activationDiffExpr(geluDerivOpType), activationDiffExpr(geluTanhDerivOpType),
+	activationDiffExpr(swishDerivOpType), activationDiffExpr(mishDerivOpType),
+	activationDiffExpr(hardSigmoidDerivOpType), activationDiffExpr(hardSwishDerivOpType),
+
+	nondiffUnaryOpExpr, nondiffUnaryOpExpr, nondiffUnaryOpExpr,
+	nondiffUnaryOpExpr, nondiffUnaryOpExpr, nondiffUnaryOpExpr, nondiffUnaryOpExpr,
+	nondiffUnaryOpExpr, nondiffUnaryOpExpr,
+
+	nondiffUnaryOpExpr,
+
+	conjDiffExpr,
 }
 
 var ʘUnaryOpDiffFns = [maxʘUnaryOperator]func(x, y *exprgraph.Node) error{
@@ -153,6 +266,19 @@ var ʘUnaryOpDiffFns = [maxʘUnaryOperator]func(x, y *exprgraph.Node) error{
 	inverseDiff, inverseSqrtDiff, cubeDiff, tanhDiff, sigmoidDiff,
 
 	log1pDiff, expm1Diff, softplusDiff,
+
+	activationDiff(reluDerivOpType), activationDiff(relu6DerivOpType), activationDiff(seluDerivOpType),
+	activationDiff(geluDerivOpType), activationDiff(geluTanhDerivOpType),
+	activationDiff(swishDerivOpType), activationDiff(mishDerivOpType),
+	activationDiff(hardSigmoidDerivOpType), activationDiff(hardSwishDerivOpType),
+
+	nondiffUnaryOp, nondiffUnaryOp, nondiffUnaryOp,
+	nondiffUnaryOp, nondiffUnaryOp, nondiffUnaryOp, nondiffUnaryOp,
+	nondiffUnaryOp, nondiffUnaryOp,
+
+	nondiffUnaryOp,
+
+	conjDiff,
 }
 
 var sf64UnaryOperators = [maxʘUnaryOperator]*sf64UnaryOperator{
@@ -177,6 +303,30 @@ var sf64UnaryOperators = [maxʘUnaryOperator]*sf64UnaryOperator{
 	&log1pf64,
 	&expm1f64,
 	&softplusf64,
+
+	&reluf64,
+	&relu6f64,
+	&seluf64,
+	&geluf64,
+	&geluTanhf64,
+	&swishf64,
+	&mishf64,
+	&hardSigmoidf64,
+	&hardSwishf64,
+
+	&reluDerivf64,
+	&relu6Derivf64,
+	&seluDerivf64,
+	&geluDerivf64,
+	&geluTanhDerivf64,
+	&swishDerivf64,
+	&mishDerivf64,
+	&hardSigmoidDerivf64,
+	&hardSwishDerivf64,
+
+	nil, // not is not defined on floats
+
+	nil, // conj is the identity on floats. Conj() doesn't create an op for it
 }
 
 var sf32UnaryOperators = [maxʘUnaryOperator]*sf32UnaryOperator{
@@ -201,4 +351,52 @@ var sf32UnaryOperators = [maxʘUnaryOperator]*sf32UnaryOperator{
 	&log1pf32,
 	&expm1f32,
 	&softplusf32,
+
+	&reluf32,
+	&relu6f32,
+	&seluf32,
+	&geluf32,
+	&geluTanhf32,
+	&swishf32,
+	&mishf32,
+	&hardSigmoidf32,
+	&hardSwishf32,
+
+	&reluDerivf32,
+	&relu6Derivf32,
+	&seluDerivf32,
+	&geluDerivf32,
+	&geluTanhDerivf32,
+	&swishDerivf32,
+	&mishDerivf32,
+	&hardSigmoidDerivf32,
+	&hardSwishDerivf32,
+
+	nil, // not is not defined on floats
+
+	nil, // conj is the identity on floats. Conj() doesn't create an op for it
+}
+
+// sc128UnaryOperators are the unary operators on complex numbers. Complex64s are computed in complex128.
+// The operators that need an order, such as floor or relu, aren't defined on complex numbers.
+// Neither is abs, which returns a real number: it is a complexPartOp.
+var sc128UnaryOperators = [maxʘUnaryOperator]func(complex128) complex128{
+	signOpType:        _signc128,
+	sinOpType:         cmplx.Sin,
+	cosOpType:         cmplx.Cos,
+	expOpType:         cmplx.Exp,
+	lnOpType:          cmplx.Log,
+	log2OpType:        _log2c128,
+	negOpType:         _negc128,
+	squareOpType:      _squarec128,
+	sqrtOpType:        cmplx.Sqrt,
+	inverseOpType:     _inversec128,
+	inverseSqrtOpType: _inverseSqrtc128,
+	cubeOpType:        _cubec128,
+	tanhOpType:        cmplx.Tanh,
+	sigmoidOpType:     _sigmoidc128,
+	log1pOpType:       _log1pc128,
+	expm1OpType:       _expm1c128,
+	softplusOpType:    _softplusc128,
+	conjOpType:        _conjc128,
 }
@@ -0,0 +1,66 @@
+package operator
+
+import (
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/op"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// dtypeOf returns the dtype of a scalar type or of the elements of a tensor type
+func dtypeOf(t hm.Type) (retVal tensor.Dtype, err error) {
+	switch p := t.(type) {
+	case tensor.Dtype:
+		retVal = p
+	case factory.TensorType:
+		return dtypeOf(p.Of)
+	case *factory.TensorType:
+		return dtypeOf(p.Of)
+	case hm.TypeVariable:
+		err = errors.Errorf("instance %v does not have a dtype", p)
+	default:
+		err = errors.Errorf("Not yet implemented: %v %v", "dtypeOf", p)
+	}
+	return
+}
+
+// opNode adds a node that applies o to the inputs to the graph of the inputs, and infers its type and shape.
+func opNode(o op.Op, inputs ...*exprgraph.Node) (*exprgraph.Node, error) {
+	if len(inputs) == 0 {
+		return nil, errors.Errorf("%v expects at least one input", o)
+	}
+	g := inputs[0].Graph()
+	for _, in := range inputs {
+		if in.Graph() == nil || in.Graph() != g {
+			return nil, errors.Errorf("The inputs of %v have to be in the same graph", o)
+		}
+	}
+
+	n := g.NewVertex()
+	g.AddNode(n)
+	for i, in := range inputs {
+		g.SetWeightedEdge(g.NewWeightedEdge(n, in, float64(i)))
+	}
+	if err := g.ApplyOp(o, n); err != nil {
+		return nil, errors.Wrapf(err, "Cannot apply %v", o)
+	}
+	return n, nil
+}
+
+// unaryOpNode adds a node that applies o to x.
+func unaryOpNode(o op.Op, x *exprgraph.Node) (*exprgraph.Node, error) {
+	if err := op.CheckArity(o, 1); err != nil {
+		return nil, err
+	}
+	return opNode(o, x)
+}
+
+// binOpNode adds a node that applies o to a and b.
+func binOpNode(o op.Op, a, b *exprgraph.Node) (*exprgraph.Node, error) {
+	if err := op.CheckArity(o, 2); err != nil {
+		return nil, err
+	}
+	return opNode(o, a, b)
+}
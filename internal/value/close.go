@@ -0,0 +1,285 @@
+package value
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/value/half"
+	"gorgonia.org/tensor"
+)
+
+// Tolerance describes how close two elements have to be for CloseWithin to consider them close.
+// Two elements are close if they are equal, or if any of the enabled tolerances holds.
+type Tolerance struct {
+	Abs float64 // |a-b| <= Abs, where |x| is the modulus of complex numbers. 0 disables it
+	Rel float64 // |a-b| <= Rel·max(|a|, |b|). 0 disables it
+
+	// ULPs is the largest distance in units in the last place: the number of representable floats between a and b.
+	// The ULP distance of integers is their difference, and that of complex numbers is the larger distance of their parts. 0 disables it
+	ULPs uint64
+
+	// NaNEqual makes NaNs close to one another. A NaN is never close to a number
+	NaNEqual bool
+}
+
+// CloseReport reports how two values differ
+type CloseReport struct {
+	MaxAbsErr  float64 // the largest absolute error between two elements. A NaN compared to a number is an error of +Inf
+	Worst      int     // the index of the element with the largest absolute error. -1 if there are no elements
+	Mismatches int     // the number of elements that are out of tolerance
+	Size       int     // the number of elements compared
+}
+
+// OK returns true if all the elements are within tolerance
+func (r CloseReport) OK() bool { return r.Mismatches == 0 }
+
+func (r CloseReport) String() string {
+	return fmt.Sprintf("%d/%d elements out of tolerance. Max abs error: %v at %d", r.Mismatches, r.Size, r.MaxAbsErr, r.Worst)
+}
+
+// CloseWithin checks elementwise whether two values of the same dtype and shape are close to one another within the given tolerance,
+// and reports how they differ. Unlike Close, integers are held to the same tolerance as floats, although they are compared as integers,
+// so that integers above 2⁵³ are told apart; bools have to be equal.
+// Values of different dtypes or shapes cannot be compared and return an error.
+func CloseWithin(a, b Value, tol Tolerance) (report CloseReport, err error) {
+	report.Worst = -1
+	if a.Dtype() != b.Dtype() {
+		return report, errors.Errorf("Cannot compare values of different dtypes: %v and %v", a.Dtype(), b.Dtype())
+	}
+	if !a.Shape().Eq(b.Shape()) {
+		return report, errors.Errorf("Cannot compare values of different shapes: %v and %v", a.Shape(), b.Shape())
+	}
+
+	var ad, bd interface{}
+	if ad, err = elementsOf(a); err != nil {
+		return report, err
+	}
+	if bd, err = elementsOf(b); err != nil {
+		return report, err
+	}
+
+	// at returns the elements at i as complex128s, so that the real and complex dtypes are compared alike, with their ULP distance
+	var at func(i int) (x, y complex128, ulps uint64)
+	// ints returns the elements at i of the integer and bool dtypes, which are compared as integers: above 2⁵³, float64s cannot tell them apart
+	var ints func(i int) (x, y int64)
+	exact := false // bools have to be equal, whatever the tolerance
+	switch ad := ad.(type) {
+	case []float64:
+		bd := bd.([]float64)
+		report.Size = len(ad)
+		at = func(i int) (complex128, complex128, uint64) {
+			return real64(ad[i]), real64(bd[i]), ulpsF64(ad[i], bd[i])
+		}
+	case []float32:
+		bd := bd.([]float32)
+		report.Size = len(ad)
+		at = func(i int) (complex128, complex128, uint64) {
+			return real64(float64(ad[i])), real64(float64(bd[i])), ulpsF32(ad[i], bd[i])
+		}
+	case []half.Float16:
+		bd := bd.([]half.Float16)
+		report.Size = len(ad)
+		at = func(i int) (complex128, complex128, uint64) {
+			return real64(float64(ad[i].Float32())), real64(float64(bd[i].Float32())), ulpsInt(orderedHalf(uint16(ad[i])), orderedHalf(uint16(bd[i])))
+		}
+	case []half.BFloat16:
+		bd := bd.([]half.BFloat16)
+		report.Size = len(ad)
+		at = func(i int) (complex128, complex128, uint64) {
+			return real64(float64(ad[i].Float32())), real64(float64(bd[i].Float32())), ulpsInt(orderedHalf(uint16(ad[i])), orderedHalf(uint16(bd[i])))
+		}
+	case []complex128:
+		bd := bd.([]complex128)
+		report.Size = len(ad)
+		at = func(i int) (complex128, complex128, uint64) {
+			return ad[i], bd[i], maxU64(ulpsF64(real(ad[i]), real(bd[i])), ulpsF64(imag(ad[i]), imag(bd[i])))
+		}
+	case []complex64:
+		bd := bd.([]complex64)
+		report.Size = len(ad)
+		at = func(i int) (complex128, complex128, uint64) {
+			return complex128(ad[i]), complex128(bd[i]), maxU64(ulpsF32(real(ad[i]), real(bd[i])), ulpsF32(imag(ad[i]), imag(bd[i])))
+		}
+	case []int:
+		bd := bd.([]int)
+		report.Size = len(ad)
+		ints = func(i int) (int64, int64) { return int64(ad[i]), int64(bd[i]) }
+	case []int64:
+		bd := bd.([]int64)
+		report.Size = len(ad)
+		ints = func(i int) (int64, int64) { return ad[i], bd[i] }
+	case []int32:
+		bd := bd.([]int32)
+		report.Size = len(ad)
+		ints = func(i int) (int64, int64) { return int64(ad[i]), int64(bd[i]) }
+	case []byte:
+		bd := bd.([]byte)
+		report.Size = len(ad)
+		ints = func(i int) (int64, int64) { return int64(ad[i]), int64(bd[i]) }
+	case []bool:
+		bd := bd.([]bool)
+		report.Size = len(ad)
+		exact = true
+		ints = func(i int) (int64, int64) { return boolInt(ad[i]), boolInt(bd[i]) }
+	default:
+		return report, errors.Errorf("CloseWithin not yet implemented for %v", a.Dtype())
+	}
+
+	if exact {
+		tol = Tolerance{}
+	}
+	for i := 0; i < report.Size; i++ {
+		var ok bool
+		var absErr float64
+		if ints != nil {
+			ok, absErr = tol.closeInts(ints(i))
+		} else {
+			ok, absErr = tol.close(at(i))
+		}
+
+		if !ok {
+			report.Mismatches++
+		}
+		if report.Worst < 0 || absErr > report.MaxAbsErr {
+			report.MaxAbsErr, report.Worst = absErr, i
+		}
+	}
+	return report, nil
+}
+
+// close checks whether x and y, whose ULP distance is ulps, are close. It also returns the absolute error between them.
+func (tol Tolerance) close(x, y complex128, ulps uint64) (ok bool, absErr float64) {
+	absErr = cmplx.Abs(x - y)
+	switch {
+	case cmplx.IsNaN(x) || cmplx.IsNaN(y):
+		ok = tol.NaNEqual && cmplx.IsNaN(x) && cmplx.IsNaN(y)
+		if ok {
+			absErr = 0
+		} else {
+			absErr = math.Inf(1)
+		}
+	case x == y:
+		ok, absErr = true, 0
+	default:
+		ok = (tol.Abs > 0 && absErr <= tol.Abs) ||
+			(tol.Rel > 0 && absErr <= tol.Rel*math.Max(cmplx.Abs(x), cmplx.Abs(y))) ||
+			(tol.ULPs > 0 && ulps <= tol.ULPs)
+	}
+	return
+}
+
+// closeInts checks whether two integers are close. Their distance is a uint64, which holds the distance between any two int64s.
+// Only the relative tolerance, and the returned absolute error, are computed with float64s.
+func (tol Tolerance) closeInts(x, y int64) (ok bool, absErr float64) {
+	d := ulpsInt(x, y)
+	absErr = float64(d)
+	switch {
+	case d == 0:
+		return true, 0
+	case tol.Abs > 0 && (tol.Abs >= 1<<64 || d <= uint64(tol.Abs)):
+		return true, absErr
+	case tol.Rel > 0 && absErr <= tol.Rel*math.Max(math.Abs(float64(x)), math.Abs(float64(y))):
+		return true, absErr
+	}
+	return tol.ULPs > 0 && d <= tol.ULPs, absErr
+}
+
+// elementsOf returns the elements of a scalar or a tensor as a slice
+func elementsOf(v Value) (interface{}, error) {
+	switch vt := v.(type) {
+	case *F64:
+		return []float64{vt.Any()}, nil
+	case *F32:
+		return []float32{vt.Any()}, nil
+	case *I:
+		return []int{vt.Any()}, nil
+	case *I64:
+		return []int64{vt.Any()}, nil
+	case *I32:
+		return []int32{vt.Any()}, nil
+	case *U8:
+		return []byte{vt.Any()}, nil
+	case *B:
+		return []bool{vt.Any()}, nil
+	case *F16:
+		return []half.Float16{vt.Any()}, nil
+	case *BF16:
+		return []half.BFloat16{vt.Any()}, nil
+	case *C64:
+		return []complex64{vt.Any()}, nil
+	case *C128:
+		return []complex128{vt.Any()}, nil
+	case tensor.Tensor:
+		data := tensor.Materialize(vt).Data()
+		if vt.Shape().IsScalar() {
+			// the data of a scalar tensor is the scalar itself
+			s, _ := AnyToScalar(data)
+			return elementsOf(s)
+		}
+		return data, nil
+	}
+	return nil, errors.Errorf("CloseWithin not yet implemented for %T", v)
+}
+
+// ulpsF64 returns the number of representable float64s between a and b
+func ulpsF64(a, b float64) uint64 {
+	return ulpsInt(orderedF64(a), orderedF64(b))
+}
+
+// ulpsF32 returns the number of representable float32s between a and b
+func ulpsF32(a, b float32) uint64 {
+	return ulpsInt(int64(orderedF32(a)), int64(orderedF32(b)))
+}
+
+// orderedF64 maps a float64 to an int64 that has the same order, with consecutive floats mapped to consecutive integers
+func orderedF64(x float64) int64 {
+	i := int64(math.Float64bits(x))
+	if i < 0 {
+		i = math.MinInt64 - i
+	}
+	return i
+}
+
+// orderedF32 maps a float32 to an int32 that has the same order, with consecutive floats mapped to consecutive integers
+func orderedF32(x float32) int32 {
+	i := int32(math.Float32bits(x))
+	if i < 0 {
+		i = math.MinInt32 - i
+	}
+	return i
+}
+
+// orderedHalf maps the bits of a Float16 or a BFloat16 to an int64 that has the same order, as orderedF64 does
+func orderedHalf(bits uint16) int64 {
+	i := int64(int16(bits))
+	if i < 0 {
+		i = math.MinInt16 - i
+	}
+	return i
+}
+
+func boolInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// real64 returns x as a complex128 with no imaginary part
+func real64(x float64) complex128 { return complex(x, 0) }
+
+func maxU64(a, b uint64) uint64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func ulpsInt(a, b int64) uint64 {
+	if a > b {
+		return uint64(a) - uint64(b)
+	}
+	return uint64(b) - uint64(a)
+}
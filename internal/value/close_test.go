@@ -0,0 +1,59 @@
+package value
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/value/half"
+	"gorgonia.org/tensor"
+)
+
+func TestCloseWithin(t *testing.T) {
+	nan := math.NaN()
+	closeTests := []struct {
+		name string
+		a, b Value
+		tol  Tolerance
+
+		mismatches int
+		worst      int
+		maxAbsErr  float64
+	}{
+		{"abs", tensor.New(tensor.WithBacking([]float64{1, 2, 3})), tensor.New(tensor.WithBacking([]float64{1.05, 2, 3.2})), Tolerance{Abs: 0.1}, 1, 2, 0.2},
+		{"rel", tensor.New(tensor.WithBacking([]float64{100, 1})), tensor.New(tensor.WithBacking([]float64{101, 1.1})), Tolerance{Rel: 0.02}, 1, 0, 1},
+		{"ulps", tensor.New(tensor.WithBacking([]float32{1, -0})), tensor.New(tensor.WithBacking([]float32{math.Nextafter32(math.Nextafter32(1, 2), 2), math.SmallestNonzeroFloat32})), Tolerance{ULPs: 1}, 1, 0, float64(math.Nextafter32(math.Nextafter32(1, 2), 2) - 1)},
+		{"NaN is not equal", tensor.New(tensor.WithBacking([]float64{nan, 1})), tensor.New(tensor.WithBacking([]float64{nan, nan})), Tolerance{Abs: 1}, 2, 0, math.Inf(1)},
+		{"NaN is equal", tensor.New(tensor.WithBacking([]float64{nan, 1})), tensor.New(tensor.WithBacking([]float64{nan, nan})), Tolerance{Abs: 1, NaNEqual: true}, 1, 1, math.Inf(1)},
+		{"ints", tensor.New(tensor.WithBacking([]int{1, 5, 10})), tensor.New(tensor.WithBacking([]int{2, 5, 13})), Tolerance{ULPs: 2}, 1, 2, 3},
+		{"ints above 2⁵³", tensor.New(tensor.WithBacking([]int64{1 << 53, 1<<53 + 1})), tensor.New(tensor.WithBacking([]int64{1<<53 + 1, 1<<53 + 1})), Tolerance{}, 1, 0, 1},
+		{"ints above 2⁵³ within ULPs", NewI64(1 << 53), NewI64(1<<53 + 1), Tolerance{ULPs: 1}, 0, 0, 1},
+		{"ints above 2⁵³ within abs", tensor.New(tensor.WithBacking([]int{1<<62 + 3})), tensor.New(tensor.WithBacking([]int{1 << 62})), Tolerance{Abs: 2.5}, 1, 0, 3},
+		{"int64 extremes", NewI64(math.MinInt64), NewI64(math.MaxInt64), Tolerance{ULPs: math.MaxUint64 - 1}, 1, 0, math.MaxUint64},
+		{"bools", tensor.New(tensor.WithBacking([]bool{true, false})), tensor.New(tensor.WithBacking([]bool{true, true})), Tolerance{Abs: 10}, 1, 1, 1},
+		{"scalars", NewF64(1), NewF64(1 + 1e-9), Tolerance{Abs: 1e-8}, 0, 0, 1e-9},
+		{"halves", tensor.New(tensor.WithBacking([]half.Float16{half.NewFloat16(1), half.NewFloat16(2)})), tensor.New(tensor.WithBacking([]half.Float16{half.NewFloat16(1.001), half.NewFloat16(2.01)})), Tolerance{ULPs: 1}, 1, 1, 5.0 / 512},
+		{"complex", tensor.New(tensor.WithBacking([]complex128{1 + 1i, 3 + 4i})), tensor.New(tensor.WithBacking([]complex128{1 + 1.5i, 0})), Tolerance{Abs: 1}, 1, 1, 5},
+		{"complex scalars", NewC64(1i), NewC64(complex(0, math.Nextafter32(1, 2))), Tolerance{ULPs: 1}, 0, 0, float64(math.Nextafter32(1, 2) - 1)},
+	}
+
+	for _, ct := range closeTests {
+		report, err := CloseWithin(ct.a, ct.b, ct.tol)
+		if err != nil {
+			t.Errorf("%v: %v", ct.name, err)
+			continue
+		}
+		if report.Mismatches != ct.mismatches || report.Worst != ct.worst || math.Abs(report.MaxAbsErr-ct.maxAbsErr) > 1e-12 {
+			t.Errorf("%v: expected %d mismatches and a max abs error of %v at %d. Got %v", ct.name, ct.mismatches, ct.maxAbsErr, ct.worst, report)
+		}
+		if report.OK() != (ct.mismatches == 0) {
+			t.Errorf("%v: expected OK() to be %t", ct.name, ct.mismatches == 0)
+		}
+	}
+
+	if _, err := CloseWithin(NewF64(1), NewF32(1), Tolerance{}); err == nil {
+		t.Errorf("Expected values of different dtypes to return an error")
+	}
+	if _, err := CloseWithin(tensor.New(tensor.Of(tensor.Float64), tensor.WithShape(2, 3)), tensor.New(tensor.Of(tensor.Float64), tensor.WithShape(3, 2)), Tolerance{}); err == nil {
+		t.Errorf("Expected values of different shapes to return an error")
+	}
+}
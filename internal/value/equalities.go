@@ -2,9 +2,16 @@ package value
 
 import (
 	"gorgonia.org/dawson"
+	"gorgonia.org/gorgonia/internal/value/factory"
 	"gorgonia.org/tensor"
 )
 
+// halfTolerance is the tolerance of Close for the half-precision floats, which only have a few significant digits
+var halfTolerance = Tolerance{ULPs: 2}
+
+// complexTolerance is the tolerance of Close for complex numbers, which are compared by the modulus of their difference
+var complexTolerance = Tolerance{Abs: 1e-8, Rel: 1e-6}
+
 func scalarEq(a, b Scalar) bool {
 	switch at := a.(type) {
 	case *F64:
@@ -63,6 +70,38 @@ func scalarEq(a, b Scalar) bool {
 			return *at == *bt
 		}
 		return false
+	case *F16:
+		if bt, ok := b.(*F16); ok {
+			if at == bt {
+				return true
+			}
+			return at.Float32() == bt.Float32() // so that -0 == +0 and NaN != NaN, as with the other floats
+		}
+		return false
+	case *BF16:
+		if bt, ok := b.(*BF16); ok {
+			if at == bt {
+				return true
+			}
+			return at.Float32() == bt.Float32()
+		}
+		return false
+	case *C64:
+		if bt, ok := b.(*C64); ok {
+			if at == bt {
+				return true
+			}
+			return *at == *bt
+		}
+		return false
+	case *C128:
+		if bt, ok := b.(*C128); ok {
+			if at == bt {
+				return true
+			}
+			return *at == *bt
+		}
+		return false
 	}
 	return false
 }
@@ -79,6 +118,12 @@ func scalarClose(a, b Scalar) bool {
 			return dawson.CloseF32(float32(*at), float32(*bt))
 		}
 		return false
+	case *F16, *BF16:
+		report, err := CloseWithin(a, b, halfTolerance)
+		return err == nil && report.OK()
+	case *C64, *C128:
+		report, err := CloseWithin(a, b, complexTolerance)
+		return err == nil && report.OK()
 	default:
 		return scalarEq(a, b)
 	}
@@ -120,6 +165,12 @@ func tensorClose(a, b tensor.Tensor) bool {
 			}
 		}
 		return true
+	case factory.Float16, factory.BFloat16:
+		report, err := CloseWithin(a, b, halfTolerance)
+		return err == nil && report.OK()
+	case factory.Complex64, factory.Complex128:
+		report, err := CloseWithin(a, b, complexTolerance)
+		return err == nil && report.OK()
 	default:
 		return a.Eq(b)
 	}
@@ -2,8 +2,10 @@ package factory
 
 import (
 	"fmt"
+	"reflect"
 
 	"github.com/chewxy/hm"
+	"gorgonia.org/gorgonia/internal/value/half"
 	"gorgonia.org/tensor"
 )
 
@@ -24,6 +26,14 @@ var (
 	Byte = tensor.Uint8
 	// Bool ...
 	Bool = tensor.Bool
+	// Float16 is the IEEE 754 half-precision float. The tensor package only stores it
+	Float16 = tensor.Dtype{Type: reflect.TypeOf(half.Float16(0))}
+	// BFloat16 is the brain float. The tensor package only stores it
+	BFloat16 = tensor.Dtype{Type: reflect.TypeOf(half.BFloat16(0))}
+	// Complex64 ...
+	Complex64 = tensor.Complex64
+	// Complex128 ...
+	Complex128 = tensor.Complex128
 
 	// Ptr ...
 	Ptr = tensor.UnsafePointer // equivalent to interface{}. Ugh Ugh Ugh
@@ -40,7 +50,29 @@ var (
 	f32T hm.Type = tensor.Float32
 )
 
-var acceptableDtypes = [...]tensor.Dtype{tensor.Float64, tensor.Float32, tensor.Int, tensor.Int64, tensor.Int32, tensor.Byte, tensor.Bool}
+var acceptableDtypes = [...]tensor.Dtype{tensor.Float64, tensor.Float32, tensor.Int, tensor.Int64, tensor.Int32, tensor.Byte, tensor.Bool, Float16, BFloat16, tensor.Complex64, tensor.Complex128}
+
+func init() {
+	tensor.RegisterFloat(Float16)
+	tensor.RegisterFloat(BFloat16)
+}
+
+// IsHalf returns true if dt is one of the half-precision floats, which are computed in float32
+func IsHalf(dt tensor.Dtype) bool { return dt == Float16 || dt == BFloat16 }
+
+// IsComplex returns true if dt is a complex dtype
+func IsComplex(dt tensor.Dtype) bool { return dt == Complex64 || dt == Complex128 }
+
+// RealOf returns the dtype of the real and imaginary parts of a complex dtype. Other dtypes are returned as they are
+func RealOf(dt tensor.Dtype) tensor.Dtype {
+	switch dt {
+	case Complex64:
+		return Float32
+	case Complex128:
+		return Float64
+	}
+	return dt
+}
 
 // TensorType is a type constructor for tensors.
 //
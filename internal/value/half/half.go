@@ -0,0 +1,113 @@
+// Package half holds the half-precision floats: the IEEE 754 Float16 and the BFloat16.
+// They are storage types: arithmetic converts them to float32, and the result is rounded back to the nearest half.
+package half
+
+import (
+	"fmt"
+	"math"
+)
+
+// Float16 is an IEEE 754 half-precision float, with 1 sign bit, 5 exponent bits and 10 mantissa bits.
+// It holds about 3 significant digits, up to ±65504.
+type Float16 uint16
+
+// BFloat16 is a brain float: the upper half of a float32, with 1 sign bit, 8 exponent bits and 7 mantissa bits.
+// It holds about 2 significant digits, with the same range as a float32.
+type BFloat16 uint16
+
+// NewFloat16 rounds a float32 to the nearest Float16, ties to even. Numbers out of range become ±Inf.
+func NewFloat16(f float32) Float16 {
+	b := math.Float32bits(f)
+	sign := uint32(b>>16) & 0x8000
+	exp := int32(b>>23) & 0xff
+	mant := b & 0x7fffff
+
+	if exp == 0xff { // Inf or NaN
+		if mant != 0 {
+			return Float16(sign | 0x7e00)
+		}
+		return Float16(sign | 0x7c00)
+	}
+
+	e := exp - 127 + 15
+	switch {
+	case e >= 0x1f:
+		return Float16(sign | 0x7c00)
+	case e <= 0:
+		// subnormal: the mantissa, with its implicit bit, is shifted to a multiple of 2⁻²⁴
+		if e < -10 {
+			return Float16(sign)
+		}
+		return Float16(sign | roundShift(mant|0x800000, uint32(14-e)))
+	}
+	// a mantissa rounded up to 0x400 carries into the exponent, which is what we want
+	return Float16(sign | (uint32(e)<<10 + roundShift(mant, 13)))
+}
+
+// Float32 returns the Float16 as a float32. The conversion is exact.
+func (h Float16) Float32() float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	case 0:
+		if mant == 0 {
+			return math.Float32frombits(sign)
+		}
+		// subnormal: normalize the mantissa
+		e := uint32(127 - 15 + 1)
+		for mant&0x400 == 0 {
+			mant <<= 1
+			e--
+		}
+		return math.Float32frombits(sign | e<<23 | (mant&0x3ff)<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// Format implements fmt.Formatter. A Float16 is formatted as the float32 it holds.
+func (h Float16) Format(s fmt.State, c rune) { formatFloat(s, c, h.Float32()) }
+
+// NewBFloat16 rounds a float32 to the nearest BFloat16, ties to even.
+func NewBFloat16(f float32) BFloat16 {
+	b := math.Float32bits(f)
+	if f != f {
+		return BFloat16(b>>16 | 0x40) // keeps the NaN quiet, whatever bits are lost
+	}
+	return BFloat16(roundShift(b, 16))
+}
+
+// Float32 returns the BFloat16 as a float32. The conversion is exact.
+func (h BFloat16) Float32() float32 { return math.Float32frombits(uint32(h) << 16) }
+
+// Format implements fmt.Formatter. A BFloat16 is formatted as the float32 it holds.
+func (h BFloat16) Format(s fmt.State, c rune) { formatFloat(s, c, h.Float32()) }
+
+// roundShift shifts x right by s bits, rounding to the nearest, ties to even
+func roundShift(x, s uint32) uint32 {
+	r := x >> s
+	rem := x & (1<<s - 1)
+	half := uint32(1) << (s - 1)
+	if rem > half || (rem == half && r&1 == 1) {
+		r++
+	}
+	return r
+}
+
+func formatFloat(s fmt.State, c rune, f float32) {
+	format := "%"
+	for _, flag := range "+-# 0" {
+		if s.Flag(int(flag)) {
+			format += string(flag)
+		}
+	}
+	if width, ok := s.Width(); ok {
+		format += fmt.Sprintf("%d", width)
+	}
+	if prec, ok := s.Precision(); ok {
+		format += fmt.Sprintf(".%d", prec)
+	}
+	fmt.Fprintf(s, format+string(c), f)
+}
@@ -0,0 +1,78 @@
+package half
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestFloat16_RoundTrip(t *testing.T) {
+	for i := 0; i <= math.MaxUint16; i++ {
+		h := Float16(i)
+		f := h.Float32()
+		if f != f {
+			if g := NewFloat16(f); g.Float32() == g.Float32() {
+				t.Errorf("%#04x: expected NaN to stay NaN. Got %#04x", i, uint16(g))
+			}
+			continue
+		}
+		if g := NewFloat16(f); g != h {
+			t.Errorf("%#04x: %v round trips to %#04x", i, f, uint16(g))
+		}
+	}
+}
+
+func TestNewFloat16(t *testing.T) {
+	halfTests := []struct {
+		f    float32
+		bits Float16
+	}{
+		{1, 0x3c00},
+		{-2, 0xc000},
+		{65504, 0x7bff},
+		{65520, 0x7c00},           // rounds to +Inf
+		{1 + 1.0/2048, 0x3c00},    // tie, rounds to even
+		{1 + 3.0/2048, 0x3c02},    // tie, rounds to even
+		{1.0 / (1 << 24), 0x0001}, // smallest subnormal
+		{1.0 / (1 << 25), 0x0000}, // tie with 0, rounds to even
+		{1.5 / (1 << 24), 0x0002}, // tie, rounds to even
+		{float32(math.Inf(-1)), 0xfc00},
+		{1e-10, 0x0000},
+	}
+	for _, ht := range halfTests {
+		if h := NewFloat16(ht.f); h != ht.bits {
+			t.Errorf("NewFloat16(%v): expected %#04x. Got %#04x", ht.f, uint16(ht.bits), uint16(h))
+		}
+	}
+}
+
+func TestNewBFloat16(t *testing.T) {
+	bfloatTests := []struct {
+		f    float32
+		bits BFloat16
+	}{
+		{1, 0x3f80},
+		{-2, 0xc000},
+		{math.Float32frombits(0x3f808000), 0x3f80}, // tie, rounds to even
+		{math.Float32frombits(0x3f818000), 0x3f82}, // tie, rounds to even
+		{math.Float32frombits(0x3f808001), 0x3f81},
+		{math.MaxFloat32, 0x7f80}, // rounds to +Inf
+	}
+	for _, bt := range bfloatTests {
+		if h := NewBFloat16(bt.f); h != bt.bits {
+			t.Errorf("NewBFloat16(%v): expected %#04x. Got %#04x", bt.f, uint16(bt.bits), uint16(h))
+		}
+		if f := bt.bits.Float32(); math.Float32bits(f) != uint32(bt.bits)<<16 {
+			t.Errorf("%#04x: expected the upper half of a float32. Got %v", uint16(bt.bits), f)
+		}
+	}
+	if f := NewBFloat16(float32(math.NaN())).Float32(); f == f {
+		t.Errorf("Expected NaN to stay NaN. Got %v", f)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if s := fmt.Sprintf("%5.2f|%v", NewFloat16(1.5), NewBFloat16(-3)); s != " 1.50|-3" {
+		t.Errorf("Expected half floats to be formatted as floats. Got %q", s)
+	}
+}
@@ -0,0 +1,180 @@
+package value
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/chewxy/hm"
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/tensor"
+)
+
+// SparseRows is a tensor where only some of the rows (the slices along the first axis) are non-zero.
+// It is typically the gradient of a lookup into a large table, such as an embedding matrix, where only the rows looked up have a gradient.
+//
+// Row Indices[i] of the dense tensor is Rows[i]. An index may appear more than once, in which case the rows add up.
+// Shape returns the shape of the dense tensor, while Data returns the data of the rows only.
+type SparseRows struct {
+	Indices []int
+	Rows    *tensor.Dense // (len(Indices), shape[1:]...)
+
+	shape tensor.Shape // shape of the dense tensor
+}
+
+// NewSparseRows creates a SparseRows standing for a tensor of the given shape.
+func NewSparseRows(shape tensor.Shape, indices []int, rows *tensor.Dense) (*SparseRows, error) {
+	if shape.Dims() < 1 {
+		return nil, errors.Errorf("SparseRows cannot stand for a scalar")
+	}
+	rs := rows.Shape()
+	if rs.Dims() != shape.Dims() || rs[0] != len(indices) || !rs[1:].Eq(shape[1:]) {
+		return nil, errors.Errorf("Expected %d rows of shape %v. Got %v", len(indices), shape[1:], rs)
+	}
+	for _, idx := range indices {
+		if idx < 0 || idx >= shape[0] {
+			return nil, errors.Errorf("Row index %d is out of bounds for a tensor of shape %v", idx, shape)
+		}
+	}
+	return &SparseRows{Indices: indices, Rows: rows, shape: shape.Clone()}, nil
+}
+
+// Shape returns the shape of the dense tensor.
+func (s *SparseRows) Shape() tensor.Shape { return s.shape }
+
+// Size returns the number of elements of the dense tensor.
+func (s *SparseRows) Size() int { return s.shape.TotalSize() }
+
+// Data returns the data of the rows.
+func (s *SparseRows) Data() interface{} { return s.Rows.Data() }
+
+// Dtype returns the Dtype of the rows.
+func (s *SparseRows) Dtype() tensor.Dtype { return s.Rows.Dtype() }
+
+// Uintptr returns the pointer to the data of the rows.
+func (s *SparseRows) Uintptr() uintptr { return s.Rows.Uintptr() }
+
+// MemSize returns the size of the data of the rows.
+func (s *SparseRows) MemSize() uintptr { return s.Rows.MemSize() }
+
+// Type returns the type of the dense tensor.
+func (s *SparseRows) Type() hm.Type { return factory.MakeTensorType(s.shape.Dims(), s.Dtype()) }
+
+// RowSize returns the number of elements of each row.
+func (s *SparseRows) RowSize() int {
+	if s.shape.Dims() == 1 {
+		return 1
+	}
+	return s.shape[1:].TotalSize()
+}
+
+// Clone ...
+func (s *SparseRows) Clone() (interface{}, error) {
+	return &SparseRows{
+		Indices: append([]int(nil), s.Indices...),
+		Rows:    s.Rows.Clone().(*tensor.Dense),
+		shape:   s.shape.Clone(),
+	}, nil
+}
+
+// ZeroValue zeroes the rows.
+func (s *SparseRows) ZeroValue() Value {
+	s.Rows.Zero()
+	return s
+}
+
+// Coalesce sorts the rows by index, and adds up the rows with the same index.
+func (s *SparseRows) Coalesce() error {
+	if len(s.Indices) == 0 {
+		return nil
+	}
+	order := make([]int, len(s.Indices))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return s.Indices[order[i]] < s.Indices[order[j]] })
+
+	var indices []int
+	for _, o := range order {
+		if n := len(indices); n == 0 || indices[n-1] != s.Indices[o] {
+			indices = append(indices, s.Indices[o])
+		}
+	}
+
+	size := s.RowSize()
+	rows := tensor.New(tensor.Of(s.Dtype()), tensor.WithShape(append(tensor.Shape{len(indices)}, s.shape[1:]...)...))
+	k := -1
+	for i, o := range order {
+		if i == 0 || s.Indices[o] != s.Indices[order[i-1]] {
+			k++
+		}
+		if err := addRow(rows, k, s.Rows, o, size); err != nil {
+			return err
+		}
+	}
+	s.Indices, s.Rows = indices, rows
+	return nil
+}
+
+// ScatterAdd adds the rows to the corresponding rows of dst, which has to have the shape of the dense tensor.
+func (s *SparseRows) ScatterAdd(dst *tensor.Dense) error {
+	if !dst.Shape().Eq(s.shape) {
+		return errors.Errorf("Cannot add SparseRows of shape %v to a tensor of shape %v", s.shape, dst.Shape())
+	}
+	size := s.RowSize()
+	for i, idx := range s.Indices {
+		if err := addRow(dst, idx, s.Rows, i, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Append adds the rows of other, which has to stand for a tensor of the same shape.
+func (s *SparseRows) Append(other *SparseRows) error {
+	if !s.shape.Eq(other.shape) || s.Dtype() != other.Dtype() {
+		return errors.Errorf("Cannot append SparseRows%v of %v to SparseRows%v of %v", other.shape, other.Dtype(), s.shape, s.Dtype())
+	}
+	n := len(s.Indices) + len(other.Indices)
+	var backing interface{}
+	switch s.Dtype() {
+	case tensor.Float64:
+		backing = append(append(make([]float64, 0, n*s.RowSize()), s.Rows.Float64s()...), other.Rows.Float64s()...)
+	case tensor.Float32:
+		backing = append(append(make([]float32, 0, n*s.RowSize()), s.Rows.Float32s()...), other.Rows.Float32s()...)
+	default:
+		return errors.Errorf("SparseRows only supports float64 and float32. Got %v", s.Dtype())
+	}
+	s.Indices = append(s.Indices, other.Indices...)
+	s.Rows = tensor.New(tensor.WithShape(append(tensor.Shape{n}, s.shape[1:]...)...), tensor.WithBacking(backing))
+	return nil
+}
+
+// Format ...
+func (s *SparseRows) Format(state fmt.State, c rune) {
+	fmt.Fprintf(state, "SparseRows%v{%v: ", s.shape, s.Indices)
+	s.Rows.Format(state, c)
+	fmt.Fprint(state, "}")
+}
+
+// addRow adds row j of src to row i of dst.
+func addRow(dst *tensor.Dense, i int, src *tensor.Dense, j int, size int) error {
+	if dst.Dtype() != src.Dtype() {
+		return errors.Errorf("Cannot add rows of %v to rows of %v", src.Dtype(), dst.Dtype())
+	}
+	switch dst.Dtype() {
+	case tensor.Float64:
+		d, s := dst.Float64s()[i*size:(i+1)*size], src.Float64s()[j*size:(j+1)*size]
+		for k, v := range s {
+			d[k] += v
+		}
+	case tensor.Float32:
+		d, s := dst.Float32s()[i*size:(i+1)*size], src.Float32s()[j*size:(j+1)*size]
+		for k, v := range s {
+			d[k] += v
+		}
+	default:
+		return errors.Errorf("SparseRows only supports float64 and float32. Got %v", dst.Dtype())
+	}
+	return nil
+}
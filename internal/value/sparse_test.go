@@ -0,0 +1,49 @@
+package value
+
+import (
+	"testing"
+
+	"gorgonia.org/tensor"
+)
+
+func TestSparseRows(t *testing.T) {
+	rows := tensor.New(tensor.WithShape(3, 2), tensor.WithBacking([]float64{1, 2, 3, 4, 5, 6}))
+	s, err := NewSparseRows(tensor.Shape{4, 2}, []int{3, 0, 3}, rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewSparseRows(tensor.Shape{4, 2}, []int{3, 4, 0}, rows); err == nil {
+		t.Errorf("Expected an error for an out of bounds index")
+	}
+
+	other, _ := NewSparseRows(tensor.Shape{4, 2}, []int{1}, tensor.New(tensor.WithShape(1, 2), tensor.WithBacking([]float64{7, 8})))
+	if err = s.Append(other); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Coalesce(); err != nil {
+		t.Fatal(err)
+	}
+	correctIndices := []int{0, 1, 3}
+	correctRows := []float64{3, 4, 7, 8, 6, 8}
+	for i, idx := range s.Indices {
+		if idx != correctIndices[i] {
+			t.Errorf("Expected indices %v. Got %v", correctIndices, s.Indices)
+			break
+		}
+	}
+	if !s.Rows.Eq(tensor.New(tensor.WithShape(3, 2), tensor.WithBacking(correctRows))) {
+		t.Errorf("Expected rows %v. Got %v", correctRows, s.Rows.Data())
+	}
+
+	dense := tensor.New(tensor.WithShape(4, 2), tensor.WithBacking([]float64{1, 1, 1, 1, 1, 1, 1, 1}))
+	if err = s.ScatterAdd(dense); err != nil {
+		t.Fatal(err)
+	}
+	correct := []float64{4, 5, 8, 9, 1, 1, 7, 9}
+	for i, v := range dense.Float64s() {
+		if v != correct[i] {
+			t.Errorf("Expected %v. Got %v", correct, dense.Float64s())
+			break
+		}
+	}
+}
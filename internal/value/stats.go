@@ -0,0 +1,123 @@
+package value
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/value/half"
+)
+
+// Stats summarizes the elements of a value, such as when hunting down where NaNs and Infs come from.
+// Min, Max and Mean are over the finite elements only, and are NaN if there are none. Complex numbers are summarized by their modulus,
+// and a complex number with an infinite part counts as +Inf.
+type Stats struct {
+	Min, Max, Mean float64
+
+	NaNs, PosInfs, NegInfs int
+	FirstNonFinite         int // the index of the first NaN or Inf. -1 if there are none
+	Size                   int // the number of elements
+}
+
+// Finite returns true if there are no NaNs or Infs
+func (s Stats) Finite() bool { return s.FirstNonFinite < 0 }
+
+func (s Stats) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "min %v, max %v, mean %v over %d elements", s.Min, s.Max, s.Mean, s.Size)
+	if s.Finite() {
+		return buf.String()
+	}
+	for _, c := range []struct {
+		n    int
+		what string
+	}{{s.NaNs, "NaN"}, {s.PosInfs, "+Inf"}, {s.NegInfs, "-Inf"}} {
+		if c.n > 0 {
+			fmt.Fprintf(&buf, ", %d × %v", c.n, c.what)
+		}
+	}
+	fmt.Fprintf(&buf, " (first at %d)", s.FirstNonFinite)
+	return buf.String()
+}
+
+// StatsOf summarizes the elements of a scalar or a tensor. Bools are summarized as 0s and 1s.
+func StatsOf(v Value) (s Stats, err error) {
+	var data interface{}
+	if data, err = elementsOf(v); err != nil {
+		return s, errors.Wrap(err, "Cannot summarize")
+	}
+
+	// at returns the element at i as a float64
+	var at func(i int) float64
+	switch d := data.(type) {
+	case []float64:
+		s.Size, at = len(d), func(i int) float64 { return d[i] }
+	case []float32:
+		s.Size, at = len(d), func(i int) float64 { return float64(d[i]) }
+	case []half.Float16:
+		s.Size, at = len(d), func(i int) float64 { return float64(d[i].Float32()) }
+	case []half.BFloat16:
+		s.Size, at = len(d), func(i int) float64 { return float64(d[i].Float32()) }
+	case []complex128:
+		s.Size, at = len(d), func(i int) float64 { return modulus(d[i]) }
+	case []complex64:
+		s.Size, at = len(d), func(i int) float64 { return modulus(complex128(d[i])) }
+	case []int:
+		s.Size, at = len(d), func(i int) float64 { return float64(d[i]) }
+	case []int64:
+		s.Size, at = len(d), func(i int) float64 { return float64(d[i]) }
+	case []int32:
+		s.Size, at = len(d), func(i int) float64 { return float64(d[i]) }
+	case []byte:
+		s.Size, at = len(d), func(i int) float64 { return float64(d[i]) }
+	case []bool:
+		s.Size, at = len(d), func(i int) float64 {
+			if d[i] {
+				return 1
+			}
+			return 0
+		}
+	default:
+		return s, errors.Errorf("StatsOf not yet implemented for %v", v.Dtype())
+	}
+
+	s.FirstNonFinite = -1
+	s.Min, s.Max = math.Inf(1), math.Inf(-1)
+	var sum float64
+	var finite int
+	for i := 0; i < s.Size; i++ {
+		x := at(i)
+		switch {
+		case math.IsNaN(x):
+			s.NaNs++
+		case math.IsInf(x, 1):
+			s.PosInfs++
+		case math.IsInf(x, -1):
+			s.NegInfs++
+		default:
+			s.Min, s.Max = math.Min(s.Min, x), math.Max(s.Max, x)
+			sum += x
+			finite++
+			continue
+		}
+		if s.FirstNonFinite < 0 {
+			s.FirstNonFinite = i
+		}
+	}
+	if finite == 0 {
+		s.Min, s.Max, s.Mean = math.NaN(), math.NaN(), math.NaN()
+		return s, nil
+	}
+	s.Mean = sum / float64(finite)
+	return s, nil
+}
+
+// modulus returns |z|, or NaN if either part of z is a NaN. Unlike cmplx.Abs, a NaN with an infinite other part is still a NaN.
+func modulus(z complex128) float64 {
+	if math.IsNaN(real(z)) || math.IsNaN(imag(z)) {
+		return math.NaN()
+	}
+	return cmplx.Abs(z)
+}
@@ -0,0 +1,42 @@
+package value
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/value/half"
+	"gorgonia.org/tensor"
+)
+
+func TestStatsOf(t *testing.T) {
+	nan, inf := math.NaN(), math.Inf(1)
+	statsTests := []struct {
+		name    string
+		v       Value
+		correct Stats
+	}{
+		{"finite", tensor.New(tensor.WithBacking([]float64{1, -2, 4, 1})), Stats{Min: -2, Max: 4, Mean: 1, FirstNonFinite: -1, Size: 4}},
+		{"non-finite", tensor.New(tensor.WithBacking([]float32{1, float32(inf), 3, float32(nan), float32(-inf), float32(nan)})), Stats{Min: 1, Max: 3, Mean: 2, NaNs: 2, PosInfs: 1, NegInfs: 1, FirstNonFinite: 1, Size: 6}},
+		{"halves", tensor.New(tensor.WithBacking([]half.Float16{half.NewFloat16(0.5), half.NewFloat16(float32(nan))})), Stats{Min: 0.5, Max: 0.5, Mean: 0.5, NaNs: 1, FirstNonFinite: 1, Size: 2}},
+		{"complex", tensor.New(tensor.WithBacking([]complex128{3 + 4i, complex(inf, nan), complex(1, inf)})), Stats{Min: 5, Max: 5, Mean: 5, NaNs: 1, PosInfs: 1, FirstNonFinite: 1, Size: 3}},
+		{"ints", tensor.New(tensor.WithBacking([]int{1, 2, 6})), Stats{Min: 1, Max: 6, Mean: 3, FirstNonFinite: -1, Size: 3}},
+		{"scalar", NewF64(-inf), Stats{Min: nan, Max: nan, Mean: nan, NegInfs: 1, FirstNonFinite: 0, Size: 1}},
+	}
+
+	same := func(a, b float64) bool { return a == b || (math.IsNaN(a) && math.IsNaN(b)) }
+	for _, st := range statsTests {
+		s, err := StatsOf(st.v)
+		if err != nil {
+			t.Errorf("%v: %v", st.name, err)
+			continue
+		}
+		c := st.correct
+		if !same(s.Min, c.Min) || !same(s.Max, c.Max) || !same(s.Mean, c.Mean) ||
+			s.NaNs != c.NaNs || s.PosInfs != c.PosInfs || s.NegInfs != c.NegInfs || s.FirstNonFinite != c.FirstNonFinite || s.Size != c.Size {
+			t.Errorf("%v: expected %v. Got %v", st.name, c, s)
+		}
+		if s.Finite() != (c.FirstNonFinite < 0) {
+			t.Errorf("%v: expected Finite() to be %t", st.name, c.FirstNonFinite < 0)
+		}
+	}
+}
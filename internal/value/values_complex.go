@@ -0,0 +1,80 @@
+package value
+
+import (
+	"fmt"
+	"unsafe"
+
+	"gorgonia.org/tensor"
+)
+
+// C64 represents a complex64 value.
+type C64 complex64
+
+// C128 represents a complex128 value.
+type C128 complex128
+
+// NewC64 creates a new C64.
+func NewC64(v complex64) *C64 { r := C64(v); return &r }
+
+// NewC128 creates a new C128.
+func NewC128(v complex128) *C128 { r := C128(v); return &r }
+
+// Shape returns a scalar shape for all scalar values
+func (v *C64) Shape() tensor.Shape { return scalarShape }
+
+// Shape returns a scalar shape for all scalar values
+func (v *C128) Shape() tensor.Shape { return scalarShape }
+
+// Size returns 0 for all scalar Values
+func (v *C64) Size() int { return 0 }
+
+// Size returns 0 for all scalar Values
+func (v *C128) Size() int { return 0 }
+
+// Data returns the original representation of the Value
+func (v *C64) Data() interface{} { return v.Any() }
+
+// Data returns the original representation of the Value
+func (v *C128) Data() interface{} { return v.Any() }
+
+// Any returns the original representation of the Value
+func (v *C64) Any() complex64 { return complex64(*v) }
+
+// Any returns the original representation of the Value
+func (v *C128) Any() complex128 { return complex128(*v) }
+
+// Format implements fmt.Formatter
+func (v *C64) Format(s fmt.State, c rune) { formatScalar(v, s, c) }
+
+// Format implements fmt.Formatter
+func (v *C128) Format(s fmt.State, c rune) { formatScalar(v, s, c) }
+
+// Dtype returns the Dtype of the value
+func (v *C64) Dtype() tensor.Dtype { return tensor.Complex64 }
+
+// Dtype returns the Dtype of the value
+func (v *C128) Dtype() tensor.Dtype { return tensor.Complex128 }
+
+// IsScalarValue is a method to indicate that this is a scalar value
+func (v *C64) IsScalarValue() bool { return true }
+
+// IsScalarValue is a method to indicate that this is a scalar value
+func (v *C128) IsScalarValue() bool { return true }
+
+// Uintptr satisfies the tensor.Memory interface
+func (v *C64) Uintptr() uintptr { return uintptr(unsafe.Pointer(v)) }
+
+// Uintptr satisfies the tensor.Memory interface
+func (v *C128) Uintptr() uintptr { return uintptr(unsafe.Pointer(v)) }
+
+// MemSize satisfies the tensor.Memory interface
+func (v *C64) MemSize() uintptr { return 8 }
+
+// MemSize satisfies the tensor.Memory interface
+func (v *C128) MemSize() uintptr { return 16 }
+
+// Pointer returns the pointer as an unsafe.Pointer. Satisfies the tensor.Memory interface
+func (v *C64) Pointer() unsafe.Pointer { return unsafe.Pointer(v) }
+
+// Pointer returns the pointer as an unsafe.Pointer. Satisfies the tensor.Memory interface
+func (v *C128) Pointer() unsafe.Pointer { return unsafe.Pointer(v) }
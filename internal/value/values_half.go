@@ -0,0 +1,88 @@
+package value
+
+import (
+	"fmt"
+	"unsafe"
+
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/gorgonia/internal/value/half"
+	"gorgonia.org/tensor"
+)
+
+// F16 represents an IEEE 754 half-precision value.
+type F16 half.Float16
+
+// BF16 represents a brain float value.
+type BF16 half.BFloat16
+
+// NewF16 rounds v to the nearest half-precision value.
+func NewF16(v float32) *F16 { r := F16(half.NewFloat16(v)); return &r }
+
+// NewBF16 rounds v to the nearest brain float value.
+func NewBF16(v float32) *BF16 { r := BF16(half.NewBFloat16(v)); return &r }
+
+// Shape returns a scalar shape for all scalar values
+func (v *F16) Shape() tensor.Shape { return scalarShape }
+
+// Shape returns a scalar shape for all scalar values
+func (v *BF16) Shape() tensor.Shape { return scalarShape }
+
+// Size returns 0 for all scalar Values
+func (v *F16) Size() int { return 0 }
+
+// Size returns 0 for all scalar Values
+func (v *BF16) Size() int { return 0 }
+
+// Data returns the original representation of the Value
+func (v *F16) Data() interface{} { return v.Any() }
+
+// Data returns the original representation of the Value
+func (v *BF16) Data() interface{} { return v.Any() }
+
+// Any returns the original representation of the Value
+func (v *F16) Any() half.Float16 { return half.Float16(*v) }
+
+// Any returns the original representation of the Value
+func (v *BF16) Any() half.BFloat16 { return half.BFloat16(*v) }
+
+// Float32 returns the value as a float32. The conversion is exact.
+func (v *F16) Float32() float32 { return v.Any().Float32() }
+
+// Float32 returns the value as a float32. The conversion is exact.
+func (v *BF16) Float32() float32 { return v.Any().Float32() }
+
+// Format implements fmt.Formatter
+func (v *F16) Format(s fmt.State, c rune) { formatScalar(v, s, c) }
+
+// Format implements fmt.Formatter
+func (v *BF16) Format(s fmt.State, c rune) { formatScalar(v, s, c) }
+
+// Dtype returns the Dtype of the value
+func (v *F16) Dtype() tensor.Dtype { return factory.Float16 }
+
+// Dtype returns the Dtype of the value
+func (v *BF16) Dtype() tensor.Dtype { return factory.BFloat16 }
+
+// IsScalarValue is a method to indicate that this is a scalar value
+func (v *F16) IsScalarValue() bool { return true }
+
+// IsScalarValue is a method to indicate that this is a scalar value
+func (v *BF16) IsScalarValue() bool { return true }
+
+// Uintptr satisfies the tensor.Memory interface
+func (v *F16) Uintptr() uintptr { return uintptr(unsafe.Pointer(v)) }
+
+// Uintptr satisfies the tensor.Memory interface
+func (v *BF16) Uintptr() uintptr { return uintptr(unsafe.Pointer(v)) }
+
+// MemSize satisfies the tensor.Memory interface
+func (v *F16) MemSize() uintptr { return 2 }
+
+// MemSize satisfies the tensor.Memory interface
+func (v *BF16) MemSize() uintptr { return 2 }
+
+// Pointer returns the pointer as an unsafe.Pointer. Satisfies the tensor.Memory interface
+func (v *F16) Pointer() unsafe.Pointer { return unsafe.Pointer(v) }
+
+// Pointer returns the pointer as an unsafe.Pointer. Satisfies the tensor.Memory interface
+func (v *BF16) Pointer() unsafe.Pointer { return unsafe.Pointer(v) }
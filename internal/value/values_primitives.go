@@ -8,6 +8,8 @@ import (
 
 	"github.com/chewxy/hm"
 	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/value/factory"
+	"gorgonia.org/gorgonia/internal/value/half"
 	"gorgonia.org/tensor"
 )
 
@@ -306,7 +308,7 @@ func formatScalar(v Scalar, s fmt.State, c rune) {
 		buf.WriteRune('v')
 	case 'd':
 		switch v.(type) {
-		case *F64, *F32, *U8, *B:
+		case *F64, *F32, *F16, *BF16, *C64, *C128, *U8, *B:
 			buf.WriteRune('v')
 		default:
 			buf.WriteRune(c)
@@ -349,6 +351,16 @@ func AnyToScalar(any interface{}) (Scalar, tensor.Dtype) {
 		return NewU8(at), tensor.Byte
 	case bool:
 		return NewB(at), tensor.Bool
+	case half.Float16:
+		r := F16(at)
+		return &r, factory.Float16
+	case half.BFloat16:
+		r := BF16(at)
+		return &r, factory.BFloat16
+	case complex64:
+		return NewC64(at), tensor.Complex64
+	case complex128:
+		return NewC128(at), tensor.Complex128
 	default:
 		panic(fmt.Sprintf("%v(%T) not scalar/not handled", any, any))
 	}
@@ -362,7 +374,7 @@ func AnyToValue(any interface{}) (val Value, t hm.Type, dt tensor.Dtype, err err
 		t = TypeOf(a)
 		dt = a.Dtype()
 		return
-	case float64, float32, int, int64, int32, byte, bool:
+	case float64, float32, int, int64, int32, byte, bool, half.Float16, half.BFloat16, complex64, complex128:
 		val, dt = AnyToScalar(any)
 		t = dt
 		return
@@ -380,6 +392,14 @@ func AnyToValue(any interface{}) (val Value, t hm.Type, dt tensor.Dtype, err err
 		return NewU8(byte(a)), tensor.Uint8, tensor.Uint8, nil
 	case B:
 		return NewB(bool(a)), tensor.Bool, tensor.Bool, nil
+	case F16:
+		return &a, factory.Float16, factory.Float16, nil
+	case BF16:
+		return &a, factory.BFloat16, factory.BFloat16, nil
+	case C64:
+		return &a, tensor.Complex64, tensor.Complex64, nil
+	case C128:
+		return &a, tensor.Complex128, tensor.Complex128, nil
 	case tensor.Tensor:
 		val = a
 		t = TypeOf(a)
@@ -408,6 +428,14 @@ func One(dt tensor.Dtype) Scalar {
 		return NewU8(byte(1))
 	case tensor.Bool:
 		return NewB(true)
+	case factory.Float16:
+		return NewF16(1)
+	case factory.BFloat16:
+		return NewBF16(1)
+	case tensor.Complex64:
+		return NewC64(1)
+	case tensor.Complex128:
+		return NewC128(1)
 	default:
 		panic("Unhandled dtype")
 	}
@@ -430,6 +458,14 @@ func Zero(dt tensor.Dtype) Scalar {
 		return NewU8(byte(0))
 	case tensor.Bool:
 		return NewB(false)
+	case factory.Float16:
+		return NewF16(0)
+	case factory.BFloat16:
+		return NewBF16(0)
+	case tensor.Complex64:
+		return NewC64(0)
+	case tensor.Complex128:
+		return NewC128(0)
 	default:
 		panic("Unhandled dtype")
 	}
@@ -48,6 +48,7 @@ func Eq(a, b Value) bool {
 }
 
 // Close checks whether two values are close to one another. It's predominantly used as an alternative equality test for floats
+// Its tolerances are fixed. CloseWithin takes configurable tolerances and reports how the values differ.
 func Close(a, b Value) bool {
 	if a == nil && b == nil {
 		return true
@@ -95,6 +96,18 @@ func CloneValue(v Value) (Value, error) {
 	case *B:
 		retVal := *vt
 		return &retVal, nil
+	case *F16:
+		retVal := *vt
+		return &retVal, nil
+	case *BF16:
+		retVal := *vt
+		return &retVal, nil
+	case *C64:
+		retVal := *vt
+		return &retVal, nil
+	case *C128:
+		retVal := *vt
+		return &retVal, nil
 	case tensor.Tensor:
 		return vt.Clone().(*tensor.Dense), nil
 	case CloneErrorer:
@@ -138,6 +151,18 @@ func ZeroValue(v Value) Value {
 	case *B:
 		*vt = false
 		return vt
+	case *F16:
+		*vt = 0
+		return vt
+	case *BF16:
+		*vt = 0
+		return vt
+	case *C64:
+		*vt = 0
+		return vt
+	case *C128:
+		*vt = 0
+		return vt
 	case tensor.Tensor:
 		vt.Zero()
 		return vt
@@ -201,6 +226,34 @@ func Copy(dest, src Value) (Value, error) {
 		}
 		*destS = *srcT
 		return destS, nil
+	case *F16:
+		var destS *F16
+		if destS, ok = dest.(*F16); !ok {
+			return nil, errors.Errorf("Expected dest to be *F16. Got %T instead", dest)
+		}
+		*destS = *srcT
+		return destS, nil
+	case *BF16:
+		var destS *BF16
+		if destS, ok = dest.(*BF16); !ok {
+			return nil, errors.Errorf("Expected dest to be *BF16. Got %T instead", dest)
+		}
+		*destS = *srcT
+		return destS, nil
+	case *C64:
+		var destS *C64
+		if destS, ok = dest.(*C64); !ok {
+			return nil, errors.Errorf("Expected dest to be *C64. Got %T instead", dest)
+		}
+		*destS = *srcT
+		return destS, nil
+	case *C128:
+		var destS *C128
+		if destS, ok = dest.(*C128); !ok {
+			return nil, errors.Errorf("Expected dest to be *C128. Got %T instead", dest)
+		}
+		*destS = *srcT
+		return destS, nil
 	case tensor.Tensor:
 		var destT tensor.Tensor
 		if destT, ok = dest.(tensor.Tensor); !ok {
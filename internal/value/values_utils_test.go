@@ -15,6 +15,10 @@ var cloneValTests = []Value{
 	NewI32(10),
 	NewU8(10),
 	NewB(true),
+	NewF16(10.0),
+	NewBF16(10.0),
+	NewC64(10 + 1i),
+	NewC128(10 + 1i),
 
 	tensor.New(tensor.Of(tensor.Float64), tensor.WithShape(2, 4, 6)),
 	tensor.New(tensor.Of(tensor.Float32), tensor.WithShape(2, 4, 6)),
@@ -0,0 +1,139 @@
+package profiler
+
+import (
+	"compress/gzip"
+	"io"
+	"time"
+)
+
+// WritePprof writes what was recorded as a gzipped pprof profile, for go tool pprof.
+//
+// Every node is a sample, with the op type of the node as its caller, so the flame graph groups the nodes by op type.
+// The samples have the number of calls, the wall time (the default), the bytes of the outputs and, WithAllocs,
+// the bytes allocated, and are labelled with their op type.
+func (p *Profiler) WritePprof(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var b protobuf
+	strings := map[string]int64{"": 0}
+	table := []string{""}
+	str := func(s string) int64 {
+		i, ok := strings[s]
+		if !ok {
+			i = int64(len(table))
+			strings[s] = i
+			table = append(table, s)
+		}
+		return i
+	}
+	valueType := func(field int, typ, unit string) {
+		b.message(field, func(b *protobuf) {
+			b.int64(1, str(typ))
+			b.int64(2, str(unit))
+		})
+	}
+
+	// a function and a location per op type and per node, with the same IDs
+	var id uint64
+	function := func(name string) uint64 {
+		id++
+		fid := id
+		b.message(5, func(b *protobuf) {
+			b.uint64(1, fid)
+			b.int64(2, str(name))
+			b.int64(3, str(name))
+		})
+		b.message(4, func(b *protobuf) {
+			b.uint64(1, fid)
+			b.message(4, func(b *protobuf) { b.uint64(1, fid) })
+		})
+		return fid
+	}
+
+	sampleTypes := [][2]string{{"calls", "count"}, {"wall", "nanoseconds"}, {"output_space", "bytes"}}
+	if p.allocs {
+		sampleTypes = append(sampleTypes, [2]string{"alloc_space", "bytes"})
+	}
+	for _, t := range sampleTypes {
+		valueType(1, t[0], t[1])
+	}
+	opIDs := make(map[string]uint64, len(p.ops))
+	for _, r := range p.opRecords() {
+		opIDs[r.OpType] = function(r.OpType)
+	}
+	for _, r := range p.nodeRecords() {
+		nid := function(r.Name)
+		values := []uint64{uint64(r.Calls), uint64(r.Time), r.Output}
+		if p.allocs {
+			values = append(values, r.Alloc)
+		}
+		b.message(2, func(b *protobuf) {
+			b.packed(1, []uint64{nid, opIDs[r.OpType]})
+			b.packed(2, values)
+			b.message(3, func(b *protobuf) {
+				b.int64(1, str("op"))
+				b.int64(2, str(r.OpType))
+			})
+		})
+	}
+
+	b.int64(9, p.started.UnixNano())
+	b.int64(10, int64(time.Since(p.started)))
+	valueType(11, "wall", "nanoseconds")
+	b.int64(12, 1)
+	b.int64(14, str("wall"))
+	// the string table goes last, once all the strings are known
+	for _, s := range table {
+		b.bytes(6, []byte(s))
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.buf); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// protobuf encodes the few protocol buffer types a pprof profile needs (see github.com/google/pprof/proto/profile.proto).
+// Fields may come in any order, so messages are written as they go.
+type protobuf struct {
+	buf []byte
+}
+
+func (b *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		b.buf = append(b.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	b.buf = append(b.buf, byte(x))
+}
+
+func (b *protobuf) key(field int, wireType uint64) { b.varint(uint64(field)<<3 | wireType) }
+
+func (b *protobuf) uint64(field int, x uint64) {
+	b.key(field, 0)
+	b.varint(x)
+}
+
+func (b *protobuf) int64(field int, x int64) { b.uint64(field, uint64(x)) }
+
+func (b *protobuf) bytes(field int, data []byte) {
+	b.key(field, 2)
+	b.varint(uint64(len(data)))
+	b.buf = append(b.buf, data...)
+}
+
+func (b *protobuf) packed(field int, xs []uint64) {
+	var p protobuf
+	for _, x := range xs {
+		p.varint(x)
+	}
+	b.bytes(field, p.buf)
+}
+
+func (b *protobuf) message(field int, write func(b *protobuf)) {
+	var m protobuf
+	write(&m)
+	b.bytes(field, m.buf)
+}
@@ -0,0 +1,230 @@
+// Package profiler records where the time goes when the nodes of a graph are executed.
+//
+// A Profiler wraps the execution of every node, and aggregates the wall time, the size of the output and, optionally, the bytes
+// allocated per node and per op type over as many runs as it is given. The results can be written as a pprof profile, to be read with
+// go tool pprof (its flame graph shows the op types, then the nodes of each type), and as Chrome trace events, to be read
+// with chrome://tracing or Perfetto.
+package profiler
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value"
+)
+
+// Record aggregates the executions of a node, or of all the nodes of an op type.
+type Record struct {
+	Name   string
+	OpType string // the Go type of the op of the node (e.g. "*nnops.convolution"). Records of op types have their own type as their name
+
+	Calls  int
+	Time   time.Duration // wall time
+	Alloc  uint64        // bytes allocated while executing. Only counted WithAllocs
+	Output uint64        // bytes of the outputs
+}
+
+// Mean returns the mean wall time of an execution.
+func (r *Record) Mean() time.Duration {
+	if r.Calls == 0 {
+		return 0
+	}
+	return r.Time / time.Duration(r.Calls)
+}
+
+func (r *Record) add(d time.Duration, alloc, output uint64) {
+	r.Calls++
+	r.Time += d
+	r.Alloc += alloc
+	r.Output += output
+}
+
+// event is an execution kept for the trace
+type event struct {
+	name, opType  string
+	start         time.Duration // since the profiler started
+	dur           time.Duration
+	alloc, output uint64
+	run           int
+}
+
+// Opt is an option of a Profiler.
+type Opt func(p *Profiler)
+
+// WithTrace keeps the first max executions for WriteTrace. Without it, there is nothing to trace.
+func WithTrace(max int) Opt { return func(p *Profiler) { p.maxEvents = max } }
+
+// WithAllocs counts the bytes allocated by every execution. Counting them reads the memory statistics of the runtime before
+// and after every execution, which stops the world, so it is off by default. The bytes are counted for the whole process,
+// so they are only accurate when the nodes are executed one at a time.
+func WithAllocs() Opt { return func(p *Profiler) { p.allocs = true } }
+
+// Profiler records the executions of nodes. It is safe for concurrent use.
+type Profiler struct {
+	mu      sync.Mutex
+	started time.Time
+	runs    int
+	allocs  bool
+
+	nodes map[*exprgraph.Node]*Record // keyed by node rather than by ID, as the IDs are only unique within a graph
+	ops   map[string]*Record
+
+	maxEvents int
+	events    []event
+	runEvents []event
+}
+
+// New creates a Profiler.
+func New(opts ...Opt) *Profiler {
+	p := &Profiler{
+		started: time.Now(),
+		nodes:   make(map[*exprgraph.Node]*Record),
+		ops:     make(map[string]*Record),
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// BeginRun marks the start of a run of the graph. Runs are optional: they count the runs the records are aggregated over,
+// and are shown in the trace.
+func (p *Profiler) BeginRun() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.runs++
+	if len(p.runEvents) < p.maxEvents {
+		p.runEvents = append(p.runEvents, event{start: time.Since(p.started), run: p.runs})
+	}
+}
+
+// EndRun marks the end of a run of the graph.
+func (p *Profiler) EndRun() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if n := len(p.runEvents); n > 0 && p.runEvents[n-1].run == p.runs {
+		p.runEvents[n-1].dur = time.Since(p.started) - p.runEvents[n-1].start
+	}
+}
+
+// Runs returns the number of runs begun.
+func (p *Profiler) Runs() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.runs
+}
+
+// Do executes the op of n on the inputs and records it.
+func (p *Profiler) Do(n *exprgraph.Node, inputs ...value.Value) (value.Value, error) {
+	return p.Exec(n, func() (value.Value, error) { return n.Op.Do(inputs...) })
+}
+
+// Exec records the execution of n by do, for executors that execute nodes in other ways than with Do (e.g. UnsafeDo).
+// Executions that fail are not recorded.
+func (p *Profiler) Exec(n *exprgraph.Node, do func() (value.Value, error)) (retVal value.Value, err error) {
+	var before, alloc uint64
+	if p.allocs {
+		before = allocated()
+	}
+	start := time.Now()
+	retVal, err = do()
+	d := time.Since(start)
+	if p.allocs {
+		alloc = allocated() - before
+	}
+	if err != nil {
+		return nil, err
+	}
+	var output uint64
+	if retVal != nil {
+		output = uint64(retVal.MemSize())
+	}
+
+	opType := fmt.Sprintf("%T", n.Op)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	r, ok := p.nodes[n]
+	if !ok {
+		r = &Record{Name: exprgraph.NameOf(n), OpType: opType}
+		p.nodes[n] = r
+	}
+	r.add(d, alloc, output)
+	if r, ok = p.ops[opType]; !ok {
+		r = &Record{Name: opType, OpType: opType}
+		p.ops[opType] = r
+	}
+	r.add(d, alloc, output)
+	if len(p.events) < p.maxEvents {
+		p.events = append(p.events, event{
+			name: exprgraph.NameOf(n), opType: opType,
+			start: start.Sub(p.started), dur: d,
+			alloc: alloc, output: output,
+			run: p.runs,
+		})
+	}
+	return retVal, nil
+}
+
+// Nodes returns the records of the nodes, from the slowest to the fastest in total.
+func (p *Profiler) Nodes() []Record {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.nodeRecords()
+}
+
+// Ops returns the records of the op types, from the slowest to the fastest in total.
+func (p *Profiler) Ops() []Record {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.opRecords()
+}
+
+func (p *Profiler) nodeRecords() []Record {
+	retVal := make([]Record, 0, len(p.nodes))
+	for _, r := range p.nodes {
+		retVal = append(retVal, *r)
+	}
+	return sortRecords(retVal)
+}
+
+func (p *Profiler) opRecords() []Record {
+	retVal := make([]Record, 0, len(p.ops))
+	for _, r := range p.ops {
+		retVal = append(retVal, *r)
+	}
+	return sortRecords(retVal)
+}
+
+// Reset drops everything recorded so far.
+func (p *Profiler) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started = time.Now()
+	p.runs = 0
+	p.nodes = make(map[*exprgraph.Node]*Record)
+	p.ops = make(map[string]*Record)
+	p.events, p.runEvents = nil, nil
+}
+
+// sortRecords sorts the records from the slowest to the fastest, and by name
+func sortRecords(retVal []Record) []Record {
+	sort.Slice(retVal, func(i, j int) bool {
+		if retVal[i].Time != retVal[j].Time {
+			return retVal[i].Time > retVal[j].Time
+		}
+		return retVal[i].Name < retVal[j].Name
+	})
+	return retVal
+}
+
+// allocated returns the bytes allocated on the heap so far. runtime/metrics is cheaper, but only counts small allocations
+// in batches, which is too coarse for a single node. This is why allocations are only counted WithAllocs.
+func allocated() uint64 {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.TotalAlloc
+}
@@ -0,0 +1,155 @@
+package profiler
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	"gorgonia.org/gorgonia/internal/exprgraph"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+func TestProfiler(t *testing.T) {
+	g := exprgraph.NewGraph()
+	fc := g.NewVertex()
+	fc.Name = "fc"
+	g.AddNode(fc)
+	act := g.NewVertex()
+	act.Name = "act"
+	g.AddNode(act)
+
+	var sink []float64
+	exec := func(d time.Duration, size int) func() (value.Value, error) {
+		return func() (value.Value, error) {
+			time.Sleep(d)
+			sink = make([]float64, size)
+			return tensor.New(tensor.WithBacking(sink)), nil
+		}
+	}
+
+	p := New(WithTrace(100), WithAllocs())
+	for run := 0; run < 3; run++ {
+		p.BeginRun()
+		if _, err := p.Exec(fc, exec(2*time.Millisecond, 1000)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Exec(act, exec(0, 10)); err != nil {
+			t.Fatal(err)
+		}
+		p.EndRun()
+	}
+
+	nodes := p.Nodes()
+	if len(nodes) != 2 || nodes[0].Name != "fc" || p.Runs() != 3 {
+		t.Fatalf("Expected fc to be the slowest of 2 nodes, over 3 runs. Got %v over %d runs", nodes, p.Runs())
+	}
+	if r := nodes[0]; r.Calls != 3 || r.Mean() < 2*time.Millisecond || r.Output != 3*8000 || r.Alloc < 3*8000 {
+		t.Errorf("Expected 3 calls of at least 2ms, with 8000 bytes allocated and returned each. Got %+v", r)
+	}
+	// the nodes have no op, so they share an op type
+	if ops := p.Ops(); len(ops) != 1 || ops[0].Calls != 6 || ops[0].Output != 3*8080 {
+		t.Errorf("Expected a single op type with the records of both nodes. Got %+v", ops)
+	}
+
+	checkPprof(t, p, []string{"calls", "wall", "output_space", "alloc_space"})
+
+	var buf bytes.Buffer
+	if err := p.WriteTrace(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var trace struct {
+		TraceEvents []traceEvent `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &trace); err != nil {
+		t.Fatal(err)
+	}
+	if len(trace.TraceEvents) != 3+6 {
+		t.Errorf("Expected 3 runs and 6 executions in the trace. Got %d events", len(trace.TraceEvents))
+	}
+
+	p.Reset()
+	if len(p.Nodes()) != 0 || p.Runs() != 0 {
+		t.Error("Expected nothing to be left after a reset")
+	}
+}
+
+func TestProfiler_NoAllocs(t *testing.T) {
+	g := exprgraph.NewGraph()
+	n := g.NewVertex()
+	n.Name = "n"
+	g.AddNode(n)
+
+	p := New()
+	if _, err := p.Exec(n, func() (value.Value, error) { return tensor.New(tensor.WithBacking(make([]float64, 100))), nil }); err != nil {
+		t.Fatal(err)
+	}
+	if r := p.Nodes()[0]; r.Alloc != 0 || r.Output != 800 {
+		t.Errorf("Expected the allocations not to be counted by default. Got %+v", r)
+	}
+	checkPprof(t, p, []string{"calls", "wall", "output_space"})
+
+	// the IDs of nodes are only unique within their graph
+	g2 := exprgraph.NewGraph()
+	m := g2.NewVertex()
+	m.Name = "m"
+	g2.AddNode(m)
+	if _, err := p.Exec(m, func() (value.Value, error) { return nil, nil }); err != nil {
+		t.Fatal(err)
+	}
+	if nodes := p.Nodes(); len(nodes) != 2 {
+		t.Errorf("Expected n and m to be recorded apart, although they have the same ID. Got %+v", nodes)
+	}
+}
+
+// checkPprof checks that the pprof profile of p is valid, and that its samples are the records of the nodes,
+// each called from its op type
+func checkPprof(t *testing.T, p *Profiler, sampleTypes []string) {
+	var buf bytes.Buffer
+	if err := p.WritePprof(&buf); err != nil {
+		t.Fatal(err)
+	}
+	prof, err := profile.Parse(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = prof.CheckValid(); err != nil {
+		t.Fatal(err)
+	}
+
+	var types []string
+	for _, st := range prof.SampleType {
+		types = append(types, st.Type)
+	}
+	if !reflect.DeepEqual(types, sampleTypes) || prof.DefaultSampleType != "wall" {
+		t.Errorf("Expected the sample types %v, with wall as the default. Got %v and %v", sampleTypes, types, prof.DefaultSampleType)
+	}
+
+	nodes := p.Nodes()
+	if len(prof.Sample) != len(nodes) {
+		t.Fatalf("Expected a sample per node. Got %d samples for %d nodes", len(prof.Sample), len(nodes))
+	}
+	for i, s := range prof.Sample {
+		r := nodes[i]
+		if len(s.Location) != 2 || len(s.Location[0].Line) != 1 || len(s.Location[1].Line) != 1 {
+			t.Errorf("Expected the sample of %v to have the node and its op type as locations. Got %v", r.Name, s.Location)
+			continue
+		}
+		if name, op := s.Location[0].Line[0].Function.Name, s.Location[1].Line[0].Function.Name; name != r.Name || op != r.OpType {
+			t.Errorf("Expected the sample of %v to be called from %v. Got %v called from %v", r.Name, r.OpType, name, op)
+		}
+		values := []int64{int64(r.Calls), int64(r.Time), int64(r.Output)}
+		if len(sampleTypes) == 4 {
+			values = append(values, int64(r.Alloc))
+		}
+		if !reflect.DeepEqual(s.Value, values) {
+			t.Errorf("Expected the sample of %v to have the values %v. Got %v", r.Name, values, s.Value)
+		}
+		if ops := s.Label["op"]; len(ops) != 1 || ops[0] != r.OpType {
+			t.Errorf("Expected the sample of %v to be labelled with its op type %v. Got %v", r.Name, r.OpType, s.Label)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package profiler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// traceEvent is a complete event of the Chrome trace event format
+type traceEvent struct {
+	Name     string                 `json:"name"`
+	Category string                 `json:"cat"`
+	Phase    string                 `json:"ph"`
+	Ts       float64                `json:"ts"`  // µs
+	Dur      float64                `json:"dur"` // µs
+	Pid      int                    `json:"pid"`
+	Tid      int                    `json:"tid"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+}
+
+// WriteTrace writes the executions kept (see WithTrace) as Chrome trace event JSON, for chrome://tracing or Perfetto.
+// Every execution is a span named after its node, in the category of its op type, within the span of its run if there are runs.
+func (p *Profiler) WriteTrace(w io.Writer) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	µs := func(d time.Duration) float64 { return float64(d) / float64(time.Microsecond) }
+	events := make([]traceEvent, 0, len(p.runEvents)+len(p.events))
+	for _, e := range p.runEvents {
+		events = append(events, traceEvent{
+			Name: fmt.Sprintf("run %d", e.run), Category: "run", Phase: "X",
+			Ts: µs(e.start), Dur: µs(e.dur), Pid: 1, Tid: 1,
+		})
+	}
+	for _, e := range p.events {
+		args := map[string]interface{}{"op": e.opType, "output_bytes": e.output, "run": e.run}
+		if p.allocs {
+			args["alloc_bytes"] = e.alloc
+		}
+		events = append(events, traceEvent{
+			Name: e.name, Category: e.opType, Phase: "X",
+			Ts: µs(e.start), Dur: µs(e.dur), Pid: 1, Tid: 1,
+			Args: args,
+		})
+	}
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{events, "ns"})
+}
@@ -0,0 +1,147 @@
+package solver
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/value"
+)
+
+// LossScaler implements dynamic loss scaling for mixed-precision training, where the small gradients of a loss computed in
+// half precision would underflow to 0.
+//
+// The loss is multiplied by Scale before it is differentiated (e.g. by multiplying it by a scalar node that is given Scale before
+// every run). Step then divides the gradients by the scale before handing them to the solver. When a gradient holds an Inf or a NaN,
+// the scale was too large: the step is skipped and the scale is backed off. After a number of steps in a row without any, the scale grows again.
+//
+// The state of a LossScaler, including its factors and interval, can be saved with MarshalBinary and restored with UnmarshalBinary,
+// even into a zero LossScaler.
+type LossScaler struct {
+	Current   float64 `json:"scale"`
+	GoodSteps int     `json:"good_steps"` // steps in a row without an Inf or a NaN
+	Skipped   int     `json:"skipped"`    // steps skipped so far
+
+	Growth   float64 `json:"growth"`
+	Backoff  float64 `json:"backoff"`
+	Interval int     `json:"interval"`
+}
+
+// NewLossScaler creates a LossScaler that starts at the given scale. The scale is multiplied by backoff on an overflow,
+// and by growth after interval steps without one. 2¹⁶, 2, 0.5 and 2000 are the usual values.
+func NewLossScaler(init, growth, backoff float64, interval int) (*LossScaler, error) {
+	s := &LossScaler{Current: init, Growth: growth, Backoff: backoff, Interval: interval}
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *LossScaler) check() error {
+	switch {
+	case s.Current <= 0:
+		return errors.Errorf("Expected a positive scale. Got %v", s.Current)
+	case s.Growth < 1:
+		return errors.Errorf("Expected a growth factor of at least 1. Got %v", s.Growth)
+	case s.Backoff <= 0 || s.Backoff >= 1:
+		return errors.Errorf("Expected a backoff factor in (0, 1). Got %v", s.Backoff)
+	case s.Interval <= 0:
+		return errors.Errorf("Expected a positive growth interval. Got %d", s.Interval)
+	}
+	return nil
+}
+
+// Scale returns the scale the loss has to be multiplied by.
+func (s *LossScaler) Scale() float64 { return s.Current }
+
+// Step unscales the gradients of the parameters and has the solver take a step with them, unless one of them holds an Inf or a NaN.
+// It then updates the scale. It returns whether the solver took a step.
+//
+// The gradients held by the parameters are left untouched: the solver is given unscaled copies.
+func (s *LossScaler) Step(solver Solver, params []value.Grader) (stepped bool, err error) {
+	unscaled := make([]value.Grader, len(params))
+	finite := true
+	for i, p := range params {
+		gv, err := p.Grad()
+		if err != nil {
+			return false, errors.Wrapf(err, "Parameter %d", i)
+		}
+		var ok bool
+		if gv, ok, err = unscale(gv, 1/s.Current); err != nil {
+			return false, errors.Wrapf(err, "Parameter %d", i)
+		}
+		finite = finite && ok
+		unscaled[i] = withGrad{Grader: p, grad: gv}
+	}
+
+	if !finite {
+		s.Current *= s.Backoff
+		s.GoodSteps = 0
+		s.Skipped++
+		return false, nil
+	}
+	if err = solver.Step(unscaled); err != nil {
+		return false, err
+	}
+	if s.GoodSteps++; s.GoodSteps == s.Interval {
+		s.Current *= s.Growth
+		s.GoodSteps = 0
+	}
+	return true, nil
+}
+
+// MarshalBinary ...
+func (s *LossScaler) MarshalBinary() ([]byte, error) { return json.Marshal(s) }
+
+// UnmarshalBinary restores a state saved by MarshalBinary. A state that NewLossScaler would reject is an error, and leaves s as it was.
+func (s *LossScaler) UnmarshalBinary(data []byte) error {
+	var restored LossScaler
+	if err := json.Unmarshal(data, &restored); err != nil {
+		return errors.Wrap(err, "Failed to restore loss scaler")
+	}
+	if err := restored.check(); err != nil {
+		return errors.Wrap(err, "Failed to restore loss scaler")
+	}
+	*s = restored
+	return nil
+}
+
+// withGrad is a parameter with another gradient
+type withGrad struct {
+	value.Grader
+	grad value.Value
+}
+
+func (p withGrad) Grad() (value.Value, error) { return p.grad, nil }
+
+// unscale returns a copy of the gradient gv multiplied by inv, and whether all its elements are finite
+func unscale(gv value.Value, inv float64) (retVal value.Value, finite bool, err error) {
+	var data []float64
+	var write func()
+	if sparse, ok := gv.(*value.SparseRows); ok {
+		var c *value.SparseRows
+		if c, err = cloneSparse(sparse); err != nil {
+			return nil, false, err
+		}
+		retVal = c
+		data, write, err = floats(c.Rows)
+	} else {
+		if retVal, err = value.CloneValue(gv); err != nil {
+			return nil, false, err
+		}
+		data, write, err = floats(retVal)
+	}
+	if err != nil {
+		return nil, false, errors.Wrap(err, "Gradient")
+	}
+
+	finite = true
+	for i, g := range data {
+		if math.IsInf(g, 0) || math.IsNaN(g) {
+			finite = false
+		}
+		data[i] = g * inv
+	}
+	write()
+	return retVal, finite, nil
+}
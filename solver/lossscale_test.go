@@ -0,0 +1,97 @@
+package solver
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+// scaledQuadratic is a quadratic whose gradient is that of a loss multiplied by the scale of a LossScaler.
+// The gradient overflows when the scale is above max.
+type scaledQuadratic struct {
+	*quadratic
+	s   *LossScaler
+	max float64
+}
+
+func (q scaledQuadratic) Grad() (value.Value, error) {
+	gv, err := q.quadratic.Grad()
+	if err != nil {
+		return nil, err
+	}
+	g := gv.(*tensor.Dense).Float64s()
+	for i := range g {
+		if q.s.Scale() > q.max {
+			g[i] = math.Inf(1)
+			continue
+		}
+		g[i] *= q.s.Scale()
+	}
+	return gv, nil
+}
+
+func TestLossScaler(t *testing.T) {
+	s, err := NewLossScaler(1024, 2, 0.5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q, ref := newQuadratic(1, -2, 0.5), newQuadratic(1, -2, 0.5)
+	p := scaledQuadratic{quadratic: q, s: s, max: 4096}
+	sgd, refSGD := NewSGD(WithLearnRate(0.1)), NewSGD(WithLearnRate(0.1))
+
+	// the scale grows every 3 steps until it overflows at 8192, where the step is skipped and the scale goes back to 4096
+	for i := 0; i < 20; i++ {
+		overflow := s.Scale() > p.max
+		stepped, err := s.Step(sgd, []value.Grader{p})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stepped == overflow {
+			t.Fatalf("Step %d: expected a step to be taken only without an overflow. Stepped: %t, overflow: %t", i, stepped, overflow)
+		}
+		if stepped {
+			if err = refSGD.Step([]value.Grader{ref}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for j, w := range q.w.Float64s() {
+			if math.Abs(w-ref.w.Float64s()[j]) > 1e-12 {
+				t.Fatalf("Step %d: expected the unscaled gradients to give %v. Got %v", i, ref.w.Float64s(), q.w.Float64s())
+			}
+		}
+	}
+	if s.Scale() != 4096 || s.GoodSteps != 2 || s.Skipped != 3 {
+		t.Errorf("Expected a scale of 4096 after 2 good steps, and 3 skipped steps. Got %v after %d, and %d", s.Scale(), s.GoodSteps, s.Skipped)
+	}
+
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored LossScaler
+	if err = restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if restored != *s {
+		t.Errorf("Expected %v. Got %v", s, restored)
+	}
+	// the restored scaler carries on with the same factors and interval
+	restored.Current = 2 * p.max
+	if stepped, err := restored.Step(sgd, []value.Grader{scaledQuadratic{quadratic: q, s: &restored, max: p.max}}); err != nil || stepped {
+		t.Fatalf("Expected an overflow to skip the step. Stepped: %t, %v", stepped, err)
+	}
+	if restored.Scale() != p.max {
+		t.Errorf("Expected the scale to be backed off to %v. Got %v", p.max, restored.Scale())
+	}
+	if err = restored.UnmarshalBinary([]byte(`{"scale": 1}`)); err == nil {
+		t.Errorf("Expected a state without factors to be rejected")
+	}
+
+	for _, args := range [][4]float64{{0, 2, 0.5, 3}, {1, 0.5, 0.5, 3}, {1, 2, 1, 3}, {1, 2, 0.5, 0}} {
+		if _, err = NewLossScaler(args[0], args[1], args[2], int(args[3])); err == nil {
+			t.Errorf("Expected %v to fail", args)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package solver
+
+import "github.com/pkg/errors"
+
+// config holds the options of a solver. Not every solver uses every option.
+type config struct {
+	eta       float64 // learning rate
+	batchSize float64 // the gradients are divided by the batch size
+
+	l1reg, l2reg       float64
+	useL1Reg, useL2Reg bool
+
+	clip, clipNorm       float64
+	useClip, useClipNorm bool
+
+	eps          float64 // smoothing term
+	beta1, beta2 float64 // decay rates of the moments in Adam and Lion
+	rho          float64 // decay rate in RMSProp and Adadelta
+	momentum     float64
+	nesterov     bool
+	weightDecay  float64 // decoupled weight decay
+
+	err error // the first invalid option, returned by every step
+}
+
+// invalid records the first invalid option
+func (c *config) invalid(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// SolverOpt is a function that provides construction options for a Solver
+type SolverOpt func(c *config)
+
+// WithLearnRate sets the learning rate of the solver.
+func WithLearnRate(eta float64) SolverOpt {
+	return func(c *config) { c.eta = eta }
+}
+
+// WithBatchSize sets the batch size. The gradients are divided by the batch size before being used.
+// A batch size that isn't positive is invalid: the solver then returns an error from Step.
+func WithBatchSize(size float64) SolverOpt {
+	return func(c *config) {
+		if size <= 0 {
+			c.invalid(errors.Errorf("Expected a positive batch size. Got %v", size))
+			return
+		}
+		c.batchSize = size
+	}
+}
+
+// WithL1Reg adds λ·sign(w) to the gradients, which is the gradient of the L1 regularization λ·|w|.
+func WithL1Reg(l1reg float64) SolverOpt {
+	return func(c *config) {
+		c.l1reg = l1reg
+		c.useL1Reg = true
+	}
+}
+
+// WithL2Reg adds λ·w to the gradients, which is the gradient of the L2 regularization λ·w²/2.
+func WithL2Reg(l2reg float64) SolverOpt {
+	return func(c *config) {
+		c.l2reg = l2reg
+		c.useL2Reg = true
+	}
+}
+
+// WithClip clips each element of the gradients to [-clip, clip].
+func WithClip(clip float64) SolverOpt {
+	return func(c *config) {
+		c.clip = clip
+		c.useClip = true
+	}
+}
+
+// WithClipNorm scales the gradients down so that the L2 norm of all the gradients together is at most norm.
+func WithClipNorm(norm float64) SolverOpt {
+	return func(c *config) {
+		c.clipNorm = norm
+		c.useClipNorm = true
+	}
+}
+
+// WithEps sets the smoothing term that avoids divisions by zero.
+func WithEps(eps float64) SolverOpt {
+	return func(c *config) { c.eps = eps }
+}
+
+// WithBeta1 sets the decay rate of the first moment (Adam) or the interpolation factor of the update (Lion).
+func WithBeta1(beta1 float64) SolverOpt {
+	return func(c *config) { c.beta1 = beta1 }
+}
+
+// WithBeta2 sets the decay rate of the second moment (Adam) or of the momentum (Lion).
+func WithBeta2(beta2 float64) SolverOpt {
+	return func(c *config) { c.beta2 = beta2 }
+}
+
+// WithRho sets the decay rate of the moving averages of RMSProp and Adadelta.
+func WithRho(rho float64) SolverOpt {
+	return func(c *config) { c.rho = rho }
+}
+
+// WithMomentum sets the momentum of SGD.
+func WithMomentum(momentum float64) SolverOpt {
+	return func(c *config) { c.momentum = momentum }
+}
+
+// WithNesterov makes SGD use Nesterov momentum.
+func WithNesterov() SolverOpt {
+	return func(c *config) { c.nesterov = true }
+}
+
+// WithWeightDecay sets the decoupled weight decay λ: every step also moves the weights by -ηλw. Unlike WithL2Reg, the decay is applied
+// to the weights directly, and is not scaled by the adaptive terms or the momentum of the solver.
+func WithWeightDecay(decay float64) SolverOpt {
+	return func(c *config) { c.weightDecay = decay }
+}
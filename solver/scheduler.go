@@ -0,0 +1,427 @@
+package solver
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	_ Scheduler       = &StepLR{}
+	_ Scheduler       = &ExponentialLR{}
+	_ Scheduler       = &CosineAnnealingWarmRestarts{}
+	_ Scheduler       = &LinearWarmup{}
+	_ Scheduler       = &OneCycle{}
+	_ MetricScheduler = &ReduceOnPlateau{}
+)
+
+// Scheduler adjusts the learning rate of a solver as training goes on.
+//
+// A Scheduler sets the initial learning rate of its target when it's created. Every call to Step advances the schedule by one step
+// (whether a step is a batch or an epoch is up to the training loop) and sets the learning rate of the target.
+//
+// The state of a Scheduler can be saved with MarshalBinary and restored with UnmarshalBinary, which also sets the learning rate of the target.
+type Scheduler interface {
+	Step() float64
+	LearningRate() float64
+
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// MetricScheduler is a Scheduler that adjusts the learning rate based on a metric, such as the validation loss.
+// The metric has to be observed before calling Step.
+type MetricScheduler interface {
+	Scheduler
+	Observe(metric float64)
+}
+
+// schedule is what all the schedulers have in common
+type schedule struct {
+	target LearningRateSetter
+	Steps  int     `json:"steps"`
+	LR     float64 `json:"lr"`
+}
+
+// LearningRate returns the current learning rate.
+func (s *schedule) LearningRate() float64 { return s.LR }
+
+func (s *schedule) set(lr float64) float64 {
+	s.LR = lr
+	s.target.SetLearningRate(lr)
+	return lr
+}
+
+func marshalSchedule(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// unmarshalSchedule restores a schedule, and sets the learning rate of the target to the restored learning rate.
+func unmarshalSchedule(data []byte, v interface{}, s *schedule) error {
+	if err := json.Unmarshal(data, v); err != nil {
+		return errors.Wrap(err, "Failed to restore scheduler")
+	}
+	s.set(s.LR)
+	return nil
+}
+
+// StepLR decays the learning rate by gamma every stepSize steps.
+type StepLR struct {
+	schedule
+	base     float64
+	stepSize int
+	gamma    float64
+}
+
+// NewStepLR creates a StepLR scheduler starting at the learning rate base.
+func NewStepLR(target LearningRateSetter, base float64, stepSize int, gamma float64) (*StepLR, error) {
+	if stepSize <= 0 {
+		return nil, errors.Errorf("Expected a positive step size. Got %d", stepSize)
+	}
+	s := &StepLR{schedule: schedule{target: target}, base: base, stepSize: stepSize, gamma: gamma}
+	s.set(base)
+	return s, nil
+}
+
+// Step advances the schedule.
+func (s *StepLR) Step() float64 {
+	s.Steps++
+	return s.set(s.base * math.Pow(s.gamma, float64(s.Steps/s.stepSize)))
+}
+
+// MarshalBinary ...
+func (s *StepLR) MarshalBinary() ([]byte, error) { return marshalSchedule(s) }
+
+// UnmarshalBinary ...
+func (s *StepLR) UnmarshalBinary(data []byte) error { return unmarshalSchedule(data, s, &s.schedule) }
+
+// ExponentialLR decays the learning rate by gamma every step.
+type ExponentialLR struct {
+	schedule
+	base  float64
+	gamma float64
+}
+
+// NewExponentialLR creates an ExponentialLR scheduler starting at the learning rate base.
+func NewExponentialLR(target LearningRateSetter, base, gamma float64) *ExponentialLR {
+	s := &ExponentialLR{schedule: schedule{target: target}, base: base, gamma: gamma}
+	s.set(base)
+	return s
+}
+
+// Step advances the schedule.
+func (s *ExponentialLR) Step() float64 {
+	s.Steps++
+	return s.set(s.base * math.Pow(s.gamma, float64(s.Steps)))
+}
+
+// MarshalBinary ...
+func (s *ExponentialLR) MarshalBinary() ([]byte, error) { return marshalSchedule(s) }
+
+// UnmarshalBinary ...
+func (s *ExponentialLR) UnmarshalBinary(data []byte) error {
+	return unmarshalSchedule(data, s, &s.schedule)
+}
+
+// CosineAnnealingWarmRestarts anneals the learning rate from base to min following a cosine, and restarts at base at the end of each cycle,
+// as described in "SGDR: Stochastic Gradient Descent with Warm Restarts" (Loshchilov and Hutter, 2016) - https://arxiv.org/abs/1608.03983.
+//
+// The first cycle lasts t0 steps. Each cycle is tMult times longer than the previous one.
+type CosineAnnealingWarmRestarts struct {
+	schedule
+	base, min float64
+	tMult     int
+
+	Cycle    int `json:"cycle"`    // length of the current cycle
+	InCycle  int `json:"in_cycle"` // steps since the last restart
+	Restarts int `json:"restarts"` // number of restarts so far
+}
+
+// NewCosineAnnealingWarmRestarts creates a CosineAnnealingWarmRestarts scheduler.
+func NewCosineAnnealingWarmRestarts(target LearningRateSetter, base, min float64, t0, tMult int) (*CosineAnnealingWarmRestarts, error) {
+	if t0 <= 0 || tMult < 1 {
+		return nil, errors.Errorf("Expected a positive t0 and a tMult of at least 1. Got %d and %d", t0, tMult)
+	}
+	s := &CosineAnnealingWarmRestarts{schedule: schedule{target: target}, base: base, min: min, tMult: tMult, Cycle: t0}
+	s.set(base)
+	return s, nil
+}
+
+// Step advances the schedule.
+func (s *CosineAnnealingWarmRestarts) Step() float64 {
+	s.Steps++
+	s.InCycle++
+	if s.InCycle >= s.Cycle {
+		s.InCycle = 0
+		s.Cycle *= s.tMult
+		s.Restarts++
+	}
+	return s.set(s.min + (s.base-s.min)*(1+math.Cos(math.Pi*float64(s.InCycle)/float64(s.Cycle)))/2)
+}
+
+// MarshalBinary ...
+func (s *CosineAnnealingWarmRestarts) MarshalBinary() ([]byte, error) { return marshalSchedule(s) }
+
+// UnmarshalBinary ...
+func (s *CosineAnnealingWarmRestarts) UnmarshalBinary(data []byte) error {
+	return unmarshalSchedule(data, s, &s.schedule)
+}
+
+// LinearWarmup increases the learning rate linearly from start to base over the given number of steps.
+// After the warmup, the learning rate stays at base, unless another Scheduler is given to take over.
+type LinearWarmup struct {
+	schedule
+	start, base float64
+	warmup      int
+	then        Scheduler
+}
+
+// NewLinearWarmup creates a LinearWarmup scheduler. then may be nil.
+// If then is not nil, it should have been created with the same target, and its first step is taken once the warmup is over.
+func NewLinearWarmup(target LearningRateSetter, start, base float64, warmup int, then Scheduler) (*LinearWarmup, error) {
+	if warmup <= 0 {
+		return nil, errors.Errorf("Expected a positive number of warmup steps. Got %d", warmup)
+	}
+	s := &LinearWarmup{schedule: schedule{target: target}, start: start, base: base, warmup: warmup, then: then}
+	s.set(start)
+	return s, nil
+}
+
+// Step advances the schedule.
+func (s *LinearWarmup) Step() float64 {
+	s.Steps++
+	switch {
+	case s.Steps < s.warmup:
+		return s.set(s.start + (s.base-s.start)*float64(s.Steps)/float64(s.warmup))
+	case s.Steps == s.warmup || s.then == nil:
+		return s.set(s.base)
+	}
+	s.LR = s.then.Step()
+	return s.LR
+}
+
+type linearWarmupState struct {
+	*LinearWarmup
+	Then []byte `json:"then,omitempty"`
+}
+
+// MarshalBinary ...
+func (s *LinearWarmup) MarshalBinary() ([]byte, error) {
+	st := linearWarmupState{LinearWarmup: s}
+	if s.then != nil {
+		var err error
+		if st.Then, err = s.then.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	}
+	return marshalSchedule(st)
+}
+
+// UnmarshalBinary ...
+func (s *LinearWarmup) UnmarshalBinary(data []byte) error {
+	st := linearWarmupState{LinearWarmup: s}
+	if err := json.Unmarshal(data, &st); err != nil {
+		return errors.Wrap(err, "Failed to restore scheduler")
+	}
+	if s.then != nil && st.Then != nil {
+		if err := s.then.UnmarshalBinary(st.Then); err != nil {
+			return err
+		}
+	}
+	s.set(s.LR)
+	return nil
+}
+
+// OneCycle is the one cycle policy described in "Super-Convergence: Very Fast Training of Neural Networks Using Large Learning Rates"
+// (Smith and Topin, 2017) - https://arxiv.org/abs/1708.07120.
+//
+// The learning rate rises from max/divFactor to max during the first pctStart of the steps, then anneals down to max/(divFactor × finalDivFactor).
+// Both phases follow a cosine.
+type OneCycle struct {
+	schedule
+	max, initial, final float64
+	total, peak         int
+}
+
+// NewOneCycle creates a OneCycle scheduler over total steps. The usual values are pctStart = 0.3, divFactor = 25 and finalDivFactor = 1e4.
+func NewOneCycle(target LearningRateSetter, max float64, total int, pctStart, divFactor, finalDivFactor float64) (*OneCycle, error) {
+	if total <= 0 {
+		return nil, errors.Errorf("Expected a positive number of steps. Got %d", total)
+	}
+	if pctStart <= 0 || pctStart >= 1 {
+		return nil, errors.Errorf("Expected pctStart to be in (0, 1). Got %v", pctStart)
+	}
+	initial := max / divFactor
+	s := &OneCycle{
+		schedule: schedule{target: target},
+		max:      max,
+		initial:  initial,
+		final:    initial / finalDivFactor,
+		total:    total,
+		peak:     int(pctStart * float64(total)),
+	}
+	s.set(initial)
+	return s, nil
+}
+
+// Step advances the schedule. After the total number of steps, the learning rate stays at its final value.
+func (s *OneCycle) Step() float64 {
+	s.Steps++
+	if s.Steps <= s.peak {
+		return s.set(cosineAnneal(s.initial, s.max, float64(s.Steps)/float64(s.peak)))
+	}
+	pct := math.Min(1, float64(s.Steps-s.peak)/float64(s.total-s.peak))
+	return s.set(cosineAnneal(s.max, s.final, pct))
+}
+
+// MarshalBinary ...
+func (s *OneCycle) MarshalBinary() ([]byte, error) { return marshalSchedule(s) }
+
+// UnmarshalBinary ...
+func (s *OneCycle) UnmarshalBinary(data []byte) error { return unmarshalSchedule(data, s, &s.schedule) }
+
+// cosineAnneal goes from start to end following a cosine as pct goes from 0 to 1.
+func cosineAnneal(start, end, pct float64) float64 {
+	return end + (start-end)*(1+math.Cos(math.Pi*pct))/2
+}
+
+// PlateauMode is whether ReduceOnPlateau minimizes or maximizes the metric.
+type PlateauMode byte
+
+const (
+	MinMetric PlateauMode = iota // the metric is minimized (e.g. a validation loss)
+	MaxMetric                    // the metric is maximized (e.g. an accuracy)
+)
+
+func (m PlateauMode) String() string {
+	switch m {
+	case MinMetric:
+		return "min"
+	case MaxMetric:
+		return "max"
+	}
+	return fmt.Sprintf("PlateauMode(%d)", byte(m))
+}
+
+// MarshalText ...
+func (m PlateauMode) MarshalText() ([]byte, error) {
+	if m > MaxMetric {
+		return nil, errors.Errorf("Unknown plateau mode %v", m)
+	}
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText ...
+func (m *PlateauMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "min":
+		*m = MinMetric
+	case "max":
+		*m = MaxMetric
+	default:
+		return errors.Errorf("Unknown plateau mode %q", text)
+	}
+	return nil
+}
+
+// ReduceOnPlateau multiplies the learning rate by factor when the observed metric has not improved for more than patience steps.
+// The metric is minimized (e.g. a validation loss) or maximized (e.g. an accuracy), depending on the mode. It is only an improvement
+// if it's better than the best metric by a relative threshold.
+type ReduceOnPlateau struct {
+	schedule
+	factor    float64
+	patience  int
+	threshold float64
+	min       float64
+
+	Mode     PlateauMode `json:"mode"`
+	Best     float64     `json:"best"`
+	Bad      int         `json:"bad"` // number of steps without improvement
+	Observed bool        `json:"observed"`
+	metric   float64
+}
+
+// NewReduceOnPlateau creates a ReduceOnPlateau scheduler starting at the learning rate base. The learning rate never goes below min.
+func NewReduceOnPlateau(target LearningRateSetter, mode PlateauMode, base, factor float64, patience int, threshold, min float64) (*ReduceOnPlateau, error) {
+	if mode > MaxMetric {
+		return nil, errors.Errorf("Unknown plateau mode %v", mode)
+	}
+	if factor <= 0 || factor >= 1 {
+		return nil, errors.Errorf("Expected factor to be in (0, 1). Got %v", factor)
+	}
+	s := &ReduceOnPlateau{
+		schedule:  schedule{target: target},
+		factor:    factor,
+		patience:  patience,
+		threshold: threshold,
+		min:       min,
+		Mode:      mode,
+		Best:      math.Inf(1),
+	}
+	if mode == MaxMetric {
+		s.Best = math.Inf(-1)
+	}
+	s.set(base)
+	return s, nil
+}
+
+// Observe records the metric to use in the next Step.
+func (s *ReduceOnPlateau) Observe(metric float64) {
+	s.metric = metric
+	s.Observed = true
+}
+
+// Step advances the schedule. If no metric has been observed since the last step, the learning rate is left as it is.
+func (s *ReduceOnPlateau) Step() float64 {
+	s.Steps++
+	if !s.Observed {
+		return s.LR
+	}
+	s.Observed = false
+
+	if s.improved() {
+		s.Best = s.metric
+		s.Bad = 0
+		return s.LR
+	}
+	s.Bad++
+	if s.Bad > s.patience {
+		s.Bad = 0
+		return s.set(math.Max(s.LR*s.factor, s.min))
+	}
+	return s.LR
+}
+
+// improved checks whether the observed metric is better than the best metric so far
+func (s *ReduceOnPlateau) improved() bool {
+	if math.IsInf(s.Best, 0) {
+		return true
+	}
+	if s.Mode == MaxMetric {
+		return s.metric > s.Best*(1+s.threshold)
+	}
+	return s.metric < s.Best*(1-s.threshold)
+}
+
+// MarshalBinary ...
+func (s *ReduceOnPlateau) MarshalBinary() ([]byte, error) {
+	if math.IsInf(s.Best, 0) {
+		// JSON cannot hold infinities, so a missing best metric is marshalled as ±math.MaxFloat64
+		st := *s
+		st.Best = math.Copysign(math.MaxFloat64, s.Best)
+		return marshalSchedule(&st)
+	}
+	return marshalSchedule(s)
+}
+
+// UnmarshalBinary ...
+func (s *ReduceOnPlateau) UnmarshalBinary(data []byte) error {
+	if err := unmarshalSchedule(data, s, &s.schedule); err != nil {
+		return err
+	}
+	if math.Abs(s.Best) == math.MaxFloat64 {
+		s.Best = math.Inf(int(math.Copysign(1, s.Best)))
+	}
+	return nil
+}
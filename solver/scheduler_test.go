@@ -0,0 +1,110 @@
+package solver
+
+import (
+	"math"
+	"testing"
+)
+
+type lrRecorder struct{ lr float64 }
+
+func (r *lrRecorder) SetLearningRate(eta float64) { r.lr = eta }
+
+func TestSchedulers(t *testing.T) {
+	r := new(lrRecorder)
+	step, _ := NewStepLR(r, 1, 2, 0.5)
+	exp := NewExponentialLR(r, 1, 0.5)
+	cos, _ := NewCosineAnnealingWarmRestarts(r, 1, 0, 2, 2)
+	warmup, _ := NewLinearWarmup(r, 0, 1, 4, nil)
+	oneCycle, _ := NewOneCycle(r, 1, 10, 0.2, 10, 10)
+
+	schedTests := []struct {
+		name    string
+		s       Scheduler
+		correct []float64
+	}{
+		{"StepLR", step, []float64{1, 0.5, 0.5, 0.25, 0.25}},
+		{"ExponentialLR", exp, []float64{0.5, 0.25, 0.125, 0.0625, 0.03125}},
+		{"CosineAnnealingWarmRestarts", cos, []float64{0.5, 1, 0.8535533905932737, 0.5, 0.14644660940672627, 1}},
+		{"LinearWarmup", warmup, []float64{0.25, 0.5, 0.75, 1, 1}},
+		{"OneCycle", oneCycle, []float64{0.55, 1, 0.9623204, 0.8550179, 0.6944283}},
+	}
+
+	for _, st := range schedTests {
+		for i, want := range st.correct {
+			got := st.s.Step()
+			if math.Abs(got-want) > 1e-6 || r.lr != got {
+				t.Errorf("%v: step %d - expected %v. Got %v (target has %v)", st.name, i+1, want, got, r.lr)
+			}
+		}
+	}
+}
+
+func TestReduceOnPlateau(t *testing.T) {
+	r := new(lrRecorder)
+	s, err := NewReduceOnPlateau(r, MinMetric, 1, 0.1, 1, 0, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrics := []float64{5, 4, 4, 4, 4, 4, 4, 4}
+	correct := []float64{1, 1, 1, 0.1, 0.1, 0.01, 0.01, 0.001}
+	for i, m := range metrics {
+		s.Observe(m)
+		if got := s.Step(); math.Abs(got-correct[i]) > 1e-12 {
+			t.Errorf("Step %d: expected %v. Got %v", i+1, correct[i], got)
+		}
+	}
+
+	// restoring the state restores the learning rate of the target
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2 := new(lrRecorder)
+	s2, _ := NewReduceOnPlateau(r2, MinMetric, 1, 0.1, 1, 0, 0.001)
+	if err = s2.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if r2.lr != s.LearningRate() || s2.Best != 4 {
+		t.Errorf("Expected the state to be restored. Got learning rate %v and best %v", r2.lr, s2.Best)
+	}
+}
+
+func TestReduceOnPlateau_Max(t *testing.T) {
+	r := new(lrRecorder)
+	s, err := NewReduceOnPlateau(r, MaxMetric, 1, 0.1, 1, 0.1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 0.62 and 0.65 are not 10% better than 0.6
+	metrics := []float64{0.5, 0.6, 0.62, 0.65, 0.4}
+	correct := []float64{1, 1, 1, 0.1, 0.1}
+	for i, m := range metrics {
+		s.Observe(m)
+		if got := s.Step(); math.Abs(got-correct[i]) > 1e-12 {
+			t.Errorf("Step %d: expected %v. Got %v", i+1, correct[i], got)
+		}
+	}
+
+	// the mode is part of the state, and so is a best metric of -∞
+	fresh, _ := NewReduceOnPlateau(new(lrRecorder), MaxMetric, 1, 0.1, 1, 0.1, 0)
+	for _, st := range []*ReduceOnPlateau{s, fresh} {
+		data, err := st.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		s2, _ := NewReduceOnPlateau(new(lrRecorder), MinMetric, 1, 0.1, 1, 0.1, 0)
+		if err = s2.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if s2.Mode != MaxMetric || s2.Best != st.Best {
+			t.Errorf("Expected the mode %v and the best metric %v to be restored. Got %v and %v", MaxMetric, st.Best, s2.Mode, s2.Best)
+		}
+	}
+
+	if _, err = NewReduceOnPlateau(r, PlateauMode(2), 1, 0.1, 1, 0, 0); err == nil {
+		t.Errorf("Expected an unknown mode to return an error")
+	}
+	if err = s.UnmarshalBinary([]byte(`{"mode": "median"}`)); err == nil {
+		t.Errorf("Expected an unknown mode to fail to restore")
+	}
+}
@@ -0,0 +1,368 @@
+// Package solver provides the optimizers that update the parameters of a model using their gradients.
+package solver
+
+import (
+	"encoding/json"
+	"math"
+
+	"github.com/pkg/errors"
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+// Solver is anything that updates the values of the parameters, given their gradients.
+// The parameters are updated in place.
+//
+// A Solver keeps a state for each parameter (e.g. the moving averages of Adam). The state of params[i] is
+// the state of whatever parameter was at index i of the previous call to Step, so the parameters
+// have to be passed in the same order every time.
+//
+// When the gradient of a parameter is a *value.SparseRows (e.g. the gradient of an embedding), only the rows with a gradient
+// are updated, and only the state of those rows moves. The other rows are left as they are, rather than being updated with a zero gradient.
+type Solver interface {
+	Step(params []value.Grader) error
+}
+
+// LearningRateSetter is anything whose learning rate can be set. It is all a Scheduler needs from a Solver.
+type LearningRateSetter interface {
+	SetLearningRate(eta float64)
+}
+
+// LearningRater is any Solver whose learning rate can be changed during training.
+type LearningRater interface {
+	LearningRateSetter
+	LearningRate() float64
+}
+
+// Checkpointer is any Solver whose state can be saved and restored.
+type Checkpointer interface {
+	State() *State
+	LoadState(s *State) error
+}
+
+// State is the state of a Solver. It is serializable, so training can be checkpointed and resumed.
+type State struct {
+	// Steps is the number of steps the solver has taken.
+	Steps int `json:"steps"`
+	// Slots holds the state of each parameter: Slots[i] is the list of buffers (e.g. the first and second moments) of params[i].
+	Slots [][][]float64 `json:"slots"`
+}
+
+// MarshalBinary encodes the state as JSON.
+func (s *State) MarshalBinary() ([]byte, error) { return json.Marshal(s) }
+
+// UnmarshalBinary decodes a state encoded by MarshalBinary.
+func (s *State) UnmarshalBinary(data []byte) error { return json.Unmarshal(data, s) }
+
+// DualValueGrader wraps a *value.DualValue so it can be passed to a Solver.
+type DualValueGrader struct{ *value.DualValue }
+
+// Value returns the value of the dual value.
+func (dv DualValueGrader) Value() value.Value { return dv.DualValue.Value }
+
+// Grad returns the derivative of the dual value.
+func (dv DualValueGrader) Grad() (value.Value, error) {
+	if dv.D == nil {
+		return nil, errors.New("DualValue has no derivative")
+	}
+	return dv.D, nil
+}
+
+// DualValues converts a list of dual values into a list of value.Grader.
+func DualValues(dvs ...*value.DualValue) []value.Grader {
+	retVal := make([]value.Grader, len(dvs))
+	for i, dv := range dvs {
+		retVal[i] = DualValueGrader{dv}
+	}
+	return retVal
+}
+
+// param is a parameter with its data viewed as float64s.
+//
+// All the solvers compute in float64. float32 parameters are copied into float64s, and copied back by write.
+//
+// When the gradient is a *value.SparseRows, only the rows with a gradient are updated: w and g then hold those rows only,
+// and rows holds the index of each of them.
+type param struct {
+	w, g  []float64
+	write func()
+
+	rows    []int
+	rowSize int
+}
+
+// floats returns the data of a value as a []float64. If the data had to be copied, write copies it back into the value.
+func floats(v value.Value) (data []float64, write func(), err error) {
+	noop := func() {}
+	switch vt := v.(type) {
+	case *tensor.Dense:
+		switch vt.Dtype() {
+		case tensor.Float64:
+			return vt.Float64s(), noop, nil
+		case tensor.Float32:
+			f32s := vt.Float32s()
+			data = make([]float64, len(f32s))
+			for i, f := range f32s {
+				data[i] = float64(f)
+			}
+			write = func() {
+				for i, f := range data {
+					f32s[i] = float32(f)
+				}
+			}
+			return data, write, nil
+		}
+	case *value.F64:
+		data = []float64{float64(*vt)}
+		return data, func() { *vt = value.F64(data[0]) }, nil
+	case *value.F32:
+		data = []float64{float64(*vt)}
+		return data, func() { *vt = value.F32(data[0]) }, nil
+	}
+	return nil, nil, errors.Errorf("Solvers only work on float32 and float64 values. Got %v of %v", v, v.Dtype())
+}
+
+// gatherRows returns the given rows of a tensor as a []float64. write copies the rows back into the tensor.
+func gatherRows(v value.Value, rows []int, size int) (data []float64, write func(), err error) {
+	t, ok := v.(*tensor.Dense)
+	if !ok {
+		return nil, nil, errors.Errorf("Sparse gradients only work on tensors. Got %T", v)
+	}
+	data = make([]float64, len(rows)*size)
+	switch t.Dtype() {
+	case tensor.Float64:
+		f64s := t.Float64s()
+		for k, r := range rows {
+			copy(data[k*size:(k+1)*size], f64s[r*size:(r+1)*size])
+		}
+		write = func() {
+			for k, r := range rows {
+				copy(f64s[r*size:(r+1)*size], data[k*size:(k+1)*size])
+			}
+		}
+	case tensor.Float32:
+		f32s := t.Float32s()
+		for k, r := range rows {
+			for j := 0; j < size; j++ {
+				data[k*size+j] = float64(f32s[r*size+j])
+			}
+		}
+		write = func() {
+			for k, r := range rows {
+				for j := 0; j < size; j++ {
+					f32s[r*size+j] = float32(data[k*size+j])
+				}
+			}
+		}
+	default:
+		return nil, nil, errors.Errorf("Solvers only work on float32 and float64 values. Got %v", t.Dtype())
+	}
+	return data, write, nil
+}
+
+// base holds what all the solvers have in common: the options, the number of steps taken and the per parameter state.
+type base struct {
+	config
+	steps int
+	slots [][][]float64
+
+	nSlots int // number of buffers per parameter
+}
+
+func newBase(nSlots int, defaults config, opts ...SolverOpt) base {
+	b := base{config: defaults, nSlots: nSlots}
+	for _, opt := range opts {
+		opt(&b.config)
+	}
+	return b
+}
+
+// LearningRate returns the current learning rate.
+func (b *base) LearningRate() float64 { return b.eta }
+
+// SetLearningRate sets the learning rate used by the next steps.
+func (b *base) SetLearningRate(eta float64) { b.eta = eta }
+
+// State returns a copy of the state of the solver.
+func (b *base) State() *State { return &State{Steps: b.steps, Slots: cloneSlots(b.slots)} }
+
+// LoadState restores the state of the solver from s. The sizes of the buffers are checked at the next step.
+func (b *base) LoadState(s *State) error {
+	for i, slots := range s.Slots {
+		if len(slots) != b.nSlots {
+			return errors.Errorf("Expected %d buffers for parameter %d. Got %d", b.nSlots, i, len(slots))
+		}
+	}
+	b.steps = s.Steps
+	b.slots = cloneSlots(s.Slots)
+	return nil
+}
+
+func cloneSlots(slots [][][]float64) [][][]float64 {
+	if slots == nil {
+		return nil
+	}
+	retVal := make([][][]float64, len(slots))
+	for i, ss := range slots {
+		retVal[i] = make([][]float64, len(ss))
+		for j, slot := range ss {
+			retVal[i][j] = append([]float64(nil), slot...)
+		}
+	}
+	return retVal
+}
+
+// prepare extracts the data and gradients of the parameters and applies the gradient options (batch size, clipping and regularization) to the gradients.
+// The gradients returned are copies, so the gradients held by the parameters are left untouched.
+func (b *base) prepare(params []value.Grader) ([]param, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.slots != nil && len(b.slots) != len(params) {
+		return nil, errors.Errorf("The solver has state for %d parameters. Got %d parameters", len(b.slots), len(params))
+	}
+	if b.slots == nil {
+		b.slots = make([][][]float64, len(params))
+	}
+
+	retVal := make([]param, len(params))
+	var sumSq float64
+	for i, p := range params {
+		gv, err := p.Grad()
+		if err != nil {
+			return nil, errors.Wrapf(err, "Parameter %d", i)
+		}
+		if sparse, ok := gv.(*value.SparseRows); ok {
+			retVal[i], err = sparseParam(p.Value(), sparse)
+		} else {
+			retVal[i], err = denseParam(p.Value(), gv)
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "Parameter %d", i)
+		}
+		if err = b.initSlots(i, p.Value().Shape().TotalSize()); err != nil {
+			return nil, err
+		}
+
+		g := retVal[i].g
+		for j := range g {
+			g[j] /= b.batchSize
+			sumSq += g[j] * g[j]
+		}
+	}
+
+	// clip by the global norm of all the gradients first, then by value
+	scale := 1.0
+	if b.useClipNorm {
+		if norm := math.Sqrt(sumSq); norm > b.clipNorm {
+			scale = b.clipNorm / norm
+		}
+	}
+	for _, p := range retVal {
+		for j, g := range p.g {
+			g *= scale
+			if b.useClip {
+				g = math.Max(-b.clip, math.Min(b.clip, g))
+			}
+			if b.useL1Reg && p.w[j] != 0 {
+				g += math.Copysign(b.l1reg, p.w[j])
+			}
+			if b.useL2Reg {
+				g += b.l2reg * p.w[j]
+			}
+			p.g[j] = g
+		}
+	}
+	return retVal, nil
+}
+
+// denseParam views a parameter and its dense gradient as float64s. The gradient is copied.
+func denseParam(v, gv value.Value) (p param, err error) {
+	if p.w, p.write, err = floats(v); err != nil {
+		return
+	}
+	var g []float64
+	if g, _, err = floats(gv); err != nil {
+		return p, errors.Wrapf(err, "Gradient")
+	}
+	if len(g) != len(p.w) {
+		return p, errors.Errorf("The parameter has %d elements but its gradient has %d", len(p.w), len(g))
+	}
+	p.g = append([]float64(nil), g...)
+	return p, nil
+}
+
+// sparseParam views the rows of a parameter that have a gradient as float64s. The rows of the gradient are coalesced first,
+// so that each row is updated once.
+func sparseParam(v value.Value, sparse *value.SparseRows) (p param, err error) {
+	if !v.Shape().Eq(sparse.Shape()) {
+		return p, errors.Errorf("The parameter has the shape %v but its gradient has the shape %v", v.Shape(), sparse.Shape())
+	}
+	if sparse, err = cloneSparse(sparse); err != nil {
+		return
+	}
+	if err = sparse.Coalesce(); err != nil {
+		return
+	}
+	p.rows, p.rowSize = sparse.Indices, sparse.RowSize()
+	if p.w, p.write, err = gatherRows(v, p.rows, p.rowSize); err != nil {
+		return
+	}
+	if p.g, _, err = floats(sparse.Rows); err != nil {
+		return p, errors.Wrapf(err, "Gradient")
+	}
+	return p, nil
+}
+
+// cloneSparse clones a sparse gradient, so that coalescing it leaves the gradient held by the parameter untouched.
+func cloneSparse(sparse *value.SparseRows) (*value.SparseRows, error) {
+	c, err := sparse.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return c.(*value.SparseRows), nil
+}
+
+func (b *base) initSlots(i, size int) error {
+	if b.slots[i] == nil {
+		b.slots[i] = make([][]float64, b.nSlots)
+		for j := range b.slots[i] {
+			b.slots[i][j] = make([]float64, size)
+		}
+		return nil
+	}
+	for _, slot := range b.slots[i] {
+		if len(slot) != size {
+			return errors.Errorf("The solver has state for %d elements for parameter %d. It has %d elements", len(slot), i, size)
+		}
+	}
+	return nil
+}
+
+// step runs the update function on every parameter, and writes the parameters back.
+func (b *base) step(params []value.Grader, update func(w, g []float64, slots [][]float64)) error {
+	ps, err := b.prepare(params)
+	if err != nil {
+		return err
+	}
+	b.steps++
+	for i, p := range ps {
+		if p.rows == nil {
+			update(p.w, p.g, b.slots[i])
+			p.write()
+			continue
+		}
+
+		// sparse update: only the rows with a gradient, and their state, are touched
+		size := p.rowSize
+		slots := make([][]float64, len(b.slots[i]))
+		for k, r := range p.rows {
+			for j, slot := range b.slots[i] {
+				slots[j] = slot[r*size : (r+1)*size]
+			}
+			update(p.w[k*size:(k+1)*size], p.g[k*size:(k+1)*size], slots)
+		}
+		p.write()
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package solver
+
+import (
+	"math"
+	"testing"
+
+	"gorgonia.org/gorgonia/internal/value"
+	"gorgonia.org/tensor"
+)
+
+// quadratic is a parameter whose gradient is that of Σ (w - target)²/2
+type quadratic struct {
+	w      *tensor.Dense
+	target []float64
+}
+
+func newQuadratic(target ...float64) *quadratic {
+	return &quadratic{
+		w:      tensor.New(tensor.WithShape(len(target)), tensor.WithBacking(make([]float64, len(target)))),
+		target: target,
+	}
+}
+
+func (q *quadratic) Value() value.Value { return q.w }
+
+func (q *quadratic) Grad() (value.Value, error) {
+	g := make([]float64, len(q.target))
+	for i, w := range q.w.Float64s() {
+		g[i] = w - q.target[i]
+	}
+	return tensor.New(tensor.WithShape(len(g)), tensor.WithBacking(g)), nil
+}
+
+func (q *quadratic) dist() (retVal float64) {
+	for i, w := range q.w.Float64s() {
+		retVal = math.Max(retVal, math.Abs(w-q.target[i]))
+	}
+	return
+}
+
+func TestSolvers_Converge(t *testing.T) {
+	solverTests := []struct {
+		name  string
+		s     Solver
+		steps int
+	}{
+		{"SGD", NewSGD(WithLearnRate(0.1)), 500},
+		{"SGD momentum", NewSGD(WithLearnRate(0.05), WithMomentum(0.9)), 500},
+		{"SGD nesterov", NewSGD(WithLearnRate(0.05), WithMomentum(0.9), WithNesterov()), 500},
+		{"Adam", NewAdam(WithLearnRate(0.05)), 2000},
+		{"AdamW", NewAdamW(WithLearnRate(0.05), WithWeightDecay(1e-4)), 2000},
+		{"RMSProp", NewRMSProp(WithLearnRate(0.01)), 2000},
+		{"Adagrad", NewAdagrad(WithLearnRate(0.5)), 2000},
+		{"Adadelta", NewAdadelta(WithLearnRate(10)), 5000},
+		{"Lion", NewLion(WithLearnRate(0.001)), 5000},
+	}
+
+	for _, st := range solverTests {
+		q := newQuadratic(1, -2, 0.5)
+		for i := 0; i < st.steps; i++ {
+			if err := st.s.Step([]value.Grader{q}); err != nil {
+				t.Fatalf("%v: %v", st.name, err)
+			}
+		}
+		if d := q.dist(); d > 1e-2 {
+			t.Errorf("%v: expected the parameters to converge. Still %v away", st.name, d)
+		}
+	}
+}
+
+func TestSolvers_Checkpoint(t *testing.T) {
+	a, b := NewAdam(), NewAdam()
+	qa, qb := newQuadratic(1, 2), newQuadratic(1, 2)
+	for i := 0; i < 10; i++ {
+		if err := a.Step([]value.Grader{qa}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := a.State().MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := new(State)
+	if err = s.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if err = b.LoadState(s); err != nil {
+		t.Fatal(err)
+	}
+	copy(qb.w.Float64s(), qa.w.Float64s())
+
+	for i := 0; i < 10; i++ {
+		a.Step([]value.Grader{qa})
+		b.Step([]value.Grader{qb})
+	}
+	for i, w := range qa.w.Float64s() {
+		if w != qb.w.Float64s()[i] {
+			t.Errorf("Expected a restored solver to take the same steps. Got %v and %v", qa.w.Float64s(), qb.w.Float64s())
+			break
+		}
+	}
+}
+
+func TestSolvers_Clip(t *testing.T) {
+	s := NewSGD(WithLearnRate(1), WithClipNorm(1))
+	q := newQuadratic(30, 40)
+	if err := s.Step([]value.Grader{q}); err != nil {
+		t.Fatal(err)
+	}
+	// the gradient (-30, -40) has a norm of 50, so it's scaled down to (-0.6, -0.8)
+	correct := []float64{0.6, 0.8}
+	for i, w := range q.w.Float64s() {
+		if math.Abs(w-correct[i]) > 1e-12 {
+			t.Errorf("Expected %v. Got %v", correct, q.w.Float64s())
+			break
+		}
+	}
+}
+
+// sparseGrader is a (4, 2) parameter whose gradient only has rows 1 and 3, with row 3 appearing twice
+type sparseGrader struct{ w *tensor.Dense }
+
+func (s sparseGrader) Value() value.Value { return s.w }
+
+func (s sparseGrader) Grad() (value.Value, error) {
+	rows := tensor.New(tensor.WithShape(3, 2), tensor.WithBacking([]float64{1, 2, 3, 4, 5, 6}))
+	return value.NewSparseRows(tensor.Shape{4, 2}, []int{3, 1, 3}, rows)
+}
+
+func TestSolvers_Sparse(t *testing.T) {
+	p := sparseGrader{tensor.New(tensor.WithShape(4, 2), tensor.WithBacking(make([]float64, 8)))}
+	s := NewSGD(WithLearnRate(0.1), WithMomentum(0.5))
+	for i := 0; i < 2; i++ {
+		if err := s.Step([]value.Grader{p}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the rows of index 3 add up to (6, 8). The velocity after two steps is 1.5 times the gradient, so each row moves by -0.25g.
+	correct := []float64{0, 0, -0.75, -1, 0, 0, -1.5, -2}
+	for i, v := range p.w.Float64s() {
+		if math.Abs(v-correct[i]) > 1e-12 {
+			t.Errorf("Expected %v. Got %v", correct, p.w.Float64s())
+			break
+		}
+	}
+	if state := s.State(); state.Slots[0][0][0] != 0 || state.Slots[0][0][2] != 4.5 {
+		t.Errorf("Expected only the velocity of the rows with a gradient to move. Got %v", state.Slots[0][0])
+	}
+}
+
+func TestSolvers_BatchSize(t *testing.T) {
+	for _, size := range []float64{0, -2} {
+		for _, s := range []Solver{NewSGD(WithBatchSize(size)), NewAdam(WithBatchSize(size))} {
+			q := newQuadratic(1)
+			if err := s.Step([]value.Grader{q}); err == nil {
+				t.Errorf("Expected a batch size of %v to return an error", size)
+			}
+			if q.w.Float64s()[0] != 0 {
+				t.Errorf("Expected an invalid solver to leave the parameters alone. Got %v", q.w.Float64s())
+			}
+		}
+	}
+}
+
+func TestSolvers_WeightDecay(t *testing.T) {
+	solverTests := []struct {
+		name string
+		s    Solver
+	}{
+		{"SGD", NewSGD(WithLearnRate(0.1), WithWeightDecay(0.5))},
+		{"SGD momentum", NewSGD(WithLearnRate(0.1), WithMomentum(0.9), WithWeightDecay(0.5))},
+		{"SGD nesterov", NewSGD(WithLearnRate(0.1), WithMomentum(0.9), WithNesterov(), WithWeightDecay(0.5))},
+		{"RMSProp", NewRMSProp(WithLearnRate(0.1), WithWeightDecay(0.5))},
+		{"Adagrad", NewAdagrad(WithLearnRate(0.1), WithWeightDecay(0.5))},
+		{"Adadelta", NewAdadelta(WithLearnRate(0.1), WithWeightDecay(0.5))},
+	}
+
+	for _, st := range solverTests {
+		// at its target, the quadratic has no gradient, so only the decay moves the parameters: w ← w(1 - ηλ)
+		q := newQuadratic(2, -4)
+		copy(q.w.Float64s(), q.target)
+		if err := st.s.Step([]value.Grader{q}); err != nil {
+			t.Fatalf("%v: %v", st.name, err)
+		}
+		correct := []float64{1.9, -3.8}
+		for i, w := range q.w.Float64s() {
+			if math.Abs(w-correct[i]) > 1e-12 {
+				t.Errorf("%v: expected the weight decay to shrink the parameters to %v. Got %v", st.name, correct, q.w.Float64s())
+				break
+			}
+		}
+	}
+}
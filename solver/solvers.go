@@ -0,0 +1,192 @@
+package solver
+
+import (
+	"math"
+
+	"gorgonia.org/gorgonia/internal/value"
+)
+
+var (
+	_ Solver = &SGD{}
+	_ Solver = &Adam{}
+	_ Solver = &RMSProp{}
+	_ Solver = &Adagrad{}
+	_ Solver = &Adadelta{}
+	_ Solver = &Lion{}
+
+	_ LearningRater = &SGD{}
+	_ Checkpointer  = &SGD{}
+)
+
+// SGD is the stochastic gradient descent solver, optionally with (Nesterov) momentum.
+//
+// With a momentum μ, a velocity v is kept for each parameter:
+//	v ← μv + g
+//	w ← w - ηv            (or w ← w - η(g + μv) with Nesterov momentum)
+// A weight decay λ adds -ηλw to the update.
+type SGD struct{ base }
+
+// NewSGD creates a new SGD solver. The default learning rate is 0.01, with no momentum.
+func NewSGD(opts ...SolverOpt) *SGD {
+	defaults := config{eta: 0.01, batchSize: 1}
+	s := &SGD{newBase(0, defaults, opts...)}
+	if s.momentum != 0 {
+		s.nSlots = 1
+	}
+	return s
+}
+
+// Step updates the parameters.
+func (s *SGD) Step(params []value.Grader) error {
+	return s.step(params, func(w, g []float64, slots [][]float64) {
+		if s.momentum == 0 {
+			for i := range w {
+				w[i] -= s.eta * (g[i] + s.weightDecay*w[i])
+			}
+			return
+		}
+
+		v := slots[0]
+		for i := range w {
+			v[i] = s.momentum*v[i] + g[i]
+			if s.nesterov {
+				w[i] -= s.eta * (g[i] + s.momentum*v[i] + s.weightDecay*w[i])
+			} else {
+				w[i] -= s.eta * (v[i] + s.weightDecay*w[i])
+			}
+		}
+	})
+}
+
+// Adam is the Adam solver, described in "Adam: A Method for Stochastic Optimization" (Kingma and Ba, 2014) - https://arxiv.org/abs/1412.6980.
+//
+// When created with NewAdamW, the weight decay is decoupled from the gradient, as described in
+// "Decoupled Weight Decay Regularization" (Loshchilov and Hutter, 2017) - https://arxiv.org/abs/1711.05101.
+type Adam struct{ base }
+
+// NewAdam creates a new Adam solver. The defaults are those of the paper: η = 0.001, β1 = 0.9, β2 = 0.999, ε = 1e-8.
+func NewAdam(opts ...SolverOpt) *Adam {
+	defaults := config{eta: 0.001, batchSize: 1, beta1: 0.9, beta2: 0.999, eps: 1e-8}
+	return &Adam{newBase(2, defaults, opts...)}
+}
+
+// NewAdamW creates a new Adam solver with a decoupled weight decay. The default weight decay is 0.01.
+func NewAdamW(opts ...SolverOpt) *Adam {
+	return NewAdam(append([]SolverOpt{WithWeightDecay(0.01)}, opts...)...)
+}
+
+// Step updates the parameters.
+func (s *Adam) Step(params []value.Grader) error {
+	return s.step(params, func(w, g []float64, slots [][]float64) {
+		m, v := slots[0], slots[1]
+		t := float64(s.steps)
+		correction1 := 1 - math.Pow(s.beta1, t)
+		correction2 := 1 - math.Pow(s.beta2, t)
+		for i := range w {
+			m[i] = s.beta1*m[i] + (1-s.beta1)*g[i]
+			v[i] = s.beta2*v[i] + (1-s.beta2)*g[i]*g[i]
+			mHat := m[i] / correction1
+			vHat := v[i] / correction2
+			w[i] -= s.eta * (mHat/(math.Sqrt(vHat)+s.eps) + s.weightDecay*w[i])
+		}
+	})
+}
+
+// RMSProp is the RMSProp solver: the gradients are divided by a moving average of their magnitudes.
+//	c ← ρc + (1-ρ)g²
+//	w ← w - ηg/(√c + ε)
+type RMSProp struct{ base }
+
+// NewRMSProp creates a new RMSProp solver. The defaults are η = 0.001, ρ = 0.9, ε = 1e-8.
+func NewRMSProp(opts ...SolverOpt) *RMSProp {
+	defaults := config{eta: 0.001, batchSize: 1, rho: 0.9, eps: 1e-8}
+	return &RMSProp{newBase(1, defaults, opts...)}
+}
+
+// Step updates the parameters.
+func (s *RMSProp) Step(params []value.Grader) error {
+	return s.step(params, func(w, g []float64, slots [][]float64) {
+		c := slots[0]
+		for i := range w {
+			c[i] = s.rho*c[i] + (1-s.rho)*g[i]*g[i]
+			w[i] -= s.eta * (g[i]/(math.Sqrt(c[i])+s.eps) + s.weightDecay*w[i])
+		}
+	})
+}
+
+// Adagrad is the Adagrad solver: the gradients are divided by the root of the sum of all the past squared gradients.
+//	c ← c + g²
+//	w ← w - ηg/(√c + ε)
+type Adagrad struct{ base }
+
+// NewAdagrad creates a new Adagrad solver. The defaults are η = 0.01, ε = 1e-8.
+func NewAdagrad(opts ...SolverOpt) *Adagrad {
+	defaults := config{eta: 0.01, batchSize: 1, eps: 1e-8}
+	return &Adagrad{newBase(1, defaults, opts...)}
+}
+
+// Step updates the parameters.
+func (s *Adagrad) Step(params []value.Grader) error {
+	return s.step(params, func(w, g []float64, slots [][]float64) {
+		c := slots[0]
+		for i := range w {
+			c[i] += g[i] * g[i]
+			w[i] -= s.eta * (g[i]/(math.Sqrt(c[i])+s.eps) + s.weightDecay*w[i])
+		}
+	})
+}
+
+// Adadelta is the solver described in "ADADELTA: An Adaptive Learning Rate Method" (Zeiler, 2012) - https://arxiv.org/abs/1212.5701.
+// It keeps moving averages of both the squared gradients and the squared updates.
+type Adadelta struct{ base }
+
+// NewAdadelta creates a new Adadelta solver. The defaults are η = 1, ρ = 0.9, ε = 1e-6.
+func NewAdadelta(opts ...SolverOpt) *Adadelta {
+	defaults := config{eta: 1, batchSize: 1, rho: 0.9, eps: 1e-6}
+	return &Adadelta{newBase(2, defaults, opts...)}
+}
+
+// Step updates the parameters.
+func (s *Adadelta) Step(params []value.Grader) error {
+	return s.step(params, func(w, g []float64, slots [][]float64) {
+		sqGrad, sqDelta := slots[0], slots[1]
+		for i := range w {
+			sqGrad[i] = s.rho*sqGrad[i] + (1-s.rho)*g[i]*g[i]
+			delta := math.Sqrt(sqDelta[i]+s.eps) / math.Sqrt(sqGrad[i]+s.eps) * g[i]
+			sqDelta[i] = s.rho*sqDelta[i] + (1-s.rho)*delta*delta
+			w[i] -= s.eta * (delta + s.weightDecay*w[i])
+		}
+	})
+}
+
+// Lion is the solver described in "Symbolic Discovery of Optimization Algorithms" (Chen et al., 2023) - https://arxiv.org/abs/2302.06675.
+// Only the sign of the update is used, so every element of a parameter moves by the same amount.
+//	c ← β1m + (1-β1)g
+//	w ← w - η(sign(c) + λw)
+//	m ← β2m + (1-β2)g
+type Lion struct{ base }
+
+// NewLion creates a new Lion solver. The defaults are η = 1e-4, β1 = 0.9, β2 = 0.99, with no weight decay.
+func NewLion(opts ...SolverOpt) *Lion {
+	defaults := config{eta: 1e-4, batchSize: 1, beta1: 0.9, beta2: 0.99}
+	return &Lion{newBase(1, defaults, opts...)}
+}
+
+// Step updates the parameters.
+func (s *Lion) Step(params []value.Grader) error {
+	return s.step(params, func(w, g []float64, slots [][]float64) {
+		m := slots[0]
+		for i := range w {
+			c := s.beta1*m[i] + (1-s.beta1)*g[i]
+			var sign float64
+			switch {
+			case c > 0:
+				sign = 1
+			case c < 0:
+				sign = -1
+			}
+			w[i] -= s.eta * (sign + s.weightDecay*w[i])
+			m[i] = s.beta2*m[i] + (1-s.beta2)*g[i]
+		}
+	})
+}